@@ -1,13 +1,14 @@
 package account
 
 import (
+	"crypto/ecdsa"
 	"encoding/base64"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"github.com/ethereum/go-ethereum/accounts"
 	"github.com/ethereum/go-ethereum/accounts/keystore"
 	"github.com/glvd/accipfs/config"
-	"github.com/goextension/tool"
 	"io/ioutil"
 	"os"
 	"path/filepath"
@@ -16,28 +17,41 @@ import (
 
 var accountName = "account.json"
 
+// scrypt parameters of the keystore encryption
+var scryptN, scryptP = keystore.StandardScryptN, keystore.StandardScryptP
+
 // Identity ...
 type Identity struct {
 	PeerID  string
 	PrivKey string
 }
 
+// ErrEmptyPassphrase ...
+var ErrEmptyPassphrase = errors.New("empty passphrase")
+
 // Account ...
 type Account struct {
 	Name     string
 	Address  string
 	KeyStore KeyStore
 	Identity Identity //todo: not added on init
+	legacy   string   //cleartext password of the old config, only used for migration
+}
+
+// legacyAccount is the account stored by the old config with the cleartext password
+type legacyAccount struct {
+	Account
 	Password string
 }
 
-// NewAccount ...
-func NewAccount(cfg *config.Config) (*Account, error) {
+// NewAccount create a keystore account encrypted with the passphrase, the passphrase is never stored
+func NewAccount(cfg *config.Config, passphrase string) (*Account, error) {
+	if passphrase == "" {
+		return nil, ErrEmptyPassphrase
+	}
 	var acc Account
-	acc.Password = tool.GenerateRandomString(8)
-
-	ks := keystore.NewKeyStore(config.KeyStoreDirETH(), keystore.StandardScryptN, keystore.StandardScryptP)
-	account, err := ks.NewAccount(acc.Password)
+	ks := keystore.NewKeyStore(config.KeyStoreDirETH(), scryptN, scryptP)
+	account, err := ks.NewAccount(passphrase)
 	if err != nil {
 		return nil, err
 	}
@@ -51,32 +65,72 @@ func NewAccount(cfg *config.Config) (*Account, error) {
 	return &acc, nil
 }
 
-// LoadAccount ...
+// LoadAccount returns the default account
 func LoadAccount(cfg *config.Config) (*Account, error) {
+	if cfg.DefaultAccount != "" {
+		if v, b := cfg.Accounts[cfg.DefaultAccount]; b {
+			return decodeAccount(v)
+		}
+	}
 	if cfg.Account == "" {
 		return nil, fmt.Errorf("nil account")
 	}
-	r := strings.NewReader(cfg.Account)
+	return decodeAccount(cfg.Account)
+}
+
+func decodeAccount(v string) (*Account, error) {
+	r := strings.NewReader(v)
 	dec := base64.NewDecoder(base64.StdEncoding, r)
 	target, err := ioutil.ReadAll(dec)
 	if err != nil {
 		return nil, err
 	}
-	var acc Account
+	var acc legacyAccount
 	err = json.Unmarshal(target, &acc)
 	if err != nil {
 		return nil, err
 	}
-	return &acc, nil
+	if acc.Password != "" {
+		log.Warnw("account password is stored in the config, run 'account unlock' to remove it", "address", acc.Address)
+		acc.Account.legacy = acc.Password
+	}
+	return &acc.Account, nil
 }
 
-func saveAccountToConfig(cfg *config.Config, account *Account) error {
+func encodeAccount(account *Account) (string, error) {
 	bytes, err := json.Marshal(account)
+	if err != nil {
+		return "", err
+	}
+	return base64.StdEncoding.EncodeToString(bytes), nil
+}
+
+// saveAccountToConfig stores the account with its name, the first saved account becomes the default one
+func saveAccountToConfig(cfg *config.Config, account *Account) error {
+	acc, err := encodeAccount(account)
 	if err != nil {
 		return err
 	}
-	acc := base64.StdEncoding.EncodeToString(bytes)
-	cfg.Account = acc
+	if cfg.Accounts == nil {
+		cfg.Accounts = make(map[string]string)
+	}
+	if len(cfg.Accounts) == 0 && cfg.Account != "" && cfg.DefaultAccount == "" {
+		//keep the account of the old config as the default one, without its cleartext password
+		old, err := decodeAccount(cfg.Account)
+		if err != nil {
+			return err
+		}
+		if cfg.Account, err = encodeAccount(old); err != nil {
+			return err
+		}
+		cfg.Accounts[old.Name] = cfg.Account
+		cfg.DefaultAccount = old.Name
+	}
+	cfg.Accounts[account.Name] = acc
+	if cfg.DefaultAccount == "" || cfg.DefaultAccount == account.Name {
+		cfg.DefaultAccount = account.Name
+		cfg.Account = acc
+	}
 	return config.SaveConfig(cfg)
 }
 
@@ -85,19 +139,84 @@ func (acc *Account) Check() error {
 	path := filepath.Join(config.KeyStoreDirETH(), acc.Address)
 	_, e := os.Stat(path)
 	if e != nil && os.IsNotExist(e) {
-		bytes, e := json.Marshal(acc.KeyStore)
-		if e != nil {
-			return e
-		}
-		e = ioutil.WriteFile(path, bytes, 0755)
-		if e != nil {
-			return e
-		}
-		return nil
+		return acc.writeKeyStore(path)
 	}
 	return nil
 }
 
+func (acc *Account) writeKeyStore(path string) error {
+	bytes, e := json.Marshal(acc.KeyStore)
+	if e != nil {
+		return e
+	}
+	return ioutil.WriteFile(path, bytes, 0600)
+}
+
+// Legacy returns true when the account was loaded with a cleartext password
+func (acc *Account) Legacy() bool {
+	return acc.legacy != ""
+}
+
+// Decrypt returns the private key of the keystore
+func (acc *Account) Decrypt(passphrase string) (*ecdsa.PrivateKey, error) {
+	bytes, err := json.Marshal(acc.KeyStore)
+	if err != nil {
+		return nil, err
+	}
+	key, err := keystore.DecryptKey(bytes, passphrase)
+	if err != nil {
+		return nil, err
+	}
+	return key.PrivateKey, nil
+}
+
+// Reencrypt encrypt the keystore with the new passphrase
+func (acc *Account) Reencrypt(oldPass, newPass string) error {
+	if newPass == "" {
+		return ErrEmptyPassphrase
+	}
+	bytes, err := json.Marshal(acc.KeyStore)
+	if err != nil {
+		return err
+	}
+	key, err := keystore.DecryptKey(bytes, oldPass)
+	if err != nil {
+		return err
+	}
+	bytes, err = keystore.EncryptKey(key, newPass, scryptN, scryptP)
+	if err != nil {
+		return err
+	}
+	var ks KeyStore
+	if err := json.Unmarshal(bytes, &ks); err != nil {
+		return err
+	}
+	acc.KeyStore = ks
+	acc.legacy = ""
+	return nil
+}
+
+// ChangePassword encrypt the keystore with the new passphrase and save it to the keystore dir and config,
+// the cleartext password of the old config is removed
+func (acc *Account) ChangePassword(cfg *config.Config, oldPass, newPass string) error {
+	if err := acc.Reencrypt(oldPass, newPass); err != nil {
+		return err
+	}
+	if err := acc.writeKeyStore(filepath.Join(config.KeyStoreDirETH(), acc.Address)); err != nil {
+		return err
+	}
+	return saveAccountToConfig(cfg, acc)
+}
+
+// Migrate removes the cleartext password of the old config from the saved account
+func (acc *Account) Migrate(cfg *config.Config) error {
+	if !acc.Legacy() {
+		return nil
+	}
+	acc.legacy = ""
+	return saveAccountToConfig(cfg, acc)
+}
+
 // SaveNode ...
 func (acc *Account) Save(cfg *config.Config) error {
 	if err := acc.Check(); err != nil {
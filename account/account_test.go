@@ -13,7 +13,7 @@ func TestNewAccount(t *testing.T) {
 	}
 	cfg := config.Global()
 
-	acc, err := NewAccount(&cfg)
+	acc, err := NewAccount(&cfg, "password")
 	if err != nil {
 		t.Fatal(err)
 		return
@@ -0,0 +1,9 @@
+package account
+
+import (
+	alog "github.com/glvd/accipfs/log"
+)
+
+const module = "account"
+
+var log = alog.Module(module)
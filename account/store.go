@@ -0,0 +1,165 @@
+package account
+
+import (
+	"encoding/json"
+	"errors"
+	"sort"
+	"strings"
+
+	"github.com/ethereum/go-ethereum/accounts"
+	"github.com/ethereum/go-ethereum/accounts/keystore"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/glvd/accipfs/config"
+)
+
+// ErrAccountNotFound ...
+var ErrAccountNotFound = errors.New("account not found")
+
+// ErrAccountExists ...
+var ErrAccountExists = errors.New("account already exists")
+
+// Import imports a keystore json encrypted with the passphrase as a named account,
+// the name is the account address when empty
+func Import(cfg *config.Config, name string, keyJSON []byte, passphrase string) (*Account, error) {
+	if passphrase == "" {
+		return nil, ErrEmptyPassphrase
+	}
+	var key KeyStore
+	if err := json.Unmarshal(keyJSON, &key); err != nil {
+		return nil, err
+	}
+	if exists(cfg, key.Address) {
+		return nil, ErrAccountExists
+	}
+	ks := keystore.NewKeyStore(config.KeyStoreDirETH(), scryptN, scryptP)
+	act, err := ks.Import(keyJSON, passphrase, passphrase)
+	if err != nil {
+		return nil, err
+	}
+	return addAccount(cfg, name, &act)
+}
+
+// ImportKey imports a hex private key as a named account encrypted with the passphrase,
+// the name is the account address when empty
+func ImportKey(cfg *config.Config, name string, hexKey string, passphrase string) (*Account, error) {
+	if passphrase == "" {
+		return nil, ErrEmptyPassphrase
+	}
+	key, err := crypto.HexToECDSA(strings.TrimPrefix(hexKey, "0x"))
+	if err != nil {
+		return nil, err
+	}
+	if exists(cfg, crypto.PubkeyToAddress(key.PublicKey).Hex()) {
+		return nil, ErrAccountExists
+	}
+	ks := keystore.NewKeyStore(config.KeyStoreDirETH(), scryptN, scryptP)
+	act, err := ks.ImportECDSA(key, passphrase)
+	if err != nil {
+		return nil, err
+	}
+	return addAccount(cfg, name, &act)
+}
+
+// exists returns true when an account of the address is in the config
+func exists(cfg *config.Config, address string) bool {
+	accs, err := List(cfg)
+	if err != nil {
+		return false
+	}
+	for _, acc := range accs {
+		if common.HexToAddress(acc.KeyStore.Address) == common.HexToAddress(address) {
+			return true
+		}
+	}
+	return false
+}
+
+func addAccount(cfg *config.Config, name string, act *accounts.Account) (*Account, error) {
+	var acc Account
+	if err := acc.loadKey(act); err != nil {
+		return nil, err
+	}
+	acc.getName(act)
+	if name != "" {
+		acc.Name = name
+	}
+	if _, b := cfg.Accounts[acc.Name]; b {
+		return nil, ErrAccountExists
+	}
+	if err := saveAccountToConfig(cfg, &acc); err != nil {
+		return nil, err
+	}
+	return &acc, nil
+}
+
+// Export returns the keystore json of the account
+func (acc *Account) Export() ([]byte, error) {
+	return json.Marshal(acc.KeyStore)
+}
+
+// Load returns the named account
+func Load(cfg *config.Config, name string) (*Account, error) {
+	v, b := cfg.Accounts[name]
+	if !b {
+		if name == cfg.DefaultAccount || name == "" {
+			return LoadAccount(cfg)
+		}
+		return nil, ErrAccountNotFound
+	}
+	return decodeAccount(v)
+}
+
+// Find returns the account of the address, the default account is returned when the address is empty
+func Find(cfg *config.Config, address string) (*Account, error) {
+	if address == "" {
+		return LoadAccount(cfg)
+	}
+	if !common.IsHexAddress(address) {
+		return nil, ErrAccountNotFound
+	}
+	accs, err := List(cfg)
+	if err != nil {
+		return nil, err
+	}
+	for _, acc := range accs {
+		if common.HexToAddress(acc.KeyStore.Address) == common.HexToAddress(address) {
+			return acc, nil
+		}
+	}
+	return nil, ErrAccountNotFound
+}
+
+// List returns all the accounts sorted by name, the account of the old config is listed when it has no name entry
+func List(cfg *config.Config) ([]*Account, error) {
+	var accs []*Account
+	for _, v := range cfg.Accounts {
+		acc, err := decodeAccount(v)
+		if err != nil {
+			return nil, err
+		}
+		accs = append(accs, acc)
+	}
+	if len(cfg.Accounts) == 0 && cfg.Account != "" {
+		acc, err := decodeAccount(cfg.Account)
+		if err != nil {
+			return nil, err
+		}
+		accs = append(accs, acc)
+	}
+	sort.Slice(accs, func(i, j int) bool {
+		return accs[i].Name < accs[j].Name
+	})
+	return accs, nil
+}
+
+// Select sets the named account as the default account used by the contract transactions
+func Select(cfg *config.Config, name string) error {
+	v, b := cfg.Accounts[name]
+	if !b {
+		return ErrAccountNotFound
+	}
+	cfg.DefaultAccount = name
+	cfg.Account = v
+	return config.SaveConfig(cfg)
+}
@@ -0,0 +1,124 @@
+package account
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"io/ioutil"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/accounts/keystore"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/glvd/accipfs/config"
+)
+
+func testConfig(t *testing.T) (*config.Config, func()) {
+	dir, err := ioutil.TempDir("", "account")
+	if err != nil {
+		t.Fatal(err)
+	}
+	workDir, n, p := config.WorkDir, scryptN, scryptP
+	clean := func() {
+		config.WorkDir, scryptN, scryptP = workDir, n, p
+		os.RemoveAll(dir)
+	}
+	config.WorkDir = dir
+	cfg := config.Default()
+	cfg.Path = dir
+	if err := config.SaveConfig(cfg); err != nil {
+		clean()
+		t.Fatal(err)
+	}
+	scryptN, scryptP = keystore.LightScryptN, keystore.LightScryptP
+	return cfg, clean
+}
+
+func TestImport(t *testing.T) {
+	cfg, clean := testConfig(t)
+	defer clean()
+
+	key, err := crypto.GenerateKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	first, err := ImportKey(cfg, "first", hex.EncodeToString(crypto.FromECDSA(key)), "secret")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cfg.DefaultAccount != "first" {
+		t.Fatalf("first account should be the default one, got %s", cfg.DefaultAccount)
+	}
+	if _, err := ImportKey(cfg, "again", hex.EncodeToString(crypto.FromECDSA(key)), "secret"); err != ErrAccountExists {
+		t.Fatalf("import the same key should return ErrAccountExists, got %v", err)
+	}
+
+	other := keystore.NewKeyForDirectICAP(rand.Reader)
+	keyJSON, err := keystore.EncryptKey(other, "other", keystore.LightScryptN, keystore.LightScryptP)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := Import(cfg, "second", keyJSON, "wrong"); err == nil {
+		t.Fatal("import with wrong passphrase should fail")
+	}
+	second, err := Import(cfg, "", keyJSON, "other")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if second.Name != "0x"+hex.EncodeToString(other.Address.Bytes()) {
+		t.Fatalf("wrong default name %s", second.Name)
+	}
+
+	accs, err := List(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(accs) != 2 {
+		t.Fatalf("want 2 accounts, got %d", len(accs))
+	}
+	if err := Select(cfg, "missing"); err != ErrAccountNotFound {
+		t.Fatalf("select a missing account should return ErrAccountNotFound, got %v", err)
+	}
+	if err := Select(cfg, second.Name); err != nil {
+		t.Fatal(err)
+	}
+	def, err := LoadAccount(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if def.Name != second.Name {
+		t.Fatalf("default account should be %s, got %s", second.Name, def.Name)
+	}
+	found, err := Find(cfg, strings.ToLower(crypto.PubkeyToAddress(key.PublicKey).Hex()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if found.Name != first.Name {
+		t.Fatalf("find should return %s, got %s", first.Name, found.Name)
+	}
+	found, err = Find(cfg, strings.TrimPrefix(other.Address.Hex(), "0x"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if found.Name != second.Name {
+		t.Fatalf("find should return %s, got %s", second.Name, found.Name)
+	}
+	if _, err := Find(cfg, first.Address); err != ErrAccountNotFound {
+		t.Fatalf("find by the keystore file name should return ErrAccountNotFound, got %v", err)
+	}
+	if _, err := Find(cfg, "0x00"); err != ErrAccountNotFound {
+		t.Fatalf("find a missing address should return ErrAccountNotFound, got %v", err)
+	}
+
+	exported, err := first.Export()
+	if err != nil {
+		t.Fatal(err)
+	}
+	loaded, err := keystore.DecryptKey(exported, "secret")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if loaded.Address != crypto.PubkeyToAddress(key.PublicKey) {
+		t.Fatal("exported keystore has the wrong key")
+	}
+}
@@ -0,0 +1,111 @@
+package account
+
+import (
+	"crypto/ecdsa"
+	"errors"
+	"os"
+	"strings"
+
+	"github.com/zalando/go-keyring"
+)
+
+// EnvPassphrase is the env of the account passphrase
+const EnvPassphrase = "ACCIPFS_ACCOUNT_PASSPHRASE"
+
+// keyringService is the service name of the passphrases in the keyring
+const keyringService = "accipfs"
+
+// ErrLocked ...
+var ErrLocked = errors.New("account is locked")
+
+// Keyring stores the passphrases, it has the same shape as the os keyring libraries
+type Keyring interface {
+	Set(service, user, password string) error
+	Get(service, user string) (string, error)
+	Delete(service, user string) error
+}
+
+// osKeyring keeps the passphrases in the keyring of the os, the keychain on macos,
+// the credential manager on windows and the secret service on linux
+type osKeyring struct {
+}
+
+// DefaultKeyring returns the os keyring, the passphrase is never written to the disk by accipfs
+func DefaultKeyring() Keyring {
+	return osKeyring{}
+}
+
+// Set ...
+func (osKeyring) Set(service, user, password string) error {
+	return keyring.Set(service, strings.ToLower(user), password)
+}
+
+// Get ...
+func (osKeyring) Get(service, user string) (string, error) {
+	v, err := keyring.Get(service, strings.ToLower(user))
+	if err == keyring.ErrNotFound {
+		return "", ErrLocked
+	}
+	return v, err
+}
+
+// Delete ...
+func (osKeyring) Delete(service, user string) error {
+	err := keyring.Delete(service, strings.ToLower(user))
+	if err == keyring.ErrNotFound {
+		return nil
+	}
+	return err
+}
+
+// Passphrase returns the passphrase of the account from the env, the keyring, the legacy config or the prompt in order,
+// ErrLocked is returned when all of them are unavailable
+func (acc *Account) Passphrase(ring Keyring, prompt func() (string, error)) (string, error) {
+	if v := os.Getenv(EnvPassphrase); v != "" {
+		return v, nil
+	}
+	if ring != nil {
+		v, err := ring.Get(keyringService, acc.Address)
+		if err == nil && v != "" {
+			return v, nil
+		}
+		if err != nil && err != ErrLocked {
+			log.Warnw("read keyring", "err", err)
+		}
+	}
+	if acc.legacy != "" {
+		return acc.legacy, nil
+	}
+	if prompt != nil {
+		return prompt()
+	}
+	return "", ErrLocked
+}
+
+// Unlock checks the passphrase and keeps it in the keyring
+func (acc *Account) Unlock(ring Keyring, passphrase string) error {
+	if _, err := acc.Decrypt(passphrase); err != nil {
+		return err
+	}
+	return ring.Set(keyringService, acc.Address, passphrase)
+}
+
+// Unlocked returns true when the passphrase is kept in the keyring
+func (acc *Account) Unlocked(ring Keyring) bool {
+	_, err := ring.Get(keyringService, acc.Address)
+	return err == nil
+}
+
+// Lock removes the passphrase from the keyring
+func (acc *Account) Lock(ring Keyring) error {
+	return ring.Delete(keyringService, acc.Address)
+}
+
+// PrivateKey decrypts the keystore with the passphrase found by Passphrase
+func (acc *Account) PrivateKey(ring Keyring, prompt func() (string, error)) (*ecdsa.PrivateKey, error) {
+	pass, err := acc.Passphrase(ring, prompt)
+	if err != nil {
+		return nil, err
+	}
+	return acc.Decrypt(pass)
+}
@@ -0,0 +1,174 @@
+package account
+
+import (
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"os"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/accounts/keystore"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/glvd/accipfs/config"
+)
+
+func testAccount(t *testing.T, pass string) *Account {
+	key := keystore.NewKeyForDirectICAP(rand.Reader)
+	bytes, err := keystore.EncryptKey(key, pass, keystore.LightScryptN, keystore.LightScryptP)
+	if err != nil {
+		t.Fatal(err)
+	}
+	acc := &Account{}
+	if err := json.Unmarshal(bytes, &acc.KeyStore); err != nil {
+		t.Fatal(err)
+	}
+	acc.Address = acc.KeyStore.Address
+	return acc
+}
+
+// testKeyring keeps the passphrases in memory like the os keyring
+type testKeyring map[string]string
+
+func (k testKeyring) Set(service, user, password string) error {
+	k[service+"/"+user] = password
+	return nil
+}
+
+func (k testKeyring) Get(service, user string) (string, error) {
+	v, ok := k[service+"/"+user]
+	if !ok {
+		return "", ErrLocked
+	}
+	return v, nil
+}
+
+func (k testKeyring) Delete(service, user string) error {
+	delete(k, service+"/"+user)
+	return nil
+}
+
+func TestAccount_Unlock(t *testing.T) {
+	ring := testKeyring{}
+	acc := testAccount(t, "secret")
+
+	if _, err := acc.Passphrase(ring, nil); err != ErrLocked {
+		t.Fatalf("locked account should return ErrLocked, got %v", err)
+	}
+	if err := acc.Unlock(ring, "wrong"); err == nil {
+		t.Fatal("unlock with wrong passphrase should fail")
+	}
+	if err := acc.Unlock(ring, "secret"); err != nil {
+		t.Fatal(err)
+	}
+	if !acc.Unlocked(ring) {
+		t.Fatal("account should be unlocked")
+	}
+	key, err := acc.PrivateKey(ring, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if crypto.PubkeyToAddress(key.PublicKey) != common.HexToAddress(acc.Address) {
+		t.Fatal("wrong private key")
+	}
+	if err := acc.Lock(ring); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := acc.Passphrase(ring, nil); err != ErrLocked {
+		t.Fatalf("account should be locked again, got %v", err)
+	}
+	os.Setenv(EnvPassphrase, "secret")
+	defer os.Unsetenv(EnvPassphrase)
+	if _, err := acc.PrivateKey(ring, nil); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestAccount_Migrate(t *testing.T) {
+	acc := testAccount(t, "legacy")
+	old := legacyAccount{Account: *acc, Password: "legacy"}
+	bytes, err := json.Marshal(old)
+	if err != nil {
+		t.Fatal(err)
+	}
+	loaded, err := LoadAccount(&config.Config{Account: base64.StdEncoding.EncodeToString(bytes)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !loaded.Legacy() {
+		t.Fatal("legacy password should be loaded")
+	}
+	if _, err := loaded.PrivateKey(nil, nil); err != nil {
+		t.Fatal(err)
+	}
+	if err := loaded.Reencrypt("legacy", "new secret"); err != nil {
+		t.Fatal(err)
+	}
+	if loaded.Legacy() {
+		t.Fatal("legacy password should be removed")
+	}
+	migrated, err := json.Marshal(loaded)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var fields map[string]interface{}
+	if err := json.Unmarshal(migrated, &fields); err != nil {
+		t.Fatal(err)
+	}
+	if _, b := fields["Password"]; b {
+		t.Fatal("password should not be marshaled")
+	}
+	if _, err := loaded.Decrypt("new secret"); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestAccount_MigrateConfig(t *testing.T) {
+	cfg, clean := testConfig(t)
+	defer clean()
+	acc := testAccount(t, "legacy")
+	acc.Name = "legacy"
+	bytes, err := json.Marshal(legacyAccount{Account: *acc, Password: "legacy"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	cfg.Account = base64.StdEncoding.EncodeToString(bytes)
+
+	//the old account is kept without its password when another account is added
+	key, err := crypto.GenerateKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := ImportKey(cfg, "other", hex.EncodeToString(crypto.FromECDSA(key)), "secret"); err != nil {
+		t.Fatal(err)
+	}
+	for _, v := range []string{cfg.Account, cfg.Accounts["legacy"]} {
+		old, err := decodeAccount(v)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if old.Legacy() {
+			t.Fatal("password should be removed from the migrated entry")
+		}
+	}
+
+	//the default account is migrated by the unlock
+	cfg.Accounts = nil
+	cfg.DefaultAccount = ""
+	cfg.Account = base64.StdEncoding.EncodeToString(bytes)
+	loaded, err := LoadAccount(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := loaded.Migrate(cfg); err != nil {
+		t.Fatal(err)
+	}
+	loaded, err = LoadAccount(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if loaded.Legacy() || cfg.DefaultAccount != "legacy" {
+		t.Fatalf("account should be migrated as the default one, got %s", cfg.DefaultAccount)
+	}
+}
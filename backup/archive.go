@@ -0,0 +1,155 @@
+package backup
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"errors"
+	"io"
+	"io/ioutil"
+	"time"
+
+	"golang.org/x/crypto/scrypt"
+)
+
+// magic is the header of the backup archive version 1
+var magic = []byte("ACCIBAK\x01")
+
+const (
+	saltSize = 16
+	keySize  = 32
+	scryptN  = 1 << 15
+	scryptR  = 8
+	scryptP  = 1
+)
+
+// ErrWrongArchive ...
+var ErrWrongArchive = errors.New("wrong backup archive")
+
+// ErrWrongPassphrase ...
+var ErrWrongPassphrase = errors.New("wrong passphrase or broken archive")
+
+// File is an entry of the backup archive
+type File struct {
+	Name string
+	Data []byte
+}
+
+func deriveKey(passphrase string, salt []byte) ([]byte, error) {
+	return scrypt.Key([]byte(passphrase), salt, scryptN, scryptR, scryptP, keySize)
+}
+
+// Write packs the files into a tar.gz and writes it encrypted with the passphrase (scrypt and AES-256-GCM)
+func Write(w io.Writer, passphrase string, files []File) error {
+	var buf bytes.Buffer
+	gz := gzip.NewWriter(&buf)
+	tw := tar.NewWriter(gz)
+	now := time.Now()
+	for _, f := range files {
+		err := tw.WriteHeader(&tar.Header{
+			Name:    f.Name,
+			Mode:    0600,
+			Size:    int64(len(f.Data)),
+			ModTime: now,
+		})
+		if err != nil {
+			return err
+		}
+		if _, err := tw.Write(f.Data); err != nil {
+			return err
+		}
+	}
+	if err := tw.Close(); err != nil {
+		return err
+	}
+	if err := gz.Close(); err != nil {
+		return err
+	}
+
+	salt := make([]byte, saltSize)
+	if _, err := io.ReadFull(rand.Reader, salt); err != nil {
+		return err
+	}
+	key, err := deriveKey(passphrase, salt)
+	if err != nil {
+		return err
+	}
+	aead, err := newAEAD(key)
+	if err != nil {
+		return err
+	}
+	nonce := make([]byte, aead.NonceSize())
+	if _, err := io.ReadFull(rand.Reader, nonce); err != nil {
+		return err
+	}
+	for _, b := range [][]byte{magic, salt, nonce, aead.Seal(nil, nonce, buf.Bytes(), magic)} {
+		if _, err := w.Write(b); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Read decrypts the archive with the passphrase and returns the files
+func Read(r io.Reader, passphrase string) ([]File, error) {
+	data, err := ioutil.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+	if len(data) < len(magic)+saltSize || !bytes.Equal(data[:len(magic)], magic) {
+		return nil, ErrWrongArchive
+	}
+	data = data[len(magic):]
+	key, err := deriveKey(passphrase, data[:saltSize])
+	if err != nil {
+		return nil, err
+	}
+	data = data[saltSize:]
+	aead, err := newAEAD(key)
+	if err != nil {
+		return nil, err
+	}
+	if len(data) < aead.NonceSize() {
+		return nil, ErrWrongArchive
+	}
+	plain, err := aead.Open(nil, data[:aead.NonceSize()], data[aead.NonceSize():], magic)
+	if err != nil {
+		return nil, ErrWrongPassphrase
+	}
+	gz, err := gzip.NewReader(bytes.NewReader(plain))
+	if err != nil {
+		return nil, err
+	}
+	defer gz.Close()
+	var files []File
+	tr := tar.NewReader(gz)
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		b, err := ioutil.ReadAll(tr)
+		if err != nil {
+			return nil, err
+		}
+		files = append(files, File{
+			Name: hdr.Name,
+			Data: b,
+		})
+	}
+	return files, nil
+}
+
+func newAEAD(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
@@ -0,0 +1,222 @@
+package backup
+
+import (
+	"bytes"
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"github.com/glvd/accipfs/basis/kv"
+	"github.com/glvd/accipfs/config"
+	"github.com/glvd/accipfs/datastore"
+)
+
+// maxPendingWrites is the number of pending writes when the node cache is loaded
+const maxPendingWrites = 256
+
+const (
+	configName     = "config.json"
+	ipfsConfigName = "ipfs/config"
+	swarmKeyName   = "ipfs/" + datastore.SwarmKeyFile
+	keyStorePrefix = "keystore/"
+	nodesName      = "cache/nodes.badger"
+)
+
+// PinsFile is the name of the pin list restored into the cache dir, it is pinned by the daemon on start
+const PinsFile = "pins.json"
+
+// Paths are the local paths of the backup entries
+type Paths struct {
+	Config     string
+	IPFSConfig string
+	SwarmKey   string
+	KeyStore   string
+	NodeCache  string
+	Pins       string
+}
+
+// DefaultPaths returns the paths of the loaded config
+func DefaultPaths() Paths {
+	return Paths{
+		Config:     filepath.Join(config.WorkDir, configName),
+		IPFSConfig: filepath.Join(config.DataDirIPFS(), "config"),
+		SwarmKey:   filepath.Join(config.DataDirIPFS(), datastore.SwarmKeyFile),
+		KeyStore:   config.KeyStoreDirETH(),
+		NodeCache:  filepath.Join(config.DataDirCache(), "nodes"),
+		Pins:       filepath.Join(config.DataDirCache(), PinsFile),
+	}
+}
+
+func readFile(name, path string) (File, error) {
+	b, err := ioutil.ReadFile(path)
+	if err != nil {
+		return File{}, err
+	}
+	return File{
+		Name: name,
+		Data: b,
+	}, nil
+}
+
+func readDir(prefix, dir string) ([]File, error) {
+	infos, err := ioutil.ReadDir(dir)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	var files []File
+	for _, info := range infos {
+		if info.IsDir() {
+			continue
+		}
+		f, err := readFile(prefix+info.Name(), filepath.Join(dir, info.Name()))
+		if err != nil {
+			return nil, err
+		}
+		files = append(files, f)
+	}
+	return files, nil
+}
+
+// dumpCache writes a badger backup of the node cache, it fails when the db is held by a running daemon
+func dumpCache(dir string) ([]byte, error) {
+	if _, err := os.Stat(dir); err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	db, err := kv.Open(dir)
+	if err != nil {
+		return nil, err
+	}
+	defer db.Close()
+	var buf bytes.Buffer
+	if _, err := db.Backup(&buf, 0); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// loadCache loads the badger backup into the node cache
+func loadCache(dir string, data []byte) error {
+	db, err := kv.Open(dir)
+	if err != nil {
+		return err
+	}
+	if err := db.Load(bytes.NewReader(data), maxPendingWrites); err != nil {
+		db.Close()
+		return err
+	}
+	return db.Close()
+}
+
+// Collect reads the config, the datastore identity, the swarm key of a private network,
+// the eth keystore and the node cache, the pin list is added when pins is not nil. The node cache is the badger backup made by
+// the running daemon, it is dumped from the local db when cache is nil.
+func Collect(p Paths, pins []string, cache []byte) ([]File, error) {
+	var files []File
+	for name, path := range map[string]string{
+		configName:     p.Config,
+		ipfsConfigName: p.IPFSConfig,
+	} {
+		f, err := readFile(name, path)
+		if err != nil {
+			return nil, err
+		}
+		files = append(files, f)
+	}
+	//the public network has no swarm key
+	key, err := readFile(swarmKeyName, p.SwarmKey)
+	if err == nil {
+		files = append(files, key)
+	} else if !os.IsNotExist(err) {
+		return nil, err
+	}
+	keys, err := readDir(keyStorePrefix, p.KeyStore)
+	if err != nil {
+		return nil, err
+	}
+	files = append(files, keys...)
+	if cache == nil {
+		cache, err = dumpCache(p.NodeCache)
+		if err != nil {
+			return nil, err
+		}
+	}
+	if cache != nil {
+		files = append(files, File{
+			Name: nodesName,
+			Data: cache,
+		})
+	}
+	if pins != nil {
+		b, err := json.Marshal(pins)
+		if err != nil {
+			return nil, err
+		}
+		files = append(files, File{
+			Name: PinsFile,
+			Data: b,
+		})
+	}
+	return files, nil
+}
+
+// Config returns the config file in the archive
+func Config(files []File) (*File, bool) {
+	for i := range files {
+		if files[i].Name == configName {
+			return &files[i], true
+		}
+	}
+	return nil, false
+}
+
+func (p Paths) target(name string) (string, bool) {
+	switch {
+	case name == configName:
+		return p.Config, true
+	case name == ipfsConfigName:
+		return p.IPFSConfig, true
+	case name == swarmKeyName:
+		return p.SwarmKey, true
+	case name == PinsFile:
+		return p.Pins, true
+	case strings.HasPrefix(name, keyStorePrefix):
+		return filepath.Join(p.KeyStore, path.Base(name)), true
+	case name == nodesName:
+		return p.NodeCache, true
+	}
+	return "", false
+}
+
+// Extract writes the files to the paths, the existing files are overwritten and
+// the node cache is loaded into the db
+func Extract(p Paths, files []File) error {
+	for _, f := range files {
+		target, b := p.target(f.Name)
+		if !b {
+			log.Warnw("skip unknown backup entry", "name", f.Name)
+			continue
+		}
+		if f.Name == nodesName {
+			if err := loadCache(target, f.Data); err != nil {
+				return err
+			}
+			continue
+		}
+		if err := os.MkdirAll(filepath.Dir(target), 0700); err != nil {
+			return err
+		}
+		if err := ioutil.WriteFile(target, f.Data, 0600); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,152 @@
+package backup
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/dgraph-io/badger/v2"
+	"github.com/glvd/accipfs/basis/kv"
+)
+
+func testPaths(root string) Paths {
+	return Paths{
+		Config:     filepath.Join(root, "config.json"),
+		IPFSConfig: filepath.Join(root, ".ipfs", "config"),
+		SwarmKey:   filepath.Join(root, ".ipfs", "swarm.key"),
+		KeyStore:   filepath.Join(root, ".eth", "keystore"),
+		NodeCache:  filepath.Join(root, ".cache", "nodes"),
+		Pins:       filepath.Join(root, ".cache", PinsFile),
+	}
+}
+
+func writeTestFile(t *testing.T, path string, data string) {
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		t.Fatal(err)
+	}
+	if err := ioutil.WriteFile(path, []byte(data), 0600); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestBackup_RoundTrip(t *testing.T) {
+	root, err := ioutil.TempDir("", "backup")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(root)
+	src := testPaths(filepath.Join(root, "src"))
+	writeTestFile(t, src.Config, `{"path":"src"}`)
+	writeTestFile(t, src.IPFSConfig, `{"Identity":{"PeerID":"id"}}`)
+	writeTestFile(t, src.SwarmKey, "/key/swarm/psk/1.0.0/\n/base16/\n00")
+	writeTestFile(t, filepath.Join(src.KeyStore, "UTC--key"), `{"address":"00"}`)
+	db, err := kv.Open(src.NodeCache)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = db.Update(func(txn *badger.Txn) error {
+		return txn.Set([]byte("node"), []byte("info"))
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	//the db held by the daemon can not be dumped
+	if _, err := Collect(src, nil, nil); err == nil {
+		t.Fatal("collect a node cache in use")
+	}
+	if err := db.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	files, err := Collect(src, []string{"QmPin"}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var buf bytes.Buffer
+	if err := Write(&buf, "passphrase", files); err != nil {
+		t.Fatal(err)
+	}
+	if bytes.Contains(buf.Bytes(), []byte("PeerID")) || bytes.Contains(buf.Bytes(), []byte("psk")) {
+		t.Fatal("archive is not encrypted")
+	}
+	if _, err := Read(bytes.NewReader(buf.Bytes()), "wrong"); err != ErrWrongPassphrase {
+		t.Fatalf("wrong passphrase should fail, got %v", err)
+	}
+	restored, err := Read(bytes.NewReader(buf.Bytes()), "passphrase")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, b := Config(restored); !b {
+		t.Fatal("config not found in archive")
+	}
+
+	dst := testPaths(filepath.Join(root, "dst"))
+	if err := Extract(dst, restored); err != nil {
+		t.Fatal(err)
+	}
+	for from, to := range map[string]string{
+		src.Config:                              dst.Config,
+		src.IPFSConfig:                          dst.IPFSConfig,
+		src.SwarmKey:                            dst.SwarmKey,
+		filepath.Join(src.KeyStore, "UTC--key"): filepath.Join(dst.KeyStore, "UTC--key"),
+	} {
+		want, _ := ioutil.ReadFile(from)
+		got, err := ioutil.ReadFile(to)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(want, got) {
+			t.Fatalf("restored %s mismatch", to)
+		}
+	}
+	db, err = kv.Open(dst.NodeCache)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	err = db.View(func(txn *badger.Txn) error {
+		item, err := txn.Get([]byte("node"))
+		if err != nil {
+			return err
+		}
+		v, err := item.ValueCopy(nil)
+		if err == nil && string(v) != "info" {
+			t.Fatalf("wrong node cache value %s", v)
+		}
+		return err
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	pins, err := ioutil.ReadFile(dst.Pins)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(pins) != `["QmPin"]` {
+		t.Fatalf("wrong pins %s", pins)
+	}
+}
+
+func TestBackup_NoSwarmKey(t *testing.T) {
+	root, err := ioutil.TempDir("", "backup")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(root)
+	src := testPaths(root)
+	writeTestFile(t, src.Config, `{"path":"src"}`)
+	writeTestFile(t, src.IPFSConfig, `{"Identity":{"PeerID":"id"}}`)
+	//a node in the public network
+	files, err := Collect(src, nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, f := range files {
+		if f.Name == swarmKeyName {
+			t.Fatal("no swarm key should be collected")
+		}
+	}
+}
@@ -0,0 +1,9 @@
+package backup
+
+import (
+	alog "github.com/glvd/accipfs/log"
+)
+
+const module = "backup"
+
+var log = alog.Module(module)
@@ -0,0 +1,77 @@
+package hash
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"fmt"
+	"hash"
+	"strings"
+
+	"golang.org/x/crypto/blake2b"
+	"golang.org/x/crypto/sha3"
+	"lukechampine.com/blake3"
+)
+
+// Algorithm is the hash function of a Hasher
+type Algorithm string
+
+// Algorithms ...
+const (
+	SHA256    Algorithm = "sha256"
+	Blake2b   Algorithm = "blake2b"   //blake2b-256
+	Blake3    Algorithm = "blake3"    //blake3 with 32 bytes output
+	Keccak256 Algorithm = "keccak256" //legacy keccak-256 used by ethereum
+)
+
+// Algorithms lists the supported algorithms
+var Algorithms = []Algorithm{SHA256, Blake2b, Blake3, Keccak256}
+
+// ParseAlgorithm returns the algorithm of the name, the names are not case sensitive
+func ParseAlgorithm(name string) (Algorithm, error) {
+	alg := Algorithm(strings.ToLower(strings.TrimSpace(name)))
+	for _, a := range Algorithms {
+		if a == alg {
+			return alg, nil
+		}
+	}
+	return "", fmt.Errorf("unsupported hash algorithm(%s)", name)
+}
+
+// factory returns the constructor of the hash of the algorithm,
+// sha256 and keccak256 are keyed by hmac, blake2b and blake3 use their own keyed mode
+func factory(alg Algorithm, key []byte) (func() hash.Hash, error) {
+	switch alg {
+	case SHA256:
+		if len(key) == 0 {
+			return sha256.New, nil
+		}
+		return func() hash.Hash {
+			return hmac.New(sha256.New, key)
+		}, nil
+	case Keccak256:
+		if len(key) == 0 {
+			return sha3.NewLegacyKeccak256, nil
+		}
+		return func() hash.Hash {
+			return hmac.New(sha3.NewLegacyKeccak256, key)
+		}, nil
+	case Blake2b:
+		if len(key) > blake2b.Size {
+			return nil, fmt.Errorf("blake2b key is longer than %d bytes", blake2b.Size)
+		}
+		return func() hash.Hash {
+			h, _ := blake2b.New256(key)
+			return h
+		}, nil
+	case Blake3:
+		if len(key) == 0 {
+			key = nil
+		} else if len(key) != 32 {
+			return nil, fmt.Errorf("blake3 key must be 32 bytes")
+		}
+		return func() hash.Hash {
+			return blake3.New(32, key)
+		}, nil
+	}
+	return nil, fmt.Errorf("unsupported hash algorithm(%s)", alg)
+}
@@ -0,0 +1,38 @@
+// Package hash sums the go values with a canonical encoding, so the same
+// value gets the same hash on every node and in every client.
+//
+// A Hasher is made of an algorithm and an optional key, see Algorithm. Sum
+// uses hmac-sha256 keyed by "trfs_hash", it is the digest of the infos and
+// the digest signed by the publishers.
+//
+// # Canonical encoding
+//
+// The value is encoded to a byte stream written to a single hash state, the
+// digest is the hash of the whole stream. Every value starts with a type byte
+// and a little endian uint64 n:
+//
+//   - 0 nil, n is 0. Pointers and interfaces are followed, a nil is the zero
+//     value of the pointed type with ZeroNil.
+//   - 1 int, n is the int64 of any signed int.
+//   - 2 uint, n is the uint64 of any unsigned int.
+//   - 3 bool, n is 0 or 1.
+//   - 4 float, n is the IEEE 754 bits of the float64.
+//   - 5 complex, n is the bits of the real part followed by the 8 bytes of
+//     the imaginary part.
+//   - 6 string, n is the length followed by the bytes of the string.
+//   - 7 list, arrays and slices: n is the count followed by the items.
+//   - 8 set, a slice tagged "set": n is the count followed by the encoded
+//     items sorted by their bytes.
+//   - 9 map, n is the count followed by the entries, an entry is the encoded
+//     key followed by the encoded value, sorted by the bytes of the key.
+//   - 10 struct, n is the count of the fields followed by the fields sorted
+//     by name, a field is the name as a string followed by the value.
+//
+// The name of a field is its json name, the go name is used when the json
+// tag has no name or is "-". The exported fields not tagged "ignore" or "-"
+// are encoded, a field tagged "string" is the string of v.String(). The type
+// names are not part of the encoding and two fields of the same name are an
+// error.
+//
+// The test vectors of the encoding are in vector_test.go.
+package hash
@@ -1,26 +1,33 @@
 package hash
 
 import (
-	"crypto/hmac"
-	"crypto/sha256"
+	"bytes"
 	"encoding/binary"
 	"fmt"
 	"hash"
+	"io"
+	"math"
 	"reflect"
+	"sort"
+	"strings"
 )
 
-// Key ...
+// key is the hmac key of the default hasher
 const key = "trfs_hash"
 
-// DefaultOption ...
-var DefaultOption = &Options{
-	Hash: hmac.New(func() hash.Hash {
-		return sha256.New()
-	}, []byte(key)),
-	TagName: "hash",
-	ZeroNil: false,
+// DefaultOptions returns the options of Sum, hmac-sha256 keyed by "trfs_hash"
+func DefaultOptions() Options {
+	return Options{
+		Algorithm: SHA256,
+		Key:       []byte(key),
+		TagName:   "hash",
+		ZeroNil:   false,
+	}
 }
 
+// defaultHasher is used by Sum
+var defaultHasher = MustNew(DefaultOptions())
+
 // ErrNotStringer is returned when there's an error with hash:"string"
 type ErrNotStringer struct {
 	Field string
@@ -28,9 +35,13 @@ type ErrNotStringer struct {
 
 // Options are options that are available for hashing.
 type Options struct {
-	// Encoder is the hash function to use. If this isn't set, it will
+	// Algorithm is the hash function to use. If this isn't set, it will
 	// default to sha256.
-	Hash hash.Hash
+	Algorithm Algorithm
+
+	// Key makes a keyed hash when it is set. See factory for the keyed
+	// mode of every algorithm.
+	Key []byte
 
 	// TagName is the struct tag to look at when hashing the structure.
 	// By default this is "hash".
@@ -41,8 +52,68 @@ type Options struct {
 	ZeroNil bool
 }
 
+// Hasher sums the values with the canonical encoding, it is safe for
+// concurrent use as every Sum runs on its own hash state.
+type Hasher struct {
+	algorithm Algorithm
+	newHash   func() hash.Hash
+	tag       string
+	zeronil   bool
+}
+
+// New returns the hasher of the options
+func New(opts Options) (*Hasher, error) {
+	if opts.Algorithm == "" {
+		opts.Algorithm = SHA256
+	}
+	if opts.TagName == "" {
+		opts.TagName = "hash"
+	}
+	newHash, err := factory(opts.Algorithm, append([]byte(nil), opts.Key...))
+	if err != nil {
+		return nil, err
+	}
+	return &Hasher{
+		algorithm: opts.Algorithm,
+		newHash:   newHash,
+		tag:       opts.TagName,
+		zeronil:   opts.ZeroNil,
+	}, nil
+}
+
+// MustNew is New but panics on the error
+func MustNew(opts Options) *Hasher {
+	h, err := New(opts)
+	if err != nil {
+		panic(err)
+	}
+	return h
+}
+
+// Algorithm ...
+func (h *Hasher) Algorithm() Algorithm {
+	return h.algorithm
+}
+
+// Size returns the length of the sums in bytes
+func (h *Hasher) Size() int {
+	return h.newHash().Size()
+}
+
+// Sum returns the hash value of an arbitrary value, see the package Sum for the value rules.
+func (h *Hasher) Sum(v interface{}) ([]byte, error) {
+	w := &walker{
+		tag:     h.tag,
+		zeronil: h.zeronil,
+	}
+	s := h.newHash()
+	if err := w.visit(s, reflect.ValueOf(v), nil); err != nil {
+		return nil, err
+	}
+	return s.Sum(nil), nil
+}
+
 type walker struct {
-	h       hash.Hash
 	tag     string
 	zeronil bool
 }
@@ -63,10 +134,8 @@ func (ens *ErrNotStringer) Error() string {
 
 // Sum returns the hash value of an arbitrary value.
 //
-// If opts is nil, then default options will be used. See HashOptions
-// for the default values. The same *HashOptions value cannot be used
-// concurrently. None of the values within a *HashOptions struct are
-// safe to read/write while hashing is being done.
+// The default options are used, see DefaultOptions. It is safe for
+// concurrent use, a Hasher of New sums with the other algorithms.
 //
 // Notes on the value:
 //
@@ -94,20 +163,74 @@ func (ens *ErrNotStringer) Error() string {
 //                field implements fmt.Stringer
 //
 func Sum(v interface{}) ([]byte, error) {
-	// Reset the hash
-	DefaultOption.Hash.Reset()
+	return defaultHasher.Sum(v)
+}
 
-	// Create our walker and walk the structure
-	w := &walker{
-		h:       DefaultOption.Hash,
-		tag:     DefaultOption.TagName,
-		zeronil: DefaultOption.ZeroNil,
+// The type bytes of the canonical encoding, see the package doc
+const (
+	typeNil byte = iota
+	typeInt
+	typeUint
+	typeBool
+	typeFloat
+	typeComplex
+	typeString
+	typeList
+	typeSet
+	typeMap
+	typeStruct
+)
+
+// writeHead writes the type byte and a little endian uint64, the value of a number or the length of the others
+func writeHead(w io.Writer, typ byte, n uint64) error {
+	var b [9]byte
+	b[0] = typ
+	binary.LittleEndian.PutUint64(b[1:], n)
+	_, err := w.Write(b[:])
+	return err
+}
+
+func writeString(w io.Writer, s string) error {
+	if err := writeHead(w, typeString, uint64(len(s))); err != nil {
+		return err
 	}
-	return w.visit(reflect.ValueOf(v), nil)
+	_, err := io.WriteString(w, s)
+	return err
 }
 
-func (w *walker) visit(v reflect.Value, opts *visitOpts) ([]byte, error) {
-	t := reflect.TypeOf(0)
+// writeSorted writes the type byte, the count and the encoded items in the byte order
+func writeSorted(w io.Writer, typ byte, items [][]byte) error {
+	sort.Slice(items, func(i, j int) bool {
+		return bytes.Compare(items[i], items[j]) < 0
+	})
+	if err := writeHead(w, typ, uint64(len(items))); err != nil {
+		return err
+	}
+	for _, item := range items {
+		if _, err := w.Write(item); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// fieldName returns the name of the field in the json, the go name is used without one
+func fieldName(f reflect.StructField) string {
+	name := strings.Split(f.Tag.Get("json"), ",")[0]
+	if name == "" || name == "-" {
+		return f.Name
+	}
+	return name
+}
+
+type field struct {
+	name  string
+	value reflect.Value
+	opts  *visitOpts
+}
+
+func (w *walker) visit(out io.Writer, v reflect.Value, opts *visitOpts) error {
+	var t reflect.Type
 
 	// Loop since these can be wrapped in multiple layers of pointers
 	// and interfaces.
@@ -131,50 +254,67 @@ func (w *walker) visit(v reflect.Value, opts *visitOpts) ([]byte, error) {
 		break
 	}
 
-	// If it is nil, treat it like a zero.
 	if !v.IsValid() {
+		if t == nil {
+			return writeHead(out, typeNil, 0)
+		}
 		v = reflect.Zero(t)
 	}
 
-	// Binary writing can use raw ints, we have to convert to
-	// a sized-int, we'll choose the largest...
-	switch v.Kind() {
-	case reflect.Int:
-		v = reflect.ValueOf(int64(v.Int()))
-	case reflect.Uint:
-		v = reflect.ValueOf(uint64(v.Uint()))
+	switch k := v.Kind(); k {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return writeHead(out, typeInt, uint64(v.Int()))
+
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return writeHead(out, typeUint, v.Uint())
+
 	case reflect.Bool:
-		var tmp int8
+		var n uint64
 		if v.Bool() {
-			tmp = 1
+			n = 1
 		}
-		v = reflect.ValueOf(tmp)
-	}
+		return writeHead(out, typeBool, n)
 
-	k := v.Kind()
+	case reflect.Float32, reflect.Float64:
+		return writeHead(out, typeFloat, math.Float64bits(v.Float()))
 
-	// We can shortcut numeric values by directly binary writing them
-	if k >= reflect.Int && k <= reflect.Complex64 {
-		// A direct hash calculation
-		w.h.Reset()
-		err := binary.Write(w.h, binary.LittleEndian, v.Interface())
-		return w.h.Sum(nil), err
-	}
+	case reflect.Complex64, reflect.Complex128:
+		c := v.Complex()
+		if err := writeHead(out, typeComplex, math.Float64bits(real(c))); err != nil {
+			return err
+		}
+		var b [8]byte
+		binary.LittleEndian.PutUint64(b[:], math.Float64bits(imag(c)))
+		_, err := out.Write(b[:])
+		return err
+
+	case reflect.String:
+		return writeString(out, v.String())
 
-	switch k {
-	case reflect.Array:
-		var h []byte
+	case reflect.Array, reflect.Slice:
+		// A set is encoded in the byte order of the items, so the order of
+		// the items is not part of the hash.
 		l := v.Len()
+		if opts != nil && opts.Flags&visitFlagSet != 0 {
+			items := make([][]byte, 0, l)
+			for i := 0; i < l; i++ {
+				var buf bytes.Buffer
+				if err := w.visit(&buf, v.Index(i), nil); err != nil {
+					return err
+				}
+				items = append(items, buf.Bytes())
+			}
+			return writeSorted(out, typeSet, items)
+		}
+		if err := writeHead(out, typeList, uint64(l)); err != nil {
+			return err
+		}
 		for i := 0; i < l; i++ {
-			current, err := w.visit(v.Index(i), nil)
-			if err != nil {
-				return nil, err
+			if err := w.visit(out, v.Index(i), nil); err != nil {
+				return err
 			}
-
-			h = hashUpdateOrdered(w.h, h, current)
 		}
-
-		return h, nil
+		return nil
 
 	case reflect.Map:
 		var includeMap MapEncoder
@@ -184,36 +324,32 @@ func (w *walker) visit(v reflect.Value, opts *visitOpts) ([]byte, error) {
 			}
 		}
 
-		// Build the hash for the map. We do this by XOR-ing all the key
-		// and value hashes. This makes it deterministic despite ordering.
-		var h []byte
+		// The entries are encoded in the byte order of the encoded keys,
+		// the keys are unique so the order is total.
+		var entries [][]byte
 		for _, k := range v.MapKeys() {
 			v := v.MapIndex(k)
 			if includeMap != nil {
 				incl, err := includeMap.EncodeMap(
 					opts.StructField, k.Interface(), v.Interface())
 				if err != nil {
-					return nil, err
+					return err
 				}
 				if !incl {
 					continue
 				}
 			}
 
-			kh, err := w.visit(k, nil)
-			if err != nil {
-				return nil, err
+			var buf bytes.Buffer
+			if err := w.visit(&buf, k, nil); err != nil {
+				return err
 			}
-			vh, err := w.visit(v, nil)
-			if err != nil {
-				return nil, err
+			if err := w.visit(&buf, v, nil); err != nil {
+				return err
 			}
-
-			fieldHash := hashUpdateOrdered(w.h, kh, vh)
-			h = hashUpdateUnordered(h, fieldHash)
+			entries = append(entries, buf.Bytes())
 		}
-
-		return h, nil
+		return writeSorted(out, typeMap, entries)
 
 	case reflect.Struct:
 		parent := v.Interface()
@@ -223,11 +359,7 @@ func (w *walker) visit(v reflect.Value, opts *visitOpts) ([]byte, error) {
 		}
 
 		t := v.Type()
-		h, err := w.visit(reflect.ValueOf(t.Name()), nil)
-		if err != nil {
-			return nil, err
-		}
-
+		var fields []field
 		l := v.NumField()
 		for i := 0; i < l; i++ {
 			if innerV := v.Field(i); v.CanSet() || t.Field(i).Name != "_" {
@@ -249,7 +381,7 @@ func (w *walker) visit(v reflect.Value, opts *visitOpts) ([]byte, error) {
 					if impl, ok := innerV.Interface().(fmt.Stringer); ok {
 						innerV = reflect.ValueOf(impl.String())
 					} else {
-						return nil, &ErrNotStringer{
+						return &ErrNotStringer{
 							Field: v.Type().Field(i).Name,
 						}
 					}
@@ -259,7 +391,7 @@ func (w *walker) visit(v reflect.Value, opts *visitOpts) ([]byte, error) {
 				if include != nil {
 					incl, err := include.Encode(fieldType.Name, innerV)
 					if err != nil {
-						return nil, err
+						return err
 					}
 					if !incl {
 						continue
@@ -271,96 +403,44 @@ func (w *walker) visit(v reflect.Value, opts *visitOpts) ([]byte, error) {
 					f |= visitFlagSet
 				}
 
-				kh, err := w.visit(reflect.ValueOf(fieldType.Name), nil)
-				if err != nil {
-					return nil, err
-				}
-
-				vh, err := w.visit(innerV, &visitOpts{
-					Flags:       f,
-					Struct:      parent,
-					StructField: fieldType.Name,
+				fields = append(fields, field{
+					name:  fieldName(fieldType),
+					value: innerV,
+					opts: &visitOpts{
+						Flags:       f,
+						Struct:      parent,
+						StructField: fieldType.Name,
+					},
 				})
-				if err != nil {
-					return nil, err
-				}
-
-				fieldHash := hashUpdateOrdered(w.h, kh, vh)
-				h = hashUpdateUnordered(h, fieldHash)
 			}
 		}
 
-		return h, nil
-
-	case reflect.Slice:
-		// We have two behaviors here. If it isn't a set, then we just
-		// visit all the elements. If it is a set, then we do a deterministic
-		// hash code.
-		var h []byte
-		var set bool
-		if opts != nil {
-			set = (opts.Flags & visitFlagSet) != 0
+		// The fields are encoded in the byte order of their names, so the
+		// declaration order is not part of the hash.
+		sort.Slice(fields, func(i, j int) bool {
+			return fields[i].name < fields[j].name
+		})
+		for i := 1; i < len(fields); i++ {
+			if fields[i].name == fields[i-1].name {
+				return fmt.Errorf("hashstructure: %s has two fields named %s", t, fields[i].name)
+			}
 		}
-		l := v.Len()
-		for i := 0; i < l; i++ {
-			current, err := w.visit(v.Index(i), nil)
-			if err != nil {
-				return nil, err
+		if err := writeHead(out, typeStruct, uint64(len(fields))); err != nil {
+			return err
+		}
+		for _, f := range fields {
+			if err := writeString(out, f.name); err != nil {
+				return err
 			}
-
-			if set {
-				h = hashUpdateUnordered(h, current)
-			} else {
-				h = hashUpdateOrdered(w.h, h, current)
+			if err := w.visit(out, f.value, f.opts); err != nil {
+				return err
 			}
 		}
-
-		return h, nil
-
-	case reflect.String:
-		// Directly hash
-		w.h.Reset()
-		_, err := w.h.Write([]byte(v.String()))
-		return w.h.Sum(nil), err
+		return nil
 
 	default:
-		return nil, fmt.Errorf("unknown kind to hash: %s", k)
-	}
-
-}
-
-func hashUpdateOrdered(h hash.Hash, a, b []byte) []byte {
-	// For ordered updates, use a real hash function
-	h.Reset()
-
-	// We just panic if the binary writes fail because we are writing
-	// an int64 which should never be fail-able.
-	e1 := binary.Write(h, binary.LittleEndian, a)
-	e2 := binary.Write(h, binary.LittleEndian, b)
-	if e1 != nil {
-		panic(e1)
-	}
-	if e2 != nil {
-		panic(e2)
-	}
-
-	return h.Sum(nil)
-}
-
-func hashUpdateUnordered(a, b []byte) []byte {
-	if len(a) < len(b) {
-		tmp := b
-		b = a
-		a = tmp
-	}
-	for i := range a {
-		if i >= len(b) {
-			a[i] = a[i] ^ 0
-			continue
-		}
-		a[i] = a[i] ^ b[i]
+		return fmt.Errorf("unknown kind to hash: %s", k)
 	}
-	return a
 }
 
 // visitFlag is used as a bitmask for affecting visit behavior
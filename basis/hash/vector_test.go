@@ -0,0 +1,305 @@
+package hash_test
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"sync"
+	"testing"
+
+	"github.com/glvd/accipfs/basis/hash"
+	"github.com/glvd/accipfs/core"
+)
+
+// vectorKey is the key of the keyed vectors, 32 bytes for blake3
+var vectorKey = []byte("whats the Elvish word for friend")
+
+type sample struct {
+	Name  string
+	Size  int
+	Tags  []string `hash:"set"`
+	Attrs map[string]string
+	Skip  string `hash:"ignore"`
+	On    bool
+}
+
+var vectorValues = []interface{}{
+	"abc",
+	int64(1),
+	[]string{"a", "b"},
+	map[string]string{"a": "1", "b": "2"},
+	sample{Name: "n", Size: 3, Tags: []string{"x", "y"}, Attrs: map[string]string{"k": "v"}, Skip: "s", On: true},
+}
+
+// vectors are the sums of vectorValues
+var vectors = []struct {
+	alg   hash.Algorithm
+	keyed bool
+	sums  []string
+}{
+	{hash.SHA256, false, []string{
+		"1a4969195ba6a041e54bb0bd317d604d3f3cf3ae263c7cc3dcea29e0dab11647",
+		"46f8ec5a439c92e1df8299e1a4432a7ee172d8496b5e33e0a35a7b67163371b5",
+		"fd0995c0911f1133e976ff33531fe778c18a2c399ed1859dc476414b7fd86768",
+		"0e6f1ca62ac2dd4607fa1248bf76c42669981cd0511f1f5b7bf000aa47ed02a8",
+		"d0e387122980a9af8aa76f7b05643341febae0e2a4f62600e524c4d207bdd05f",
+	}},
+	{hash.SHA256, true, []string{
+		"ba8103eb67cd092ec9434d964244d3614c761297e71a89397263ba076f1fa10d",
+		"15dc4ce0ef7b8c6cd509370a16571e6acf4005bffcc0e71b41e456b8be914a19",
+		"6026ba82bc0df9ea1b3fac39ac3c3ee9c7ce629c5ba9416f5c8b43cbd076c20c",
+		"bc28dff6741a846d83e9605efef883e5f3bdc50fa8248f813bb31546cfce6117",
+		"6e7cbd6dcfd91ca3c00771a10a8d6caba64e721d266a7119de6163141ecf3737",
+	}},
+	{hash.Blake2b, false, []string{
+		"cc86282f53fc6c432ec03f2746c01ffaad4427b5b2bc542f4bd62ecf42ad83c1",
+		"ec8480799f6d8317f9ef5a4ac3ee029af8edce731f3da66c75aa254eb2d7aca3",
+		"41e97867246fee873991808e4792b2bdbdcf147c48c3ca06677839edfbc0f46e",
+		"13a0c1167c4924d718f4a3b0eb772721ea3435ac9f989be021ac8a75948d2da5",
+		"bb6daf6277bb2ad207165c9ca92dec7dcd1665a08abc68fb85a854f06623d68b",
+	}},
+	{hash.Blake2b, true, []string{
+		"78f3682d9cd0a7e7781a084e015f0ce18ca64a6e261b2391a95520299542f239",
+		"51239346a777006b5c2f59e3b8fd21cc048e6f34327073d9cad10c28050c9f0f",
+		"25b848ad919e41f133c894a5f01b3c7d50ba0c78a92977b0b1bf2e2d1a5e9893",
+		"fa7a4e17062343a2a0e301dccd50b3c25ab01fa6c69f8e9807bdfe5c3eed0fa9",
+		"ac5b116ea816bad39d527fbb08c95ea29059c35605a82af750f1c1e76ce9e323",
+	}},
+	{hash.Blake3, false, []string{
+		"85e279ddb02aa1200389f764b99a812e5e3c0c8ddc02501e8e3df872c4232400",
+		"89be45b3119620d28d5dacb1dd589b02ee259adf2671d34686445da547a18820",
+		"5257a44003fd89efada892683e61b540ece3e01cb3aeb7be76ef399f92203292",
+		"0a1a1c2785794e8115193fbc70dc5ca4d3b0edcd65e297a9bfd8890d14b1291f",
+		"06ea4f04f7f9f1cba9d399fa30b54120ffe0688f33bb7979798de2a9f1b2ba8d",
+	}},
+	{hash.Blake3, true, []string{
+		"c5c32c6b7df7d7b295439aed150e65492a4ba38c711d915ba4ada4bb48a694d1",
+		"9f915b04ccea26628760b3cdcfe4191e1ccad5f9da799dba527f0a2b6f32743a",
+		"6e60225af8bd4432aab9806d21c8ccb0d89c442d5d4b5ff3b000b72088f4e987",
+		"69794d372c85ebb6248c0ae9307206bd7cf40e4877c13adb37a2b08b2e54b9ae",
+		"32ea4e60bbd5ffad3099dc40bcd0cc88c05b6a236eacd00923b11077164c2ac6",
+	}},
+	{hash.Keccak256, false, []string{
+		"a162f7813b1ea6e69aacd0f55a19556220245f50a02d332dac5cb413f806966b",
+		"7c94b4d802b655cc7722b2eba9afb74feced3834677a42fc7879c9bccc8a8fd7",
+		"ff14387f73d5a960926bbb1ec83d97bb45331bc6e99a48f2a4b7398c5b7b910b",
+		"4b17325922084dd423c3f459ef78d6974afb160a81c6d0eaf3950a1af5e344b7",
+		"40516cf6ab8c4ca3bd7e4e38d51b50dc42e2f7152e87a2107ef0f5e764ad0e90",
+	}},
+	{hash.Keccak256, true, []string{
+		"a2082e200ba7f230e637e3ff6488ba2f608e398de1aab6151db466d4f32934fa",
+		"f6bd2f4f85c1d5d56f6e33afee04d2a74b526123524d44dc9e28f09c0ae0b1f6",
+		"f5b555f541f9b6dfec2b7ac82c50a52d752d640780a47d63572c2b3e4f266881",
+		"a4f63cc3aa5ddea11af05d89f575dd30bd261bf7af87212d2c0fddbbce866333",
+		"baf8519699c496549b4cae1b18dcc704404a18cbf32b8b612a738c2efa97cf4e",
+	}},
+}
+
+func TestVectors(t *testing.T) {
+	for _, vector := range vectors {
+		opts := hash.Options{Algorithm: vector.alg}
+		if vector.keyed {
+			opts.Key = vectorKey
+		}
+		h, err := hash.New(opts)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for i, v := range vectorValues {
+			sum, err := h.Sum(v)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if hex.EncodeToString(sum) != vector.sums[i] {
+				t.Errorf("%s keyed(%v) value %d: got %x want %s", vector.alg, vector.keyed, i, sum, vector.sums[i])
+			}
+		}
+	}
+}
+
+// TestSumVectors keeps the sums of the default hasher, the infos are addressed by them
+func TestSumVectors(t *testing.T) {
+	for _, vector := range []struct {
+		v   interface{}
+		sum string
+	}{
+		{"", "d18166dd367bab74312de11e63307942469e4f1b5551c4db635eac15c24a5df6"},
+		{"abc", "ec9235b2c33254c20b0f040259b45ae53049a175c4a77e587607ed322d23232f"},
+		{1, "4128cd4520402d48957f1a2062f2064ac96466843019d2d0d0313c514a713d52"},
+		{true, "d0b8619bc4835f3e5f162fbe12631a23a4a1e70aaf5b4234ce14e5f49afe2006"},
+		{core.DataInfoV1{
+			RootHash:  "QmRoot",
+			MediaInfo: core.MediaInfo{No: "abc-001", Intro: "intro"},
+			MediaHash: "QmMedia",
+			Version:   core.DataInfoVersion1,
+		}, "00b1bcd841d9f8cf5f70b64f8971b9bb03f8dafd249e865ef55aae25294df2b7"},
+	} {
+		sum, err := hash.Sum(vector.v)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if hex.EncodeToString(sum) != vector.sum {
+			t.Errorf("%#v: got %x want %s", vector.v, sum, vector.sum)
+		}
+	}
+}
+
+func recordA() interface{} {
+	type record struct {
+		A string
+		B int
+	}
+	return record{A: "a", B: 1}
+}
+
+func recordB() interface{} {
+	type record struct {
+		B int
+		A string
+	}
+	return record{A: "a", B: 1}
+}
+
+func TestFieldOrder(t *testing.T) {
+	a, err := hash.Sum(recordA())
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, err := hash.Sum(recordB())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if hex.EncodeToString(a) != hex.EncodeToString(b) {
+		t.Fatalf("field order changed the sum %x != %x", a, b)
+	}
+}
+
+// head is the type byte and the little endian uint64 of the encoding
+func head(typ byte, n uint64) []byte {
+	b := make([]byte, 9)
+	b[0] = typ
+	binary.LittleEndian.PutUint64(b[1:], n)
+	return b
+}
+
+func str(s string) []byte {
+	return append(head(6, uint64(len(s))), s...)
+}
+
+// TestEncoding builds the byte stream of the package doc by hand, as a client in another language does
+func TestEncoding(t *testing.T) {
+	type record struct {
+		Name  string            `json:"name"`
+		Count int               `json:"count,omitempty"`
+		Tags  []string          `json:"tags" hash:"set"`
+		Attrs map[string]uint16 `json:"-"`
+		Ptr   *bool
+		Skip  string `hash:"ignore"`
+	}
+	v := record{Name: "n", Count: -1, Tags: []string{"y", "x"}, Attrs: map[string]uint16{"b": 2, "a": 1}, Skip: "s"}
+
+	var stream bytes.Buffer
+	stream.Write(head(10, 5))
+	stream.Write(str("Attrs"))
+	stream.Write(head(9, 2))
+	stream.Write(str("a"))
+	stream.Write(head(2, 1))
+	stream.Write(str("b"))
+	stream.Write(head(2, 2))
+	stream.Write(str("Ptr"))
+	stream.Write(head(0, 0))
+	stream.Write(str("count"))
+	stream.Write(head(1, ^uint64(0)))
+	stream.Write(str("name"))
+	stream.Write(str("n"))
+	stream.Write(str("tags"))
+	stream.Write(head(8, 2))
+	stream.Write(str("x"))
+	stream.Write(str("y"))
+	want := sha256.Sum256(stream.Bytes())
+
+	h, err := hash.New(hash.Options{Algorithm: hash.SHA256})
+	if err != nil {
+		t.Fatal(err)
+	}
+	sum, err := h.Sum(v)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(sum, want[:]) {
+		t.Fatalf("got %x want %x", sum, want)
+	}
+}
+
+func TestEncodingAmbiguity(t *testing.T) {
+	//the concatenation of the items is not the same list
+	a, _ := hash.Sum([]string{"ab", "c"})
+	b, _ := hash.Sum([]string{"a", "bc"})
+	if bytes.Equal(a, b) {
+		t.Fatal("lists of the same concatenation have the same sum")
+	}
+	//a map is not the struct of the same names
+	m, _ := hash.Sum(map[string]string{"A": "a"})
+	s, _ := hash.Sum(struct{ A string }{A: "a"})
+	if bytes.Equal(m, s) {
+		t.Fatal("map and struct have the same sum")
+	}
+	//two fields of the same name
+	if _, err := hash.Sum(struct {
+		A string `json:"b"`
+		B string
+	}{}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := hash.Sum(struct {
+		A string `json:"B"`
+		B string
+	}{}); err == nil {
+		t.Fatal("fields of the same name hashed")
+	}
+}
+
+func TestConcurrent(t *testing.T) {
+	want, err := hash.Sum(vectorValues[4])
+	if err != nil {
+		t.Fatal(err)
+	}
+	var wg sync.WaitGroup
+	for i := 0; i < 16; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 100; j++ {
+				sum, err := hash.Sum(vectorValues[4])
+				if err != nil || hex.EncodeToString(sum) != hex.EncodeToString(want) {
+					t.Errorf("concurrent sum %x want %x: %v", sum, want, err)
+					return
+				}
+			}
+		}()
+	}
+	wg.Wait()
+}
+
+func TestNew(t *testing.T) {
+	for _, opts := range []hash.Options{
+		{Algorithm: "md5"},
+		{Algorithm: hash.Blake3, Key: []byte("short")},
+		{Algorithm: hash.Blake2b, Key: make([]byte, 65)},
+	} {
+		if _, err := hash.New(opts); err == nil {
+			t.Errorf("options %+v accepted", opts)
+		}
+	}
+	h, err := hash.New(hash.Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if h.Algorithm() != hash.SHA256 || h.Size() != 32 {
+		t.Fatalf("default hasher %s %d", h.Algorithm(), h.Size())
+	}
+	if alg, err := hash.ParseAlgorithm(" Keccak256 "); err != nil || alg != hash.Keccak256 {
+		t.Fatalf("parse algorithm %s %v", alg, err)
+	}
+}
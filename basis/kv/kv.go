@@ -0,0 +1,28 @@
+package kv
+
+import (
+	"os"
+
+	"github.com/dgraph-io/badger/v2"
+	"github.com/dgraph-io/badger/v2/options"
+)
+
+// Options returns the badger options of the local stores in path
+func Options(path string) badger.Options {
+	opts := badger.DefaultOptions(path)
+	opts.CompactL0OnClose = false
+	opts.Truncate = true
+	opts.ValueLogLoadingMode = options.FileIO
+	opts.TableLoadingMode = options.MemoryMap
+	opts.MaxTableSize = 16 << 20
+	opts.Logger = nil
+	return opts
+}
+
+// Open opens the badger db in path with Options, the directory is made when it is absent
+func Open(path string) (*badger.DB, error) {
+	if err := os.MkdirAll(path, 0755); err != nil {
+		return nil, err
+	}
+	return badger.Open(Options(path))
+}
@@ -0,0 +1,266 @@
+package catalog
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"testing"
+
+	"github.com/glvd/accipfs/core"
+	blocks "github.com/ipfs/go-block-format"
+	"github.com/ipfs/go-cid"
+	format "github.com/ipfs/go-ipld-format"
+)
+
+// mapDAG keeps the raw blocks and decodes them like the datastore does
+type mapDAG map[cid.Cid][]byte
+
+func (d mapDAG) Get(ctx context.Context, c cid.Cid) (format.Node, error) {
+	raw, ok := d[c]
+	if !ok {
+		return nil, errors.New("block not found")
+	}
+	block, err := blocks.NewBlockWithCid(raw, c)
+	if err != nil {
+		return nil, err
+	}
+	return format.Decode(block)
+}
+
+func (d mapDAG) Add(ctx context.Context, n format.Node) error {
+	d[n.Cid()] = n.RawData()
+	return nil
+}
+
+const testHash = "QmPZ9gcCEpqKTo6aq61g2nXGUhM4iCL3ewB6LDXZCtioEB"
+
+func testInfos() []*core.DataInfoV1 {
+	var infos []*core.DataInfoV1
+	for _, no := range []string{"ABP-001", "ABP-002", "SSNI-100", "X"} {
+		infos = append(infos, &core.DataInfoV1{
+			RootHash:  testHash,
+			MediaInfo: core.MediaInfo{No: no, Intro: "intro of " + no},
+			MediaHash: "bafyreigh2akiscaildcqabsyg3dfr6chu3fgpregiymsck7e7aqa4s52zy",
+			Info:      core.Info{ThumbHash: "wrong hash"},
+			Version:   core.DataInfoVersion1,
+		})
+	}
+	return infos
+}
+
+func TestExportImport(t *testing.T) {
+	for _, codec := range []string{CodecCBOR, CodecJSON} {
+		t.Run(codec, func(t *testing.T) {
+			dag := make(mapDAG)
+			root, err := Export(context.Background(), dag, testInfos(), ExportOptions{Codec: codec})
+			if err != nil {
+				t.Fatal(err)
+			}
+			again, err := Export(context.Background(), make(mapDAG), testInfos(), ExportOptions{Codec: codec})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !root.Equals(again) {
+				t.Fatalf("export is not deterministic %s != %s", root, again)
+			}
+			//1 root, 3 shards(ab, ss, x_) and 4 records
+			if len(dag) != 8 {
+				t.Fatalf("want 8 blocks, got %d", len(dag))
+			}
+			n, err := dag.Get(context.Background(), root)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(n.Links()) != 3 {
+				t.Fatalf("root should link 3 shards, got %d", len(n.Links()))
+			}
+			imported, err := Import(context.Background(), dag, root)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(imported.Records) != 4 || len(imported.Invalid) != 0 {
+				t.Fatalf("want 4 records, got %d invalid %v", len(imported.Records), imported.Invalid)
+			}
+			if imported.Records[0].MediaInfo.No != "ABP-001" || imported.Records[3].MediaInfo.Intro != "intro of X" {
+				t.Fatalf("wrong records %+v", imported.Records)
+			}
+			if len(imported.Refs) != 2 {
+				t.Fatalf("want 2 refs, got %v", imported.Refs)
+			}
+		})
+	}
+}
+
+func TestExport_SplitShard(t *testing.T) {
+	const maxSize = 1 << 10
+	var infos []*core.DataInfoV1
+	for i := 0; i < 200; i++ {
+		infos = append(infos, &core.DataInfoV1{
+			RootHash:  testHash,
+			MediaInfo: core.MediaInfo{No: fmt.Sprintf("ABP-%03d", i)},
+			Version:   core.DataInfoVersion1,
+		})
+	}
+	//the numbers padded to the same prefix stay in one shard
+	for _, no := range []string{"ab", "AB"} {
+		infos = append(infos, &core.DataInfoV1{
+			RootHash:  testHash,
+			MediaInfo: core.MediaInfo{No: no},
+			Version:   core.DataInfoVersion1,
+		})
+	}
+	dag := make(mapDAG)
+	root, err := Export(context.Background(), dag, infos, ExportOptions{MaxShardSize: maxSize})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var r Root
+	if err := get(context.Background(), dag, root, &r); err != nil {
+		t.Fatal(err)
+	}
+	var shard Shard
+	if err := get(context.Background(), dag, r.Shards["ab"], &shard); err != nil {
+		t.Fatal(err)
+	}
+	if len(shard.Records) != 0 || len(shard.Shards) == 0 {
+		t.Fatalf("the big shard should be split, got %d records %d shards", len(shard.Records), len(shard.Shards))
+	}
+	for c, raw := range dag {
+		var s Shard
+		if c == root || get(context.Background(), dag, c, &s) != nil || s.Prefix == "" {
+			continue
+		}
+		if len(raw) > maxSize && len(s.Records) > 2 {
+			t.Fatalf("shard %s has %d bytes", s.Prefix, len(raw))
+		}
+	}
+	imported, err := Import(context.Background(), dag, root)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(imported.Records) != len(infos) || len(imported.Invalid) != 0 {
+		t.Fatalf("want %d records, got %d invalid %v", len(infos), len(imported.Records), imported.Invalid)
+	}
+	again, err := Export(context.Background(), make(mapDAG), infos, ExportOptions{MaxShardSize: maxSize})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !root.Equals(again) {
+		t.Fatalf("export is not deterministic %s != %s", root, again)
+	}
+}
+
+func TestImport_Tampered(t *testing.T) {
+	dag := make(mapDAG)
+	infos := testInfos()[:1]
+	record, err := encode(&Record{
+		No:   infos[0].MediaInfo.No,
+		Hash: infos[0].Hash(),
+		Data: `{"media_info":{"no":"ABP-001","intro":"changed"}}`,
+	}, CodecCBOR)
+	if err != nil {
+		t.Fatal(err)
+	}
+	dag.Add(context.Background(), record)
+	shard, _ := encode(&Shard{Prefix: "ab", Records: map[string]cid.Cid{"ABP-001": record.Cid()}}, CodecCBOR)
+	dag.Add(context.Background(), shard)
+	root, _ := encode(&Root{Version: Version, Count: 1, Shards: map[string]cid.Cid{"ab": shard.Cid()}}, CodecCBOR)
+	dag.Add(context.Background(), root)
+	imported, err := Import(context.Background(), dag, root.Cid())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(imported.Records) != 0 || len(imported.Invalid) != 1 {
+		t.Fatalf("tampered record should be refused %+v", imported)
+	}
+}
+
+func TestImportChecked(t *testing.T) {
+	dag := make(mapDAG)
+	infos := testInfos()
+	infos[1].Signer = "eth:0x0000000000000000000000000000000000000001"
+	root, err := Export(context.Background(), dag, infos, ExportOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	imported, err := ImportChecked(context.Background(), dag, root, func(info *core.DataInfoV1) error {
+		if info.Signer != "" {
+			return errors.New("untrusted " + info.Signer)
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(imported.Records) != 3 || len(imported.Invalid) != 1 || imported.Invalid[0] != "ABP-002" {
+		t.Fatalf("checked record should be refused %+v", imported)
+	}
+}
+
+func TestCatalog(t *testing.T) {
+	dir, err := ioutil.TempDir("", "catalog")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	c, err := OpenCatalog(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+	for _, info := range testInfos() {
+		if err := c.Put(info); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := c.Put(&core.DataInfoV1{}); err != ErrNoNumber {
+		t.Fatalf("want ErrNoNumber, got %v", err)
+	}
+	info, err := c.Get("SSNI-100")
+	if err != nil || info.MediaInfo.Intro != "intro of SSNI-100" {
+		t.Fatalf("wrong record %+v %v", info, err)
+	}
+	if err := c.Delete("SSNI-100"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := c.Get("SSNI-100"); err != ErrNotFound {
+		t.Fatalf("want ErrNotFound, got %v", err)
+	}
+	records, err := c.Records()
+	if err != nil || len(records) != 3 {
+		t.Fatalf("want 3 records, got %d %v", len(records), err)
+	}
+
+	//the security of a sealed content is kept by the root hash
+	if _, err := c.Security(testHash); err != ErrNotFound {
+		t.Fatalf("want ErrNotFound, got %v", err)
+	}
+	sealed := testInfos()[0]
+	sealed.Security.Key = "seal1:record"
+	if err := c.Put(sealed); err != nil {
+		t.Fatal(err)
+	}
+	if security, err := c.Security(testHash); err != nil || security != "seal1:record" {
+		t.Fatalf("wrong security %s %v", security, err)
+	}
+	if err := c.PutSecurity("QmSealed", "seal1:file"); err != nil {
+		t.Fatal(err)
+	}
+	if security, err := c.Security("QmSealed"); err != nil || security != "seal1:file" {
+		t.Fatalf("wrong security %s %v", security, err)
+	}
+	records, err = c.Records()
+	if err != nil || len(records) != 3 {
+		t.Fatalf("the security should not be a record, got %d %v", len(records), err)
+	}
+}
+
+func TestShardPrefix(t *testing.T) {
+	for no, want := range map[string]string{"ABP-001": "ab", "X": "x_", "a/b": "a_", "中文字幕": "中文"} {
+		if got := ShardPrefix(no, 2); got != want {
+			t.Errorf("ShardPrefix(%s) got %s, want %s", no, got, want)
+		}
+	}
+}
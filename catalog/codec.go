@@ -0,0 +1,133 @@
+package catalog
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+
+	blocks "github.com/ipfs/go-block-format"
+	"github.com/ipfs/go-cid"
+	cbornode "github.com/ipfs/go-ipld-cbor"
+	format "github.com/ipfs/go-ipld-format"
+	mh "github.com/multiformats/go-multihash"
+)
+
+// Codecs of the catalog dag
+const (
+	CodecCBOR = "dag-cbor"
+	CodecJSON = "dag-json"
+)
+
+// DagJSON is the multicodec of dag-json which the cid package does not know yet
+const DagJSON = 0x0129
+
+func init() {
+	cbornode.RegisterCborType(Root{})
+	cbornode.RegisterCborType(Shard{})
+	cbornode.RegisterCborType(Record{})
+	format.Register(cid.DagCBOR, cbornode.DecodeBlock)
+	format.Register(DagJSON, DecodeJSONBlock)
+}
+
+// jsonNode is a dag-json node, the links and paths are resolved on the same data model of dag-cbor
+type jsonNode struct {
+	*cbornode.Node
+	raw []byte
+	cid cid.Cid
+}
+
+var _ format.Node = &jsonNode{}
+
+func newJSONNode(n *cbornode.Node, raw []byte) (*jsonNode, error) {
+	c, err := cid.Prefix{
+		Version:  1,
+		Codec:    DagJSON,
+		MhType:   mh.SHA2_256,
+		MhLength: -1,
+	}.Sum(raw)
+	if err != nil {
+		return nil, err
+	}
+	return &jsonNode{Node: n, raw: raw, cid: c}, nil
+}
+
+// DecodeJSONBlock decodes a dag-json block
+func DecodeJSONBlock(block blocks.Block) (format.Node, error) {
+	n, err := cbornode.FromJSON(bytes.NewReader(block.RawData()), mh.SHA2_256, -1)
+	if err != nil {
+		return nil, err
+	}
+	return &jsonNode{Node: n, raw: block.RawData(), cid: block.Cid()}, nil
+}
+
+// RawData ...
+func (n *jsonNode) RawData() []byte {
+	return n.raw
+}
+
+// Cid ...
+func (n *jsonNode) Cid() cid.Cid {
+	return n.cid
+}
+
+// String ...
+func (n *jsonNode) String() string {
+	return n.cid.String()
+}
+
+// Loggable ...
+func (n *jsonNode) Loggable() map[string]interface{} {
+	return map[string]interface{}{
+		"node_type": "dag-json",
+		"cid":       n.cid,
+	}
+}
+
+// Copy ...
+func (n *jsonNode) Copy() format.Node {
+	return &jsonNode{
+		Node: n.Node.Copy().(*cbornode.Node),
+		raw:  append([]byte(nil), n.raw...),
+		cid:  n.cid,
+	}
+}
+
+// Size ...
+func (n *jsonNode) Size() (uint64, error) {
+	return uint64(len(n.raw)), nil
+}
+
+// Stat ...
+func (n *jsonNode) Stat() (*format.NodeStat, error) {
+	return &format.NodeStat{}, nil
+}
+
+// encode returns the node of obj in the codec
+func encode(obj interface{}, codec string) (format.Node, error) {
+	n, err := cbornode.WrapObject(obj, mh.SHA2_256, -1)
+	if err != nil {
+		return nil, err
+	}
+	switch codec {
+	case CodecCBOR, "":
+		return n, nil
+	case CodecJSON:
+		raw, err := n.MarshalJSON()
+		if err != nil {
+			return nil, err
+		}
+		return newJSONNode(n, raw)
+	}
+	return nil, fmt.Errorf("unsupported codec(%s)", codec)
+}
+
+// decode reads the node into obj by the codec of its cid
+func decode(n format.Node, obj interface{}) error {
+	switch n.Cid().Type() {
+	case cid.DagCBOR:
+		return cbornode.DecodeInto(n.RawData(), obj)
+	case DagJSON:
+		return json.Unmarshal(n.RawData(), obj)
+	}
+	return fmt.Errorf("unsupported codec(%d) of %s", n.Cid().Type(), n.Cid())
+}
@@ -0,0 +1,297 @@
+package catalog
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/glvd/accipfs/core"
+	"github.com/ipfs/go-cid"
+	format "github.com/ipfs/go-ipld-format"
+)
+
+// Version is the version of the catalog dag
+const Version = 1
+
+// DefaultPrefixLen is the length of the media number prefix which the records are sharded by
+const DefaultPrefixLen = 2
+
+// DefaultMaxShardSize is the encoded size over which a shard is split by the longer prefixes,
+// it keeps the shards far below the block size limit of the bitswap
+const DefaultMaxShardSize = 256 << 10
+
+// Root is the root node of the catalog dag
+type Root struct {
+	Version int                `refmt:"version" json:"version"`
+	Count   int                `refmt:"count" json:"count"`
+	Shards  map[string]cid.Cid `refmt:"shards" json:"shards"`
+}
+
+// Shard links the records whose media number starts with the prefix, a shard bigger than the
+// max size links the sub shards of the prefixes one character longer instead of the records
+type Shard struct {
+	Prefix  string             `refmt:"prefix" json:"prefix"`
+	Records map[string]cid.Cid `refmt:"records,omitempty" json:"records,omitempty"`
+	Shards  map[string]cid.Cid `refmt:"shards,omitempty" json:"shards,omitempty"`
+}
+
+// Record is a data info with the content it references
+type Record struct {
+	No   string    `refmt:"no" json:"no"`
+	Hash string    `refmt:"hash" json:"hash"` //hash of the data to verify it
+	Data string    `refmt:"data" json:"data"` //marshaled data info
+	Refs []cid.Cid `refmt:"refs" json:"refs"`
+}
+
+// DAG is the dag which the catalog is exported to and imported from
+type DAG interface {
+	Get(ctx context.Context, c cid.Cid) (format.Node, error)
+	Add(ctx context.Context, n format.Node) error
+}
+
+// ExportOptions ...
+type ExportOptions struct {
+	Codec        string //dag-cbor or dag-json, dag-cbor is used when empty
+	PrefixLen    int    //DefaultPrefixLen is used when not positive
+	MaxShardSize int    //DefaultMaxShardSize is used when not positive
+}
+
+// Imported is the result of an import
+type Imported struct {
+	Records []*core.DataInfoV1
+	Refs    []cid.Cid
+	Invalid []string //media numbers of the records which failed to verify
+}
+
+// ShardPrefix returns the lower case prefix of the media number with n characters, short numbers are padded with _
+func ShardPrefix(no string, n int) string {
+	if n <= 0 {
+		n = DefaultPrefixLen
+	}
+	prefix := strings.ToLower(no)
+	if utf8.RuneCountInString(prefix) > n {
+		prefix = string([]rune(prefix)[:n])
+	}
+	prefix = strings.Replace(prefix, "/", "_", -1)
+	for utf8.RuneCountInString(prefix) < n {
+		prefix += "_"
+	}
+	return prefix
+}
+
+// References returns the cids referenced by the data info, the invalid ones are skipped
+func References(info *core.DataInfoV1) []cid.Cid {
+	var refs []cid.Cid
+	seen := make(map[cid.Cid]bool)
+	for _, hash := range []string{info.RootHash, info.MediaHash, info.Info.ThumbHash, info.Info.PosterHash} {
+		if hash == "" {
+			continue
+		}
+		c, err := cid.Decode(hash)
+		if err != nil {
+			log.Warnw("skip invalid reference", "no", info.MediaInfo.No, "hash", hash, "err", err)
+			continue
+		}
+		if !seen[c] {
+			seen[c] = true
+			refs = append(refs, c)
+		}
+	}
+	return refs
+}
+
+// Export adds the records to dag sharded by the media number prefix and returns the root,
+// the same records always build the same root. The nodes are not pinned, the caller pins the root
+// recursively to keep them from the gc
+func Export(ctx context.Context, dag DAG, infos []*core.DataInfoV1, opts ExportOptions) (cid.Cid, error) {
+	if opts.PrefixLen <= 0 {
+		opts.PrefixLen = DefaultPrefixLen
+	}
+	if opts.MaxShardSize <= 0 {
+		opts.MaxShardSize = DefaultMaxShardSize
+	}
+	shards := make(map[string]map[string]cid.Cid)
+	count := 0
+	for _, info := range infos {
+		no := info.MediaInfo.No
+		if no == "" {
+			return cid.Undef, ErrNoNumber
+		}
+		data, err := info.Marshal()
+		if err != nil {
+			return cid.Undef, err
+		}
+		n, err := encode(&Record{
+			No:   no,
+			Hash: info.Hash(),
+			Data: string(data),
+			Refs: References(info),
+		}, opts.Codec)
+		if err != nil {
+			return cid.Undef, err
+		}
+		if err := dag.Add(ctx, n); err != nil {
+			return cid.Undef, err
+		}
+		prefix := ShardPrefix(no, opts.PrefixLen)
+		records, ok := shards[prefix]
+		if !ok {
+			records = make(map[string]cid.Cid)
+			shards[prefix] = records
+		}
+		if _, ok := records[no]; !ok {
+			count++
+		}
+		records[no] = n.Cid()
+	}
+	root := &Root{
+		Version: Version,
+		Count:   count,
+		Shards:  make(map[string]cid.Cid),
+	}
+	for prefix, records := range shards {
+		c, err := addShard(ctx, dag, prefix, opts.PrefixLen, records, opts)
+		if err != nil {
+			return cid.Undef, err
+		}
+		root.Shards[prefix] = c
+	}
+	n, err := encode(root, opts.Codec)
+	if err != nil {
+		return cid.Undef, err
+	}
+	if err := dag.Add(ctx, n); err != nil {
+		return cid.Undef, err
+	}
+	return n.Cid(), nil
+}
+
+// addShard adds the shard of the records whose prefix has n characters, the shard is split by
+// the prefixes of n+1 characters while it is bigger than the max size
+func addShard(ctx context.Context, dag DAG, prefix string, n int, records map[string]cid.Cid, opts ExportOptions) (cid.Cid, error) {
+	node, err := encode(&Shard{Prefix: prefix, Records: records}, opts.Codec)
+	if err != nil {
+		return cid.Undef, err
+	}
+	if len(node.RawData()) > opts.MaxShardSize {
+		subs := make(map[string]map[string]cid.Cid)
+		longer := false
+		for no, c := range records {
+			sub := ShardPrefix(no, n+1)
+			if subs[sub] == nil {
+				subs[sub] = make(map[string]cid.Cid)
+			}
+			subs[sub][no] = c
+			if utf8.RuneCountInString(no) > n {
+				longer = true
+			}
+		}
+		//the numbers padded to the same prefix can not be split anymore
+		if len(subs) > 1 || longer {
+			shard := &Shard{Prefix: prefix, Shards: make(map[string]cid.Cid)}
+			for sub, records := range subs {
+				c, err := addShard(ctx, dag, sub, n+1, records, opts)
+				if err != nil {
+					return cid.Undef, err
+				}
+				shard.Shards[sub] = c
+			}
+			if node, err = encode(shard, opts.Codec); err != nil {
+				return cid.Undef, err
+			}
+		}
+	}
+	if err := dag.Add(ctx, node); err != nil {
+		return cid.Undef, err
+	}
+	return node.Cid(), nil
+}
+
+func get(ctx context.Context, dag DAG, c cid.Cid, obj interface{}) error {
+	n, err := dag.Get(ctx, c)
+	if err != nil {
+		return err
+	}
+	return decode(n, obj)
+}
+
+// Import walks the catalog dag of root and returns the verified records with their references
+func Import(ctx context.Context, dag DAG, root cid.Cid) (*Imported, error) {
+	return ImportChecked(ctx, dag, root, nil)
+}
+
+// ImportChecked is Import with an extra check of every record, the records refused by check are invalid
+func ImportChecked(ctx context.Context, dag DAG, root cid.Cid, check func(info *core.DataInfoV1) error) (*Imported, error) {
+	var r Root
+	if err := get(ctx, dag, root, &r); err != nil {
+		return nil, err
+	}
+	if r.Version != Version {
+		return nil, fmt.Errorf("unsupported catalog version(%d)", r.Version)
+	}
+	var prefixes []string
+	for prefix := range r.Shards {
+		prefixes = append(prefixes, prefix)
+	}
+	sort.Strings(prefixes)
+	imported := &Imported{}
+	seen := make(map[cid.Cid]bool)
+	for _, prefix := range prefixes {
+		if err := importShard(ctx, dag, r.Shards[prefix], check, imported, seen); err != nil {
+			return nil, err
+		}
+	}
+	return imported, nil
+}
+
+// importShard adds the verified records of the shard and its sub shards to imported
+func importShard(ctx context.Context, dag DAG, c cid.Cid, check func(info *core.DataInfoV1) error, imported *Imported, seen map[cid.Cid]bool) error {
+	var shard Shard
+	if err := get(ctx, dag, c, &shard); err != nil {
+		return err
+	}
+	var nos []string
+	for no := range shard.Records {
+		nos = append(nos, no)
+	}
+	sort.Strings(nos)
+	for _, no := range nos {
+		var record Record
+		if err := get(ctx, dag, shard.Records[no], &record); err != nil {
+			return err
+		}
+		var info core.DataInfoV1
+		if err := info.Unmarshal([]byte(record.Data)); err != nil || !info.Verify(record.Hash) || info.MediaInfo.No != no {
+			log.Warnw("invalid catalog record", "no", no, "err", err)
+			imported.Invalid = append(imported.Invalid, no)
+			continue
+		}
+		if check != nil {
+			if err := check(&info); err != nil {
+				log.Warnw("refused catalog record", "no", no, "err", err)
+				imported.Invalid = append(imported.Invalid, no)
+				continue
+			}
+		}
+		imported.Records = append(imported.Records, &info)
+		for _, ref := range record.Refs {
+			if !seen[ref] {
+				seen[ref] = true
+				imported.Refs = append(imported.Refs, ref)
+			}
+		}
+	}
+	var prefixes []string
+	for prefix := range shard.Shards {
+		prefixes = append(prefixes, prefix)
+	}
+	sort.Strings(prefixes)
+	for _, prefix := range prefixes {
+		if err := importShard(ctx, dag, shard.Shards[prefix], check, imported, seen); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,9 @@
+package catalog
+
+import (
+	alog "github.com/glvd/accipfs/log"
+)
+
+const module = "catalog"
+
+var log = alog.Module(module)
@@ -0,0 +1,153 @@
+package catalog
+
+import (
+	"errors"
+
+	"github.com/dgraph-io/badger/v2"
+	"github.com/glvd/accipfs/basis/kv"
+	"github.com/glvd/accipfs/core"
+)
+
+const recordPrefix = "record/"
+
+// securityPrefix is the prefix of the Security.Key of the sealed contents by the root hash
+const securityPrefix = "security/"
+
+// ErrNoNumber ...
+var ErrNoNumber = errors.New("data info has no media number")
+
+// ErrNotFound ...
+var ErrNotFound = errors.New("data info not found")
+
+// Catalog keeps the data info records of the local media library by the media number
+type Catalog struct {
+	db *badger.DB
+}
+
+func recordKey(no string) []byte {
+	return []byte(recordPrefix + no)
+}
+
+func securityKey(hash string) []byte {
+	return []byte(securityPrefix + hash)
+}
+
+// OpenCatalog ...
+func OpenCatalog(path string) (*Catalog, error) {
+	db, err := kv.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	return &Catalog{db: db}, nil
+}
+
+// Put adds or replaces the record of the media number, the Security.Key of a sealed content
+// is kept by the root hash too
+func (c *Catalog) Put(info *core.DataInfoV1) error {
+	if info.MediaInfo.No == "" {
+		return ErrNoNumber
+	}
+	encode, err := info.Marshal()
+	if err != nil {
+		return err
+	}
+	return c.db.Update(func(txn *badger.Txn) error {
+		if info.Security.Key != "" && info.RootHash != "" {
+			if err := txn.Set(securityKey(info.RootHash), []byte(info.Security.Key)); err != nil {
+				return err
+			}
+		}
+		return txn.Set(recordKey(info.MediaInfo.No), encode)
+	})
+}
+
+// PutSecurity keeps the Security.Key of the sealed content of the root hash added without a record
+func (c *Catalog) PutSecurity(hash string, security string) error {
+	return c.db.Update(func(txn *badger.Txn) error {
+		return txn.Set(securityKey(hash), []byte(security))
+	})
+}
+
+// Security returns the Security.Key of the sealed content of the root hash
+func (c *Catalog) Security(hash string) (string, error) {
+	var security string
+	err := c.db.View(func(txn *badger.Txn) error {
+		item, err := txn.Get(securityKey(hash))
+		if err == badger.ErrKeyNotFound {
+			return ErrNotFound
+		}
+		if err != nil {
+			return err
+		}
+		b, err := item.ValueCopy(nil)
+		security = string(b)
+		return err
+	})
+	return security, err
+}
+
+// Get ...
+func (c *Catalog) Get(no string) (*core.DataInfoV1, error) {
+	var info core.DataInfoV1
+	err := c.db.View(func(txn *badger.Txn) error {
+		item, err := txn.Get(recordKey(no))
+		if err == badger.ErrKeyNotFound {
+			return ErrNotFound
+		}
+		if err != nil {
+			return err
+		}
+		return item.Value(info.Unmarshal)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &info, nil
+}
+
+// Delete ...
+func (c *Catalog) Delete(no string) error {
+	return c.db.Update(func(txn *badger.Txn) error {
+		return txn.Delete(recordKey(no))
+	})
+}
+
+// Range calls f with every record ordered by the media number until f returns false
+func (c *Catalog) Range(f func(info *core.DataInfoV1) bool) error {
+	return c.db.View(func(txn *badger.Txn) error {
+		iter := txn.NewIterator(badger.DefaultIteratorOptions)
+		defer iter.Close()
+		prefix := []byte(recordPrefix)
+		for iter.Seek(prefix); iter.ValidForPrefix(prefix); iter.Next() {
+			var info core.DataInfoV1
+			if err := iter.Item().Value(info.Unmarshal); err != nil {
+				return err
+			}
+			if !f(&info) {
+				return nil
+			}
+		}
+		return nil
+	})
+}
+
+// Records returns all the records ordered by the media number
+func (c *Catalog) Records() ([]*core.DataInfoV1, error) {
+	var infos []*core.DataInfoV1
+	err := c.Range(func(info *core.DataInfoV1) bool {
+		infos = append(infos, info)
+		return true
+	})
+	return infos, err
+}
+
+// Close ...
+func (c *Catalog) Close() error {
+	if c.db != nil {
+		defer func() {
+			c.db = nil
+		}()
+		return c.db.Close()
+	}
+	return nil
+}
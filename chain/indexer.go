@@ -0,0 +1,507 @@
+package chain
+
+import (
+	"context"
+	"encoding/json"
+	"math/big"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/accounts/abi/bind"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/glvd/accipfs/contract/dmessage"
+	"github.com/glvd/accipfs/contract/dtag"
+	"github.com/glvd/accipfs/contract/node"
+	"github.com/glvd/accipfs/contract/token"
+	"github.com/glvd/accipfs/core"
+	"go.uber.org/atomic"
+)
+
+// key prefixes of the indexed data
+const (
+	prefixTag    = "tag/"
+	prefixPin    = "pin/"
+	prefixNode   = "node/"
+	prefixWriter = "writer/"
+)
+
+// DefaultSyncInterval is used when the sync interval is not set
+const DefaultSyncInterval = 15 * time.Second
+
+// node kinds of the node registry
+const (
+	NodeIPFS       = "ipfs"
+	NodePublicIPFS = "public_ipfs"
+	NodeETH        = "eth"
+	NodeSigner     = "signer"
+)
+
+// dtag methods which change the ids of a tag
+var tagMethods = map[string]bool{
+	"addTagId":      true,
+	"addTagIds":     true,
+	"setTagIds":     true,
+	"replaceTagIds": true,
+	"fixTagIds":     true,
+	"addTagMessage": true,
+}
+
+// indexed events
+const (
+	eventPinSuccess          = "pinSuccess"
+	eventWritershipIncreased = "WritershipIncreased"
+	eventWritershipDecreased = "WritershipDecreased"
+)
+
+// Backend is the chain backend the indexer follows
+type Backend interface {
+	bind.ContractCaller
+	bind.ContractFilterer
+	HeaderByNumber(ctx context.Context, number *big.Int) (*types.Header, error)
+	BlockByHash(ctx context.Context, hash common.Hash) (*types.Block, error)
+	TransactionReceipt(ctx context.Context, txHash common.Hash) (*types.Receipt, error)
+}
+
+// Contracts is the addresses of the followed contracts, zero address is not followed
+type Contracts struct {
+	Tag     common.Address
+	Node    common.Address
+	Token   common.Address
+	Message common.Address
+}
+
+// Pin is the pinSuccess event of the token contract
+type Pin struct {
+	User  string `json:"user"`
+	Hash  string `json:"hash"`
+	Date  string `json:"date"`
+	Block uint64 `json:"block"`
+}
+
+// Indexer follows the contracts from the checkpoint and keeps the media catalog and the node registry in the store,
+// the infos of the tagged ids and the node lists are passed to the registered callbacks
+type Indexer struct {
+	backend       Backend
+	store         *Store
+	contracts     Contracts
+	start         uint64
+	confirmations uint64
+	tagABI        abi.ABI
+	events        map[common.Hash]string
+	tag           *dtag.DTagCaller
+	node          *node.AccelerateNodeCaller
+	token         *token.DhTokenFilterer
+	message       *dmessage.DMessageFilterer
+	messages      *dmessage.DMessageCaller
+	lock          sync.Mutex
+	head          *atomic.Uint64
+	reorgs        *atomic.Uint64
+	lastSync      *atomic.Int64
+	nodesCB       func(kind string, nodes []string)
+	infosCB       func(infos []*core.DataInfoV1)
+}
+
+// NewIndexer create an indexer which starts from the start block when the store has no checkpoint,
+// a block is indexed after it has the confirmations
+func NewIndexer(backend Backend, store *Store, contracts Contracts, start, confirmations uint64) (*Indexer, error) {
+	tagABI, err := abi.JSON(strings.NewReader(dtag.DTagABI))
+	if err != nil {
+		return nil, err
+	}
+	tokenABI, err := abi.JSON(strings.NewReader(token.DhTokenABI))
+	if err != nil {
+		return nil, err
+	}
+	messageABI, err := abi.JSON(strings.NewReader(dmessage.DMessageABI))
+	if err != nil {
+		return nil, err
+	}
+	x := &Indexer{
+		backend:       backend,
+		store:         store,
+		contracts:     contracts,
+		start:         start,
+		confirmations: confirmations,
+		tagABI:        tagABI,
+		events: map[common.Hash]string{
+			tokenABI.Events[eventPinSuccess].ID():            eventPinSuccess,
+			messageABI.Events[eventWritershipIncreased].ID(): eventWritershipIncreased,
+			messageABI.Events[eventWritershipDecreased].ID(): eventWritershipDecreased,
+		},
+		head:     atomic.NewUint64(0),
+		reorgs:   atomic.NewUint64(0),
+		lastSync: atomic.NewInt64(0),
+	}
+	if x.tag, err = dtag.NewDTagCaller(contracts.Tag, backend); err != nil {
+		return nil, err
+	}
+	if x.node, err = node.NewAccelerateNodeCaller(contracts.Node, backend); err != nil {
+		return nil, err
+	}
+	if x.token, err = token.NewDhTokenFilterer(contracts.Token, backend); err != nil {
+		return nil, err
+	}
+	if x.message, err = dmessage.NewDMessageFilterer(contracts.Message, backend); err != nil {
+		return nil, err
+	}
+	if x.messages, err = dmessage.NewDMessageCaller(contracts.Message, backend); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+// RegisterNodes sets the callback of the changed node registry
+func (x *Indexer) RegisterNodes(f func(kind string, nodes []string)) {
+	x.nodesCB = f
+}
+
+// RegisterInfos sets the callback of the infos of the ids added to the tags,
+// the infos are not removed again when the block is rolled back
+func (x *Indexer) RegisterInfos(f func(infos []*core.DataInfoV1)) {
+	x.infosCB = f
+}
+
+// Sync indexes the blocks from the checkpoint to the confirmed head, the blocks of a reorganized branch are rolled back first
+func (x *Indexer) Sync(ctx context.Context) error {
+	x.lock.Lock()
+	defer x.lock.Unlock()
+	head, err := x.backend.HeaderByNumber(ctx, nil)
+	if err != nil {
+		return err
+	}
+	x.head.Store(head.Number.Uint64())
+	if head.Number.Uint64() < x.confirmations {
+		return nil
+	}
+	target := head.Number.Uint64() - x.confirmations
+	for {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		cp, ok, err := x.store.Checkpoint()
+		if err != nil {
+			return err
+		}
+		next := x.start
+		if ok {
+			next = cp.Number + 1
+		}
+		if next > target {
+			break
+		}
+		header, err := x.backend.HeaderByNumber(ctx, new(big.Int).SetUint64(next))
+		if err != nil {
+			return err
+		}
+		if ok && cp.Hash != "" && header.ParentHash != common.HexToHash(cp.Hash) {
+			log.Warnw("chain reorganized", "number", cp.Number, "hash", cp.Hash)
+			if _, err := x.store.Rollback(); err != nil {
+				return err
+			}
+			x.reorgs.Inc()
+			continue
+		}
+		if err := x.index(ctx, header); err != nil {
+			return err
+		}
+	}
+	x.lastSync.Store(time.Now().Unix())
+	return nil
+}
+
+func (x *Indexer) index(ctx context.Context, header *types.Header) error {
+	hash := header.Hash()
+	block, err := x.backend.BlockByHash(ctx, hash)
+	if err != nil {
+		return err
+	}
+	changes := make(map[string][]byte)
+	opts := &bind.CallOpts{Context: ctx, BlockNumber: block.Number()}
+	nodeChanged := false
+	var infos []*core.DataInfoV1
+	for _, tx := range block.Transactions() {
+		if tx.To() == nil || len(tx.Data()) < 4 {
+			continue
+		}
+		to := *tx.To()
+		if to != x.contracts.Tag && to != x.contracts.Node {
+			continue
+		}
+		receipt, err := x.backend.TransactionReceipt(ctx, tx.Hash())
+		if err != nil {
+			return err
+		}
+		if receipt == nil || receipt.Status != types.ReceiptStatusSuccessful {
+			continue
+		}
+		if to == x.contracts.Node {
+			nodeChanged = true
+			continue
+		}
+		added, err := x.indexTag(opts, tx.Data(), changes)
+		if err != nil {
+			log.Warnw("index tag", "tx", tx.Hash().Hex(), "err", err)
+		}
+		infos = append(infos, x.infos(opts, added)...)
+	}
+	var nodes map[string][]string
+	if nodeChanged {
+		if nodes, err = x.nodes(opts); err != nil {
+			return err
+		}
+		for kind, list := range nodes {
+			encode, err := json.Marshal(list)
+			if err != nil {
+				return err
+			}
+			changes[prefixNode+kind] = encode
+		}
+	}
+	if err := x.indexLogs(ctx, hash, changes); err != nil {
+		return err
+	}
+	err = x.store.Apply(Block{Number: block.NumberU64(), Hash: hash.Hex()}, changes)
+	if err != nil {
+		return err
+	}
+	if x.nodesCB != nil {
+		for kind, list := range nodes {
+			x.nodesCB(kind, list)
+		}
+	}
+	if x.infosCB != nil && len(infos) > 0 {
+		x.infosCB(infos)
+	}
+	return nil
+}
+
+// indexTag reads the ids of the tag changed by the dtag transaction input and returns the added ids
+func (x *Indexer) indexTag(opts *bind.CallOpts, input []byte, changes map[string][]byte) ([]string, error) {
+	method, err := x.tagABI.MethodById(input[:4])
+	if err != nil {
+		return nil, err
+	}
+	if !tagMethods[method.Name] {
+		return nil, nil
+	}
+	args, err := method.Inputs.UnpackValues(input[4:])
+	if err != nil {
+		return nil, err
+	}
+	tag, _ := args[0].(string)
+	sub, _ := args[1].(string)
+	ids, err := x.tag.GetTagIds(opts, tag, sub)
+	if err != nil {
+		return nil, err
+	}
+	key := prefixTag + tag + "/" + sub
+	var old []string
+	if b, ok := changes[key]; ok {
+		err = json.Unmarshal(b, &old)
+	} else {
+		err = x.load(key, &old)
+	}
+	if err != nil {
+		return nil, err
+	}
+	encode, err := json.Marshal(ids)
+	if err != nil {
+		return nil, err
+	}
+	changes[key] = encode
+	known := make(map[string]bool, len(old))
+	for _, id := range old {
+		known[id] = true
+	}
+	var added []string
+	for _, id := range ids {
+		if !known[id] {
+			added = append(added, id)
+		}
+	}
+	return added, nil
+}
+
+// infos reads the messages of the ids, the messages which are not a data info with the media number are skipped
+func (x *Indexer) infos(opts *bind.CallOpts, ids []string) []*core.DataInfoV1 {
+	var infos []*core.DataInfoV1
+	for _, id := range ids {
+		message, err := x.messages.GetMessage(opts, id)
+		if err != nil {
+			log.Warnw("read tag message", "id", id, "err", err)
+			continue
+		}
+		var info core.DataInfoV1
+		if err := info.Unmarshal([]byte(message)); err != nil || info.MediaInfo.No == "" {
+			log.Debugw("tag message is not a data info", "id", id, "err", err)
+			continue
+		}
+		infos = append(infos, &info)
+	}
+	return infos
+}
+
+// nodes reads all the node lists of the node contract
+func (x *Indexer) nodes(opts *bind.CallOpts) (map[string][]string, error) {
+	getters := map[string]func(*bind.CallOpts) ([]string, error){
+		NodeIPFS:       x.node.GetIpfsNodes,
+		NodePublicIPFS: x.node.GetPublicIpfsNodes,
+		NodeETH:        x.node.GetEthNodes,
+		NodeSigner:     x.node.GetSignerNodes,
+	}
+	nodes := make(map[string][]string, len(getters))
+	for kind, get := range getters {
+		list, err := get(opts)
+		if err != nil {
+			return nil, err
+		}
+		nodes[kind] = list
+	}
+	return nodes, nil
+}
+
+// indexLogs indexes the pinSuccess events of the token and the writership events of the message contract in the block
+func (x *Indexer) indexLogs(ctx context.Context, hash common.Hash, changes map[string][]byte) error {
+	var addrs []common.Address
+	if x.contracts.Token != (common.Address{}) {
+		addrs = append(addrs, x.contracts.Token)
+	}
+	if x.contracts.Message != (common.Address{}) {
+		addrs = append(addrs, x.contracts.Message)
+	}
+	if len(addrs) == 0 {
+		return nil
+	}
+	logs, err := x.backend.FilterLogs(ctx, ethereum.FilterQuery{
+		BlockHash: &hash,
+		Addresses: addrs,
+	})
+	if err != nil {
+		return err
+	}
+	for _, l := range logs {
+		if l.Removed || len(l.Topics) == 0 {
+			continue
+		}
+		switch x.events[l.Topics[0]] {
+		case eventPinSuccess:
+			if l.Address != x.contracts.Token {
+				continue
+			}
+			ev, err := x.token.ParsePinSuccess(l)
+			if err != nil {
+				return err
+			}
+			encode, err := json.Marshal(Pin{
+				User:  ev.User.Hex(),
+				Hash:  ev.Hash,
+				Date:  ev.Date,
+				Block: l.BlockNumber,
+			})
+			if err != nil {
+				return err
+			}
+			changes[prefixPin+ev.Hash] = encode
+		case eventWritershipIncreased:
+			if l.Address != x.contracts.Message {
+				continue
+			}
+			ev, err := x.message.ParseWritershipIncreased(l)
+			if err != nil {
+				return err
+			}
+			changes[prefixWriter+ev.NewWriter.Hex()] = []byte{1}
+		case eventWritershipDecreased:
+			if l.Address != x.contracts.Message {
+				continue
+			}
+			ev, err := x.message.ParseWritershipDecreased(l)
+			if err != nil {
+				return err
+			}
+			changes[prefixWriter+ev.OldWriter.Hex()] = nil
+		}
+	}
+	return nil
+}
+
+// Run syncs every interval until ctx is done, DefaultSyncInterval is used when interval is not positive
+func (x *Indexer) Run(ctx context.Context, interval time.Duration) {
+	if interval <= 0 {
+		interval = DefaultSyncInterval
+	}
+	t := time.NewTicker(interval)
+	defer t.Stop()
+	for {
+		if err := x.Sync(ctx); err != nil && ctx.Err() == nil {
+			log.Errorw("sync chain", "err", err)
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-t.C:
+		}
+	}
+}
+
+// Status ...
+func (x *Indexer) Status(ctx context.Context, req *core.ChainStatusReq) (*core.ChainStatusResp, error) {
+	cp, _, err := x.store.Checkpoint()
+	if err != nil {
+		return nil, err
+	}
+	return &core.ChainStatusResp{
+		Height:   cp.Number,
+		Hash:     cp.Hash,
+		Head:     x.head.Load(),
+		Reorgs:   x.reorgs.Load(),
+		LastSync: x.lastSync.Load(),
+	}, nil
+}
+
+// TagIDs returns the indexed ids of the tag
+func (x *Indexer) TagIDs(tag, sub string) ([]string, error) {
+	var ids []string
+	return ids, x.load(prefixTag+tag+"/"+sub, &ids)
+}
+
+// Nodes returns the indexed node list of the kind
+func (x *Indexer) Nodes(kind string) ([]string, error) {
+	var nodes []string
+	return nodes, x.load(prefixNode+kind, &nodes)
+}
+
+// Pins returns all the indexed pinSuccess events
+func (x *Indexer) Pins() ([]Pin, error) {
+	var pins []Pin
+	err := x.store.Range(prefixPin, func(key string, value []byte) bool {
+		var p Pin
+		if err := json.Unmarshal(value, &p); err == nil {
+			pins = append(pins, p)
+		}
+		return true
+	})
+	return pins, err
+}
+
+// Writers returns the indexed writers of the message contract
+func (x *Indexer) Writers() ([]string, error) {
+	var writers []string
+	err := x.store.Range(prefixWriter, func(key string, value []byte) bool {
+		writers = append(writers, strings.TrimPrefix(key, prefixWriter))
+		return true
+	})
+	return writers, err
+}
+
+func (x *Indexer) load(key string, v interface{}) error {
+	b, err := x.store.Get(key)
+	if err != nil || b == nil {
+		return err
+	}
+	return json.Unmarshal(b, v)
+}
@@ -0,0 +1,254 @@
+package chain
+
+import (
+	"context"
+	"errors"
+	"io/ioutil"
+	"math/big"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/accounts/abi/bind"
+	"github.com/ethereum/go-ethereum/accounts/abi/bind/backends"
+	"github.com/ethereum/go-ethereum/common"
+	ethcore "github.com/ethereum/go-ethereum/core"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/glvd/accipfs/contract/dmessage"
+	"github.com/glvd/accipfs/contract/dtag"
+	"github.com/glvd/accipfs/contract/token"
+	"github.com/glvd/accipfs/core"
+)
+
+func testStore(t *testing.T) (*Store, func()) {
+	dir, err := ioutil.TempDir("", "chain")
+	if err != nil {
+		t.Fatal(err)
+	}
+	store, err := OpenStore(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return store, func() {
+		store.Close()
+		os.RemoveAll(dir)
+	}
+}
+
+func TestIndexer_Tag(t *testing.T) {
+	key, err := crypto.GenerateKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	auth := bind.NewKeyedTransactor(key)
+	backend := backends.NewSimulatedBackend(ethcore.GenesisAlloc{
+		auth.From: {Balance: big.NewInt(1000000000000000000)},
+	}, 8000000)
+	defer backend.Close()
+	msgAddr, _, msg, err := dmessage.DeployDMessage(auth, backend)
+	if err != nil {
+		t.Fatal(err)
+	}
+	backend.Commit()
+	tagAddr, _, tag, err := dtag.DeployDTag(auth, backend, msgAddr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	backend.Commit()
+
+	store, clean := testStore(t)
+	defer clean()
+	x, err := NewIndexer(backend, store, Contracts{Tag: tagAddr, Message: msgAddr}, 0, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var nos []string
+	x.RegisterInfos(func(infos []*core.DataInfoV1) {
+		for _, info := range infos {
+			nos = append(nos, info.MediaInfo.No)
+		}
+	})
+	for _, id := range []string{"QmFirst", "QmSecond"} {
+		info := core.DataInfoV1{RootHash: id, MediaInfo: core.MediaInfo{No: strings.ToLower(id)}}
+		if _, err := msg.AddMessage(auth, id, info.JSON()); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := tag.AddTagId(auth, "video", "new", id); err != nil {
+			t.Fatal(err)
+		}
+		backend.Commit()
+		//the simulated backend only calls at the head block
+		if err := x.Sync(context.Background()); err != nil {
+			t.Fatal(err)
+		}
+	}
+	ids, err := x.TagIDs("video", "new")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Join(ids, ",") != "QmFirst,QmSecond" {
+		t.Fatalf("wrong tag ids %v", ids)
+	}
+	//only the infos of the added ids are passed to the catalog
+	if strings.Join(nos, ",") != "qmfirst,qmsecond" {
+		t.Fatalf("wrong indexed infos %v", nos)
+	}
+	status, err := x.Status(context.Background(), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if status.Height != 4 || status.Head != 4 {
+		t.Fatalf("wrong sync height %d/%d", status.Height, status.Head)
+	}
+}
+
+// fakeChain is a chain of empty blocks with pinSuccess logs which can be reorganized
+type fakeChain struct {
+	bind.ContractCaller
+	headers []*types.Header
+	logs    map[common.Hash][]types.Log
+}
+
+func (c *fakeChain) HeaderByNumber(ctx context.Context, number *big.Int) (*types.Header, error) {
+	if number == nil {
+		return c.headers[len(c.headers)-1], nil
+	}
+	if number.Uint64() >= uint64(len(c.headers)) {
+		return nil, ethereum.NotFound
+	}
+	return c.headers[number.Uint64()], nil
+}
+
+func (c *fakeChain) BlockByHash(ctx context.Context, hash common.Hash) (*types.Block, error) {
+	for _, h := range c.headers {
+		if h.Hash() == hash {
+			return types.NewBlockWithHeader(h), nil
+		}
+	}
+	return nil, ethereum.NotFound
+}
+
+func (c *fakeChain) TransactionReceipt(ctx context.Context, txHash common.Hash) (*types.Receipt, error) {
+	return nil, ethereum.NotFound
+}
+
+func (c *fakeChain) FilterLogs(ctx context.Context, query ethereum.FilterQuery) ([]types.Log, error) {
+	return c.logs[*query.BlockHash], nil
+}
+
+func (c *fakeChain) SubscribeFilterLogs(ctx context.Context, query ethereum.FilterQuery, ch chan<- types.Log) (ethereum.Subscription, error) {
+	return nil, errors.New("not supported")
+}
+
+// extend appends a block with the pin logs after the block number, the blocks after number are dropped
+func (c *fakeChain) extend(t *testing.T, number uint64, fork int64, pins ...string) {
+	c.headers = c.headers[:number+1]
+	header := &types.Header{
+		ParentHash: c.headers[number].Hash(),
+		Number:     new(big.Int).SetUint64(number + 1),
+		Extra:      big.NewInt(fork).Bytes(),
+	}
+	c.headers = append(c.headers, header)
+	parsed, err := abi.JSON(strings.NewReader(token.DhTokenABI))
+	if err != nil {
+		t.Fatal(err)
+	}
+	event := parsed.Events[eventPinSuccess]
+	for _, pin := range pins {
+		data, err := event.Inputs.NonIndexed().Pack(pin, "2020-01-01")
+		if err != nil {
+			t.Fatal(err)
+		}
+		c.logs[header.Hash()] = append(c.logs[header.Hash()], types.Log{
+			Address:     common.HexToAddress("0x01"),
+			Topics:      []common.Hash{event.ID(), common.BytesToHash(common.HexToAddress("0x02").Bytes())},
+			Data:        data,
+			BlockNumber: number + 1,
+			BlockHash:   header.Hash(),
+		})
+	}
+}
+
+func TestIndexer_Reorg(t *testing.T) {
+	chain := &fakeChain{
+		headers: []*types.Header{{Number: big.NewInt(0)}},
+		logs:    make(map[common.Hash][]types.Log),
+	}
+	chain.extend(t, 0, 0, "QmA")
+	chain.extend(t, 1, 0, "QmB")
+	chain.extend(t, 2, 0)
+
+	store, clean := testStore(t)
+	defer clean()
+	x, err := NewIndexer(chain, store, Contracts{Token: common.HexToAddress("0x01")}, 0, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := x.Sync(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	pins, err := x.Pins()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(pins) != 2 {
+		t.Fatalf("want 2 pins, got %d", len(pins))
+	}
+
+	//block 2 with QmB is replaced by a longer fork
+	chain.extend(t, 1, 1, "QmC")
+	chain.extend(t, 2, 1)
+	chain.extend(t, 3, 1)
+	if err := x.Sync(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	pins, err = x.Pins()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var hashes []string
+	for _, p := range pins {
+		hashes = append(hashes, p.Hash)
+	}
+	if strings.Join(hashes, ",") != "QmA,QmC" {
+		t.Fatalf("wrong pins after reorg %v", hashes)
+	}
+	status, err := x.Status(context.Background(), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if status.Reorgs != 1 || status.Height != 3 || status.Hash != chain.headers[3].Hash().Hex() {
+		t.Fatalf("wrong status %+v", status)
+	}
+
+	//resume from the checkpoint with a new indexer
+	resumed, err := NewIndexer(chain, store, Contracts{Token: common.HexToAddress("0x01")}, 0, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := resumed.Sync(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	status, err = resumed.Status(context.Background(), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if status.Height != 4 {
+		t.Fatalf("resumed indexer should sync to 4, got %d", status.Height)
+	}
+}
+
+func TestIndexer_RunZeroInterval(t *testing.T) {
+	store, clean := testStore(t)
+	defer clean()
+	x, err := NewIndexer(&fakeChain{headers: []*types.Header{{Number: big.NewInt(0)}}}, store, Contracts{}, 0, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	x.Run(ctx, 0)
+}
@@ -0,0 +1,9 @@
+package chain
+
+import (
+	alog "github.com/glvd/accipfs/log"
+)
+
+const module = "chain"
+
+var log = alog.Module(module)
@@ -0,0 +1,224 @@
+package chain
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"github.com/dgraph-io/badger/v2"
+	"github.com/glvd/accipfs/basis/kv"
+)
+
+// ReorgDepth is the count of the recent blocks which can be rolled back
+const ReorgDepth = 128
+
+const checkpointKey = "checkpoint"
+
+// ErrReorgTooDeep ...
+var ErrReorgTooDeep = errors.New("chain reorganization is deeper than the kept blocks")
+
+// Block ...
+type Block struct {
+	Number uint64 `json:"number"`
+	Hash   string `json:"hash"`
+}
+
+// undo is the value of a key before a block was applied
+type undo struct {
+	Key    string `json:"key"`
+	Value  []byte `json:"value"`
+	Exists bool   `json:"exists"`
+}
+
+// Store keeps the indexed data with the checkpoint and the undo journal of the recent blocks
+type Store struct {
+	db *badger.DB
+}
+
+func blockKey(number uint64) []byte {
+	return []byte(fmt.Sprintf("block/%020d", number))
+}
+
+func undoKey(number uint64) []byte {
+	return []byte(fmt.Sprintf("undo/%020d", number))
+}
+
+// OpenStore ...
+func OpenStore(path string) (*Store, error) {
+	db, err := kv.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	return &Store{db: db}, nil
+}
+
+func get(txn *badger.Txn, key []byte) ([]byte, bool, error) {
+	item, err := txn.Get(key)
+	if err == badger.ErrKeyNotFound {
+		return nil, false, nil
+	}
+	if err != nil {
+		return nil, false, err
+	}
+	v, err := item.ValueCopy(nil)
+	return v, err == nil, err
+}
+
+// Checkpoint returns the last applied block, ok is false when nothing is applied
+func (s *Store) Checkpoint() (b Block, ok bool, err error) {
+	err = s.db.View(func(txn *badger.Txn) error {
+		v, exists, err := get(txn, []byte(checkpointKey))
+		if err != nil || !exists {
+			return err
+		}
+		ok = true
+		return json.Unmarshal(v, &b)
+	})
+	return
+}
+
+// Apply writes the changes of the block and moves the checkpoint to it, a nil value deletes the key
+func (s *Store) Apply(b Block, changes map[string][]byte) error {
+	return s.db.Update(func(txn *badger.Txn) error {
+		var undos []undo
+		for key, value := range changes {
+			prev, exists, err := get(txn, []byte(key))
+			if err != nil {
+				return err
+			}
+			undos = append(undos, undo{Key: key, Value: prev, Exists: exists})
+			if value == nil {
+				err = txn.Delete([]byte(key))
+			} else {
+				err = txn.Set([]byte(key), value)
+			}
+			if err != nil {
+				return err
+			}
+		}
+		encode, err := json.Marshal(undos)
+		if err != nil {
+			return err
+		}
+		if err := txn.Set(undoKey(b.Number), encode); err != nil {
+			return err
+		}
+		if err := txn.Set(blockKey(b.Number), []byte(b.Hash)); err != nil {
+			return err
+		}
+		if b.Number >= ReorgDepth {
+			old := b.Number - ReorgDepth
+			if err := txn.Delete(undoKey(old)); err != nil {
+				return err
+			}
+			if err := txn.Delete(blockKey(old)); err != nil {
+				return err
+			}
+		}
+		checkpoint, err := json.Marshal(b)
+		if err != nil {
+			return err
+		}
+		return txn.Set([]byte(checkpointKey), checkpoint)
+	})
+}
+
+// Rollback reverts the changes of the checkpoint block and moves the checkpoint to its parent
+func (s *Store) Rollback() (Block, error) {
+	var parent Block
+	err := s.db.Update(func(txn *badger.Txn) error {
+		v, exists, err := get(txn, []byte(checkpointKey))
+		if err != nil {
+			return err
+		}
+		if !exists {
+			return ErrReorgTooDeep
+		}
+		var b Block
+		if err := json.Unmarshal(v, &b); err != nil {
+			return err
+		}
+		v, exists, err = get(txn, undoKey(b.Number))
+		if err != nil {
+			return err
+		}
+		if !exists {
+			return ErrReorgTooDeep
+		}
+		var undos []undo
+		if err := json.Unmarshal(v, &undos); err != nil {
+			return err
+		}
+		for _, u := range undos {
+			if u.Exists {
+				err = txn.Set([]byte(u.Key), u.Value)
+			} else {
+				err = txn.Delete([]byte(u.Key))
+			}
+			if err != nil {
+				return err
+			}
+		}
+		if err := txn.Delete(undoKey(b.Number)); err != nil {
+			return err
+		}
+		if err := txn.Delete(blockKey(b.Number)); err != nil {
+			return err
+		}
+		if b.Number == 0 {
+			return txn.Delete([]byte(checkpointKey))
+		}
+		parent.Number = b.Number - 1
+		hash, _, err := get(txn, blockKey(parent.Number))
+		if err != nil {
+			return err
+		}
+		parent.Hash = string(hash)
+		checkpoint, err := json.Marshal(parent)
+		if err != nil {
+			return err
+		}
+		return txn.Set([]byte(checkpointKey), checkpoint)
+	})
+	return parent, err
+}
+
+// Get returns the value of key, nil is returned when not found
+func (s *Store) Get(key string) ([]byte, error) {
+	var v []byte
+	err := s.db.View(func(txn *badger.Txn) (err error) {
+		v, _, err = get(txn, []byte(key))
+		return err
+	})
+	return v, err
+}
+
+// Range calls f with every key and value under the prefix until f returns false
+func (s *Store) Range(prefix string, f func(key string, value []byte) bool) error {
+	return s.db.View(func(txn *badger.Txn) error {
+		iter := txn.NewIterator(badger.DefaultIteratorOptions)
+		defer iter.Close()
+		p := []byte(prefix)
+		for iter.Seek(p); iter.ValidForPrefix(p); iter.Next() {
+			v, err := iter.Item().ValueCopy(nil)
+			if err != nil {
+				return err
+			}
+			if !f(string(iter.Item().Key()), v) {
+				return nil
+			}
+		}
+		return nil
+	})
+}
+
+// Close ...
+func (s *Store) Close() error {
+	if s.db != nil {
+		defer func() {
+			s.db = nil
+		}()
+		return s.db.Close()
+	}
+	return nil
+}
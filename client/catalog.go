@@ -0,0 +1,35 @@
+package client
+
+import (
+	"context"
+	"github.com/glvd/accipfs/core"
+)
+
+// CatalogAPI ...
+func (c *client) CatalogAPI() core.CatalogAPI {
+	return c
+}
+
+// CatalogExport ...
+func (c *client) CatalogExport(ctx context.Context, req *core.CatalogExportReq) (resp *core.CatalogExportResp, err error) {
+	resp = new(core.CatalogExportResp)
+	err = c.doPost(ctx, "catalog/export", req, resp)
+	return
+}
+
+// CatalogImport ...
+func (c *client) CatalogImport(ctx context.Context, req *core.CatalogImportReq) (resp *core.CatalogImportResp, err error) {
+	resp = new(core.CatalogImportResp)
+	err = c.doPost(ctx, "catalog/import", req, resp)
+	return
+}
+
+// CatalogExport ...
+func CatalogExport(ctx context.Context, req *core.CatalogExportReq) (resp *core.CatalogExportResp, err error) {
+	return DefaultClient.CatalogAPI().CatalogExport(ctx, req)
+}
+
+// CatalogImport ...
+func CatalogImport(ctx context.Context, req *core.CatalogImportReq) (resp *core.CatalogImportResp, err error) {
+	return DefaultClient.CatalogAPI().CatalogImport(ctx, req)
+}
@@ -0,0 +1,23 @@
+package client
+
+import (
+	"context"
+	"github.com/glvd/accipfs/core"
+)
+
+// ChainAPI ...
+func (c *client) ChainAPI() core.ChainAPI {
+	return c
+}
+
+// Status ...
+func (c *client) Status(ctx context.Context, req *core.ChainStatusReq) (resp *core.ChainStatusResp, err error) {
+	resp = new(core.ChainStatusResp)
+	err = c.doPost(ctx, "chain/status", req, resp)
+	return
+}
+
+// ChainStatus ...
+func ChainStatus(ctx context.Context, req *core.ChainStatusReq) (resp *core.ChainStatusResp, err error) {
+	return DefaultClient.ChainAPI().Status(ctx, req)
+}
@@ -192,3 +192,15 @@ func Add(ctx context.Context, req *core.NodeAddReq) (resp *core.NodeAddResp, err
 func UploadFile(ctx context.Context, req *core.UploadReq) (resp *core.UploadResp, err error) {
 	return DefaultClient.DataStoreAPI().UploadFile(ctx, req)
 }
+
+// Pay ...
+func Pay(ctx context.Context, req *core.PayReq) (resp *core.PayResp, err error) {
+	return DefaultClient.Pay(ctx, req)
+}
+
+// Pay ...
+func (c *client) Pay(ctx context.Context, req *core.PayReq) (resp *core.PayResp, err error) {
+	resp = new(core.PayResp)
+	err = c.doPost(ctx, "pay", req, resp)
+	return
+}
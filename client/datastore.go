@@ -2,7 +2,13 @@ package client
 
 import (
 	"context"
+	"fmt"
 	"github.com/glvd/accipfs/core"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
 )
 
 // DataStoreAPI ...
@@ -40,3 +46,152 @@ func (c *client) UploadFile(ctx context.Context, req *core.UploadReq) (resp *cor
 	err = c.doPost(ctx, "ds/upload", req, resp)
 	return
 }
+
+// RepoGC ...
+func (c *client) RepoGC(ctx context.Context, req *core.RepoGCReq) (resp *core.RepoGCResp, err error) {
+	resp = new(core.RepoGCResp)
+	err = c.doPost(ctx, "ds/repo/gc", req, resp)
+	return
+}
+
+// RepoStat ...
+func (c *client) RepoStat(ctx context.Context, req *core.RepoStatReq) (resp *core.RepoStatResp, err error) {
+	resp = new(core.RepoStatResp)
+	err = c.doPost(ctx, "ds/repo/stat", req, resp)
+	return
+}
+
+// DataStoreRepoGC ...
+func DataStoreRepoGC(ctx context.Context, req *core.RepoGCReq) (resp *core.RepoGCResp, err error) {
+	return DefaultClient.DataStoreAPI().RepoGC(ctx, req)
+}
+
+// DataStoreRepoStat ...
+func DataStoreRepoStat(ctx context.Context, req *core.RepoStatReq) (resp *core.RepoStatResp, err error) {
+	return DefaultClient.DataStoreAPI().RepoStat(ctx, req)
+}
+
+// RepoVerify ...
+func (c *client) RepoVerify(ctx context.Context, req *core.RepoVerifyReq) (resp *core.RepoVerifyResp, err error) {
+	resp = new(core.RepoVerifyResp)
+	err = c.doPost(ctx, "ds/repo/verify", req, resp)
+	return
+}
+
+// RepoRepair ...
+func (c *client) RepoRepair(ctx context.Context, req *core.RepoRepairReq) (resp *core.RepoRepairResp, err error) {
+	resp = new(core.RepoRepairResp)
+	err = c.doPost(ctx, "ds/repo/repair", req, resp)
+	return
+}
+
+// DataStoreRepoVerify ...
+func DataStoreRepoVerify(ctx context.Context, req *core.RepoVerifyReq) (resp *core.RepoVerifyResp, err error) {
+	return DefaultClient.DataStoreAPI().RepoVerify(ctx, req)
+}
+
+// DataStoreRepoRepair ...
+func DataStoreRepoRepair(ctx context.Context, req *core.RepoRepairReq) (resp *core.RepoRepairResp, err error) {
+	return DefaultClient.DataStoreAPI().RepoRepair(ctx, req)
+}
+
+// Get ...
+func (c *client) Get(ctx context.Context, req *core.GetReq) (*core.GetResp, error) {
+	uri := "get/" + req.Hash
+	if ep := strings.TrimPrefix(req.Endpoint, "/"); ep != "" {
+		uri += "/" + ep
+	}
+	request, err := http.NewRequest(http.MethodGet, c.RequestURL(uri), nil)
+	if err != nil {
+		return nil, err
+	}
+	if ctx != nil {
+		request = request.WithContext(ctx)
+	}
+	if req.Key != "" {
+		request.Header.Set(core.ContentKeyHeader, req.Key)
+		request.Header.Set(core.ContentProofHeader, req.Proof)
+		request.Header.Set(core.ContentTimeHeader, strconv.FormatInt(req.Time, 10))
+	}
+	response, err := c.cli.Do(request)
+	if err != nil {
+		return nil, err
+	}
+	defer response.Body.Close()
+	if response.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("get %s: %s", req.Hash, response.Status)
+	}
+	if _, err := io.Copy(req.Writer, response.Body); err != nil {
+		return nil, err
+	}
+	return &core.GetResp{}, nil
+}
+
+// DagExport ...
+func (c *client) DagExport(ctx context.Context, req *core.DagExportReq) (*core.DagExportResp, error) {
+	query := url.Values{}
+	for _, root := range req.Roots {
+		query.Add("root", root)
+	}
+	if req.Version > 0 {
+		query.Set("version", strconv.Itoa(req.Version))
+	}
+	request, err := http.NewRequest(http.MethodGet, requestQuery(c.RequestURL("ds/dag/export"), query), nil)
+	if err != nil {
+		return nil, err
+	}
+	if ctx != nil {
+		request = request.WithContext(ctx)
+	}
+	response, err := c.cli.Do(request)
+	if err != nil {
+		return nil, err
+	}
+	defer response.Body.Close()
+	if !strings.HasPrefix(response.Header.Get("Content-Type"), "application/vnd.ipld.car") {
+		resp := new(core.DagExportResp)
+		return resp, responseDecoder(response.Body, resp)
+	}
+	if _, err := io.Copy(req.Writer, response.Body); err != nil {
+		return nil, err
+	}
+	return &core.DagExportResp{}, nil
+}
+
+// DagImport ...
+func (c *client) DagImport(ctx context.Context, req *core.DagImportReq) (*core.DagImportResp, error) {
+	query := url.Values{}
+	if req.PinRoots {
+		query.Set("pin", "true")
+	}
+	request, err := http.NewRequest(http.MethodPost, requestQuery(c.RequestURL("ds/dag/import"), query), req.Reader)
+	if err != nil {
+		return nil, err
+	}
+	if ctx != nil {
+		request = request.WithContext(ctx)
+	}
+	request.Header.Set("Content-Type", "application/vnd.ipld.car")
+	response, err := c.cli.Do(request)
+	if err != nil {
+		return nil, err
+	}
+	defer response.Body.Close()
+	resp := new(core.DagImportResp)
+	return resp, responseDecoder(response.Body, resp)
+}
+
+// DataStoreGet ...
+func DataStoreGet(ctx context.Context, req *core.GetReq) (*core.GetResp, error) {
+	return DefaultClient.DataStoreAPI().Get(ctx, req)
+}
+
+// DataStoreDagExport ...
+func DataStoreDagExport(ctx context.Context, req *core.DagExportReq) (*core.DagExportResp, error) {
+	return DefaultClient.DataStoreAPI().DagExport(ctx, req)
+}
+
+// DataStoreDagImport ...
+func DataStoreDagImport(ctx context.Context, req *core.DagImportReq) (*core.DagImportResp, error) {
+	return DefaultClient.DataStoreAPI().DagImport(ctx, req)
+}
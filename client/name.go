@@ -0,0 +1,71 @@
+package client
+
+import (
+	"context"
+	"github.com/glvd/accipfs/core"
+)
+
+// NameAPI ...
+func (c *client) NameAPI() core.NameAPI {
+	return c
+}
+
+// Publish ...
+func (c *client) Publish(ctx context.Context, req *core.NamePublishReq) (resp *core.NamePublishResp, err error) {
+	resp = new(core.NamePublishResp)
+	err = c.doPost(ctx, "name/publish", req, resp)
+	return
+}
+
+// Resolve ...
+func (c *client) Resolve(ctx context.Context, req *core.NameResolveReq) (resp *core.NameResolveResp, err error) {
+	resp = new(core.NameResolveResp)
+	err = c.doPost(ctx, "name/resolve", req, resp)
+	return
+}
+
+// KeyGen ...
+func (c *client) KeyGen(ctx context.Context, req *core.KeyGenReq) (resp *core.KeyGenResp, err error) {
+	resp = new(core.KeyGenResp)
+	err = c.doPost(ctx, "key/gen", req, resp)
+	return
+}
+
+// KeyList ...
+func (c *client) KeyList(ctx context.Context, req *core.KeyListReq) (resp *core.KeyListResp, err error) {
+	resp = new(core.KeyListResp)
+	err = c.doPost(ctx, "key/list", req, resp)
+	return
+}
+
+// KeyRm ...
+func (c *client) KeyRm(ctx context.Context, req *core.KeyRmReq) (resp *core.KeyRmResp, err error) {
+	resp = new(core.KeyRmResp)
+	err = c.doPost(ctx, "key/rm", req, resp)
+	return
+}
+
+// NamePublish ...
+func NamePublish(ctx context.Context, req *core.NamePublishReq) (resp *core.NamePublishResp, err error) {
+	return DefaultClient.NameAPI().Publish(ctx, req)
+}
+
+// NameResolve ...
+func NameResolve(ctx context.Context, req *core.NameResolveReq) (resp *core.NameResolveResp, err error) {
+	return DefaultClient.NameAPI().Resolve(ctx, req)
+}
+
+// KeyGen ...
+func KeyGen(ctx context.Context, req *core.KeyGenReq) (resp *core.KeyGenResp, err error) {
+	return DefaultClient.NameAPI().KeyGen(ctx, req)
+}
+
+// KeyList ...
+func KeyList(ctx context.Context, req *core.KeyListReq) (resp *core.KeyListResp, err error) {
+	return DefaultClient.NameAPI().KeyList(ctx, req)
+}
+
+// KeyRm ...
+func KeyRm(ctx context.Context, req *core.KeyRmReq) (resp *core.KeyRmResp, err error) {
+	return DefaultClient.NameAPI().KeyRm(ctx, req)
+}
@@ -29,6 +29,18 @@ func NodeList(ctx context.Context, req *core.NodeListReq) (resp *core.NodeListRe
 	return DefaultClient.NodeAPI().List(ctx, req)
 }
 
+// Backup ...
+func (c *client) Backup(ctx context.Context, req *core.NodeBackupReq) (resp *core.NodeBackupResp, err error) {
+	resp = new(core.NodeBackupResp)
+	err = c.doPost(ctx, "node/backup", req, resp)
+	return
+}
+
+// NodeBackup ...
+func NodeBackup(ctx context.Context, req *core.NodeBackupReq) (resp *core.NodeBackupResp, err error) {
+	return DefaultClient.NodeAPI().Backup(ctx, req)
+}
+
 // Link ...
 func (c *client) Link(ctx context.Context, req *core.NodeLinkReq) (resp *core.NodeLinkResp, err error) {
 	resp = new(core.NodeLinkResp)
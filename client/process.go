@@ -0,0 +1,23 @@
+package client
+
+import (
+	"context"
+	"github.com/glvd/accipfs/core"
+)
+
+// ProcessAPI ...
+func (c *client) ProcessAPI() core.ProcessAPI {
+	return c
+}
+
+// Processes ...
+func (c *client) Processes(ctx context.Context, req *core.ProcessListReq) (resp *core.ProcessListResp, err error) {
+	resp = new(core.ProcessListResp)
+	err = c.doPost(ctx, "process/list", req, resp)
+	return
+}
+
+// ProcessList ...
+func ProcessList(ctx context.Context, req *core.ProcessListReq) (resp *core.ProcessListResp, err error) {
+	return DefaultClient.ProcessAPI().Processes(ctx, req)
+}
@@ -0,0 +1,47 @@
+package client
+
+import (
+	"context"
+	"github.com/glvd/accipfs/core"
+)
+
+// StatsAPI ...
+func (c *client) StatsAPI() core.StatsAPI {
+	return c
+}
+
+// Bandwidth ...
+func (c *client) Bandwidth(ctx context.Context, req *core.StatsBandwidthReq) (resp *core.StatsBandwidthResp, err error) {
+	resp = new(core.StatsBandwidthResp)
+	err = c.doPost(ctx, "stats/bw", req, resp)
+	return
+}
+
+// StatsBandwidth ...
+func StatsBandwidth(ctx context.Context, req *core.StatsBandwidthReq) (resp *core.StatsBandwidthResp, err error) {
+	return DefaultClient.StatsAPI().Bandwidth(ctx, req)
+}
+
+// QoS ...
+func (c *client) QoS(ctx context.Context, req *core.StatsQoSReq) (resp *core.StatsQoSResp, err error) {
+	resp = new(core.StatsQoSResp)
+	err = c.doPost(ctx, "stats/qos", req, resp)
+	return
+}
+
+// StatsQoS ...
+func StatsQoS(ctx context.Context, req *core.StatsQoSReq) (resp *core.StatsQoSResp, err error) {
+	return DefaultClient.StatsAPI().QoS(ctx, req)
+}
+
+// Stream ...
+func (c *client) Stream(ctx context.Context, req *core.StatsStreamReq) (resp *core.StatsStreamResp, err error) {
+	resp = new(core.StatsStreamResp)
+	err = c.doPost(ctx, "stats/stream", req, resp)
+	return
+}
+
+// StatsStream ...
+func StatsStream(ctx context.Context, req *core.StatsStreamReq) (resp *core.StatsStreamResp, err error) {
+	return DefaultClient.StatsAPI().Stream(ctx, req)
+}
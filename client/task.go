@@ -0,0 +1,35 @@
+package client
+
+import (
+	"context"
+	"github.com/glvd/accipfs/core"
+)
+
+// TaskAPI ...
+func (c *client) TaskAPI() core.TaskAPI {
+	return c
+}
+
+// Jobs ...
+func (c *client) Jobs(ctx context.Context, req *core.TaskListReq) (resp *core.TaskListResp, err error) {
+	resp = new(core.TaskListResp)
+	err = c.doPost(ctx, "task/list", req, resp)
+	return
+}
+
+// JobCancel ...
+func (c *client) JobCancel(ctx context.Context, req *core.TaskCancelReq) (resp *core.TaskCancelResp, err error) {
+	resp = new(core.TaskCancelResp)
+	err = c.doPost(ctx, "task/cancel", req, resp)
+	return
+}
+
+// TaskList ...
+func TaskList(ctx context.Context, req *core.TaskListReq) (resp *core.TaskListResp, err error) {
+	return DefaultClient.TaskAPI().Jobs(ctx, req)
+}
+
+// TaskCancel ...
+func TaskCancel(ctx context.Context, req *core.TaskCancelReq) (resp *core.TaskCancelResp, err error) {
+	return DefaultClient.TaskAPI().JobCancel(ctx, req)
+}
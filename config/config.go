@@ -19,8 +19,12 @@ const _configExt = ".json"
 const _dataDirETH = ".eth"
 const _dataDirIPFS = ".ipfs"
 const _dataDirCache = ".cache"
+const _logDir = "logs"
 const _localGateway = "http://127.0.0.1:%d"
 
+// DefaultGatewayHost is the listen address of the gateway, only the local clients are served by default
+const DefaultGatewayHost = "127.0.0.1"
+
 const _ipfsAddr = "/ip4/127.0.0.1/tcp/%d"
 
 // DefaultNodeContractAddr ...
@@ -31,24 +35,78 @@ var DefaultTokenContractAddr = "0x9064322CfeE623A447ba5aF0dA6AD3341c073535"
 
 // IPFSConfig ...
 type IPFSConfig struct {
-	Enable    bool   `json:"enable" mapstructure:"enable"`
-	LogOutput bool   `json:"log_output" mapstructure:"log_output"` //output log to screen
-	Name      string `json:"name" mapstructure:"name"`
-	API       int    `json:"api" mapstructure:"api"`
-	Gateway   int    `json:"gateway" mapstructure:"gateway"`
-	Timeout   int    `json:"timeout" mapstructure:"timeout"`
+	Enable     bool            `json:"enable" mapstructure:"enable"`
+	LogOutput  bool            `json:"log_output" mapstructure:"log_output"` //output log to screen
+	Name       string          `json:"name" mapstructure:"name"`
+	Mode       string          `json:"mode" mapstructure:"mode"`               //embedded or external
+	APIAddress string          `json:"api_address" mapstructure:"api_address"` //multiaddr or url of the external daemon api, the local bin is started when empty
+	API        int             `json:"api" mapstructure:"api"`
+	Gateway    int             `json:"gateway" mapstructure:"gateway"`
+	Timeout    int             `json:"timeout" mapstructure:"timeout"`
+	Supervise  SuperviseConfig `json:"supervise" mapstructure:"supervise"`
+	Repo       IPFSRepoConfig  `json:"repo" mapstructure:"repo"`
+}
+
+// IPFSRepoConfig is applied to the repo of the embedded node on start
+type IPFSRepoConfig struct {
+	Datastore string        `json:"datastore" mapstructure:"datastore"` //badgerds, flatfs or levelds, only used when the repo is created
+	Routing   string        `json:"routing" mapstructure:"routing"`     //dht, dhtclient or none
+	Swarm     []string      `json:"swarm" mapstructure:"swarm"`         //swarm listen addresses, the repo ones are kept when empty
+	ConnMgr   ConnMgrConfig `json:"conn_mgr" mapstructure:"conn_mgr"`
+	Profiles  []string      `json:"profiles" mapstructure:"profiles"` //ipfs config profiles applied in order, like server or lowpower
+	Private   bool          `json:"private" mapstructure:"private"`   //only join the private network of the swarm.key in the repo
+}
+
+// ConnMgrConfig ...
+type ConnMgrConfig struct {
+	LowWater    int           `json:"low_water" mapstructure:"low_water"`
+	HighWater   int           `json:"high_water" mapstructure:"high_water"`     //0 keeps the repo settings
+	GracePeriod time.Duration `json:"grace_period" mapstructure:"grace_period"` //seconds
 }
 
 // ETHConfig ...
 type ETHConfig struct {
-	Enable      bool   `json:"enable" mapstructure:"enable"`
-	LogOutput   bool   `json:"log_output" mapstructure:"log_output"` //output log to screen
-	Name        string `json:"name" mapstructure:"name"`             //bin name
-	Port        int    `json:"port" mapstructure:"port"`
-	NodeAddr    string `json:"node_addr" mapstructure:"node_addr"`       //node contract address
-	TokenAddr   string `json:"token_addr" mapstructure:"token_addr"`     //token contract address
-	MessageAddr string `json:"message_addr" mapstructure:"message_addr"` //dmessage contract address
-	DTagAddr    string `json:"dtag_addr" mapstructure:"dtag_addr"`       //dtag contract address
+	Enable      bool            `json:"enable" mapstructure:"enable"`
+	LogOutput   bool            `json:"log_output" mapstructure:"log_output"` //output log to screen
+	Name        string          `json:"name" mapstructure:"name"`             //bin name
+	Port        int             `json:"port" mapstructure:"port"`
+	NodeAddr    string          `json:"node_addr" mapstructure:"node_addr"`       //node contract address
+	TokenAddr   string          `json:"token_addr" mapstructure:"token_addr"`     //token contract address
+	MessageAddr string          `json:"message_addr" mapstructure:"message_addr"` //dmessage contract address
+	DTagAddr    string          `json:"dtag_addr" mapstructure:"dtag_addr"`       //dtag contract address
+	Tx          TxConfig        `json:"tx" mapstructure:"tx"`
+	Index       IndexConfig     `json:"index" mapstructure:"index"`
+	Supervise   SuperviseConfig `json:"supervise" mapstructure:"supervise"`
+}
+
+// SuperviseConfig ...
+type SuperviseConfig struct {
+	Restart       string        `json:"restart" mapstructure:"restart"`               //always, on-failure or never
+	MinBackoff    time.Duration `json:"min_backoff" mapstructure:"min_backoff"`       //seconds before the first restart
+	MaxBackoff    time.Duration `json:"max_backoff" mapstructure:"max_backoff"`       //max seconds between restarts
+	MaxRestarts   int           `json:"max_restarts" mapstructure:"max_restarts"`     //0 means unlimited
+	ProbeInterval time.Duration `json:"probe_interval" mapstructure:"probe_interval"` //liveness probe seconds, 0 disables the probe
+	ProbeTimeout  time.Duration `json:"probe_timeout" mapstructure:"probe_timeout"`
+	ProbeFailures int           `json:"probe_failures" mapstructure:"probe_failures"` //failed probes in a row before restart
+	LogMaxSize    int64         `json:"log_max_size" mapstructure:"log_max_size"`     //MiB of a log file before rotated
+	LogMaxFiles   int           `json:"log_max_files" mapstructure:"log_max_files"`   //rotated log files kept
+}
+
+// IndexConfig ...
+type IndexConfig struct {
+	Enable        bool          `json:"enable" mapstructure:"enable"`
+	Start         uint64        `json:"start" mapstructure:"start"`                 //first block indexed without checkpoint
+	Confirmations uint64        `json:"confirmations" mapstructure:"confirmations"` //blocks behind the head before indexed
+	Interval      time.Duration `json:"interval" mapstructure:"interval"`           //sync interval seconds
+}
+
+// TxConfig ...
+type TxConfig struct {
+	ReceiptTimeout time.Duration `json:"receipt_timeout" mapstructure:"receipt_timeout"` //seconds to wait for the receipt
+	BumpInterval   time.Duration `json:"bump_interval" mapstructure:"bump_interval"`     //seconds before a pending transaction is resent with bumped gas price
+	BumpPercent    int64         `json:"bump_percent" mapstructure:"bump_percent"`       //gas price increase percent of every bump
+	MaxBumps       int           `json:"max_bumps" mapstructure:"max_bumps"`
+	GasMargin      int64         `json:"gas_margin" mapstructure:"gas_margin"` //percent added to the estimated gas limit
 }
 
 // AWSConfig ...
@@ -90,6 +148,67 @@ type NodeConfig struct {
 	PoolMax       int           `json:"pool_max"  mapstructure:"pool_max"`
 }
 
+// PayConfig ...
+type PayConfig struct {
+	Enable   bool          `json:"enable" mapstructure:"enable"`
+	Price    int64         `json:"price" mapstructure:"price"`       //token price per MiB
+	Interval time.Duration `json:"interval" mapstructure:"interval"` //settle interval seconds
+}
+
+// RateConfig ...
+type RateConfig struct {
+	Rate  int64 `json:"rate" mapstructure:"rate"`   //tokens per second, 0 means unlimited
+	Burst int64 `json:"burst" mapstructure:"burst"` //bucket size, the rate is used when 0
+}
+
+// QoSConfig ...
+type QoSConfig struct {
+	Enable  bool               `json:"enable" mapstructure:"enable"`
+	Global  RateConfig         `json:"global" mapstructure:"global"`   //bytes per second of all get streams
+	Token   RateConfig         `json:"token" mapstructure:"token"`     //bytes per second of every api token
+	IP      RateConfig         `json:"ip" mapstructure:"ip"`           //bytes per second of every remote ip
+	Peer    RateConfig         `json:"peer" mapstructure:"peer"`       //node requests per second of every peer id
+	Classes map[string]float64 `json:"classes" mapstructure:"classes"` //rate multiple of the priority classes
+	Tokens  map[string]string  `json:"tokens" mapstructure:"tokens"`   //api token to priority class
+	Peers   map[string]string  `json:"peers" mapstructure:"peers"`     //peer id to priority class
+}
+
+// GatewayConfig ...
+type GatewayConfig struct {
+	Enable  bool     `json:"enable" mapstructure:"enable"`
+	Host    string   `json:"host" mapstructure:"host"` //listen address, 0.0.0.0 exposes the gateway to the network
+	Port    int      `json:"port" mapstructure:"port"`
+	Domains []string `json:"domains" mapstructure:"domains"` //domains of the subdomain requests like <cid>.ipfs.<domain>
+}
+
+// GCConfig ...
+type GCConfig struct {
+	Enable    bool          `json:"enable" mapstructure:"enable"`
+	HighWater int           `json:"high_water" mapstructure:"high_water"` //percent of limit which triggers the gc
+	LowWater  int           `json:"low_water" mapstructure:"low_water"`   //percent of limit kept after the gc
+	Interval  time.Duration `json:"interval" mapstructure:"interval"`     //check interval seconds
+}
+
+// StreamConfig ...
+type StreamConfig struct {
+	Prefetch int `json:"prefetch" mapstructure:"prefetch"` //count of the hls segments fetched ahead of the player, 0 disables the prefetch
+	Workers  int `json:"workers" mapstructure:"workers"`   //count of the segments prefetched at once
+}
+
+// TaskConfig ...
+type TaskConfig struct {
+	Workers    int           `json:"workers" mapstructure:"workers"`         //count of the jobs running at once
+	MaxRetries int           `json:"max_retries" mapstructure:"max_retries"` //retries of a failed job
+	Backoff    time.Duration `json:"backoff" mapstructure:"backoff"`         //seconds before the first retry, doubled on every retry
+	MaxBackoff time.Duration `json:"max_backoff" mapstructure:"max_backoff"` //max seconds between the retries
+}
+
+// TrustConfig ...
+type TrustConfig struct {
+	Publishers    []string `json:"publishers" mapstructure:"publishers"`         //accepted signers of the infos, eth:<address> or p2p:<peer id>, the signed infos of the others are refused
+	RequireSigned bool     `json:"require_signed" mapstructure:"require_signed"` //refuse the unsigned infos, the publishers must be set
+}
+
 // HashConfig ...
 type HashConfig struct {
 	Path string `json:"path" mapstructure:"path"`
@@ -97,23 +216,32 @@ type HashConfig struct {
 
 // Config ...
 type Config struct {
-	Node       NodeConfig     `json:"node" mapstructure:"node"`
-	API        APIConfig      `json:"api" mapstructure:"api"`
-	UseTLS     bool           `json:"use_tls" mapstructure:"use_tls"`
-	TLS        TLSCertificate `json:"tls" mapstructure:"tls"`
-	Schema     string         `json:"schema" mapstructure:"schema"`
-	Path       string         `json:"path" mapstructure:"path" `
-	Account    string         `json:"account" mapstructure:"account"`
-	Identity   string         `json:"identity" mapstructure:"identity"`
-	PrivateKey string         `json:"private_key" mapstructure:"private_key"`
-	ETH        ETHConfig      `json:"eth" mapstructure:"eth"`
-	IPFS       IPFSConfig     `json:"ipfs" mapstructure:"ipfs"`
-	AWS        AWSConfig      `json:"aws" mapstructure:"aws"`
-	Interval   int64          `json:"interval" mapstructure:"interval"`
-	NodeType   int            `json:"node_type" mapstructure:"node_type"`
-	Limit      int64          `json:"limit" mapstructure:"limit"`
-	Debug      bool           `json:"debug" mapstructure:"debug"`
-	BootNode   []string       `json:"boot_node" mapstructure:"boot_node"`
+	Node           NodeConfig        `json:"node" mapstructure:"node"`
+	API            APIConfig         `json:"api" mapstructure:"api"`
+	UseTLS         bool              `json:"use_tls" mapstructure:"use_tls"`
+	TLS            TLSCertificate    `json:"tls" mapstructure:"tls"`
+	Schema         string            `json:"schema" mapstructure:"schema"`
+	Path           string            `json:"path" mapstructure:"path" `
+	Account        string            `json:"account" mapstructure:"account"` //default account
+	Accounts       map[string]string `json:"accounts" mapstructure:"accounts"`
+	DefaultAccount string            `json:"default_account" mapstructure:"default_account"`
+	Identity       string            `json:"identity" mapstructure:"identity"`
+	PrivateKey     string            `json:"private_key" mapstructure:"private_key"`
+	ETH            ETHConfig         `json:"eth" mapstructure:"eth"`
+	IPFS           IPFSConfig        `json:"ipfs" mapstructure:"ipfs"`
+	AWS            AWSConfig         `json:"aws" mapstructure:"aws"`
+	Pay            PayConfig         `json:"pay" mapstructure:"pay"`
+	QoS            QoSConfig         `json:"qos" mapstructure:"qos"`
+	GC             GCConfig          `json:"gc" mapstructure:"gc"`
+	Gateway        GatewayConfig     `json:"gateway" mapstructure:"gateway"`
+	Task           TaskConfig        `json:"task" mapstructure:"task"`
+	Stream         StreamConfig      `json:"stream" mapstructure:"stream"`
+	Trust          TrustConfig       `json:"trust" mapstructure:"trust"`
+	Interval       int64             `json:"interval" mapstructure:"interval"`
+	NodeType       int               `json:"node_type" mapstructure:"node_type"`
+	Limit          int64             `json:"limit" mapstructure:"limit"` //max datastore storage GiB, 0 means unlimited
+	Debug          bool              `json:"debug" mapstructure:"debug"`
+	BootNode       []string          `json:"boot_node" mapstructure:"boot_node"`
 }
 
 // WorkDir ...
@@ -163,7 +291,7 @@ func SaveConfig(config *Config) error {
 		return e
 	}
 	*_config = *config
-	return ioutil.WriteFile(filepath.Join(WorkDir, _configName+_configExt), by, 0755)
+	return ioutil.WriteFile(filepath.Join(WorkDir, _configName+_configExt), by, 0600)
 }
 
 // Global ...
@@ -206,16 +334,98 @@ func Default() *Config {
 			TokenAddr:   DefaultTokenContractAddr,
 			MessageAddr: "",
 			DTagAddr:    "",
+			Tx: TxConfig{
+				ReceiptTimeout: 300,
+				BumpInterval:   60,
+				BumpPercent:    20,
+				MaxBumps:       3,
+				GasMargin:      20,
+			},
+			Index: IndexConfig{
+				Enable:        true,
+				Start:         0,
+				Confirmations: 6,
+				Interval:      15,
+			},
+			Supervise: SuperviseConfig{
+				Restart:       "always",
+				MinBackoff:    1,
+				MaxBackoff:    60,
+				MaxRestarts:   0,
+				ProbeInterval: 10,
+				ProbeTimeout:  5,
+				ProbeFailures: 3,
+				LogMaxSize:    10,
+				LogMaxFiles:   5,
+			},
 		},
 		IPFS: IPFSConfig{
 			Enable:    true,
 			LogOutput: true,
 			Name:      "ipfs",
+			Mode:      "embedded",
 			API:       5001,
 			Gateway:   8080,
 			Timeout:   30,
+			Repo: IPFSRepoConfig{
+				Datastore: "badgerds",
+				Routing:   "dht",
+			},
+			Supervise: SuperviseConfig{
+				Restart:       "always",
+				MinBackoff:    1,
+				MaxBackoff:    60,
+				MaxRestarts:   0,
+				ProbeInterval: 10,
+				ProbeTimeout:  5,
+				ProbeFailures: 3,
+				LogMaxSize:    10,
+				LogMaxFiles:   5,
+			},
+		},
+		AWS: AWSConfig{},
+		Pay: PayConfig{
+			Enable:   false,
+			Price:    1,
+			Interval: 600,
+		},
+		QoS: QoSConfig{
+			Enable: false,
+			IP: RateConfig{
+				Rate:  8 << 20,
+				Burst: 16 << 20,
+			},
+			Peer: RateConfig{
+				Rate:  20,
+				Burst: 40,
+			},
+			Classes: map[string]float64{
+				"free": 1,
+				"paid": 4,
+			},
+		},
+		GC: GCConfig{
+			Enable:    true,
+			HighWater: 90,
+			LowWater:  70,
+			Interval:  3600,
+		},
+		Gateway: GatewayConfig{
+			Enable:  true,
+			Host:    DefaultGatewayHost,
+			Port:    8088,
+			Domains: []string{"localhost"},
+		},
+		Stream: StreamConfig{
+			Prefetch: 3,
+			Workers:  4,
+		},
+		Task: TaskConfig{
+			Workers:    4,
+			MaxRetries: 5,
+			Backoff:    10,
+			MaxBackoff: 600,
 		},
-		AWS:      AWSConfig{},
 		Interval: 30,
 		NodeType: 0x01,
 		Limit:    500,
@@ -274,6 +484,11 @@ func DataDirCache() string {
 	return filepath.Join(Global().Path, _dataDirCache)
 }
 
+// LogDir ...
+func LogDir() string {
+	return filepath.Join(Global().Path, _logDir)
+}
+
 // KeyDir ...
 func KeyDir() string {
 	return filepath.Join(Global().Path, _keyDir)
@@ -300,6 +515,11 @@ func IPFSGatewayURL() string {
 	return fmt.Sprintf(_localGateway, Global().IPFS.Gateway)
 }
 
+// GatewayURL ...
+func GatewayURL() string {
+	return fmt.Sprintf(_localGateway, Global().Gateway.Port)
+}
+
 // RPCAddr ...
 func RPCAddr() string {
 	return Global().rpcAddr()
@@ -1,12 +1,22 @@
 package main
 
 import (
+	"context"
+	"crypto/ecdsa"
+	"encoding/hex"
 	"encoding/json"
 	"fmt"
 	"github.com/glvd/accipfs/account"
+	"github.com/glvd/accipfs/client"
 	"github.com/glvd/accipfs/config"
+	"github.com/glvd/accipfs/core"
+	"github.com/glvd/accipfs/seal"
 	"github.com/spf13/cobra"
+	"io"
 	"io/ioutil"
+	"os"
+	"os/signal"
+	"time"
 )
 
 func accountCmd() *cobra.Command {
@@ -15,7 +25,10 @@ func accountCmd() *cobra.Command {
 		Short: "Account info",
 		Long:  "Account show the information with your account",
 	}
-	cmd.AddCommand(accountInfoCmd(), accountSaveCmd())
+	cmd.AddCommand(accountInfoCmd(), accountSaveCmd(), accountBalanceCmd(), accountPrepayCmd(),
+		accountUnlockCmd(), accountLockCmd(), accountChangePasswordCmd(),
+		accountImportCmd(), accountExportCmd(), accountListCmd(), accountDefaultCmd(),
+		accountPubKeyCmd(), accountUnwrapCmd(), accountOpenCmd())
 	return cmd
 }
 
@@ -58,7 +71,7 @@ func accountSaveCmd() *cobra.Command {
 			if err != nil {
 				panic(err)
 			}
-			err = ioutil.WriteFile(path, indent, 0755)
+			err = ioutil.WriteFile(path, indent, 0600)
 			if err != nil {
 				return
 			}
@@ -67,3 +80,438 @@ func accountSaveCmd() *cobra.Command {
 	cmd.Flags().StringVar(&path, "path", "account.json", "save account for backup")
 	return cmd
 }
+
+// envNewPassphrase is the env of the new account passphrase used by change-password
+const envNewPassphrase = "ACCIPFS_ACCOUNT_NEW_PASSPHRASE"
+
+func accountUnlockCmd() *cobra.Command {
+	var address string
+	cmd := &cobra.Command{
+		Use:   "unlock",
+		Short: "unlock the account",
+		Long:  "unlock check the account passphrase and keep it in the os keyring for the daemon",
+		Run: func(cmd *cobra.Command, args []string) {
+			config.Initialize()
+			cfg := config.Global()
+			acc, err := account.Find(&cfg, address)
+			if err != nil {
+				fmt.Printf("load account failed error(%v)\n", err)
+				return
+			}
+			pass, err := readPassphrase(account.EnvPassphrase, "Enter account passphrase: ", false)
+			if err != nil {
+				fmt.Printf("read passphrase failed error(%v)\n", err)
+				return
+			}
+			if err := acc.Unlock(account.DefaultKeyring(), pass); err != nil {
+				fmt.Printf("unlock failed error(%v)\n", err)
+				return
+			}
+			if acc.Legacy() {
+				if err := acc.Migrate(&cfg); err != nil {
+					fmt.Printf("remove the password from the config failed error(%v)\n", err)
+					return
+				}
+				fmt.Println("the account password is removed from the config")
+			}
+			fmt.Println("account unlocked:", acc.Name)
+		},
+	}
+	cmd.Flags().StringVar(&address, "address", "", "the account address, the default account is used when empty")
+	return cmd
+}
+
+func accountLockCmd() *cobra.Command {
+	var address string
+	cmd := &cobra.Command{
+		Use:   "lock",
+		Short: "lock the account",
+		Long:  "lock remove the account passphrase from the os keyring",
+		Run: func(cmd *cobra.Command, args []string) {
+			config.Initialize()
+			cfg := config.Global()
+			acc, err := account.Find(&cfg, address)
+			if err != nil {
+				fmt.Printf("load account failed error(%v)\n", err)
+				return
+			}
+			if err := acc.Lock(account.DefaultKeyring()); err != nil {
+				fmt.Printf("lock failed error(%v)\n", err)
+				return
+			}
+			fmt.Println("account locked:", acc.Name)
+		},
+	}
+	cmd.Flags().StringVar(&address, "address", "", "the account address, the default account is used when empty")
+	return cmd
+}
+
+func accountChangePasswordCmd() *cobra.Command {
+	var address string
+	cmd := &cobra.Command{
+		Use:   "change-password",
+		Short: "change the account passphrase",
+		Long:  "change-password encrypt the keystore with a new passphrase, the cleartext password of an old config is removed",
+		Run: func(cmd *cobra.Command, args []string) {
+			config.Initialize()
+			cfg := config.Global()
+			acc, err := account.Find(&cfg, address)
+			if err != nil {
+				fmt.Printf("load account failed error(%v)\n", err)
+				return
+			}
+			ring := account.DefaultKeyring()
+			old, err := acc.Passphrase(ring, func() (string, error) {
+				return readPassphrase(account.EnvPassphrase, "Enter current passphrase: ", false)
+			})
+			if err != nil {
+				fmt.Printf("read passphrase failed error(%v)\n", err)
+				return
+			}
+			pass, err := readPassphrase(envNewPassphrase, "Enter new passphrase: ", true)
+			if err != nil {
+				fmt.Printf("read passphrase failed error(%v)\n", err)
+				return
+			}
+			if err := acc.ChangePassword(&cfg, old, pass); err != nil {
+				fmt.Printf("change password failed error(%v)\n", err)
+				return
+			}
+			if acc.Unlocked(ring) {
+				if err := acc.Unlock(ring, pass); err != nil {
+					fmt.Printf("update keyring failed error(%v)\n", err)
+				}
+			}
+			fmt.Println("account password changed:", acc.Name)
+		},
+	}
+	cmd.Flags().StringVar(&address, "address", "", "the account address, the default account is used when empty")
+	return cmd
+}
+
+func accountImportCmd() *cobra.Command {
+	var name, key string
+	cmd := &cobra.Command{
+		Use:   "import [keystore]",
+		Short: "import an account",
+		Long:  "import an account from a keystore json file or a hex private key with --key",
+		Args:  cobra.MaximumNArgs(1),
+		Run: func(cmd *cobra.Command, args []string) {
+			config.Initialize()
+			cfg := config.Global()
+			if key == "" && len(args) == 0 {
+				fmt.Println("keystore file or private key is required")
+				return
+			}
+			var acc *account.Account
+			if key != "" {
+				pass, err := readPassphrase(account.EnvPassphrase, "Enter new account passphrase: ", true)
+				if err != nil {
+					fmt.Printf("read passphrase failed error(%v)\n", err)
+					return
+				}
+				acc, err = account.ImportKey(&cfg, name, key, pass)
+				if err != nil {
+					fmt.Printf("import failed error(%v)\n", err)
+					return
+				}
+			} else {
+				keyJSON, err := ioutil.ReadFile(args[0])
+				if err != nil {
+					fmt.Printf("read keystore failed error(%v)\n", err)
+					return
+				}
+				pass, err := readPassphrase(account.EnvPassphrase, "Enter keystore passphrase: ", false)
+				if err != nil {
+					fmt.Printf("read passphrase failed error(%v)\n", err)
+					return
+				}
+				acc, err = account.Import(&cfg, name, keyJSON, pass)
+				if err != nil {
+					fmt.Printf("import failed error(%v)\n", err)
+					return
+				}
+			}
+			fmt.Println("account imported:", acc.Name)
+		},
+	}
+	cmd.Flags().StringVar(&name, "name", "", "account name, the address is used when empty")
+	cmd.Flags().StringVar(&key, "key", "", "import the hex private key instead of a keystore file")
+	return cmd
+}
+
+func accountExportCmd() *cobra.Command {
+	var path string
+	cmd := &cobra.Command{
+		Use:   "export [name]",
+		Short: "export an account",
+		Long:  "export the keystore json of an account, the default account is exported when no name is given",
+		Args:  cobra.MaximumNArgs(1),
+		Run: func(cmd *cobra.Command, args []string) {
+			config.Initialize()
+			cfg := config.Global()
+			name := ""
+			if len(args) > 0 {
+				name = args[0]
+			}
+			acc, err := account.Load(&cfg, name)
+			if err != nil {
+				fmt.Printf("load account failed error(%v)\n", err)
+				return
+			}
+			keyJSON, err := acc.Export()
+			if err != nil {
+				fmt.Printf("export failed error(%v)\n", err)
+				return
+			}
+			if path == "" {
+				fmt.Println(string(keyJSON))
+				return
+			}
+			if err := ioutil.WriteFile(path, keyJSON, 0600); err != nil {
+				fmt.Printf("write keystore failed error(%v)\n", err)
+				return
+			}
+			fmt.Println("account exported:", path)
+		},
+	}
+	cmd.Flags().StringVar(&path, "output", "", "write the keystore to the file instead of stdout")
+	return cmd
+}
+
+func accountListCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:   "list",
+		Short: "list the accounts",
+		Long:  "list all the accounts, the default account is marked with *",
+		Run: func(cmd *cobra.Command, args []string) {
+			config.Initialize()
+			cfg := config.Global()
+			accs, err := account.List(&cfg)
+			if err != nil {
+				fmt.Printf("list accounts failed error(%v)\n", err)
+				return
+			}
+			ring := account.DefaultKeyring()
+			for _, acc := range accs {
+				mark := " "
+				if acc.Name == cfg.DefaultAccount || len(accs) == 1 {
+					mark = "*"
+				}
+				state := "locked"
+				if acc.Unlocked(ring) {
+					state = "unlocked"
+				}
+				fmt.Printf("%s %s 0x%s %s\n", mark, acc.Name, acc.KeyStore.Address, state)
+			}
+		},
+	}
+}
+
+func accountDefaultCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:   "default [name]",
+		Short: "select the default account",
+		Long:  "select the default account used by the contract transactions",
+		Args:  cobra.ExactArgs(1),
+		Run: func(cmd *cobra.Command, args []string) {
+			config.Initialize()
+			cfg := config.Global()
+			if err := account.Select(&cfg, args[0]); err != nil {
+				fmt.Printf("select account failed error(%v)\n", err)
+				return
+			}
+			fmt.Println("default account:", args[0])
+		},
+	}
+}
+
+func accountBalanceCmd() *cobra.Command {
+	var peer string
+	cmd := &cobra.Command{
+		Use:   "balance",
+		Short: "show the payment balances",
+		Long:  "show the token balance of your account and the payment balance of every paid node",
+		Run: func(cmd *cobra.Command, args []string) {
+			runPay(&core.PayReq{Peer: peer})
+		},
+	}
+	cmd.Flags().StringVar(&peer, "peer", "", "only show the balance of the peer")
+	return cmd
+}
+
+func accountPrepayCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:   "prepay [peer] [address] [amount]",
+		Short: "prepay to a accelerate node",
+		Long:  "prepay transfer the token amount to the address of a accelerate node and settle the bandwidth of the datastore peer with it",
+		Args:  cobra.ExactArgs(3),
+		Run: func(cmd *cobra.Command, args []string) {
+			runPay(&core.PayReq{
+				Peer:    args[0],
+				Address: args[1],
+				Amount:  args[2],
+			})
+		},
+	}
+}
+
+func runPay(req *core.PayReq) {
+	config.Initialize()
+	cfg := config.Global()
+	client.InitGlobalClient(&cfg)
+	ctx, cancelFunc := context.WithCancel(context.TODO())
+	defer cancelFunc()
+	done := make(chan error)
+	go func(c context.Context) {
+		var err error
+		defer func() {
+			done <- err
+		}()
+		resp, err := client.Pay(c, req)
+		if err != nil {
+			fmt.Printf("pay failed error(%v)\n", err)
+			return
+		}
+		fmt.Println("account:", resp.Account)
+		fmt.Println("token:", resp.Token)
+		for _, b := range resp.Balances {
+			fmt.Printf("peer:%s address:%s bytes:%d paid:%s balance:%s\n", b.Peer, b.Address, b.Bytes, b.Paid, b.Balance)
+		}
+	}(ctx)
+	sigs := make(chan os.Signal, 1)
+	signal.Notify(sigs, os.Interrupt)
+	select {
+	case <-sigs:
+	case v := <-done:
+		if v != nil {
+			panic(v)
+		}
+	}
+}
+
+// accountPrivateKey returns the private key of the account by the keyring or the prompted passphrase
+func accountPrivateKey(cfg *config.Config) (*ecdsa.PrivateKey, error) {
+	acc, err := account.LoadAccount(cfg)
+	if err != nil {
+		return nil, err
+	}
+	return acc.PrivateKey(account.DefaultKeyring(), func() (string, error) {
+		return readPassphrase(account.EnvPassphrase, "Enter account passphrase: ", false)
+	})
+}
+
+func accountPubKeyCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:   "pubkey",
+		Short: "show the account public key",
+		Long:  "pubkey show the compressed public key of the account which the content can be sealed for",
+		Run: func(cmd *cobra.Command, args []string) {
+			config.Initialize()
+			cfg := config.Global()
+			key, err := accountPrivateKey(&cfg)
+			if err != nil {
+				fmt.Printf("load account key failed error(%v)\n", err)
+				return
+			}
+			fmt.Println(seal.PublicKeyHex(&key.PublicKey))
+		},
+	}
+}
+
+func accountUnwrapCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:   "unwrap [security]",
+		Short: "unwrap a content key",
+		Long:  "unwrap decrypt the content key of a sealed content by the account, the key never leaves the client",
+		Args:  cobra.ExactArgs(1),
+		Run: func(cmd *cobra.Command, args []string) {
+			config.Initialize()
+			cfg := config.Global()
+			key, err := accountPrivateKey(&cfg)
+			if err != nil {
+				fmt.Printf("load account key failed error(%v)\n", err)
+				return
+			}
+			content, err := seal.Unwrap(args[0], key)
+			if err != nil {
+				fmt.Printf("unwrap failed error(%v)\n", err)
+				return
+			}
+			fmt.Println(hex.EncodeToString(content))
+		},
+	}
+}
+
+func accountOpenCmd() *cobra.Command {
+	var output string
+	var daemon bool
+	cmd := &cobra.Command{
+		Use:   "open [security] [hash]",
+		Short: "open a sealed content",
+		Long: "open get the sealed content from the daemon and decrypt it by the content key unwrapped by the account on the client, " +
+			"with --daemon the daemon decrypts it after the account proves it is a recipient of the content",
+		Args: cobra.ExactArgs(2),
+		Run: func(cmd *cobra.Command, args []string) {
+			config.Initialize()
+			cfg := config.Global()
+			key, err := accountPrivateKey(&cfg)
+			if err != nil {
+				fmt.Printf("load account key failed error(%v)\n", err)
+				return
+			}
+			content, err := seal.Unwrap(args[0], key)
+			if err != nil {
+				fmt.Printf("unwrap failed error(%v)\n", err)
+				return
+			}
+			req := &core.GetReq{
+				Hash: args[1],
+			}
+			if daemon {
+				req.Time = time.Now().Unix()
+				req.Proof, err = seal.Prove(req.Hash, req.Time, key)
+				if err != nil {
+					fmt.Printf("prove failed error(%v)\n", err)
+					return
+				}
+				req.Key = hex.EncodeToString(content)
+			}
+			var w io.Writer = os.Stdout
+			if output != "" {
+				file, err := os.Create(output)
+				if err != nil {
+					fmt.Printf("create output failed error(%v)\n", err)
+					return
+				}
+				defer file.Close()
+				w = file
+			}
+			client.InitGlobalClient(&cfg)
+			if daemon {
+				req.Writer = w
+				if _, err := client.DataStoreGet(context.Background(), req); err != nil {
+					fmt.Fprintf(os.Stderr, "open failed error(%v)\n", err)
+				}
+				return
+			}
+			pr, pw := io.Pipe()
+			req.Writer = pw
+			go func() {
+				_, err := client.DataStoreGet(context.Background(), req)
+				pw.CloseWithError(err)
+			}()
+			defer pr.Close()
+			r, err := seal.NewReader(pr, content)
+			if err == nil {
+				_, err = io.Copy(w, r)
+			}
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "open failed error(%v)\n", err)
+			}
+		},
+	}
+	cmd.Flags().StringVarP(&output, "output", "o", "", "path of the opened file, it is written to stdout when not set")
+	cmd.Flags().BoolVar(&daemon, "daemon", false, "let the daemon decrypt the content with the proof of the account")
+	return cmd
+}
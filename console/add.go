@@ -2,18 +2,30 @@ package main
 
 import (
 	"context"
+	"crypto/ecdsa"
+	"encoding/json"
 	"fmt"
 	"github.com/glvd/accipfs/client"
 	"github.com/glvd/accipfs/config"
 	"github.com/glvd/accipfs/core"
+	"github.com/glvd/accipfs/ingest"
+	"github.com/glvd/accipfs/seal"
+	"github.com/glvd/accipfs/sign"
 	"github.com/spf13/cobra"
+	"io/ioutil"
 	"os"
 	"os/signal"
+	"sync"
 )
 
 func addCmd() *cobra.Command {
 	var path string
 	var info string
+	var manifest, checkpoint, report string
+	var workers, retries int
+	var transcode, encrypt, signed bool
+	var recipients []string
+	var workDir string
 	cmd := &cobra.Command{
 		Use:   "add",
 		Short: "add a source to this node",
@@ -23,6 +35,31 @@ func addCmd() *cobra.Command {
 			config.Initialize()
 			cfg := config.Global()
 			client.InitGlobalClient(&cfg)
+			if encrypt {
+				key, err := accountPrivateKey(&cfg)
+				if err != nil {
+					fmt.Printf("load account key failed error(%v)\n", err)
+					return
+				}
+				recipients = append(recipients, seal.PublicKeyHex(&key.PublicKey))
+			}
+			if manifest != "" {
+				if transcode && len(recipients) > 0 {
+					fmt.Println("the transcoded files can not be sealed, add them without --transcode")
+					return
+				}
+				var key *ecdsa.PrivateKey
+				if signed {
+					var err error
+					key, err = accountPrivateKey(&cfg)
+					if err != nil {
+						fmt.Printf("load account key failed error(%v)\n", err)
+						return
+					}
+				}
+				addManifest(manifest, checkpoint, report, workers, retries, transcode, workDir, key, recipients)
+				return
+			}
 			if len(args) <= 0 {
 				return
 			}
@@ -35,14 +72,26 @@ func addCmd() *cobra.Command {
 					done <- err
 				}()
 				file, err := client.UploadFile(c, &core.UploadReq{
-					Path: args[0],
+					Path:       args[0],
+					Recipients: recipients,
 				})
 				if err != nil {
 					return
 				}
-				add, err := client.Add(c, &core.NodeAddReq{
+				req := &core.NodeAddReq{
 					Hash: file.Hash,
-				})
+				}
+				if file.Security != "" {
+					fmt.Println("security key:", file.Security)
+					//the envelope is kept by the daemon to check the recipients opening the file
+					info := core.DataInfoV1{
+						RootHash: file.Hash,
+						Security: core.Security{Key: file.Security},
+						Version:  core.DataInfoVersion1,
+					}
+					req.JSNFO = info.JSON()
+				}
+				add, err := client.Add(c, req)
 				if err != nil {
 					return
 				}
@@ -64,5 +113,115 @@ func addCmd() *cobra.Command {
 	}
 	cmd.Flags().StringVar(&path, "path", "", "set the file dirctory path to add")
 	cmd.Flags().StringVar(&info, "info", "", "set the file info to load")
+	cmd.Flags().StringVar(&manifest, "manifest", "", "add the files of a json or csv manifest with their media info")
+	cmd.Flags().StringVar(&checkpoint, "checkpoint", "", "set the checkpoint file to resume the manifest (default manifest path with .checkpoint)")
+	cmd.Flags().StringVar(&report, "report", "", "write the summary report of the manifest as json to the file")
+	cmd.Flags().IntVar(&workers, "workers", ingest.DefaultWorkers, "set the count of the files added at once")
+	cmd.Flags().IntVar(&retries, "retries", 0, "set the retries of a failed file with backoff")
+	cmd.Flags().BoolVar(&encrypt, "encrypt", false, "seal the file for the account with a new content key")
+	cmd.Flags().StringSliceVar(&recipients, "recipient", nil, "seal the file for the public key, it can be set many times")
+	cmd.Flags().BoolVar(&transcode, "transcode", false, "transcode the files of the manifest to hls with thumbnails and posters by the local ffmpeg")
+	cmd.Flags().BoolVar(&signed, "sign", false, "sign the infos of the manifest with the account key as their publisher")
+	cmd.Flags().StringVar(&workDir, "workdir", "", "set the directory of the transcoding output (default system temp directory)")
 	return cmd
 }
+
+func addManifest(manifest, checkpoint, report string, workers, retries int, transcode bool, workDir string, key *ecdsa.PrivateKey, recipients []string) {
+	items, err := ingest.LoadManifest(manifest)
+	if err != nil {
+		fmt.Printf("load manifest failed error(%v)\n", err)
+		return
+	}
+	if checkpoint == "" {
+		checkpoint = manifest + ".checkpoint"
+	}
+	cp, err := ingest.OpenCheckpoint(checkpoint)
+	if err != nil {
+		fmt.Printf("open checkpoint failed error(%v)\n", err)
+		return
+	}
+	defer cp.Close()
+	//the security keys of the sealed files by their hash until their infos are added
+	var securities sync.Map
+	in := ingest.New(func(ctx context.Context, path string) (string, error) {
+		resp, err := client.UploadFile(ctx, &core.UploadReq{
+			Path:       path,
+			Recipients: recipients,
+		})
+		if err != nil {
+			return "", err
+		}
+		if resp.Security != "" {
+			securities.Store(resp.Hash, resp.Security)
+		}
+		return resp.Hash, nil
+	}, func(ctx context.Context, hash string, info *core.DataInfoV1) (string, error) {
+		if v, ok := securities.Load(hash); ok {
+			securities.Delete(hash)
+			info.Security.Key = v.(string)
+		}
+		if key != nil {
+			if err := sign.ETH(info, key); err != nil {
+				return "", err
+			}
+		}
+		resp, err := client.Add(ctx, &core.NodeAddReq{
+			Hash:  hash,
+			JSNFO: info.JSON(),
+		})
+		if err != nil {
+			return "", err
+		}
+		return resp.Hash, nil
+	}, workers)
+	in.SetCheckpoint(cp)
+	in.SetRetries(retries)
+	if transcode {
+		ffmpeg, err := ingest.LookupFFmpeg()
+		if err != nil {
+			fmt.Println("skip transcoding:", err)
+		} else {
+			in.SetTranscoder(ffmpeg, workDir)
+		}
+	}
+	in.OnResult(func(result *ingest.Result) {
+		switch {
+		case result.Skipped:
+			fmt.Println("skip", result.Path, result.Hash)
+		case result.Error != "":
+			fmt.Println("failed", result.Path, result.Error)
+		default:
+			fmt.Println("added", result.Path, result.Hash)
+		}
+	})
+	fmt.Printf("add %d items of manifest %s\n", len(items), manifest)
+
+	ctx, cancelFunc := context.WithCancel(context.TODO())
+	defer cancelFunc()
+	sigs := make(chan os.Signal, 1)
+	signal.Notify(sigs, os.Interrupt)
+	go func() {
+		<-sigs
+		fmt.Println("interrupted, run again to resume from", checkpoint)
+		cancelFunc()
+	}()
+	summary, err := in.Run(ctx, items)
+	if err != nil {
+		fmt.Printf("add manifest failed error(%v)\n", err)
+	}
+	fmt.Printf("total: %d added: %d skipped: %d failed: %d\n", summary.Total, summary.Added, summary.Skipped, summary.Failed)
+	for _, cid := range summary.CIDs() {
+		fmt.Println(cid)
+	}
+	if report == "" {
+		return
+	}
+	data, err := json.MarshalIndent(summary, "", "  ")
+	if err != nil {
+		fmt.Printf("marshal report failed error(%v)\n", err)
+		return
+	}
+	if err := ioutil.WriteFile(report, data, 0644); err != nil {
+		fmt.Printf("write report failed error(%v)\n", err)
+	}
+}
@@ -0,0 +1,125 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"github.com/glvd/accipfs/backup"
+	"github.com/glvd/accipfs/client"
+	"github.com/glvd/accipfs/config"
+	"github.com/glvd/accipfs/controller"
+	"github.com/glvd/accipfs/core"
+	"github.com/spf13/cobra"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+)
+
+// envBackupPassphrase is the env of the backup passphrase, it is prompted when not set
+const envBackupPassphrase = "ACCIPFS_BACKUP_PASSPHRASE"
+
+func backupCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "backup",
+		Short: "backup the node",
+		Long:  "backup the node identity, account and caches to an encrypted archive",
+	}
+	cmd.AddCommand(backupCreateCmd())
+	return cmd
+}
+
+func backupCreateCmd() *cobra.Command {
+	var output string
+	var withPins bool
+	cmd := &cobra.Command{
+		Use:   "create",
+		Short: "create a backup archive",
+		Long:  "create an encrypted archive with the config, datastore identity, swarm key, eth keystore and node cache",
+		Run: func(cmd *cobra.Command, args []string) {
+			config.Initialize()
+			cfg := config.Global()
+			client.InitGlobalClient(&cfg)
+			var pins []string
+			if withPins {
+				resp, err := client.DataStorePinLs(context.TODO(), &core.DataStorePinLsReq{})
+				if err != nil {
+					fmt.Printf("get pin list failed error(%v)\n", err)
+					return
+				}
+				pins = append([]string{}, resp.Pins...)
+			}
+			//the node cache of a running daemon is dumped by the daemon
+			var cache []byte
+			if resp, err := client.NodeBackup(context.TODO(), &core.NodeBackupReq{}); err == nil {
+				cache = resp.Cache
+			}
+			files, err := backup.Collect(backup.DefaultPaths(), pins, cache)
+			if err != nil {
+				fmt.Printf("collect backup failed error(%v)\n", err)
+				return
+			}
+			pass, err := readPassphrase(envBackupPassphrase, "Enter backup passphrase: ", true)
+			if err != nil {
+				fmt.Printf("read passphrase failed error(%v)\n", err)
+				return
+			}
+			file, err := os.OpenFile(output, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, 0600)
+			if err != nil {
+				fmt.Printf("create backup failed error(%v)\n", err)
+				return
+			}
+			defer file.Close()
+			if err := backup.Write(file, pass, files); err != nil {
+				fmt.Printf("write backup failed error(%v)\n", err)
+				return
+			}
+			fmt.Printf("backup %d files to %s\n", len(files), output)
+		},
+	}
+	cmd.Flags().StringVar(&output, "output", "accipfs.backup", "the backup archive path")
+	cmd.Flags().BoolVar(&withPins, "pins", false, "add the pin list of the running daemon")
+	return cmd
+}
+
+// restoreNode recreates the node from the backup archive
+func restoreNode(path string) error {
+	file, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	pass, err := readPassphrase(envBackupPassphrase, "Enter backup passphrase: ", false)
+	if err != nil {
+		return err
+	}
+	files, err := backup.Read(file, pass)
+	if err != nil {
+		return err
+	}
+	cf, b := backup.Config(files)
+	if !b {
+		return fmt.Errorf("config not found in %s", path)
+	}
+	err = ioutil.WriteFile(filepath.Join(config.WorkDir, "config.json"), cf.Data, 0600)
+	if err != nil {
+		return err
+	}
+	if err := config.LoadConfig(); err != nil {
+		return err
+	}
+	cfg := config.Global()
+	if err := config.SaveGenesis(&cfg); err != nil {
+		return err
+	}
+	if err := cfg.Init(); err != nil {
+		return err
+	}
+	if err := controller.New(&cfg).Initialize(); err != nil {
+		return err
+	}
+	//overwrite the new created identity and caches with the backup
+	if err := backup.Extract(backup.DefaultPaths(), files); err != nil {
+		return err
+	}
+	fmt.Printf("restore %d files from %s\n", len(files), path)
+	return nil
+}
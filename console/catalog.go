@@ -0,0 +1,97 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"github.com/glvd/accipfs/client"
+	"github.com/glvd/accipfs/config"
+	"github.com/glvd/accipfs/core"
+	"github.com/spf13/cobra"
+	"os"
+	"os/signal"
+)
+
+func catalogCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "catalog",
+		Short: "export and import the media catalog",
+		Long:  "move the media catalog between the nodes as an ipld dag",
+	}
+	cmd.AddCommand(catalogExportCmd(), catalogImportCmd())
+	return cmd
+}
+
+func runCatalog(f func(ctx context.Context) error) {
+	config.Initialize()
+	cfg := config.Global()
+	client.InitGlobalClient(&cfg)
+	ctx, cancelFunc := context.WithCancel(context.TODO())
+	defer cancelFunc()
+	done := make(chan error)
+	go func(c context.Context) {
+		done <- f(c)
+	}(ctx)
+	sigs := make(chan os.Signal, 1)
+	signal.Notify(sigs, os.Interrupt)
+	select {
+	case <-sigs:
+	case v := <-done:
+		if v != nil {
+			fmt.Printf("catalog failed error(%v)\n", v)
+		}
+	}
+}
+
+func catalogExportCmd() *cobra.Command {
+	var req core.CatalogExportReq
+	cmd := &cobra.Command{
+		Use:   "export",
+		Short: "export the catalog",
+		Long:  "export the records of the catalog as an ipld dag sharded by the media number prefix and print its root",
+		Run: func(cmd *cobra.Command, args []string) {
+			runCatalog(func(ctx context.Context) error {
+				resp, err := client.CatalogExport(ctx, &req)
+				if err != nil {
+					return err
+				}
+				fmt.Printf("exported %d records\n", resp.Records)
+				fmt.Println("root:", resp.Root)
+				return nil
+			})
+		},
+	}
+	cmd.Flags().StringVar(&req.Codec, "codec", "dag-cbor", "codec of the dag: dag-cbor or dag-json")
+	cmd.Flags().IntVar(&req.PrefixLen, "prefix", 2, "length of the media number prefix the records are sharded by")
+	return cmd
+}
+
+func catalogImportCmd() *cobra.Command {
+	var noPin bool
+	cmd := &cobra.Command{
+		Use:   "import [root]",
+		Short: "import a catalog",
+		Long:  "walk the catalog dag of the root, pin the referenced content and load the records",
+		Args:  cobra.ExactArgs(1),
+		Run: func(cmd *cobra.Command, args []string) {
+			runCatalog(func(ctx context.Context) error {
+				resp, err := client.CatalogImport(ctx, &core.CatalogImportReq{
+					Root:  args[0],
+					NoPin: noPin,
+				})
+				if err != nil {
+					return err
+				}
+				fmt.Printf("imported %d records, pinned %d\n", resp.Records, len(resp.Pinned))
+				for _, v := range resp.Invalid {
+					fmt.Println("invalid record", v)
+				}
+				for _, v := range resp.Failed {
+					fmt.Println("pin failed", v)
+				}
+				return nil
+			})
+		},
+	}
+	cmd.Flags().BoolVar(&noPin, "nopin", false, "only load the records without pinning the referenced content")
+	return cmd
+}
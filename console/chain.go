@@ -0,0 +1,63 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"github.com/glvd/accipfs/client"
+	"github.com/glvd/accipfs/config"
+	"github.com/glvd/accipfs/core"
+	"github.com/spf13/cobra"
+	"os"
+	"os/signal"
+	"time"
+)
+
+func chainCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "chain",
+		Short: "show the contract index",
+		Long:  "show the sync status of the contract event indexer",
+	}
+	cmd.AddCommand(chainStatusCmd())
+	return cmd
+}
+
+func chainStatusCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:   "status",
+		Short: "show the index status",
+		Long:  "show the indexed height, the chain head and the handled reorganizations",
+		Run: func(cmd *cobra.Command, args []string) {
+			config.Initialize()
+			cfg := config.Global()
+			client.InitGlobalClient(&cfg)
+			ctx, cancelFunc := context.WithCancel(context.TODO())
+			defer cancelFunc()
+			done := make(chan error)
+			go func(c context.Context) {
+				resp, err := client.ChainStatus(c, &core.ChainStatusReq{})
+				if err != nil {
+					done <- err
+					return
+				}
+				fmt.Println("height:", resp.Height)
+				fmt.Println("hash:", resp.Hash)
+				fmt.Println("head:", resp.Head)
+				fmt.Println("reorgs:", resp.Reorgs)
+				if resp.LastSync > 0 {
+					fmt.Println("last sync:", time.Unix(resp.LastSync, 0).Format(time.RFC3339))
+				}
+				done <- nil
+			}(ctx)
+			sigs := make(chan os.Signal, 1)
+			signal.Notify(sigs, os.Interrupt)
+			select {
+			case <-sigs:
+			case v := <-done:
+				if v != nil {
+					fmt.Printf("chain status failed error(%v)\n", v)
+				}
+			}
+		},
+	}
+}
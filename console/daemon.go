@@ -41,7 +41,10 @@ func daemonCmd() *cobra.Command {
 			defer func() {
 				linker.Stop()
 			}()
-			linker.Start()
+			if err := linker.Start(); err != nil {
+				fmt.Printf("start failed error(%v)\n", err)
+				return
+			}
 			waitingInterruptSignal()
 		},
 	}
@@ -0,0 +1,110 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"github.com/glvd/accipfs/client"
+	"github.com/glvd/accipfs/config"
+	"github.com/glvd/accipfs/core"
+	"github.com/spf13/cobra"
+	"io"
+	"os"
+	"os/signal"
+)
+
+func dagCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "dag",
+		Short: "export and import dags as car files",
+		Long:  "move the dags between the nodes and the offline storages as car v1 or v2 files",
+	}
+	cmd.AddCommand(dagExportCmd(), dagImportCmd())
+	return cmd
+}
+
+func runDag(f func(ctx context.Context) error) {
+	config.Initialize()
+	cfg := config.Global()
+	client.InitGlobalClient(&cfg)
+	ctx, cancelFunc := context.WithCancel(context.TODO())
+	defer cancelFunc()
+	done := make(chan error)
+	go func(c context.Context) {
+		done <- f(c)
+	}(ctx)
+	sigs := make(chan os.Signal, 1)
+	signal.Notify(sigs, os.Interrupt)
+	select {
+	case <-sigs:
+	case v := <-done:
+		if v != nil {
+			fmt.Fprintf(os.Stderr, "dag failed error(%v)\n", v)
+		}
+	}
+}
+
+func dagExportCmd() *cobra.Command {
+	var output string
+	var version int
+	cmd := &cobra.Command{
+		Use:   "export [root]...",
+		Short: "export dags to a car file",
+		Long:  "write the dags of the roots to a car file, the car is written to stdout when no output is set",
+		Args:  cobra.MinimumNArgs(1),
+		Run: func(cmd *cobra.Command, args []string) {
+			runDag(func(ctx context.Context) error {
+				var w io.Writer = os.Stdout
+				if output != "" {
+					file, err := os.Create(output)
+					if err != nil {
+						return err
+					}
+					defer file.Close()
+					w = file
+				}
+				_, err := client.DataStoreDagExport(ctx, &core.DagExportReq{
+					Roots:   args,
+					Version: version,
+					Writer:  w,
+				})
+				return err
+			})
+		},
+	}
+	cmd.Flags().StringVarP(&output, "output", "o", "", "path of the car file")
+	cmd.Flags().IntVar(&version, "version", 1, "version of the car file: 1 or 2")
+	return cmd
+}
+
+func dagImportCmd() *cobra.Command {
+	var pin bool
+	cmd := &cobra.Command{
+		Use:   "import [file]",
+		Short: "import a car file",
+		Long:  "add the blocks of a car v1 or v2 file to the datastore and print the roots",
+		Args:  cobra.ExactArgs(1),
+		Run: func(cmd *cobra.Command, args []string) {
+			runDag(func(ctx context.Context) error {
+				file, err := os.Open(args[0])
+				if err != nil {
+					return err
+				}
+				defer file.Close()
+				resp, err := client.DataStoreDagImport(ctx, &core.DagImportReq{
+					Reader:   file,
+					PinRoots: pin,
+				})
+				if err != nil {
+					return err
+				}
+				fmt.Printf("imported %d blocks\n", resp.Blocks)
+				for _, root := range resp.Roots {
+					fmt.Println("root:", root)
+				}
+				return nil
+			})
+		},
+	}
+	cmd.Flags().BoolVar(&pin, "pin", true, "pin the roots of the car file")
+	return cmd
+}
@@ -4,14 +4,18 @@ import (
 	"github.com/glvd/accipfs/account"
 	"github.com/glvd/accipfs/config"
 	"github.com/glvd/accipfs/controller"
+	"github.com/glvd/accipfs/datastore"
 	ipfsCfg "github.com/ipfs/go-ipfs-config"
 	"github.com/ipfs/interface-go-ipfs-core/options"
 	"github.com/spf13/cobra"
+	"io/ioutil"
 	"os"
 )
 
 func initCmd() *cobra.Command {
 	var restore string
+	var private bool
+	var swarmKey string
 	cmd := &cobra.Command{
 		Use:   "init",
 		Short: "init run",
@@ -20,9 +24,22 @@ func initCmd() *cobra.Command {
 
 		},
 		Run: func(cmd *cobra.Command, args []string) {
+			if restore != "" {
+				if err := restoreNode(restore); err != nil {
+					panic(err)
+				}
+				return
+			}
 			cfg := config.Default()
+			key, err := readSwarmKey(swarmKey, private)
+			if err != nil {
+				panic(err)
+			}
+			if key != nil {
+				cfg.IPFS.Repo.Private = true
+			}
 
-			err := config.SaveGenesis(cfg)
+			err = config.SaveGenesis(cfg)
 			if err != nil {
 				panic(err)
 			}
@@ -35,6 +52,11 @@ func initCmd() *cobra.Command {
 			if err := c.Initialize(); err != nil {
 				panic(err)
 			}
+			if key != nil {
+				if err := datastore.WriteSwarmKey(os.Getenv("IPFS_PATH"), key); err != nil {
+					panic(err)
+				}
+			}
 			//file, err := os.OpenFile("key", os.O_CREATE|os.O_SYNC|os.O_RDWR|os.O_TRUNC, 0755)
 			//if err != nil {
 			//	panic(err)
@@ -51,7 +73,11 @@ func initCmd() *cobra.Command {
 				panic(err)
 			}
 
-			acc, err := account.NewAccount(cfg)
+			pass, err := readPassphrase(account.EnvPassphrase, "Enter account passphrase: ", true)
+			if err != nil {
+				panic(err)
+			}
+			acc, err := account.NewAccount(cfg, pass)
 			if err != nil {
 				panic(err)
 			}
@@ -65,6 +91,20 @@ func initCmd() *cobra.Command {
 
 		},
 	}
-	cmd.Flags().StringVar(&restore, "restore", "", "init from a account file")
+	cmd.Flags().StringVar(&restore, "restore", "", "init from a backup archive")
+	cmd.Flags().BoolVar(&private, "private", false, "init a private network with a new swarm key")
+	cmd.Flags().StringVar(&swarmKey, "swarm-key", "", "init a private network joined with the swarm key file")
 	return cmd
 }
+
+// readSwarmKey reads the swarm key from path or generates a new one when private is set,
+// nil is returned for the public network
+func readSwarmKey(path string, private bool) ([]byte, error) {
+	if path != "" {
+		return ioutil.ReadFile(path)
+	}
+	if private {
+		return datastore.GenerateSwarmKey()
+	}
+	return nil, nil
+}
@@ -45,7 +45,7 @@ func main() {
 	}
 	config.WorkDir = path
 
-	rootCmd.AddCommand(initCmd(), daemonCmd(), idCmd(), nodeCmd(), versionCmd(), tagCmd(), pinCmd(), addCmd(), accountCmd())
+	rootCmd.AddCommand(initCmd(), daemonCmd(), idCmd(), nodeCmd(), versionCmd(), tagCmd(), pinCmd(), addCmd(), accountCmd(), statsCmd(), repoCmd(), backupCmd(), chainCmd(), processCmd(), nameCmd(), catalogCmd(), dagCmd(), taskCmd())
 	rootCmd.PersistentFlags().StringVar(&accipfs.DefaultPath, "path", ".", "set work path")
 
 	rootCmd.PersistentFlags().StringVar(&log.Output, "log-output", "stdout", "set the output log name")
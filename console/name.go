@@ -0,0 +1,174 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/glvd/accipfs/client"
+	"github.com/glvd/accipfs/config"
+	"github.com/glvd/accipfs/core"
+	"github.com/spf13/cobra"
+	"os"
+	"os/signal"
+	"time"
+)
+
+func nameCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "name",
+		Short: "publish and resolve the ipns names",
+		Long:  "publish the mutable ipns names pointing to the media content and resolve them",
+	}
+	cmd.AddCommand(namePublishCmd(), nameResolveCmd(), nameKeyCmd())
+	return cmd
+}
+
+func runName(f func(ctx context.Context) error) {
+	config.Initialize()
+	cfg := config.Global()
+	client.InitGlobalClient(&cfg)
+	ctx, cancelFunc := context.WithCancel(context.TODO())
+	defer cancelFunc()
+	done := make(chan error)
+	go func(c context.Context) {
+		done <- f(c)
+	}(ctx)
+	sigs := make(chan os.Signal, 1)
+	signal.Notify(sigs, os.Interrupt)
+	select {
+	case <-sigs:
+	case v := <-done:
+		if v != nil {
+			fmt.Printf("name failed error(%v)\n", v)
+		}
+	}
+}
+
+func namePublishCmd() *cobra.Command {
+	var req core.NamePublishReq
+	cmd := &cobra.Command{
+		Use:   "publish [path]",
+		Short: "publish a path to the name",
+		Long:  "publish the ipfs path or the root of the local catalog to the ipns name of the key",
+		Args:  cobra.MaximumNArgs(1),
+		Run: func(cmd *cobra.Command, args []string) {
+			runName(func(ctx context.Context) error {
+				if len(args) > 0 {
+					req.Path = args[0]
+				}
+				if req.Path == "" && !req.Catalog {
+					return errors.New("a path or --catalog is required")
+				}
+				resp, err := client.NamePublish(ctx, &req)
+				if err != nil {
+					return err
+				}
+				fmt.Printf("published to %s: %s\n", resp.Name, resp.Value)
+				return nil
+			})
+		},
+	}
+	cmd.Flags().BoolVar(&req.Catalog, "catalog", false, "publish the root of the local catalog")
+	cmd.Flags().StringVar(&req.Key, "key", "", "name of the key to publish with, the node key is used when empty")
+	cmd.Flags().DurationVar(&req.Lifetime, "lifetime", 24*time.Hour, "lifetime of the record")
+	cmd.Flags().DurationVar(&req.TTL, "ttl", 0, "cache ttl of the record")
+	return cmd
+}
+
+func nameResolveCmd() *cobra.Command {
+	var noCache bool
+	cmd := &cobra.Command{
+		Use:   "resolve [name]",
+		Short: "resolve a name",
+		Long:  "resolve the ipns name to the ipfs path it points to",
+		Args:  cobra.ExactArgs(1),
+		Run: func(cmd *cobra.Command, args []string) {
+			runName(func(ctx context.Context) error {
+				resp, err := client.NameResolve(ctx, &core.NameResolveReq{
+					Name:    args[0],
+					NoCache: noCache,
+				})
+				if err != nil {
+					return err
+				}
+				fmt.Println(resp.Path)
+				return nil
+			})
+		},
+	}
+	cmd.Flags().BoolVar(&noCache, "nocache", false, "do not use the cached records")
+	return cmd
+}
+
+func nameKeyCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "key",
+		Short: "manage the keys of the names",
+		Long:  "generate, list and remove the keys which the names are published with",
+	}
+	cmd.AddCommand(nameKeyGenCmd(), nameKeyListCmd(), nameKeyRmCmd())
+	return cmd
+}
+
+func nameKeyGenCmd() *cobra.Command {
+	var req core.KeyGenReq
+	cmd := &cobra.Command{
+		Use:   "gen [name]",
+		Short: "generate a key",
+		Long:  "generate a new key with the name",
+		Args:  cobra.ExactArgs(1),
+		Run: func(cmd *cobra.Command, args []string) {
+			runName(func(ctx context.Context) error {
+				req.Name = args[0]
+				resp, err := client.KeyGen(ctx, &req)
+				if err != nil {
+					return err
+				}
+				fmt.Println(resp.Key.Name, resp.Key.ID)
+				return nil
+			})
+		},
+	}
+	cmd.Flags().StringVar(&req.Type, "type", "ed25519", "type of the key: rsa or ed25519")
+	cmd.Flags().IntVar(&req.Size, "size", 0, "bits of the rsa key")
+	return cmd
+}
+
+func nameKeyListCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:   "list",
+		Short: "list the keys",
+		Long:  "list the names and ids of the keys",
+		Run: func(cmd *cobra.Command, args []string) {
+			runName(func(ctx context.Context) error {
+				resp, err := client.KeyList(ctx, &core.KeyListReq{})
+				if err != nil {
+					return err
+				}
+				for _, key := range resp.Keys {
+					fmt.Println(key.Name, key.ID)
+				}
+				return nil
+			})
+		},
+	}
+}
+
+func nameKeyRmCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:   "rm [name]",
+		Short: "remove a key",
+		Long:  "remove the key with the name",
+		Args:  cobra.ExactArgs(1),
+		Run: func(cmd *cobra.Command, args []string) {
+			runName(func(ctx context.Context) error {
+				resp, err := client.KeyRm(ctx, &core.KeyRmReq{Name: args[0]})
+				if err != nil {
+					return err
+				}
+				fmt.Println("removed", resp.Key.Name, resp.Key.ID)
+				return nil
+			})
+		},
+	}
+}
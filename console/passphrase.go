@@ -0,0 +1,37 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+
+	"golang.org/x/crypto/ssh/terminal"
+)
+
+// readPassphrase returns the passphrase from the env, or prompts it on the terminal
+func readPassphrase(env string, prompt string, confirm bool) (string, error) {
+	if v := os.Getenv(env); v != "" {
+		return v, nil
+	}
+	fmt.Print(prompt)
+	pass, err := terminal.ReadPassword(int(os.Stdin.Fd()))
+	fmt.Println()
+	if err != nil {
+		return "", err
+	}
+	if len(pass) == 0 {
+		return "", errors.New("empty passphrase")
+	}
+	if confirm {
+		fmt.Print("Repeat passphrase: ")
+		again, err := terminal.ReadPassword(int(os.Stdin.Fd()))
+		fmt.Println()
+		if err != nil {
+			return "", err
+		}
+		if string(again) != string(pass) {
+			return "", errors.New("passphrases do not match")
+		}
+	}
+	return string(pass), nil
+}
@@ -0,0 +1,71 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"github.com/glvd/accipfs/client"
+	"github.com/glvd/accipfs/config"
+	"github.com/glvd/accipfs/core"
+	"github.com/spf13/cobra"
+	"os"
+	"os/signal"
+	"time"
+)
+
+func processCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "process",
+		Short: "show the supervised processes",
+		Long:  "show the state of the node processes supervised by the daemon",
+	}
+	cmd.AddCommand(processListCmd())
+	return cmd
+}
+
+func processListCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:   "list",
+		Short: "list the processes",
+		Long:  "list the state, pid, restarts and the liveness of every supervised process",
+		Run: func(cmd *cobra.Command, args []string) {
+			config.Initialize()
+			cfg := config.Global()
+			client.InitGlobalClient(&cfg)
+			ctx, cancelFunc := context.WithCancel(context.TODO())
+			defer cancelFunc()
+			done := make(chan error)
+			go func(c context.Context) {
+				resp, err := client.ProcessList(c, &core.ProcessListReq{})
+				if err != nil {
+					done <- err
+					return
+				}
+				for _, p := range resp.Processes {
+					fmt.Printf("%s: %s pid(%d) restarts(%d) healthy(%t)\n", p.Name, p.State, p.Pid, p.Restarts, p.Healthy)
+					if p.Version != "" {
+						fmt.Println("  version:", p.Version)
+					}
+					if p.Started > 0 {
+						fmt.Println("  started:", time.Unix(p.Started, 0).Format(time.RFC3339))
+					}
+					if p.LastError != "" {
+						fmt.Printf("  last error(exit %d): %s\n", p.ExitCode, p.LastError)
+					}
+					if p.LogFile != "" {
+						fmt.Println("  log:", p.LogFile)
+					}
+				}
+				done <- nil
+			}(ctx)
+			sigs := make(chan os.Signal, 1)
+			signal.Notify(sigs, os.Interrupt)
+			select {
+			case <-sigs:
+			case v := <-done:
+				if v != nil {
+					fmt.Printf("process list failed error(%v)\n", v)
+				}
+			}
+		},
+	}
+}
@@ -0,0 +1,153 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"github.com/dustin/go-humanize"
+	"github.com/glvd/accipfs/client"
+	"github.com/glvd/accipfs/config"
+	"github.com/glvd/accipfs/core"
+	"github.com/spf13/cobra"
+	"os"
+	"os/signal"
+	"time"
+)
+
+func repoCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "repo",
+		Short: "manage the datastore repo",
+		Long:  "show the storage usage and collect the garbage of the datastore repo",
+	}
+	cmd.AddCommand(repoGCCmd(), repoStatCmd(), repoVerifyCmd(), repoRepairCmd())
+	return cmd
+}
+
+func runRepo(f func(ctx context.Context) error) {
+	config.Initialize()
+	cfg := config.Global()
+	client.InitGlobalClient(&cfg)
+	ctx, cancelFunc := context.WithCancel(context.TODO())
+	defer cancelFunc()
+	done := make(chan error)
+	go func(c context.Context) {
+		done <- f(c)
+	}(ctx)
+	sigs := make(chan os.Signal, 1)
+	signal.Notify(sigs, os.Interrupt)
+	select {
+	case <-sigs:
+	case v := <-done:
+		if v != nil {
+			fmt.Printf("repo failed error(%v)\n", v)
+		}
+	}
+}
+
+func repoGCCmd() *cobra.Command {
+	var force bool
+	cmd := &cobra.Command{
+		Use:   "gc",
+		Short: "collect the garbage",
+		Long:  "remove the unpinned blocks and evict the least recently used data over the storage limit",
+		Run: func(cmd *cobra.Command, args []string) {
+			runRepo(func(ctx context.Context) error {
+				resp, err := client.DataStoreRepoGC(ctx, &core.RepoGCReq{
+					Force: force,
+				})
+				if err != nil {
+					return err
+				}
+				for _, v := range resp.Evicted {
+					fmt.Println("evicted", v)
+				}
+				fmt.Printf("removed %d blocks, size %s -> %s\n", resp.Removed, humanize.IBytes(resp.Before), humanize.IBytes(resp.After))
+				return nil
+			})
+		},
+	}
+	cmd.Flags().BoolVar(&force, "force", true, "collect even if the repo is under the high watermark")
+	return cmd
+}
+
+func repoStatCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:   "stat",
+		Short: "show the repo stat",
+		Long:  "show the storage usage, object count, pin count and the caches of the datastore repo",
+		Run: func(cmd *cobra.Command, args []string) {
+			runRepo(func(ctx context.Context) error {
+				resp, err := client.DataStoreRepoStat(ctx, &core.RepoStatReq{})
+				if err != nil {
+					return err
+				}
+				fmt.Println("path:", resp.Path)
+				fmt.Println("size:", humanize.IBytes(resp.Size))
+				fmt.Println("objects:", resp.Objects)
+				fmt.Println("pins:", resp.Pins)
+				if resp.Limit == 0 {
+					fmt.Println("limit: unlimited")
+				} else {
+					fmt.Println("limit:", humanize.IBytes(resp.Limit))
+					fmt.Println("high water:", humanize.IBytes(resp.HighWater))
+					fmt.Println("low water:", humanize.IBytes(resp.LowWater))
+				}
+				fmt.Println("cached roots:", resp.Cached)
+				for _, v := range resp.Caches {
+					fmt.Printf("cache %s: %d keys, %s\n", v.Name, v.Keys, humanize.IBytes(uint64(v.Size)))
+				}
+				return nil
+			})
+		},
+	}
+}
+
+func repoVerifyCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:   "verify",
+		Short: "verify the blocks",
+		Long:  "re-hash all the blocks in the datastore repo and report the corrupt ones",
+		Run: func(cmd *cobra.Command, args []string) {
+			runRepo(func(ctx context.Context) error {
+				resp, err := client.DataStoreRepoVerify(ctx, &core.RepoVerifyReq{})
+				if err != nil {
+					return err
+				}
+				for _, v := range resp.Corrupt {
+					fmt.Println("corrupt", v)
+				}
+				fmt.Printf("checked %d blocks, %d corrupt\n", resp.Checked, len(resp.Corrupt))
+				return nil
+			})
+		},
+	}
+}
+
+func repoRepairCmd() *cobra.Command {
+	var timeout time.Duration
+	cmd := &cobra.Command{
+		Use:   "repair",
+		Short: "repair the corrupt blocks",
+		Long:  "drop the corrupt blocks and fetch them again from the providers",
+		Run: func(cmd *cobra.Command, args []string) {
+			runRepo(func(ctx context.Context) error {
+				resp, err := client.DataStoreRepoRepair(ctx, &core.RepoRepairReq{
+					Timeout: timeout,
+				})
+				if err != nil {
+					return err
+				}
+				for _, v := range resp.Repaired {
+					fmt.Println("repaired", v)
+				}
+				for _, v := range resp.Failed {
+					fmt.Println("failed", v)
+				}
+				fmt.Printf("checked %d blocks, %d repaired, %d failed\n", resp.Checked, len(resp.Repaired), len(resp.Failed))
+				return nil
+			})
+		},
+	}
+	cmd.Flags().DurationVar(&timeout, "timeout", 30*time.Second, "fetch timeout of every corrupt block")
+	return cmd
+}
@@ -0,0 +1,160 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"github.com/dustin/go-humanize"
+	"github.com/glvd/accipfs/client"
+	"github.com/glvd/accipfs/config"
+	"github.com/glvd/accipfs/core"
+	"github.com/spf13/cobra"
+	"os"
+	"os/signal"
+	"time"
+)
+
+func statsCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "stats",
+		Short: "show the node statistics",
+		Long:  "show the statistics collected by the local node",
+	}
+	cmd.AddCommand(statsBandwidthCmd(), statsQoSCmd(), statsStreamCmd())
+	return cmd
+}
+
+func statsBandwidthCmd() *cobra.Command {
+	var kind string
+	var top int
+	var window time.Duration
+	cmd := &cobra.Command{
+		Use:   "bw",
+		Short: "show the bandwidth usage",
+		Long:  "show the top bandwidth usage of the peers and the root cids",
+		Run: func(cmd *cobra.Command, args []string) {
+			config.Initialize()
+			cfg := config.Global()
+			client.InitGlobalClient(&cfg)
+			ctx, cancelFunc := context.WithCancel(context.TODO())
+			defer cancelFunc()
+			done := make(chan error)
+			go func(c context.Context) {
+				var err error
+				defer func() {
+					done <- err
+				}()
+				resp, err := client.StatsBandwidth(c, &core.StatsBandwidthReq{
+					Kind:   kind,
+					Top:    top,
+					Window: window,
+				})
+				if err != nil {
+					fmt.Printf("get bandwidth failed error(%v)\n", err)
+					return
+				}
+				printBandwidth("peers:", resp.Peers)
+				printBandwidth("cids:", resp.CIDs)
+			}(ctx)
+			sigs := make(chan os.Signal, 1)
+			signal.Notify(sigs, os.Interrupt)
+			select {
+			case <-sigs:
+			case v := <-done:
+				if v != nil {
+					panic(v)
+				}
+			}
+		},
+	}
+	cmd.Flags().StringVar(&kind, "kind", "", "only show the kind of bandwidth(peer,cid)")
+	cmd.Flags().IntVar(&top, "top", 10, "show the top n records")
+	cmd.Flags().DurationVar(&window, "window", 0, "only count the bytes in the last window(max 24h)")
+	return cmd
+}
+
+func statsQoSCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:   "qos",
+		Short: "show the rate limit hits",
+		Long:  "show the throttled and rejected counts of every rate limit scope",
+		Run: func(cmd *cobra.Command, args []string) {
+			config.Initialize()
+			cfg := config.Global()
+			client.InitGlobalClient(&cfg)
+			ctx, cancelFunc := context.WithCancel(context.TODO())
+			defer cancelFunc()
+			done := make(chan error)
+			go func(c context.Context) {
+				var err error
+				defer func() {
+					done <- err
+				}()
+				resp, err := client.StatsQoS(c, &core.StatsQoSReq{})
+				if err != nil {
+					fmt.Printf("get qos failed error(%v)\n", err)
+					return
+				}
+				for _, l := range resp.Limits {
+					fmt.Printf("%s throttled:%d rejected:%d wait:%s\n", l.Scope, l.Throttled, l.Rejected, time.Duration(l.WaitTime)*time.Millisecond)
+				}
+			}(ctx)
+			sigs := make(chan os.Signal, 1)
+			signal.Notify(sigs, os.Interrupt)
+			select {
+			case <-sigs:
+			case v := <-done:
+				if v != nil {
+					panic(v)
+				}
+			}
+		},
+	}
+}
+
+func statsStreamCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:   "stream",
+		Short: "show the stream prefetch hits",
+		Long:  "show the hit rate of the hls segments prefetched by the stream api",
+		Run: func(cmd *cobra.Command, args []string) {
+			config.Initialize()
+			cfg := config.Global()
+			client.InitGlobalClient(&cfg)
+			ctx, cancelFunc := context.WithCancel(context.TODO())
+			defer cancelFunc()
+			done := make(chan error)
+			go func(c context.Context) {
+				var err error
+				defer func() {
+					done <- err
+				}()
+				resp, err := client.StatsStream(c, &core.StatsStreamReq{})
+				if err != nil {
+					fmt.Printf("get stream stats failed error(%v)\n", err)
+					return
+				}
+				fmt.Printf("requests:%d hits:%d late:%d misses:%d hit rate:%.2f%%\n", resp.Requests, resp.Hits, resp.Late, resp.Misses, resp.HitRate*100)
+				fmt.Printf("prefetched:%d failed:%d\n", resp.Prefetched, resp.Failed)
+			}(ctx)
+			sigs := make(chan os.Signal, 1)
+			signal.Notify(sigs, os.Interrupt)
+			select {
+			case <-sigs:
+			case v := <-done:
+				if v != nil {
+					panic(v)
+				}
+			}
+		},
+	}
+}
+
+func printBandwidth(title string, stats []core.BandwidthStat) {
+	if len(stats) == 0 {
+		return
+	}
+	fmt.Println(title)
+	for _, s := range stats {
+		fmt.Printf("%s in:%s out:%s\n", s.Key, humanize.IBytes(s.In), humanize.IBytes(s.Out))
+	}
+}
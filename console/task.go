@@ -0,0 +1,92 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"github.com/glvd/accipfs/client"
+	"github.com/glvd/accipfs/config"
+	"github.com/glvd/accipfs/core"
+	"github.com/spf13/cobra"
+	"os"
+	"os/signal"
+	"time"
+)
+
+func taskCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "task",
+		Short: "show the background jobs",
+		Long:  "show and cancel the jobs of the daemon task queue",
+	}
+	cmd.AddCommand(taskListCmd(), taskCancelCmd())
+	return cmd
+}
+
+func runTask(f func(ctx context.Context) error) {
+	config.Initialize()
+	cfg := config.Global()
+	client.InitGlobalClient(&cfg)
+	ctx, cancelFunc := context.WithCancel(context.TODO())
+	defer cancelFunc()
+	done := make(chan error)
+	go func(c context.Context) {
+		done <- f(c)
+	}(ctx)
+	sigs := make(chan os.Signal, 1)
+	signal.Notify(sigs, os.Interrupt)
+	select {
+	case <-sigs:
+	case v := <-done:
+		if v != nil {
+			fmt.Printf("task failed error(%v)\n", v)
+		}
+	}
+}
+
+func taskListCmd() *cobra.Command {
+	var req core.TaskListReq
+	cmd := &cobra.Command{
+		Use:   "list",
+		Short: "list the jobs",
+		Long:  "list the unfinished jobs and the recent finished ones with their state and attempts",
+		Run: func(cmd *cobra.Command, args []string) {
+			runTask(func(ctx context.Context) error {
+				resp, err := client.TaskList(ctx, &req)
+				if err != nil {
+					return err
+				}
+				for _, job := range resp.Jobs {
+					fmt.Printf("%s: %s %s priority(%d) attempts(%d)\n", job.ID, job.Kind, job.State, job.Priority, job.Attempts)
+					if job.NotBefore > 0 {
+						fmt.Println("  next retry:", time.Unix(0, job.NotBefore).Format(time.RFC3339))
+					}
+					if job.Error != "" {
+						fmt.Println("  error:", job.Error)
+					}
+				}
+				return nil
+			})
+		},
+	}
+	cmd.Flags().StringVar(&req.State, "state", "", "only list the jobs of the state: pending, running, retrying, done, failed or canceled")
+	return cmd
+}
+
+func taskCancelCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:   "cancel [id]",
+		Short: "cancel a job",
+		Long:  "cancel a pending, retrying or running job",
+		Args:  cobra.ExactArgs(1),
+		Run: func(cmd *cobra.Command, args []string) {
+			runTask(func(ctx context.Context) error {
+				_, err := client.TaskCancel(ctx, &core.TaskCancelReq{ID: args[0]})
+				if err != nil {
+					return err
+				}
+				fmt.Println("canceled", args[0])
+				return nil
+			})
+		},
+	}
+}
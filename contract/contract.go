@@ -1,17 +1,21 @@
 package contract
 
 import (
+	"context"
 	"crypto/ecdsa"
+	"path/filepath"
+	"sync"
+
 	"github.com/ethereum/go-ethereum/accounts/abi/bind"
-	"github.com/ethereum/go-ethereum/accounts/keystore"
 	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
 	"github.com/ethereum/go-ethereum/ethclient"
 	"github.com/glvd/accipfs/account"
 	"github.com/glvd/accipfs/config"
+	"github.com/glvd/accipfs/contract/dtag"
 	"github.com/glvd/accipfs/contract/node"
 	"github.com/glvd/accipfs/contract/token"
-	"io/ioutil"
-	"path/filepath"
 )
 
 const keyStore = `{"address":"945d35cd4a6549213e8d37feb5d708ec98906902","crypto":{"cipher":"aes-128-ctr","ciphertext":"649f5c7def3f345c39dc6f10e5438e179a5f06ff1d9ef2467ff7c84ec94f1a2a","cipherparams":{"iv":"0d66dfbc2c978ed1989e2fca05c16abe"},"kdf":"scrypt","kdfparams":{"dklen":32,"n":262144,"p":1,"r":8,"salt":"547ed9895deda897adbe09058ebfb24fb5036695d490c2127da45c4f7ec9e4a8"},"mac":"db76804c69ceb8705de1a73ae0caf4761bd73c3d42aa43f801c03e7fdda6adff"},"id":"9aaeec2d-d639-425a-83f7-a0956dcc78a1","version":3}`
@@ -24,80 +28,181 @@ type instance struct {
 	tokenAddr common.Address
 	tagAddr   common.Address
 	key       *ecdsa.PrivateKey
+	lock      sync.Mutex
+	txm       *TxManager
 }
 
+// txDir is the dir of the pending transactions under the cache dir
+const txDir = "transactions"
+
 // Contractor ...
 type Contractor interface {
-	Node(call NodeCall) error
-	Token(call TokenCall) error
+	Address() common.Address
+	Node(ctx context.Context, call NodeCall) error
+	Token(ctx context.Context, call TokenCall) error
+	NodeTransact(ctx context.Context, call NodeSend) (*types.Receipt, error)
+	TokenTransact(ctx context.Context, call TokenSend) (*types.Receipt, error)
+	TagTransact(ctx context.Context, call TagSend) (*types.Receipt, error)
+	Pending() ([]*PendingTx, error)
 }
 
-// NodeCall ...
-type NodeCall func(node *node.AccelerateNode, opts *bind.TransactOpts) error
+// NodeCall reads the node contract, the transactions are sent by NodeTransact
+type NodeCall func(node *node.AccelerateNodeCaller, opts *bind.CallOpts) error
 
-// TokenCall ...
-type TokenCall func(token *token.DhToken, opts *bind.TransactOpts) error
+// TokenCall reads the token contract, the transactions are sent by TokenTransact
+type TokenCall func(token *token.DhTokenCaller, opts *bind.CallOpts) error
 
-// FileKey ...
-func FileKey(cfg *config.Config) *ecdsa.PrivateKey {
-	newAccount, e := account.NewAccount(cfg)
-	if e != nil {
-		panic(e)
-	}
+// NodeSend ...
+type NodeSend func(node *node.AccelerateNode, opts *bind.TransactOpts) (*types.Transaction, error)
 
-	bys, e := ioutil.ReadFile(filepath.Join(config.KeyStoreDirETH(), newAccount.Address))
-	if e != nil {
-		panic(e)
-	}
+// TokenSend ...
+type TokenSend func(token *token.DhToken, opts *bind.TransactOpts) (*types.Transaction, error)
+
+// TagSend ...
+type TagSend func(tag *dtag.DTag, opts *bind.TransactOpts) (*types.Transaction, error)
 
-	keys, err := keystore.DecryptKey(bys, newAccount.Password)
+// FileKey returns the private key of the default account, the passphrase is read from
+// the env or the os keyring so the account must be unlocked by one of them
+func FileKey(cfg *config.Config) (*ecdsa.PrivateKey, error) {
+	acc, err := account.LoadAccount(cfg)
 	if err != nil {
-		panic(e)
+		return nil, err
 	}
-	return keys.PrivateKey
+	return acc.PrivateKey(account.DefaultKeyring(), nil)
 }
 
-// Loader ...
-func Loader(cfg *config.Config) Contractor {
+// Loader returns the contractor sending transactions with the default account
+func Loader(cfg *config.Config) (Contractor, error) {
+	key, err := FileKey(cfg)
+	if err != nil {
+		return nil, err
+	}
 	return &instance{
 		cfg:       cfg,
 		tagAddr:   common.HexToAddress(cfg.ETH.DTagAddr),
 		nodeAddr:  common.HexToAddress(cfg.ETH.NodeAddr),
 		tokenAddr: common.HexToAddress(cfg.ETH.TokenAddr),
-		key:       FileKey(cfg),
-	}
+		key:       key,
+	}, nil
 }
 
-//Node contract: Node init acceleratenode contract
-func (c *instance) Node(call NodeCall) error {
-	o := bind.NewKeyedTransactor(c.key)
+// Address returns the address of the transaction sender
+func (c *instance) Address() common.Address {
+	return crypto.PubkeyToAddress(c.key.PublicKey)
+}
 
-	// gateway redirect to private chain
-	client, err := ethclient.Dial(config.ETHAddr())
+// callOpts returns the options of the read-only calls from the sender address
+func (c *instance) callOpts(ctx context.Context) *bind.CallOpts {
+	return &bind.CallOpts{
+		Pending: true,
+		From:    c.Address(),
+		Context: ctx,
+	}
+}
+
+// Node calls the read-only methods of the node contract with the client of the transaction manager
+func (c *instance) Node(ctx context.Context, call NodeCall) error {
+	if _, err := c.manager(ctx); err != nil {
+		return err
+	}
+	instance, err := node.NewAccelerateNodeCaller(c.nodeAddr, c.cli)
 	if err != nil {
 		return err
 	}
-	defer client.Close()
-	instance, err := node.NewAccelerateNode(c.nodeAddr, client)
+	return call(instance, c.callOpts(ctx))
+}
+
+// Token calls the read-only methods of the token contract with the client of the transaction manager
+func (c *instance) Token(ctx context.Context, call TokenCall) error {
+	if _, err := c.manager(ctx); err != nil {
+		return err
+	}
+	instance, err := token.NewDhTokenCaller(c.tokenAddr, c.cli)
 	if err != nil {
 		return err
 	}
+	return call(instance, c.callOpts(ctx))
+}
 
-	return call(instance, o)
+// manager returns the transaction manager, the pending transactions of the last run are resumed when it is created
+func (c *instance) manager(ctx context.Context) (*TxManager, error) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	if c.txm != nil {
+		return c.txm, nil
+	}
+	client, err := ethclient.Dial(config.ETHAddr())
+	if err != nil {
+		return nil, err
+	}
+	chainID, err := client.ChainID(ctx)
+	if err != nil {
+		client.Close()
+		return nil, err
+	}
+	store, err := OpenTxStore(filepath.Join(config.DataDirCache(), txDir))
+	if err != nil {
+		client.Close()
+		return nil, err
+	}
+	c.cli = client
+	c.txm = NewTxManager(client, c.key, chainID, store, c.cfg.ETH.Tx)
+	if err := c.txm.Resume(ctx); err != nil {
+		log.Warnw("resume pending transactions", "err", err)
+	}
+	return c.txm, nil
 }
 
-//Token contract: Token init DHToken contract
-func (c *instance) Token(call TokenCall) error {
-	o := bind.NewKeyedTransactor(c.key)
+// NodeTransact sends the node contract transaction through the transaction manager and waits for the receipt
+func (c *instance) NodeTransact(ctx context.Context, call NodeSend) (*types.Receipt, error) {
+	m, err := c.manager(ctx)
+	if err != nil {
+		return nil, err
+	}
+	instance, err := node.NewAccelerateNode(c.nodeAddr, c.cli)
+	if err != nil {
+		return nil, err
+	}
+	return m.Send(ctx, func(opts *bind.TransactOpts) (*types.Transaction, error) {
+		return call(instance, opts)
+	})
+}
 
-	// gateway redirect to private chain
-	client, err := ethclient.Dial(config.ETHAddr())
+// TokenTransact sends the token contract transaction through the transaction manager and waits for the receipt
+func (c *instance) TokenTransact(ctx context.Context, call TokenSend) (*types.Receipt, error) {
+	m, err := c.manager(ctx)
 	if err != nil {
-		return err
+		return nil, err
 	}
-	instance, err := token.NewDhToken(c.tokenAddr, client)
+	instance, err := token.NewDhToken(c.tokenAddr, c.cli)
 	if err != nil {
-		return err
+		return nil, err
+	}
+	return m.Send(ctx, func(opts *bind.TransactOpts) (*types.Transaction, error) {
+		return call(instance, opts)
+	})
+}
+
+// TagTransact sends the dtag contract transaction through the transaction manager and waits for the receipt
+func (c *instance) TagTransact(ctx context.Context, call TagSend) (*types.Receipt, error) {
+	m, err := c.manager(ctx)
+	if err != nil {
+		return nil, err
+	}
+	instance, err := dtag.NewDTag(c.tagAddr, c.cli)
+	if err != nil {
+		return nil, err
+	}
+	return m.Send(ctx, func(opts *bind.TransactOpts) (*types.Transaction, error) {
+		return call(instance, opts)
+	})
+}
+
+// Pending returns the transactions waiting for the receipts
+func (c *instance) Pending() ([]*PendingTx, error) {
+	m, err := c.manager(context.Background())
+	if err != nil {
+		return nil, err
 	}
-	return call(instance, o)
+	return m.Pending()
 }
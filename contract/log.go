@@ -0,0 +1,9 @@
+package contract
+
+import (
+	alog "github.com/glvd/accipfs/log"
+)
+
+const module = "contract"
+
+var log = alog.Module(module)
@@ -0,0 +1,293 @@
+package contract
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"errors"
+	"math/big"
+	"sync"
+	"time"
+
+	"github.com/ethereum/go-ethereum/accounts/abi/bind"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/glvd/accipfs/config"
+)
+
+// ErrReceiptTimeout ...
+var ErrReceiptTimeout = errors.New("wait transaction receipt timeout")
+
+// ErrTransactionFailed ...
+var ErrTransactionFailed = errors.New("transaction failed")
+
+// ErrWrongSigner ...
+var ErrWrongSigner = errors.New("not authorized to sign this account")
+
+// defaultPoll is the interval of the receipt polling
+const defaultPoll = time.Second
+
+// TxBackend is the backend the transactions are sent to
+type TxBackend interface {
+	bind.ContractBackend
+	TransactionReceipt(ctx context.Context, txHash common.Hash) (*types.Receipt, error)
+}
+
+// SendFunc sends a transaction with the opts filled by the transaction manager
+type SendFunc func(opts *bind.TransactOpts) (*types.Transaction, error)
+
+// TxManager sends the transactions of an account with serialized nonces and waits for the receipts,
+// the pending transactions are persisted and resent with bumped gas price when they are stuck
+type TxManager struct {
+	backend        TxBackend
+	key            *ecdsa.PrivateKey
+	from           common.Address
+	signer         types.Signer
+	store          *TxStore
+	lock           sync.Mutex
+	nonce          uint64
+	hasNonce       bool
+	poll           time.Duration
+	receiptTimeout time.Duration
+	bumpInterval   time.Duration
+	bumpPercent    int64
+	maxBumps       int
+	gasMargin      int64
+}
+
+// NewTxManager create a transaction manager of the key, the homestead signer is used when chainID is nil
+func NewTxManager(backend TxBackend, key *ecdsa.PrivateKey, chainID *big.Int, store *TxStore, cfg config.TxConfig) *TxManager {
+	var signer types.Signer = types.HomesteadSigner{}
+	if chainID != nil {
+		signer = types.NewEIP155Signer(chainID)
+	}
+	return &TxManager{
+		backend:        backend,
+		key:            key,
+		from:           crypto.PubkeyToAddress(key.PublicKey),
+		signer:         signer,
+		store:          store,
+		poll:           defaultPoll,
+		receiptTimeout: cfg.ReceiptTimeout * time.Second,
+		bumpInterval:   cfg.BumpInterval * time.Second,
+		bumpPercent:    cfg.BumpPercent,
+		maxBumps:       cfg.MaxBumps,
+		gasMargin:      cfg.GasMargin,
+	}
+}
+
+// Address returns the address of the transaction sender
+func (m *TxManager) Address() common.Address {
+	return m.from
+}
+
+// Send sends the transaction created by fn and waits for its receipt,
+// the gas limit is estimated by the binding when fn keeps opts.GasLimit zero
+func (m *TxManager) Send(ctx context.Context, fn SendFunc) (*types.Receipt, error) {
+	p, err := m.send(ctx, fn)
+	if err != nil {
+		return nil, err
+	}
+	return m.wait(ctx, p)
+}
+
+func (m *TxManager) send(ctx context.Context, fn SendFunc) (*PendingTx, error) {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	nonce, err := m.nextNonce(ctx)
+	if err != nil {
+		return nil, err
+	}
+	gasPrice, err := m.backend.SuggestGasPrice(ctx)
+	if err != nil {
+		return nil, err
+	}
+	opts := &bind.TransactOpts{
+		From:     m.from,
+		Nonce:    new(big.Int).SetUint64(nonce),
+		GasPrice: gasPrice,
+		Context:  ctx,
+		Signer: func(_ types.Signer, addr common.Address, tx *types.Transaction) (*types.Transaction, error) {
+			if addr != m.from {
+				return nil, ErrWrongSigner
+			}
+			gas := tx.Gas() + tx.Gas()*uint64(m.gasMargin)/100
+			return types.SignTx(rebuild(tx, gas, tx.GasPrice()), m.signer, m.key)
+		},
+	}
+	tx, err := fn(opts)
+	if err != nil {
+		return nil, err
+	}
+	m.nonce = nonce + 1
+	p := &PendingTx{
+		From:  m.from.Hex(),
+		Nonce: tx.Nonce(),
+		Sent:  time.Now().Unix(),
+	}
+	if err := p.update(tx); err != nil {
+		return nil, err
+	}
+	if err := m.store.Put(p); err != nil {
+		log.Errorw("persist pending transaction", "hash", tx.Hash().Hex(), "err", err)
+	}
+	return p, nil
+}
+
+// nextNonce returns the larger one of the tracked nonce and the pending nonce of the backend
+func (m *TxManager) nextNonce(ctx context.Context) (uint64, error) {
+	nonce, err := m.backend.PendingNonceAt(ctx, m.from)
+	if err != nil {
+		return 0, err
+	}
+	if !m.hasNonce {
+		pending, err := m.store.Pending(m.from)
+		if err != nil {
+			return 0, err
+		}
+		for _, p := range pending {
+			if p.Nonce >= m.nonce {
+				m.nonce = p.Nonce + 1
+			}
+		}
+		m.hasNonce = true
+	}
+	if m.nonce > nonce {
+		return m.nonce, nil
+	}
+	return nonce, nil
+}
+
+// rebuild returns the unsigned copy of tx with the gas limit and gas price
+func rebuild(tx *types.Transaction, gas uint64, gasPrice *big.Int) *types.Transaction {
+	if tx.To() == nil {
+		return types.NewContractCreation(tx.Nonce(), tx.Value(), gas, gasPrice, tx.Data())
+	}
+	return types.NewTransaction(tx.Nonce(), *tx.To(), tx.Value(), gas, gasPrice, tx.Data())
+}
+
+// receipt returns the receipt of any sent version of p, nil is returned when none is mined
+func (m *TxManager) receipt(ctx context.Context, p *PendingTx) *types.Receipt {
+	for i := len(p.Hashes) - 1; i >= 0; i-- {
+		receipt, err := m.backend.TransactionReceipt(ctx, common.HexToHash(p.Hashes[i]))
+		if err == nil && receipt != nil {
+			return receipt
+		}
+	}
+	return nil
+}
+
+func (m *TxManager) wait(ctx context.Context, p *PendingTx) (*types.Receipt, error) {
+	if m.receiptTimeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, m.receiptTimeout)
+		defer cancel()
+	}
+	t := time.NewTicker(m.poll)
+	defer t.Stop()
+	for {
+		if receipt := m.receipt(ctx, p); receipt != nil {
+			return receipt, m.done(p, receipt)
+		}
+		if m.stuck(p) {
+			if err := m.bump(ctx, p); err != nil {
+				log.Warnw("bump transaction", "nonce", p.Nonce, "err", err)
+			}
+		}
+		select {
+		case <-ctx.Done():
+			if ctx.Err() == context.DeadlineExceeded {
+				return nil, ErrReceiptTimeout
+			}
+			return nil, ctx.Err()
+		case <-t.C:
+		}
+	}
+}
+
+func (m *TxManager) done(p *PendingTx, receipt *types.Receipt) error {
+	if err := m.store.Delete(m.from, p.Nonce); err != nil {
+		log.Errorw("remove pending transaction", "nonce", p.Nonce, "err", err)
+	}
+	if receipt.Status != types.ReceiptStatusSuccessful {
+		return ErrTransactionFailed
+	}
+	return nil
+}
+
+func (m *TxManager) stuck(p *PendingTx) bool {
+	return m.bumpInterval > 0 && p.Bumps < m.maxBumps &&
+		time.Since(time.Unix(p.Sent, 0)) >= m.bumpInterval
+}
+
+// bump resends p with the gas price increased by bumpPercent, the suggested gas price is used when it is higher
+func (m *TxManager) bump(ctx context.Context, p *PendingTx) error {
+	tx, err := p.Transaction()
+	if err != nil {
+		return err
+	}
+	price := new(big.Int).Mul(tx.GasPrice(), big.NewInt(100+m.bumpPercent))
+	price.Div(price, big.NewInt(100))
+	if price.Cmp(tx.GasPrice()) <= 0 {
+		price.Add(tx.GasPrice(), big.NewInt(1))
+	}
+	if suggested, err := m.backend.SuggestGasPrice(ctx); err == nil && suggested.Cmp(price) > 0 {
+		price = suggested
+	}
+	signed, err := types.SignTx(rebuild(tx, tx.Gas(), price), m.signer, m.key)
+	if err != nil {
+		return err
+	}
+	if err := m.backend.SendTransaction(ctx, signed); err != nil {
+		return err
+	}
+	log.Infow("bump transaction", "nonce", p.Nonce, "hash", signed.Hash().Hex(), "gas_price", price.String())
+	p.Bumps++
+	p.Sent = time.Now().Unix()
+	if err := p.update(signed); err != nil {
+		return err
+	}
+	return m.store.Put(p)
+}
+
+// Resume checks the persisted pending transactions, the mined ones are removed,
+// the stuck ones are bumped and the others are sent again
+func (m *TxManager) Resume(ctx context.Context) error {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	pending, err := m.store.Pending(m.from)
+	if err != nil {
+		return err
+	}
+	for _, p := range pending {
+		if p.Nonce >= m.nonce {
+			m.nonce = p.Nonce + 1
+		}
+		if receipt := m.receipt(ctx, p); receipt != nil {
+			if err := m.done(p, receipt); err != nil {
+				log.Warnw("pending transaction", "nonce", p.Nonce, "err", err)
+			}
+			continue
+		}
+		if m.stuck(p) {
+			if err := m.bump(ctx, p); err != nil {
+				log.Warnw("bump transaction", "nonce", p.Nonce, "err", err)
+			}
+			continue
+		}
+		tx, err := p.Transaction()
+		if err != nil {
+			return err
+		}
+		if err := m.backend.SendTransaction(ctx, tx); err != nil {
+			log.Warnw("resend transaction", "nonce", p.Nonce, "err", err)
+		}
+	}
+	m.hasNonce = true
+	return nil
+}
+
+// Pending returns the persisted pending transactions of the account
+func (m *TxManager) Pending() ([]*PendingTx, error) {
+	return m.store.Pending(m.from)
+}
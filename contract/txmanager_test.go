@@ -0,0 +1,188 @@
+package contract
+
+import (
+	"context"
+	"io/ioutil"
+	"math/big"
+	"os"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/accounts/abi/bind"
+	"github.com/ethereum/go-ethereum/accounts/abi/bind/backends"
+	"github.com/ethereum/go-ethereum/common"
+	ethcore "github.com/ethereum/go-ethereum/core"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/params"
+	"github.com/glvd/accipfs/config"
+)
+
+// testBin deploys a contract which code is a single STOP
+var testBin = common.FromHex("6001600c60003960016000f300")
+
+// dropBackend drops the first drop transactions, they will never be mined
+type dropBackend struct {
+	*backends.SimulatedBackend
+	lock sync.Mutex
+	drop int
+}
+
+func (b *dropBackend) SendTransaction(ctx context.Context, tx *types.Transaction) error {
+	b.lock.Lock()
+	if b.drop > 0 {
+		b.drop--
+		b.lock.Unlock()
+		return nil
+	}
+	b.lock.Unlock()
+	return b.SimulatedBackend.SendTransaction(ctx, tx)
+}
+
+func testTxManager(t *testing.T, drop int) (*TxManager, *dropBackend, func()) {
+	key, err := crypto.GenerateKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	backend := &dropBackend{
+		SimulatedBackend: backends.NewSimulatedBackend(ethcore.GenesisAlloc{
+			crypto.PubkeyToAddress(key.PublicKey): {Balance: big.NewInt(1000000000000000000)},
+		}, 8000000),
+		drop: drop,
+	}
+	dir, err := ioutil.TempDir("", "transactions")
+	if err != nil {
+		t.Fatal(err)
+	}
+	store, err := OpenTxStore(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	m := NewTxManager(backend, key, params.AllEthashProtocolChanges.ChainID, store, config.Default().ETH.Tx)
+	m.poll = 10 * time.Millisecond
+	return m, backend, func() {
+		store.Close()
+		backend.Close()
+		os.RemoveAll(dir)
+	}
+}
+
+// mine commits a block every 10ms until done is closed
+func mine(backend *dropBackend, done chan struct{}) {
+	for {
+		select {
+		case <-done:
+			return
+		case <-time.After(10 * time.Millisecond):
+			backend.Commit()
+		}
+	}
+}
+
+func deploy(backend bind.ContractBackend) SendFunc {
+	return func(opts *bind.TransactOpts) (*types.Transaction, error) {
+		_, tx, _, err := bind.DeployContract(opts, abi.ABI{}, testBin, backend)
+		return tx, err
+	}
+}
+
+func TestTxManager_Send(t *testing.T) {
+	m, backend, clean := testTxManager(t, 0)
+	defer clean()
+	done := make(chan struct{})
+	defer close(done)
+	go mine(backend, done)
+
+	var wg sync.WaitGroup
+	receipts := make([]*types.Receipt, 3)
+	errs := make([]error, 3)
+	for i := range receipts {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			receipts[i], errs[i] = m.Send(context.Background(), deploy(backend))
+		}(i)
+	}
+	wg.Wait()
+	for i, err := range errs {
+		if err != nil {
+			t.Fatal(err)
+		}
+		if receipts[i].Status != types.ReceiptStatusSuccessful {
+			t.Fatalf("transaction %d failed", i)
+		}
+	}
+	nonce, err := backend.NonceAt(context.Background(), m.Address(), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if nonce != 3 {
+		t.Fatalf("want nonce 3, got %d", nonce)
+	}
+	pending, err := m.Pending()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(pending) != 0 {
+		t.Fatalf("mined transactions should be removed, got %d", len(pending))
+	}
+}
+
+func TestTxManager_Bump(t *testing.T) {
+	m, backend, clean := testTxManager(t, 1)
+	defer clean()
+	m.bumpInterval = 20 * time.Millisecond
+	m.maxBumps = 1
+	done := make(chan struct{})
+	defer close(done)
+	go mine(backend, done)
+
+	receipt, err := m.Send(context.Background(), deploy(backend))
+	if err != nil {
+		t.Fatal(err)
+	}
+	tx, _, err := backend.TransactionByHash(context.Background(), receipt.TxHash)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if tx.GasPrice().Cmp(big.NewInt(1)) <= 0 {
+		t.Fatalf("mined transaction should have bumped gas price, got %v", tx.GasPrice())
+	}
+}
+
+func TestTxManager_Resume(t *testing.T) {
+	m, backend, clean := testTxManager(t, 1)
+	defer clean()
+	m.receiptTimeout = 50 * time.Millisecond
+	m.bumpInterval = 0
+
+	if _, err := m.Send(context.Background(), deploy(backend)); err != ErrReceiptTimeout {
+		t.Fatalf("dropped transaction should timeout, got %v", err)
+	}
+	pending, err := m.Pending()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(pending) != 1 {
+		t.Fatalf("want 1 pending transaction, got %d", len(pending))
+	}
+
+	//restart with the same store
+	resumed := NewTxManager(backend, m.key, params.AllEthashProtocolChanges.ChainID, m.store, config.TxConfig{})
+	if err := resumed.Resume(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	backend.Commit()
+	if err := resumed.Resume(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	pending, err = resumed.Pending()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(pending) != 0 {
+		t.Fatalf("resent transaction should be mined, got %d pending", len(pending))
+	}
+}
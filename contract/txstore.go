@@ -0,0 +1,125 @@
+package contract
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/dgraph-io/badger/v2"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/rlp"
+	"github.com/glvd/accipfs/basis/kv"
+)
+
+// PendingTx is a sent transaction waiting for its receipt
+type PendingTx struct {
+	From   string        `json:"from"`
+	Nonce  uint64        `json:"nonce"`
+	Hashes []string      `json:"hashes"` //hashes of every sent version, the last one is the current
+	Raw    hexutil.Bytes `json:"raw"`    //rlp of the current signed transaction
+	Sent   int64         `json:"sent"`
+	Bumps  int           `json:"bumps"`
+}
+
+// TxStore persists the pending transactions
+type TxStore struct {
+	db *badger.DB
+}
+
+// Marshal ...
+func (p PendingTx) Marshal() ([]byte, error) {
+	return json.Marshal(p)
+}
+
+// Unmarshal ...
+func (p *PendingTx) Unmarshal(b []byte) error {
+	return json.Unmarshal(b, p)
+}
+
+// Transaction decodes the current signed transaction
+func (p PendingTx) Transaction() (*types.Transaction, error) {
+	var tx types.Transaction
+	if err := rlp.DecodeBytes(p.Raw, &tx); err != nil {
+		return nil, err
+	}
+	return &tx, nil
+}
+
+// update sets tx as the current version
+func (p *PendingTx) update(tx *types.Transaction) error {
+	raw, err := rlp.EncodeToBytes(tx)
+	if err != nil {
+		return err
+	}
+	p.Raw = raw
+	p.Hashes = append(p.Hashes, tx.Hash().Hex())
+	return nil
+}
+
+func txKey(from common.Address, nonce uint64) []byte {
+	return []byte(fmt.Sprintf("%s/%020d", from.Hex(), nonce))
+}
+
+// OpenTxStore ...
+func OpenTxStore(path string) (*TxStore, error) {
+	db, err := kv.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	return &TxStore{db: db}, nil
+}
+
+// Put ...
+func (s *TxStore) Put(p *PendingTx) error {
+	encode, err := p.Marshal()
+	if err != nil {
+		return err
+	}
+	return s.db.Update(func(txn *badger.Txn) error {
+		return txn.Set(txKey(common.HexToAddress(p.From), p.Nonce), encode)
+	})
+}
+
+// Delete ...
+func (s *TxStore) Delete(from common.Address, nonce uint64) error {
+	return s.db.Update(func(txn *badger.Txn) error {
+		return txn.Delete(txKey(from, nonce))
+	})
+}
+
+// Pending returns the pending transactions of the account ordered by nonce
+func (s *TxStore) Pending(from common.Address) ([]*PendingTx, error) {
+	var txs []*PendingTx
+	prefix := []byte(from.Hex() + "/")
+	err := s.db.View(func(txn *badger.Txn) error {
+		iter := txn.NewIterator(badger.DefaultIteratorOptions)
+		defer iter.Close()
+		for iter.Seek(prefix); iter.ValidForPrefix(prefix); iter.Next() {
+			var p PendingTx
+			err := iter.Item().Value(func(val []byte) error {
+				return p.Unmarshal(val)
+			})
+			if err != nil {
+				return err
+			}
+			txs = append(txs, &p)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return txs, nil
+}
+
+// Close ...
+func (s *TxStore) Close() error {
+	if s.db != nil {
+		defer func() {
+			s.db = nil
+		}()
+		return s.db.Close()
+	}
+	return nil
+}
@@ -0,0 +1,67 @@
+package controller
+
+import (
+	"context"
+
+	"github.com/glvd/accipfs/core"
+	"github.com/glvd/accipfs/datastore"
+	"github.com/ipfs/go-cid"
+	"github.com/ipfs/interface-go-ipfs-core/path"
+)
+
+// RegisterLinked set the func called with the roots of the imported data
+func (c *Controller) RegisterLinked(f func(ctx context.Context, hash string) error) {
+	c.linked = f
+}
+
+// DagExport streams the dags of the roots to req.Writer as a car file
+func (c *Controller) DagExport(ctx context.Context, req *core.DagExportReq) (*core.DagExportResp, error) {
+	if c.ds == nil {
+		return nil, ErrDataStoreNotReady
+	}
+	var roots []cid.Cid
+	for _, root := range req.Roots {
+		resolved, err := c.ds.ResolvePath(ctx, path.New(root))
+		if err != nil {
+			return nil, err
+		}
+		roots = append(roots, resolved.Cid())
+	}
+	if err := datastore.ExportCAR(ctx, c.ds.Dag(), roots, req.Writer, req.Version); err != nil {
+		return nil, err
+	}
+	return &core.DagExportResp{}, nil
+}
+
+// DagImport adds the blocks of the car file from req.Reader, the roots are pinned when req.PinRoots is set
+func (c *Controller) DagImport(ctx context.Context, req *core.DagImportReq) (*core.DagImportResp, error) {
+	if c.ds == nil {
+		return nil, ErrDataStoreNotReady
+	}
+	roots, count, err := datastore.ImportCAR(ctx, c.ds.Dag(), req.Reader)
+	if err != nil {
+		return nil, err
+	}
+	resp := &core.DagImportResp{Blocks: count}
+	for _, root := range roots {
+		resp.Roots = append(resp.Roots, root.String())
+	}
+	if req.PinRoots {
+		var pins []string
+		for _, root := range roots {
+			pins = append(pins, path.IpfsPath(root).String())
+		}
+		if _, err := c.PinAdd(ctx, &core.DataStorePinAddReq{Pins: pins}); err != nil {
+			return nil, err
+		}
+		resp.Pinned = true
+	}
+	if c.linked != nil {
+		for _, root := range resp.Roots {
+			if err := c.linked(ctx, root); err != nil {
+				return nil, err
+			}
+		}
+	}
+	return resp, nil
+}
@@ -2,21 +2,22 @@ package controller
 
 import (
 	"context"
-	"encoding/base64"
+	"errors"
+	"io"
 	"os"
 	"sync"
 	"time"
 
 	"github.com/glvd/accipfs/config"
 	"github.com/glvd/accipfs/core"
-	version "github.com/ipfs/go-ipfs"
+	"github.com/glvd/accipfs/datastore"
+	"github.com/glvd/accipfs/gc"
+	"github.com/glvd/accipfs/supervisor"
 	files "github.com/ipfs/go-ipfs-files"
+	iface "github.com/ipfs/interface-go-ipfs-core"
 	"github.com/ipfs/interface-go-ipfs-core/options"
 	"github.com/ipfs/interface-go-ipfs-core/path"
-	ic "github.com/libp2p/go-libp2p-core/crypto"
-	"github.com/libp2p/go-libp2p-core/host"
 	"github.com/libp2p/go-libp2p-core/peer"
-	"github.com/libp2p/go-libp2p/p2p/protocol/identify"
 	"go.uber.org/atomic"
 )
 
@@ -38,8 +39,14 @@ type Controller struct {
 	isRunning *atomic.Bool
 	services  []core.ControllerService
 	ethNode   *nodeBinETH
-	ipfsNode  *nodeLibIPFS
+	ds        datastore.DataStore
 	cfg       *config.Config
+	access    *gc.Access
+	protector func() []string
+	caches    func() []core.CacheStat
+	linked    func(ctx context.Context, hash string) error
+	gcLock    *sync.Mutex
+	sup       *supervisor.Supervisor
 }
 
 // New ...
@@ -47,10 +54,12 @@ func New(cfg *config.Config) *Controller {
 	c := &Controller{
 		cfg:      cfg,
 		services: make([]core.ControllerService, IndexMax),
+		gcLock:   &sync.Mutex{},
+		sup:      supervisor.New(),
 	}
 
 	if cfg.ETH.Enable {
-		eth := newNodeBinETH(cfg)
+		eth := newNodeBinETH(cfg, c.sup)
 		eth.MessageHandle(func(s string) {
 			output("[info]", s)
 			//log.Infow(s, "tag", "eth")
@@ -59,12 +68,20 @@ func New(cfg *config.Config) *Controller {
 		c.ethNode = eth
 	}
 	if cfg.IPFS.Enable {
-		ipfs := newNodeLibIPFS(cfg)
+		var ipfs interface {
+			core.ControllerService
+			datastore.DataStore
+		}
+		if cfg.IPFS.Mode == datastore.ModeExternal {
+			ipfs = newNodeBinIPFS(cfg, c.sup)
+		} else {
+			ipfs = newNodeLibIPFS(cfg)
+		}
 		ipfs.MessageHandle(func(s string) {
 			output("[datastore]", s)
 		})
 		c.services[IndexIPFS] = ipfs
-		c.ipfsNode = ipfs
+		c.ds = ipfs
 	}
 	c.isRunning = atomic.NewBool(false)
 	return c
@@ -134,8 +151,20 @@ func (c *Controller) Stop() (e error) {
 	return
 }
 
-func (c *Controller) dataNode() *nodeLibIPFS {
-	return c.ipfsNode
+// ProcessAPI ...
+func (c *Controller) ProcessAPI() core.ProcessAPI {
+	return c
+}
+
+// Processes returns the status of the supervised processes
+func (c *Controller) Processes(ctx context.Context, req *core.ProcessListReq) (*core.ProcessListResp, error) {
+	return &core.ProcessListResp{
+		Processes: c.sup.Status(),
+	}, nil
+}
+
+func (c *Controller) dataNode() datastore.DataStore {
+	return c.ds
 }
 
 func (c *Controller) infoNode() *nodeBinETH {
@@ -144,29 +173,10 @@ func (c *Controller) infoNode() *nodeBinETH {
 
 // ID ...
 func (c *Controller) ID(ctx context.Context) (*core.DataStoreInfo, error) {
-	ipfsNode := c.ipfsNode.node
-	info := new(core.DataStoreInfo)
-	info.ID = ipfsNode.Identity.Pretty()
-
-	pk := ipfsNode.PrivateKey.GetPublic()
-	pkb, err := ic.MarshalPublicKey(pk)
-	if err != nil {
-		return nil, err
+	if c.ds == nil {
+		return nil, ErrDataStoreNotReady
 	}
-	info.PublicKey = base64.StdEncoding.EncodeToString(pkb)
-
-	if ipfsNode.PeerHost != nil {
-		addrs, err := peer.AddrInfoToP2pAddrs(host.InfoFromHost(ipfsNode.PeerHost))
-		if err != nil {
-			return nil, err
-		}
-		for _, a := range addrs {
-			info.Addresses = append(info.Addresses, a.String())
-		}
-	}
-	info.ProtocolVersion = identify.LibP2PVersion
-	info.AgentVersion = version.UserAgent
-	return info, nil
+	return c.ds.ID(ctx)
 }
 
 // DataStoreAPI ...
@@ -199,8 +209,34 @@ func (c *Controller) GetUnixfs(ctx context.Context, urlPath string, endpoint str
 	return node, err
 }
 
+// Get streams the file of the hash and the endpoint to req.Writer
+func (c *Controller) Get(ctx context.Context, req *core.GetReq) (*core.GetResp, error) {
+	node, err := c.GetUnixfs(ctx, req.Hash, req.Endpoint)
+	if err != nil {
+		return nil, err
+	}
+	defer node.Close()
+	file, ok := node.(files.File)
+	if !ok {
+		return nil, errors.New("target is not a file")
+	}
+	if _, err := io.Copy(req.Writer, file); err != nil {
+		return nil, err
+	}
+	return &core.GetResp{}, nil
+}
+
 // PinAdd ...
 func (c *Controller) PinAdd(ctx context.Context, req *core.DataStorePinAddReq) (*core.DataStorePinAddResp, error) {
+	for _, p := range req.Pins {
+		err := c.dataNode().Pin().Add(ctx, path.New(p), func(settings *options.PinAddSettings) error {
+			settings.Recursive = true
+			return nil
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
 	return &core.DataStorePinAddResp{}, nil
 }
 
@@ -225,25 +261,66 @@ func (c *Controller) PinLs(ctx context.Context, req *core.DataStorePinLsReq) (*c
 	return &core.DataStorePinLsResp{Pins: pins}, nil
 }
 
+// BandwidthByPeer returns the total bytes received from every datastore peer
+func (c *Controller) BandwidthByPeer() map[string]uint64 {
+	totals := make(map[string]uint64)
+	for id, total := range c.BandwidthTotals() {
+		totals[id] = total.In
+	}
+	return totals
+}
+
+// BandwidthTotals returns the total bytes exchanged with every datastore peer
+func (c *Controller) BandwidthTotals() map[string]core.BandwidthTotal {
+	if c.ds == nil {
+		return make(map[string]core.BandwidthTotal)
+	}
+	totals, err := c.ds.Bandwidth(context.TODO())
+	if err != nil {
+		log.Warnw("datastore bandwidth", "err", err)
+		return make(map[string]core.BandwidthTotal)
+	}
+	return totals
+}
+
 // HandleSwarm ...
 func (c *Controller) HandleSwarm(info peer.AddrInfo) error {
-	return c.ipfsNode.Swarm().Connect(context.TODO(), info)
+	if c.ds == nil {
+		return ErrDataStoreNotReady
+	}
+	return c.ds.Swarm().Connect(context.TODO(), info)
 }
 
 // UploadFile ...
 func (c *Controller) UploadFile(ctx context.Context, req *core.UploadReq) (*core.UploadResp, error) {
+	if e := c.checkQuota(ctx); e != nil {
+		return &core.UploadResp{}, e
+	}
 	stat, e := os.Stat(req.Path)
 	if e != nil {
 		return &core.UploadResp{}, e
 	}
 	var node files.Node
+	var security string
 	//var err error
 	if !stat.IsDir() {
 		file, e := os.Open(req.Path)
 		if e != nil {
 			return &core.UploadResp{}, e
 		}
-		node = files.NewReaderFile(file)
+		defer file.Close()
+		if len(req.Recipients) > 0 {
+			sealed, s, e := sealFile(file, req.Recipients)
+			if e != nil {
+				return &core.UploadResp{}, e
+			}
+			defer sealed.Close()
+			node, security = files.NewReaderFile(sealed), s
+		} else {
+			node = files.NewReaderFile(file)
+		}
+	} else if len(req.Recipients) > 0 {
+		return &core.UploadResp{}, errors.New("sealing a directory is not supported")
 	} else {
 		sf, e := files.NewSerialFile(req.Path, false, stat)
 		if e != nil {
@@ -269,6 +346,15 @@ func (c *Controller) UploadFile(ctx context.Context, req *core.UploadReq) (*core
 		return &core.UploadResp{}, e
 	}
 	return &core.UploadResp{
-		Hash: resolved.Cid().String(),
+		Hash:     resolved.Cid().String(),
+		Security: security,
 	}, nil
 }
+
+// Dag returns the dag service of the datastore
+func (c *Controller) Dag() (iface.APIDagService, error) {
+	if c.ds == nil {
+		return nil, ErrDataStoreNotReady
+	}
+	return c.ds.Dag(), nil
+}
@@ -0,0 +1,106 @@
+package controller
+
+import (
+	"context"
+
+	"github.com/glvd/accipfs/core"
+	iface "github.com/ipfs/interface-go-ipfs-core"
+	"github.com/ipfs/interface-go-ipfs-core/options"
+	"github.com/ipfs/interface-go-ipfs-core/path"
+)
+
+// NameAPI ...
+func (c *Controller) NameAPI() core.NameAPI {
+	return c
+}
+
+// Publish ...
+func (c *Controller) Publish(ctx context.Context, req *core.NamePublishReq) (*core.NamePublishResp, error) {
+	if c.ds == nil {
+		return nil, ErrDataStoreNotReady
+	}
+	opts := []options.NamePublishOption{options.Name.AllowOffline(true)}
+	if req.Key != "" {
+		opts = append(opts, options.Name.Key(req.Key))
+	}
+	if req.Lifetime > 0 {
+		opts = append(opts, options.Name.ValidTime(req.Lifetime))
+	}
+	if req.TTL > 0 {
+		opts = append(opts, options.Name.TTL(req.TTL))
+	}
+	entry, err := c.ds.Name().Publish(ctx, path.New(req.Path), opts...)
+	if err != nil {
+		return nil, err
+	}
+	return &core.NamePublishResp{
+		Name:  entry.Name(),
+		Value: entry.Value().String(),
+	}, nil
+}
+
+// Resolve ...
+func (c *Controller) Resolve(ctx context.Context, req *core.NameResolveReq) (*core.NameResolveResp, error) {
+	if c.ds == nil {
+		return nil, ErrDataStoreNotReady
+	}
+	p, err := c.ds.Name().Resolve(ctx, req.Name, options.Name.Cache(!req.NoCache))
+	if err != nil {
+		return nil, err
+	}
+	return &core.NameResolveResp{Path: p.String()}, nil
+}
+
+func keyInfo(key iface.Key) core.KeyInfo {
+	return core.KeyInfo{
+		Name: key.Name(),
+		ID:   key.ID().Pretty(),
+	}
+}
+
+// KeyGen ...
+func (c *Controller) KeyGen(ctx context.Context, req *core.KeyGenReq) (*core.KeyGenResp, error) {
+	if c.ds == nil {
+		return nil, ErrDataStoreNotReady
+	}
+	var opts []options.KeyGenerateOption
+	if req.Type != "" {
+		opts = append(opts, options.Key.Type(req.Type))
+	}
+	if req.Size > 0 {
+		opts = append(opts, options.Key.Size(req.Size))
+	}
+	key, err := c.ds.Key().Generate(ctx, req.Name, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return &core.KeyGenResp{Key: keyInfo(key)}, nil
+}
+
+// KeyList ...
+func (c *Controller) KeyList(ctx context.Context, req *core.KeyListReq) (*core.KeyListResp, error) {
+	if c.ds == nil {
+		return nil, ErrDataStoreNotReady
+	}
+	keys, err := c.ds.Key().List(ctx)
+	if err != nil {
+		return nil, err
+	}
+	resp := &core.KeyListResp{}
+	for _, key := range keys {
+		resp.Keys = append(resp.Keys, keyInfo(key))
+	}
+	return resp, nil
+}
+
+// KeyRm ...
+func (c *Controller) KeyRm(ctx context.Context, req *core.KeyRmReq) (*core.KeyRmResp, error) {
+	if c.ds == nil {
+		return nil, ErrDataStoreNotReady
+	}
+	key, err := c.ds.Key().Remove(ctx, req.Name)
+	if err != nil {
+		return nil, err
+	}
+	return &core.KeyRmResp{Key: keyInfo(key)}, nil
+}
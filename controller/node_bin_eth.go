@@ -13,7 +13,7 @@ import (
 	"github.com/glvd/accipfs/contract/node"
 	"github.com/glvd/accipfs/contract/token"
 	"github.com/glvd/accipfs/core"
-	"github.com/goextension/io"
+	"github.com/glvd/accipfs/supervisor"
 	"os"
 	"os/exec"
 	"path/filepath"
@@ -61,12 +61,11 @@ type ETHProtocol struct {
 	Eth ETHProtocolInfo `json:"eth"`
 }
 type nodeBinETH struct {
-	ctx     context.Context
-	cancel  context.CancelFunc
 	cfg     *config.Config
 	genesis *config.Genesis
 	name    string
-	cmd     *exec.Cmd
+	sup     *supervisor.Supervisor
+	proc    *supervisor.Process
 	msg     func(s string)
 	client  *ethclient.Client
 }
@@ -87,18 +86,21 @@ func (n *nodeBinETH) Msg(s string) {
 
 // Stop ...
 func (n *nodeBinETH) Stop() error {
-	if n.cmd != nil {
-		n.cancel()
-		n.cmd = nil
+	if n.proc != nil {
+		defer func() {
+			n.proc = nil
+		}()
+		return n.proc.Stop()
 	}
 	return nil
 }
 
 // Start ...
 func (n *nodeBinETH) Start() error {
+	var args []string
 	_, err := os.Stat(filepath.Join(n.cfg.Path, "password"))
 	if core.NodeAccount.CompareInt(n.cfg.NodeType) && err == nil {
-		n.cmd = exec.CommandContext(n.ctx, n.name,
+		args = []string{
 			"--datadir", config.DataDirETH(),
 			"--networkid", strconv.FormatInt(n.genesis.Config.ChainID, 10),
 			"--allow-insecure-unlock",
@@ -107,40 +109,34 @@ func (n *nodeBinETH) Start() error {
 			"--unlock", "54c0fa4a3d982656c51fe7dfbdcc21923a7678cb",
 			"--password", filepath.Join(n.cfg.Path, "password"),
 			"--mine", "--nodiscover",
-		)
+		}
 	} else {
-		n.cmd = exec.CommandContext(n.ctx, n.name,
+		args = []string{
 			"--datadir", config.DataDirETH(),
 			"--networkid", strconv.FormatInt(n.genesis.Config.ChainID, 10),
 			"--rpccorsdomain", "*", "--rpc", "--rpcport", "8545", "--rpcaddr", "127.0.0.1",
 			"--rpcapi", "admin,eth,net,web3,personal,miner",
 			"--mine", "--nodiscover",
-		)
+		}
 	}
 
-	output("geth cmd: ", n.cmd.Args)
-	pipe, err2 := n.cmd.StderrPipe()
-	if err2 != nil {
-		return err2
+	output("geth cmd: ", args)
+	spec := supervisor.Spec{
+		Name:        "geth",
+		Path:        n.name,
+		Args:        args,
+		VersionArgs: []string{"version"},
+		Probe:       supervisor.RPCProbe(config.ETHAddr(), "web3_clientVersion"),
+		LogFile:     filepath.Join(config.LogDir(), "geth.log"),
 	}
-	stdoutPipe, err2 := n.cmd.StdoutPipe()
-	if err2 != nil {
-		return err2
-	}
-	m := io.MultiReader(pipe, stdoutPipe)
+	spec.Configure(n.cfg.ETH.Supervise)
 	if n.cfg.ETH.LogOutput && n.msg != nil {
-		go basis.PipeReader(n.ctx, m, n.msg)
-	}
-	//else {
-	//	go basis.PipeDummy(n.ctx, module, m)
-	//}
-
-	err = n.cmd.Start()
-	if err != nil {
-		return err
+		spec.Output = n.msg
 	}
+	n.proc = supervisor.NewProcess(spec)
+	n.sup.Add(n.proc)
 	//geth --datadir /root/.ethereum --miner.gasprice 1000 --targetgaslimit 50000000  --networkid 20190723 --allow-insecure-unlock --rpc --rpcaddr 0.0.0.0 --rpccorsdomain '*' --rpcapi db,eth,net,web3,personal --unlock 54C0fa4a3d982656c51fe7dFBdCc21923a7678cB --password /root/.ethereum/password --nodiscover --mine
-	return nil
+	return n.proc.Start()
 }
 
 // Initialize ...
@@ -158,19 +154,17 @@ func (n *nodeBinETH) Initialize() error {
 	return nil
 }
 
-func newNodeBinETH(cfg *config.Config) *nodeBinETH {
+func newNodeBinETH(cfg *config.Config, sup *supervisor.Supervisor) *nodeBinETH {
 	path := filepath.Join(cfg.Path, "bin", basis.BinName(cfg.ETH.Name))
 	genesis, err := config.LoadGenesis(cfg)
 	if err != nil {
 		panic(err)
 	}
-	ctx, cancelFunc := context.WithCancel(context.Background())
 	return &nodeBinETH{
-		ctx:     ctx,
-		cancel:  cancelFunc,
 		cfg:     cfg,
 		genesis: genesis,
 		name:    path,
+		sup:     sup,
 	}
 }
 
@@ -6,14 +6,14 @@ import (
 	"github.com/glvd/accipfs/basis"
 	"github.com/glvd/accipfs/config"
 	"github.com/glvd/accipfs/core"
-	"github.com/goextension/io"
+	"github.com/glvd/accipfs/datastore"
+	"github.com/glvd/accipfs/supervisor"
 	files "github.com/ipfs/go-ipfs-files"
-	httpapi "github.com/ipfs/go-ipfs-http-client"
 	iface "github.com/ipfs/interface-go-ipfs-core"
 	"github.com/ipfs/interface-go-ipfs-core/options"
 	"github.com/ipfs/interface-go-ipfs-core/path"
 	"github.com/libp2p/go-libp2p-core/peer"
-	"github.com/multiformats/go-multiaddr"
+	"net/http"
 	"os"
 	"os/exec"
 	"path/filepath"
@@ -23,15 +23,15 @@ import (
 )
 
 var _ core.ControllerService = &nodeBinIPFS{}
+var _ datastore.DataStore = &nodeBinIPFS{}
 
 type nodeBinIPFS struct {
-	ctx    context.Context
-	cancel context.CancelFunc
-	cfg    *config.Config
-	name   string
-	cmd    *exec.Cmd
-	msg    func(string)
-	api    *httpapi.HttpApi
+	cfg  *config.Config
+	name string
+	sup  *supervisor.Supervisor
+	proc *supervisor.Process
+	msg  func(string)
+	*datastore.Remote
 }
 
 // MessageHandle ...
@@ -46,44 +46,62 @@ func (n *nodeBinIPFS) Msg(s string) {
 	}
 }
 
+// managed returns if the daemon is the local bin started by the supervisor, or an external one of the api address
+func (n *nodeBinIPFS) managed() bool {
+	return n.cfg.IPFS.APIAddress == ""
+}
+
 // Start ...
 func (n *nodeBinIPFS) Start() error {
-	n.cmd = exec.CommandContext(n.ctx, n.name, "daemon", "--routing", "none")
-	output("ipfs cmd: ", n.cmd.Args)
-	pipe, err2 := n.cmd.StderrPipe()
-	if err2 != nil {
-		return err2
+	if !n.managed() {
+		logI("use external datastore", "api", n.cfg.IPFS.APIAddress)
+		return nil
 	}
-	stdoutPipe, err2 := n.cmd.StdoutPipe()
-	if err2 != nil {
-		return err2
+	args := []string{"daemon", "--routing", "none"}
+	output("ipfs cmd: ", args)
+	spec := supervisor.Spec{
+		Name:        "ipfs",
+		Path:        n.name,
+		Args:        args,
+		VersionArgs: []string{"version"},
+		Probe:       supervisor.HTTPProbe(http.MethodPost, config.IPFSAPIURL()+"/api/v0/version"),
+		LogFile:     filepath.Join(config.LogDir(), "ipfs.log"),
 	}
-	m := io.MultiReader(pipe, stdoutPipe)
-	if n.cfg.IPFS.LogOutput {
-		go basis.PipeReader(n.ctx, m, n.msg)
+	spec.Configure(n.cfg.IPFS.Supervise)
+	if n.cfg.IPFS.Repo.Private {
+		key, err := datastore.ReadSwarmKey(config.DataDirIPFS())
+		if err != nil {
+			return err
+		}
+		if key == nil {
+			return datastore.ErrNoSwarmKey
+		}
+		spec.Env = append(spec.Env, "LIBP2P_FORCE_PNET=1")
 	}
-	//else {
-	//	go basis.PipeDummy(n.ctx, module, m)
-	//}
-	err := n.cmd.Start()
-	if err != nil {
-		return err
+	if n.cfg.IPFS.LogOutput {
+		spec.Output = n.Msg
 	}
-
-	return nil
+	n.proc = supervisor.NewProcess(spec)
+	n.sup.Add(n.proc)
+	return n.proc.Start()
 }
 
 // Stop ...
 func (n *nodeBinIPFS) Stop() error {
-	if n.cmd != nil {
-		n.cancel()
-		n.cmd = nil
+	if n.proc != nil {
+		defer func() {
+			n.proc = nil
+		}()
+		return n.proc.Stop()
 	}
 	return nil
 }
 
 // Initialize ...
 func (n *nodeBinIPFS) Initialize() error {
+	if !n.managed() {
+		return nil
+	}
 	_, err := os.Stat(config.DataDirIPFS())
 	if err != nil && os.IsNotExist(err) {
 		_ = os.MkdirAll(config.DataDirIPFS(), 0755)
@@ -119,14 +137,24 @@ func (n *nodeBinIPFS) Initialize() error {
 	return nil
 }
 
-func newNodeBinIPFS(cfg *config.Config) *nodeBinIPFS {
+// gcPinsFile is the file of the temporary pins of the datastore gc under the cache dir
+const gcPinsFile = "gc_pins.json"
+
+func newNodeBinIPFS(cfg *config.Config, sup *supervisor.Supervisor) *nodeBinIPFS {
 	path := filepath.Join(cfg.Path, "bin", basis.BinName(cfg.IPFS.Name))
-	ctx, cancelFunc := context.WithCancel(context.Background())
+	addr := cfg.IPFS.APIAddress
+	if addr == "" {
+		addr = config.IPFSAPIAddr()
+	}
+	remote, err := datastore.NewRemote(addr, filepath.Join(config.DataDirCache(), gcPinsFile))
+	if err != nil {
+		panic(err)
+	}
 	return &nodeBinIPFS{
-		ctx:    ctx,
-		cancel: cancelFunc,
 		cfg:    cfg,
 		name:   path,
+		sup:    sup,
+		Remote: remote,
 	}
 }
 
@@ -151,6 +179,21 @@ func (n *nodeBinIPFS) getVersion() (ver [3]int) {
 	return
 }
 
+// ID returns the info of the daemon, the private network is only known for the local repo
+func (n *nodeBinIPFS) ID(ctx context.Context) (*core.DataStoreInfo, error) {
+	info, err := n.Remote.ID(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if n.managed() {
+		info.Network, err = datastore.NetworkOf(config.DataDirIPFS())
+		if err != nil {
+			return nil, err
+		}
+	}
+	return info, nil
+}
+
 // IsReady ...
 func (n *nodeBinIPFS) IsReady() bool {
 	ctx, cancel := context.WithTimeout(context.TODO(), 3*time.Second)
@@ -163,32 +206,6 @@ func (n *nodeBinIPFS) IsReady() bool {
 	return true
 }
 
-// APIContext ...
-func (n *nodeBinIPFS) API() *httpapi.HttpApi {
-	if n.api == nil {
-		ma, err := multiaddr.NewMultiaddr(config.IPFSAPIAddr())
-		if err != nil {
-			panic(err)
-		}
-		api, e := httpapi.NewApi(ma)
-		if e != nil {
-			panic(e)
-		}
-		n.api = api
-	}
-	return n.api
-}
-
-// ID get self serviceNode info
-func (n *nodeBinIPFS) ID(ctx context.Context) (pid *core.DataStoreInfo, e error) {
-	pid = &core.DataStoreInfo{}
-	e = n.API().Request("id").Exec(ctx, pid)
-	if e != nil {
-		return nil, e
-	}
-	return pid, nil
-}
-
 // PinAdd ...
 func (n *nodeBinIPFS) AddFile(ctx context.Context, filename string, option options.UnixfsAddOption) (hash string, e error) {
 	stat, e := os.Stat(filename)
@@ -210,7 +227,7 @@ func (n *nodeBinIPFS) AddFile(ctx context.Context, filename string, option optio
 		}
 		node = sf
 	}
-	resolved, e := n.API().Unixfs().Add(ctx, node, option)
+	resolved, e := n.Unixfs().Add(ctx, node, option)
 	if e != nil {
 		return "", e
 	}
@@ -220,26 +237,26 @@ func (n *nodeBinIPFS) AddFile(ctx context.Context, filename string, option optio
 // PinAdd ...
 func (n *nodeBinIPFS) PinAdd(ctx context.Context, hash string) (e error) {
 	p := path.New(hash)
-	return n.API().Pin().Add(ctx, p, options.Pin.Recursive(true))
+	return n.Pin().Add(ctx, p, options.Pin.Recursive(true))
 }
 
 // PinLS ...
 func (n *nodeBinIPFS) PinLS(ctx context.Context) (pins <-chan iface.Pin, e error) {
-	return n.API().Pin().Ls(ctx, options.Pin.Ls.Recursive())
+	return n.Pin().Ls(ctx, options.Pin.Ls.Recursive())
 }
 
 // PinRm ...
 func (n *nodeBinIPFS) PinRm(ctx context.Context, hash string) (e error) {
 	p := path.New(hash)
-	return n.API().Pin().Rm(ctx, p)
+	return n.Pin().Rm(ctx, p)
 }
 
 // SwarmPeers ...
 func (n *nodeBinIPFS) SwarmPeers(ctx context.Context) ([]iface.ConnectionInfo, error) {
-	return n.API().Swarm().Peers(ctx)
+	return n.Swarm().Peers(ctx)
 }
 
 // SwarmPeers ...
 func (n *nodeBinIPFS) SwarmConnect(ctx context.Context, info peer.AddrInfo) error {
-	return n.API().Swarm().Connect(ctx, info)
+	return n.Swarm().Connect(ctx, info)
 }
@@ -2,22 +2,34 @@ package controller
 
 import (
 	"context"
+	"encoding/base64"
 	"fmt"
 	"github.com/glvd/accipfs/config"
 	"github.com/glvd/accipfs/core"
+	"github.com/glvd/accipfs/datastore"
 	"github.com/glvd/accipfs/plugin/loader"
+	"github.com/ipfs/go-cid"
 	ipfsversion "github.com/ipfs/go-ipfs"
+	blockstore "github.com/ipfs/go-ipfs-blockstore"
 	ipfsconfig "github.com/ipfs/go-ipfs-config"
 	ipfscore "github.com/ipfs/go-ipfs/core"
 	"github.com/ipfs/go-ipfs/core/coreapi"
+	"github.com/ipfs/go-ipfs/core/corerepo"
 	"github.com/ipfs/go-ipfs/core/node/libp2p"
+	ipfsgc "github.com/ipfs/go-ipfs/gc"
 	"github.com/ipfs/go-ipfs/repo"
 	"github.com/ipfs/go-ipfs/repo/fsrepo"
 	intercore "github.com/ipfs/interface-go-ipfs-core"
 	"github.com/ipfs/interface-go-ipfs-core/options"
+	ic "github.com/libp2p/go-libp2p-core/crypto"
+	"github.com/libp2p/go-libp2p-core/host"
+	"github.com/libp2p/go-libp2p-core/peer"
+	"github.com/libp2p/go-libp2p-core/pnet"
+	"github.com/libp2p/go-libp2p/p2p/protocol/identify"
 	"go.uber.org/atomic"
 	"os"
 	"path/filepath"
+	"reflect"
 	"runtime"
 )
 
@@ -35,6 +47,7 @@ type nodeLibIPFS struct {
 }
 
 var _ core.ControllerService = &nodeLibIPFS{}
+var _ datastore.DataStore = &nodeLibIPFS{}
 
 func setupPlugins(externalPluginsPath string) (*loader.PluginLoader, error) {
 	// Load any external plugins if available on externalPluginsPath
@@ -117,8 +130,11 @@ func (n *nodeLibIPFS) Initialize() error {
 	if _, err := setupPlugins(""); err != nil {
 		return err
 	}
+	if fsrepo.IsInitialized(n.configRoot) {
+		return nil
+	}
 	// Create a Repo
-	if err := createRepo(n.ctx, n.configRoot); err != nil {
+	if err := createRepo(n.configRoot, n.cfg.IPFS); err != nil {
 		return fmt.Errorf("failed to create temp repo: %s", err)
 	}
 
@@ -138,7 +154,7 @@ func (n *nodeLibIPFS) MessageHandle(f func(s string)) {
 
 }
 
-func createRepo(ctx context.Context, repoPath string) error {
+func createRepo(repoPath string, ipfs config.IPFSConfig) error {
 	identity, err := ipfsconfig.CreateIdentity(os.Stdout, []options.KeyGenerateOption{options.Key.Type(options.Ed25519Key)})
 	if err != nil {
 		return err
@@ -149,27 +165,68 @@ func createRepo(ctx context.Context, repoPath string) error {
 	if err != nil {
 		return err
 	}
-	cfg.Datastore.Spec = badgerSpec()
+	applied, err := datastore.ApplyConfig(cfg, ipfs, nil)
+	if err != nil {
+		return err
+	}
+	cfg.Datastore.Spec, err = datastore.DatastoreSpec(ipfs.Repo.Datastore)
+	if err != nil {
+		return err
+	}
 	// Create the repo with the config
 	err = fsrepo.Init(repoPath, cfg)
 	if err != nil {
 		return fmt.Errorf("failed to init ephemeral node: %s", err)
 	}
+	r, err := fsrepo.Open(repoPath)
+	if err != nil {
+		return err
+	}
+	defer r.Close()
+	return datastore.SetAppliedProfiles(r, applied)
+}
 
-	return nil
+// applyConfig writes the changed settings to the config of the opened repo
+func applyConfig(r repo.Repo, ipfs config.IPFSConfig) (*ipfsconfig.Config, error) {
+	rc, err := r.Config()
+	if err != nil {
+		return nil, err
+	}
+	cfg, err := rc.Clone()
+	if err != nil {
+		return nil, err
+	}
+	applied, err := datastore.ApplyConfig(cfg, ipfs, datastore.AppliedProfiles(r))
+	if err != nil {
+		return nil, err
+	}
+	if ipfs.Repo.Datastore != "" {
+		spec, err := datastore.DatastoreSpec(ipfs.Repo.Datastore)
+		if err != nil {
+			return nil, err
+		}
+		if !reflect.DeepEqual(spec, cfg.Datastore.Spec) {
+			log.Warnw("the datastore of an existing repo can not be changed", "datastore", ipfs.Repo.Datastore)
+		}
+	}
+	if err := r.SetConfig(cfg); err != nil {
+		return nil, err
+	}
+	//set after the config, the key is kept by SetConfig but not part of cfg
+	if err := datastore.SetAppliedProfiles(r, applied); err != nil {
+		return nil, err
+	}
+	return cfg, nil
 }
 
-func badgerSpec() map[string]interface{} {
-	return map[string]interface{}{
-		"type":   "measure",
-		"prefix": "badger.datastore",
-		"child": map[string]interface{}{
-			"type":       "badgerds",
-			"path":       "badgerds",
-			"syncWrites": false,
-			"truncate":   true,
-		},
+func routingOption(typ string) libp2p.RoutingOption {
+	switch typ {
+	case datastore.RoutingDHTClient:
+		return libp2p.DHTClientOption
+	case datastore.RoutingNone:
+		return libp2p.NilRouterOption
 	}
+	return libp2p.DHTOption
 }
 
 func (n *nodeLibIPFS) startIPFSNode(ctx context.Context, path string) (intercore.CoreAPI, error) {
@@ -179,6 +236,17 @@ func (n *nodeLibIPFS) startIPFSNode(ctx context.Context, path string) (intercore
 	if _, err := setupPlugins(""); err != nil {
 		return nil, err
 	}
+	if n.cfg.IPFS.Repo.Private {
+		key, err := datastore.ReadSwarmKey(path)
+		if err != nil {
+			return nil, err
+		}
+		if key == nil {
+			return nil, datastore.ErrNoSwarmKey
+		}
+		//refuse to start in the public network when the key is broken
+		pnet.ForcePrivateNetwork = true
+	}
 	// Spawning an ephemeral IPFS node
 	return n.createNode(ctx, path)
 }
@@ -190,14 +258,18 @@ func (n *nodeLibIPFS) createNode(ctx context.Context, repoPath string) (intercor
 	if err != nil {
 		return nil, err
 	}
+	cfg, err := applyConfig(repo, n.cfg.IPFS)
+	if err != nil {
+		repo.Close()
+		return nil, err
+	}
 
 	// Construct the node
 
 	nodeOptions := &ipfscore.BuildCfg{
 		Online:  true,
-		Routing: libp2p.DHTOption, // This option sets the node to be a full DHT node (both fetching and storing DHT Records)
-		// Routing: libp2p.DHTClientOption, // This option sets the node to be a client DHT node (only fetching records)
-		Repo: repo,
+		Routing: routingOption(cfg.Routing.Type),
+		Repo:    repo,
 	}
 	n.nodeConfig = nodeOptions
 
@@ -209,3 +281,157 @@ func (n *nodeLibIPFS) createNode(ctx context.Context, repoPath string) (intercor
 	// Attach the Core API to the constructed node
 	return coreapi.NewCoreAPI(node)
 }
+
+// ID ...
+func (n *nodeLibIPFS) ID(ctx context.Context) (*core.DataStoreInfo, error) {
+	if n.node == nil {
+		return nil, ErrDataStoreNotReady
+	}
+	info := new(core.DataStoreInfo)
+	info.ID = n.node.Identity.Pretty()
+
+	pk := n.node.PrivateKey.GetPublic()
+	pkb, err := ic.MarshalPublicKey(pk)
+	if err != nil {
+		return nil, err
+	}
+	info.PublicKey = base64.StdEncoding.EncodeToString(pkb)
+
+	if n.node.PeerHost != nil {
+		addrs, err := peer.AddrInfoToP2pAddrs(host.InfoFromHost(n.node.PeerHost))
+		if err != nil {
+			return nil, err
+		}
+		for _, a := range addrs {
+			info.Addresses = append(info.Addresses, a.String())
+		}
+	}
+	info.ProtocolVersion = identify.LibP2PVersion
+	info.AgentVersion = ipfsversion.UserAgent
+	swarmKey, err := n.node.Repo.SwarmKey()
+	if err != nil {
+		return nil, err
+	}
+	if swarmKey != nil {
+		info.Network, err = datastore.Fingerprint(swarmKey)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return info, nil
+}
+
+// Bandwidth ...
+func (n *nodeLibIPFS) Bandwidth(ctx context.Context) (map[string]core.BandwidthTotal, error) {
+	totals := make(map[string]core.BandwidthTotal)
+	if n.node == nil || n.node.Reporter == nil {
+		return totals, nil
+	}
+	for id, stats := range n.node.Reporter.GetBandwidthByPeer() {
+		totals[id.Pretty()] = core.BandwidthTotal{
+			In:  uint64(stats.TotalIn),
+			Out: uint64(stats.TotalOut),
+		}
+	}
+	return totals, nil
+}
+
+// RepoSize ...
+func (n *nodeLibIPFS) RepoSize(ctx context.Context) (uint64, error) {
+	if n.node == nil {
+		return 0, ErrDataStoreNotReady
+	}
+	stat, err := corerepo.RepoSize(ctx, n.node)
+	if err != nil {
+		return 0, err
+	}
+	return stat.RepoSize, nil
+}
+
+// RepoStat ...
+func (n *nodeLibIPFS) RepoStat(ctx context.Context) (*datastore.RepoStat, error) {
+	size, err := n.RepoSize(ctx)
+	if err != nil {
+		return nil, err
+	}
+	stat := &datastore.RepoStat{
+		Path: n.configRoot,
+		Size: size,
+	}
+	keys, err := n.node.Blockstore.AllKeysChan(ctx)
+	if err != nil {
+		return nil, err
+	}
+	for range keys {
+		stat.Objects++
+	}
+	recursive, err := n.node.Pinning.RecursiveKeys(ctx)
+	if err != nil {
+		return nil, err
+	}
+	direct, err := n.node.Pinning.DirectKeys(ctx)
+	if err != nil {
+		return nil, err
+	}
+	stat.Pins = len(recursive) + len(direct)
+	return stat, nil
+}
+
+// GC ...
+func (n *nodeLibIPFS) GC(ctx context.Context, keep []cid.Cid) (uint64, error) {
+	if n.node == nil {
+		return 0, ErrDataStoreNotReady
+	}
+	roots, err := corerepo.BestEffortRoots(n.node.FilesRoot)
+	if err != nil {
+		return 0, err
+	}
+	roots = append(roots, keep...)
+	var removed uint64
+	err = corerepo.CollectResult(ctx, ipfsgc.GC(ctx, n.node.Blockstore, n.node.Repo.Datastore(), n.node.Pinning, roots), func(k cid.Cid) {
+		removed++
+	})
+	return removed, err
+}
+
+// Verify ...
+func (n *nodeLibIPFS) Verify(ctx context.Context) (checked uint64, corrupt []cid.Cid, err error) {
+	if n.node == nil {
+		return 0, nil, ErrDataStoreNotReady
+	}
+	return verifyBlocks(ctx, n.node.Blockstore)
+}
+
+// verifyBlocks re-hashes every block of the blockstore and returns the corrupt ones
+func verifyBlocks(ctx context.Context, bs blockstore.Blockstore) (checked uint64, corrupt []cid.Cid, err error) {
+	keys, err := bs.AllKeysChan(ctx)
+	if err != nil {
+		return 0, nil, err
+	}
+	for k := range keys {
+		checked++
+		b, err := bs.Get(k)
+		if err != nil {
+			log.Warnw("read block failed", "cid", k, "err", err)
+			corrupt = append(corrupt, k)
+			continue
+		}
+		sum, err := k.Prefix().Sum(b.RawData())
+		if err != nil || !sum.Equals(k) {
+			corrupt = append(corrupt, k)
+		}
+	}
+	return checked, corrupt, ctx.Err()
+}
+
+// Refetch ...
+func (n *nodeLibIPFS) Refetch(ctx context.Context, k cid.Cid) error {
+	if n.node == nil {
+		return ErrDataStoreNotReady
+	}
+	if err := n.node.Blockstore.DeleteBlock(k); err != nil {
+		return err
+	}
+	_, err := n.node.Blocks.GetBlock(ctx, k)
+	return err
+}
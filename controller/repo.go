@@ -0,0 +1,291 @@
+package controller
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/glvd/accipfs/core"
+	"github.com/glvd/accipfs/gc"
+	"github.com/ipfs/go-cid"
+	"github.com/ipfs/interface-go-ipfs-core/path"
+)
+
+// gib is the unit of the storage limit
+const gib = 1 << 30
+
+// DefaultGCInterval is used when the gc interval is not set
+const DefaultGCInterval = time.Hour
+
+// ErrStorageQuota ...
+var ErrStorageQuota = errors.New("datastore storage quota exceeded")
+
+// ErrDataStoreNotReady ...
+var ErrDataStoreNotReady = errors.New("datastore is not ready")
+
+// RegisterAccess set the access stats used by the lru eviction
+func (c *Controller) RegisterAccess(access *gc.Access) {
+	c.access = access
+}
+
+// RegisterProtector set the func returns the roots which must not be evicted
+func (c *Controller) RegisterProtector(f func() []string) {
+	c.protector = f
+}
+
+// RegisterCaches set the func returns the stats of the node caches
+func (c *Controller) RegisterCaches(f func() []core.CacheStat) {
+	c.caches = f
+}
+
+// CleanGCPins removes the temporary pins left in the datastore by an interrupted gc
+func (c *Controller) CleanGCPins(ctx context.Context) error {
+	cleaner, ok := c.ds.(interface {
+		CleanGCPins(ctx context.Context) error
+	})
+	if !ok {
+		return nil
+	}
+	return cleaner.CleanGCPins(ctx)
+}
+
+// RecordAccess records that the root cid was served, the size is the cumulative
+// size of the root dag as the lru eviction removes the whole root
+func (c *Controller) RecordAccess(ctx context.Context, cid string) {
+	if c.access == nil {
+		return
+	}
+	var size uint64
+	if ds := c.dataNode(); ds != nil {
+		stat, err := ds.Object().Stat(ctx, path.New(cid))
+		if err != nil {
+			log.Debugw("stat accessed root", "cid", cid, "err", err)
+		} else {
+			size = uint64(stat.CumulativeSize)
+		}
+	}
+	//a zero size keeps the size known by the access stats
+	if err := c.access.Touch(cid, size); err != nil {
+		log.Errorw("record access", "cid", cid, "err", err)
+	}
+}
+
+func (c *Controller) watermarks() (limit, high, low uint64) {
+	if c.cfg.Limit > 0 {
+		limit = uint64(c.cfg.Limit) * gib
+	}
+	high, low = gc.Watermarks(limit, c.cfg.GC.HighWater, c.cfg.GC.LowWater)
+	return
+}
+
+func (c *Controller) repoSize(ctx context.Context) (uint64, error) {
+	if c.ds == nil {
+		return 0, ErrDataStoreNotReady
+	}
+	return c.ds.RepoSize(ctx)
+}
+
+// RepoStat ...
+func (c *Controller) RepoStat(ctx context.Context, req *core.RepoStatReq) (*core.RepoStatResp, error) {
+	if c.ds == nil {
+		return nil, ErrDataStoreNotReady
+	}
+	stat, err := c.ds.RepoStat(ctx)
+	if err != nil {
+		return nil, err
+	}
+	limit, high, low := c.watermarks()
+	resp := &core.RepoStatResp{
+		Path:    stat.Path,
+		Size:    stat.Size,
+		Limit:   limit,
+		Objects: stat.Objects,
+		Pins:    stat.Pins,
+	}
+	if c.caches != nil {
+		resp.Caches = c.caches()
+	}
+	if limit != 0 {
+		resp.HighWater = high
+		resp.LowWater = low
+	}
+	if c.access != nil {
+		stats, err := c.access.Stats()
+		if err != nil {
+			return nil, err
+		}
+		resp.Cached = len(stats)
+	}
+	return resp, nil
+}
+
+// RepoGC collects the unpinned blocks, the least recently accessed roots are evicted until the repo fits in the low watermark
+func (c *Controller) RepoGC(ctx context.Context, req *core.RepoGCReq) (*core.RepoGCResp, error) {
+	c.gcLock.Lock()
+	defer c.gcLock.Unlock()
+	before, err := c.repoSize(ctx)
+	if err != nil {
+		return nil, err
+	}
+	resp := &core.RepoGCResp{
+		Before: before,
+		After:  before,
+	}
+	_, high, low := c.watermarks()
+	if !req.Force && before < high {
+		return resp, nil
+	}
+
+	var stats []gc.AccessStat
+	if c.access != nil {
+		stats, err = c.access.Stats()
+		if err != nil {
+			return nil, err
+		}
+	}
+	budget := low
+	if budget != gc.Unlimited {
+		var cached uint64
+		for _, s := range stats {
+			cached += s.Size
+		}
+		//the size which can not be evicted is taken from the budget
+		if before > cached {
+			if before-cached >= budget {
+				budget = 0
+			} else {
+				budget -= before - cached
+			}
+		}
+	}
+	var protected []string
+	if c.protector != nil {
+		protected = c.protector()
+	}
+	plan := gc.NewPlan(stats, protected, budget)
+
+	var roots []cid.Cid
+	for _, s := range plan.Keep {
+		root, err := cid.Decode(s)
+		if err != nil {
+			log.Warnw("skip wrong root", "cid", s, "err", err)
+			continue
+		}
+		roots = append(roots, root)
+	}
+	removed, err := c.ds.GC(ctx, roots)
+	if err != nil {
+		return nil, err
+	}
+	resp.Removed = int(removed)
+	if c.access != nil && len(plan.Evict) > 0 {
+		if err := c.access.Remove(plan.Evict...); err != nil {
+			return nil, err
+		}
+	}
+	resp.Evicted = plan.Evict
+	resp.After, err = c.repoSize(ctx)
+	if err != nil {
+		return nil, err
+	}
+	log.Infow("repo gc", "removed", resp.Removed, "evicted", len(resp.Evicted), "before", resp.Before, "after", resp.After)
+	return resp, nil
+}
+
+// verify re-hashes every block in the blockstore and returns the corrupt ones
+func (c *Controller) verify(ctx context.Context) (checked uint64, corrupt []cid.Cid, err error) {
+	if c.ds == nil {
+		return 0, nil, ErrDataStoreNotReady
+	}
+	return c.ds.Verify(ctx)
+}
+
+// RepoVerify ...
+func (c *Controller) RepoVerify(ctx context.Context, req *core.RepoVerifyReq) (*core.RepoVerifyResp, error) {
+	checked, corrupt, err := c.verify(ctx)
+	if err != nil {
+		return nil, err
+	}
+	resp := &core.RepoVerifyResp{
+		Checked: checked,
+	}
+	for _, k := range corrupt {
+		resp.Corrupt = append(resp.Corrupt, k.String())
+	}
+	return resp, nil
+}
+
+// RepoRepair drops the corrupt blocks and fetches them again from the providers
+func (c *Controller) RepoRepair(ctx context.Context, req *core.RepoRepairReq) (*core.RepoRepairResp, error) {
+	c.gcLock.Lock()
+	defer c.gcLock.Unlock()
+	checked, corrupt, err := c.verify(ctx)
+	if err != nil {
+		return nil, err
+	}
+	resp := &core.RepoRepairResp{
+		Checked: checked,
+	}
+	timeout := req.Timeout
+	if timeout <= 0 {
+		timeout = time.Duration(c.cfg.IPFS.Timeout) * time.Second
+	}
+	if timeout <= 0 {
+		timeout = 30 * time.Second
+	}
+	for _, k := range corrupt {
+		fetch, cancel := context.WithTimeout(ctx, timeout)
+		err := c.ds.Refetch(fetch, k)
+		cancel()
+		if err != nil {
+			log.Warnw("fetch corrupt block", "cid", k, "err", err)
+			resp.Failed = append(resp.Failed, k.String())
+			continue
+		}
+		resp.Repaired = append(resp.Repaired, k.String())
+	}
+	return resp, nil
+}
+
+// checkQuota runs the gc when the repo reaches the high watermark and fails when the limit is still exceeded
+func (c *Controller) checkQuota(ctx context.Context) error {
+	limit, high, _ := c.watermarks()
+	if limit == 0 {
+		return nil
+	}
+	size, err := c.repoSize(ctx)
+	if err != nil {
+		return err
+	}
+	if size < high {
+		return nil
+	}
+	resp, err := c.RepoGC(ctx, &core.RepoGCReq{})
+	if err != nil {
+		return err
+	}
+	if resp.After >= limit {
+		return ErrStorageQuota
+	}
+	return nil
+}
+
+// RunGC checks the repo size every interval and collects it when the high watermark is reached,
+// DefaultGCInterval is used when interval is not positive
+func (c *Controller) RunGC(ctx context.Context, interval time.Duration) {
+	if interval <= 0 {
+		interval = DefaultGCInterval
+	}
+	t := time.NewTicker(interval)
+	defer t.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-t.C:
+			if _, err := c.RepoGC(ctx, &core.RepoGCReq{}); err != nil && err != ErrDataStoreNotReady {
+				log.Errorw("repo gc", "err", err)
+			}
+		}
+	}
+}
@@ -0,0 +1,128 @@
+package controller
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+
+	"github.com/glvd/accipfs/config"
+	"github.com/glvd/accipfs/core"
+	"github.com/glvd/accipfs/datastore"
+	blocks "github.com/ipfs/go-block-format"
+	"github.com/ipfs/go-cid"
+	ds "github.com/ipfs/go-datastore"
+	dssync "github.com/ipfs/go-datastore/sync"
+	blockstore "github.com/ipfs/go-ipfs-blockstore"
+)
+
+// testStore keeps the blocks in memory and refetches them from the providers
+type testStore struct {
+	datastore.DataStore
+	bs        blockstore.Blockstore
+	providers map[cid.Cid]blocks.Block
+}
+
+func newTestStore() *testStore {
+	return &testStore{
+		bs:        blockstore.NewBlockstore(dssync.MutexWrap(ds.NewMapDatastore())),
+		providers: make(map[cid.Cid]blocks.Block),
+	}
+}
+
+// add puts a block known by the providers, the stored data is replaced by corrupt when not nil
+func (s *testStore) add(t *testing.T, data string, corrupt []byte) cid.Cid {
+	b := blocks.NewBlock([]byte(data))
+	s.providers[b.Cid()] = b
+	stored := blocks.Block(b)
+	if corrupt != nil {
+		var err error
+		stored, err = blocks.NewBlockWithCid(corrupt, b.Cid())
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := s.bs.Put(stored); err != nil {
+		t.Fatal(err)
+	}
+	return b.Cid()
+}
+
+func (s *testStore) Verify(ctx context.Context) (uint64, []cid.Cid, error) {
+	return verifyBlocks(ctx, s.bs)
+}
+
+func (s *testStore) Refetch(ctx context.Context, k cid.Cid) error {
+	if err := s.bs.DeleteBlock(k); err != nil {
+		return err
+	}
+	b, ok := s.providers[k]
+	if !ok {
+		return errors.New("no provider")
+	}
+	return s.bs.Put(b)
+}
+
+func testController(store datastore.DataStore) *Controller {
+	return &Controller{
+		cfg:    config.Default(),
+		ds:     store,
+		gcLock: &sync.Mutex{},
+	}
+}
+
+func TestController_RepoVerify(t *testing.T) {
+	store := newTestStore()
+	store.add(t, "good", nil)
+	bad := store.add(t, "bad", []byte("corrupt"))
+	c := testController(store)
+
+	resp, err := c.RepoVerify(context.Background(), &core.RepoVerifyReq{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.Checked != 2 {
+		t.Fatalf("checked %d blocks", resp.Checked)
+	}
+	if len(resp.Corrupt) != 1 || resp.Corrupt[0] != bad.String() {
+		t.Fatalf("corrupt blocks %v want %s", resp.Corrupt, bad)
+	}
+
+	if _, err := testController(nil).RepoVerify(context.Background(), &core.RepoVerifyReq{}); !errors.Is(err, ErrDataStoreNotReady) {
+		t.Fatalf("verify without datastore: %v", err)
+	}
+}
+
+func TestController_RepoRepair(t *testing.T) {
+	store := newTestStore()
+	store.add(t, "good", nil)
+	bad := store.add(t, "bad", []byte("corrupt"))
+	lost := store.add(t, "lost", []byte("corrupt"))
+	delete(store.providers, lost)
+	c := testController(store)
+
+	resp, err := c.RepoRepair(context.Background(), &core.RepoRepairReq{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(resp.Repaired) != 1 || resp.Repaired[0] != bad.String() {
+		t.Fatalf("repaired %v want %s", resp.Repaired, bad)
+	}
+	if len(resp.Failed) != 1 || resp.Failed[0] != lost.String() {
+		t.Fatalf("failed %v want %s", resp.Failed, lost)
+	}
+
+	verify, err := c.RepoVerify(context.Background(), &core.RepoVerifyReq{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if verify.Checked != 2 || len(verify.Corrupt) != 0 {
+		t.Fatalf("after repair checked %d corrupt %v", verify.Checked, verify.Corrupt)
+	}
+}
+
+func TestController_RunGCZeroInterval(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	testController(nil).RunGC(ctx, 0)
+}
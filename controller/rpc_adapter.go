@@ -40,7 +40,12 @@ func (a adapter) Get(r *http.Request, req *core.GetReq, resp *core.GetResp) erro
 
 // Pay ...
 func (a adapter) Pay(r *http.Request, req *core.PayReq, resp *core.PayResp) error {
-	panic("implement me")
+	pay, err := a.api.Pay(r.Context(), req)
+	if err != nil {
+		return err
+	}
+	*resp = *pay
+	return nil
 }
 
 func newAdapter(api core.API) JSONRPCAdapter {
@@ -0,0 +1,41 @@
+package controller
+
+import (
+	"crypto/ecdsa"
+	"io"
+
+	"github.com/glvd/accipfs/seal"
+)
+
+// sealFile returns the reader of the file sealed by a new content key and the key wrapped for the recipients,
+// the reader must be closed to stop the sealing
+func sealFile(file io.Reader, recipients []string) (io.ReadCloser, string, error) {
+	var pubs []*ecdsa.PublicKey
+	for _, r := range recipients {
+		pub, err := seal.ParsePublicKey(r)
+		if err != nil {
+			return nil, "", err
+		}
+		pubs = append(pubs, pub)
+	}
+	key, err := seal.NewKey()
+	if err != nil {
+		return nil, "", err
+	}
+	security, err := seal.Wrap(key, pubs)
+	if err != nil {
+		return nil, "", err
+	}
+	pr, pw := io.Pipe()
+	go func() {
+		w, err := seal.NewWriter(pw, key)
+		if err == nil {
+			_, err = io.Copy(w, file)
+		}
+		if err == nil {
+			err = w.Close()
+		}
+		pw.CloseWithError(err)
+	}()
+	return pr, security, nil
+}
@@ -2,6 +2,7 @@ package core
 
 import (
 	"context"
+	"io"
 	"time"
 )
 
@@ -34,10 +35,26 @@ type PingResp struct {
 
 // PayReq ...
 type PayReq struct {
+	Peer    string //datastore peer id of the accelerator node
+	Address string //eth address to receive the token
+	Amount  string //prepay amount, only query balances when empty
+}
+
+// PayBalance ...
+type PayBalance struct {
+	Peer       string
+	Address    string
+	Bytes      uint64
+	Paid       string
+	Balance    string
+	LastSettle int64
 }
 
 // PayResp ...
 type PayResp struct {
+	Account  string
+	Token    string
+	Balances []PayBalance
 }
 
 // IDReq ...
@@ -71,6 +88,16 @@ type NodeListResp struct {
 	Nodes map[string]NodeInfo
 }
 
+// NodeBackupReq ...
+type NodeBackupReq struct {
+}
+
+// NodeBackupResp ...
+type NodeBackupResp struct {
+	// Cache is the badger backup of the node cache
+	Cache []byte
+}
+
 // NodeUnlinkReq ...
 type NodeUnlinkReq struct {
 	Peers []string
@@ -120,23 +147,316 @@ type NodeAddResp struct {
 
 // UploadReq ...
 type UploadReq struct {
-	Path string
+	Path       string
+	Recipients []string //public keys in hex, the file is sealed for them when set
 	//Option options.UnixfsAddOption
 }
 
 // UploadResp ...
 type UploadResp struct {
-	Hash string
+	Hash     string
+	Security string //content key wrapped for the recipients, kept in DataInfoV1.Security.Key
 }
 
+// headers of the get opening a sealed content, the key is never taken from the query
+const (
+	ContentKeyHeader   = "X-Content-Key"
+	ContentProofHeader = "X-Content-Proof"
+	ContentTimeHeader  = "X-Content-Time"
+)
+
 // GetReq ...
 type GetReq struct {
+	Hash     string
+	Endpoint string
+	Writer   io.Writer `json:"-"` //writer the content is streamed to, a sealed content stays sealed unless Key is set
+	Key      string    //hex content key, the daemon opens the sealed content on get when it is set with the proof
+	Proof    string    //signature of a recipient of the content over the hash and Time, see seal.Prove
+	Time     int64     //unix time of the proof
 }
 
 // GetResp ...
 type GetResp struct {
 }
 
+// StatsBandwidthReq ...
+type StatsBandwidthReq struct {
+	Kind   string //peer or cid, both are returned when empty
+	Top    int
+	Window time.Duration
+}
+
+// StatsBandwidthResp ...
+type StatsBandwidthResp struct {
+	Peers []BandwidthStat
+	CIDs  []BandwidthStat
+}
+
+// StatsQoSReq ...
+type StatsQoSReq struct {
+}
+
+// StatsQoSResp ...
+type StatsQoSResp struct {
+	Limits []LimitStat
+}
+
+// StatsStreamReq ...
+type StatsStreamReq struct {
+}
+
+// StatsStreamResp ...
+type StatsStreamResp struct {
+	Requests   uint64 //segment requests of the stream api
+	Hits       uint64 //segments prefetched before the request
+	Late       uint64 //segments still prefetching on the request
+	Misses     uint64
+	Prefetched uint64
+	Failed     uint64
+	HitRate    float64
+}
+
+// ChainStatusReq ...
+type ChainStatusReq struct {
+}
+
+// ChainStatusResp ...
+type ChainStatusResp struct {
+	Height   uint64 //last indexed block
+	Hash     string
+	Head     uint64 //head block of the chain
+	Reorgs   uint64
+	LastSync int64
+}
+
+// ProcessStatus ...
+type ProcessStatus struct {
+	Name      string
+	Path      string
+	Version   string
+	State     string //starting, running, backoff, stopped, exited or failed
+	Pid       int
+	Restarts  int
+	ExitCode  int
+	LastError string
+	Started   int64
+	Healthy   bool //last liveness probe passed
+	LogFile   string
+}
+
+// ProcessListReq ...
+type ProcessListReq struct {
+}
+
+// ProcessListResp ...
+type ProcessListResp struct {
+	Processes []ProcessStatus
+}
+
+// NamePublishReq ...
+type NamePublishReq struct {
+	Path     string        //ipfs path to publish
+	Catalog  bool          //publish the root of the local catalog instead of Path
+	Key      string        //key name, the node key "self" is used when empty
+	Lifetime time.Duration //lifetime of the record
+	TTL      time.Duration //cache ttl of the record
+}
+
+// NamePublishResp ...
+type NamePublishResp struct {
+	Name  string
+	Value string
+}
+
+// NameResolveReq ...
+type NameResolveReq struct {
+	Name    string
+	NoCache bool
+}
+
+// NameResolveResp ...
+type NameResolveResp struct {
+	Path string
+}
+
+// KeyInfo ...
+type KeyInfo struct {
+	Name string
+	ID   string
+}
+
+// KeyGenReq ...
+type KeyGenReq struct {
+	Name string
+	Type string //rsa or ed25519
+	Size int    //bits of the rsa key
+}
+
+// KeyGenResp ...
+type KeyGenResp struct {
+	Key KeyInfo
+}
+
+// KeyListReq ...
+type KeyListReq struct {
+}
+
+// KeyListResp ...
+type KeyListResp struct {
+	Keys []KeyInfo
+}
+
+// KeyRmReq ...
+type KeyRmReq struct {
+	Name string
+}
+
+// KeyRmResp ...
+type KeyRmResp struct {
+	Key KeyInfo
+}
+
+// CatalogExportReq ...
+type CatalogExportReq struct {
+	Codec     string //dag-cbor or dag-json
+	PrefixLen int    //length of the media number prefix the records are sharded by
+}
+
+// CatalogExportResp ...
+type CatalogExportResp struct {
+	Root    string
+	Records int
+}
+
+// CatalogImportReq ...
+type CatalogImportReq struct {
+	Root  string
+	NoPin bool //only load the records without pinning the referenced content
+}
+
+// CatalogImportResp ...
+type CatalogImportResp struct {
+	Records int
+	Invalid []string //media numbers of the records which failed to verify
+	Pinned  []string
+	Failed  []string //referenced content failed to pin, they are queued to retry
+}
+
+// DagExportReq ...
+type DagExportReq struct {
+	Roots   []string
+	Version int       //car version 1 or 2
+	Writer  io.Writer `json:"-"` //writer the car file is streamed to
+}
+
+// DagExportResp ...
+type DagExportResp struct {
+}
+
+// DagImportReq ...
+type DagImportReq struct {
+	Reader   io.Reader `json:"-"` //reader of the car v1 or v2 file
+	PinRoots bool
+}
+
+// DagImportResp ...
+type DagImportResp struct {
+	Roots  []string
+	Blocks int
+	Pinned bool
+}
+
+// JobStatus ...
+type JobStatus struct {
+	ID        string
+	Kind      string
+	Priority  int
+	State     string //pending, running, retrying, done, failed or canceled
+	Attempts  int
+	Error     string
+	Created   int64
+	Updated   int64
+	NotBefore int64 //unix nano of the next retry
+}
+
+// TaskListReq ...
+type TaskListReq struct {
+	State string //all the states when empty
+}
+
+// TaskListResp ...
+type TaskListResp struct {
+	Jobs []JobStatus
+}
+
+// TaskCancelReq ...
+type TaskCancelReq struct {
+	ID string
+}
+
+// TaskCancelResp ...
+type TaskCancelResp struct {
+}
+
+// RepoGCReq ...
+type RepoGCReq struct {
+	Force bool //collect even if the repo is under the high watermark
+}
+
+// RepoGCResp ...
+type RepoGCResp struct {
+	Removed int      //removed blocks
+	Evicted []string //evicted root cids
+	Before  uint64
+	After   uint64
+}
+
+// RepoStatReq ...
+type RepoStatReq struct {
+}
+
+// CacheStat ...
+type CacheStat struct {
+	Name string
+	Keys int
+	Size int64
+}
+
+// RepoStatResp ...
+type RepoStatResp struct {
+	Path      string
+	Size      uint64
+	Limit     uint64 //0 means unlimited
+	HighWater uint64
+	LowWater  uint64
+	Objects   uint64
+	Pins      int
+	Cached    int //accessed roots tracked for eviction
+	Caches    []CacheStat
+}
+
+// RepoVerifyReq ...
+type RepoVerifyReq struct {
+}
+
+// RepoVerifyResp ...
+type RepoVerifyResp struct {
+	Checked uint64
+	Corrupt []string
+}
+
+// RepoRepairReq ...
+type RepoRepairReq struct {
+	Timeout time.Duration //fetch timeout of every corrupt block
+}
+
+// RepoRepairResp ...
+type RepoRepairResp struct {
+	Checked  uint64
+	Repaired []string
+	Failed   []string
+}
+
 // RequestTag ...
 type RequestTag int
 
@@ -159,8 +479,15 @@ type API interface {
 	Ping(ctx context.Context, req *PingReq) (*PingResp, error)
 	ID(ctx context.Context, req *IDReq) (*IDResp, error)
 	Add(ctx context.Context, req *NodeAddReq) (*NodeAddResp, error)
+	Pay(ctx context.Context, req *PayReq) (*PayResp, error)
 	NodeAPI() NodeAPI
 	DataStoreAPI() DataStoreAPI
+	StatsAPI() StatsAPI
+	ChainAPI() ChainAPI
+	ProcessAPI() ProcessAPI
+	NameAPI() NameAPI
+	CatalogAPI() CatalogAPI
+	TaskAPI() TaskAPI
 }
 
 // NodeAPI ...
@@ -170,6 +497,7 @@ type NodeAPI interface {
 	Unlink(ctx context.Context, req *NodeUnlinkReq) (*NodeUnlinkResp, error)
 	List(ctx context.Context, req *NodeListReq) (*NodeListResp, error)
 	NodeAddrInfo(ctx context.Context, req *AddrReq) (*AddrResp, error)
+	Backup(ctx context.Context, req *NodeBackupReq) (*NodeBackupResp, error)
 }
 
 // DataStoreAPI ...
@@ -177,4 +505,49 @@ type DataStoreAPI interface {
 	PinLs(ctx context.Context, req *DataStorePinLsReq) (*DataStorePinLsResp, error)
 	PinAdd(ctx context.Context, req *DataStorePinAddReq) (*DataStorePinAddResp, error)
 	UploadFile(ctx context.Context, req *UploadReq) (*UploadResp, error)
+	RepoGC(ctx context.Context, req *RepoGCReq) (*RepoGCResp, error)
+	RepoStat(ctx context.Context, req *RepoStatReq) (*RepoStatResp, error)
+	RepoVerify(ctx context.Context, req *RepoVerifyReq) (*RepoVerifyResp, error)
+	RepoRepair(ctx context.Context, req *RepoRepairReq) (*RepoRepairResp, error)
+	Get(ctx context.Context, req *GetReq) (*GetResp, error)
+	DagExport(ctx context.Context, req *DagExportReq) (*DagExportResp, error)
+	DagImport(ctx context.Context, req *DagImportReq) (*DagImportResp, error)
+}
+
+// StatsAPI ...
+type StatsAPI interface {
+	Bandwidth(ctx context.Context, req *StatsBandwidthReq) (*StatsBandwidthResp, error)
+	QoS(ctx context.Context, req *StatsQoSReq) (*StatsQoSResp, error)
+	Stream(ctx context.Context, req *StatsStreamReq) (*StatsStreamResp, error)
+}
+
+// ChainAPI ...
+type ChainAPI interface {
+	Status(ctx context.Context, req *ChainStatusReq) (*ChainStatusResp, error)
+}
+
+// ProcessAPI ...
+type ProcessAPI interface {
+	Processes(ctx context.Context, req *ProcessListReq) (*ProcessListResp, error)
+}
+
+// NameAPI ...
+type NameAPI interface {
+	Publish(ctx context.Context, req *NamePublishReq) (*NamePublishResp, error)
+	Resolve(ctx context.Context, req *NameResolveReq) (*NameResolveResp, error)
+	KeyGen(ctx context.Context, req *KeyGenReq) (*KeyGenResp, error)
+	KeyList(ctx context.Context, req *KeyListReq) (*KeyListResp, error)
+	KeyRm(ctx context.Context, req *KeyRmReq) (*KeyRmResp, error)
+}
+
+// TaskAPI ...
+type TaskAPI interface {
+	Jobs(ctx context.Context, req *TaskListReq) (*TaskListResp, error)
+	JobCancel(ctx context.Context, req *TaskCancelReq) (*TaskCancelResp, error)
+}
+
+// CatalogAPI ...
+type CatalogAPI interface {
+	CatalogExport(ctx context.Context, req *CatalogExportReq) (*CatalogExportResp, error)
+	CatalogImport(ctx context.Context, req *CatalogImportReq) (*CatalogImportResp, error)
 }
@@ -0,0 +1,23 @@
+package core
+
+// BandwidthRecorder ...
+type BandwidthRecorder interface {
+	RecordPeer(id string, in, out uint64)
+	RecordCID(cid string, in, out uint64)
+}
+
+// BandwidthTotal ...
+type BandwidthTotal struct {
+	In  uint64
+	Out uint64
+}
+
+// BandwidthStat ...
+type BandwidthStat struct {
+	Key        string
+	In         uint64
+	Out        uint64
+	TotalIn    uint64
+	TotalOut   uint64
+	LastUpdate int64
+}
@@ -93,16 +93,18 @@ type Info struct {
 
 // DataInfoV1 ...
 type DataInfoV1 struct {
-	RootHash   string    `xorm:"root_hash" json:"root_hash"`     //源信息
-	MediaInfo  MediaInfo `xorm:"media_info" json:"media_info"`   //媒体信息
-	MediaURI   string    `xorm:"media_uri" json:"media_uri"`     //入口地址
-	MediaHash  string    `xorm:"media_hash" json:"media_hash"`   //入口HASH
-	MediaIndex string    `xorm:"media_index" json:"media_index"` //入口名称
-	Info       Info      `xorm:"info" json:"info"`               //补充信息
-	InfoURI    string    `xorm:"info_uri" json:"info_uri"`       //补充信息地址
-	Security   Security  `xorm:"security" json:"security"`       //安全验证
-	LastUpdate int64     `xorm:"last_update" json:"last_update"` //最后更新时间
-	Version    Version   `xorm:"version" json:"version"`         //版本
+	RootHash   string    `xorm:"root_hash" json:"root_hash"`                         //源信息
+	MediaInfo  MediaInfo `xorm:"media_info" json:"media_info"`                       //媒体信息
+	MediaURI   string    `xorm:"media_uri" json:"media_uri"`                         //入口地址
+	MediaHash  string    `xorm:"media_hash" json:"media_hash"`                       //入口HASH
+	MediaIndex string    `xorm:"media_index" json:"media_index"`                     //入口名称
+	Info       Info      `xorm:"info" json:"info"`                                   //补充信息
+	InfoURI    string    `xorm:"info_uri" json:"info_uri"`                           //补充信息地址
+	Security   Security  `xorm:"security" json:"security"`                           //安全验证
+	LastUpdate int64     `xorm:"last_update" json:"last_update"`                     //最后更新时间
+	Version    Version   `xorm:"version" json:"version"`                             //版本
+	Signer     string    `xorm:"signer" json:"signer,omitempty" hash:"ignore"`       //发布者
+	Signature  string    `xorm:"signature" json:"signature,omitempty" hash:"ignore"` //发布者签名
 }
 
 // JSON ...
@@ -138,6 +140,16 @@ func (v *DataInfoV1) Hash() string {
 	return fmt.Sprintf("%x", sum)
 }
 
+// Digest returns the canonical digest signed by the publisher, the signature fields are excluded
+func (v *DataInfoV1) Digest() ([]byte, error) {
+	return hash.Sum(v)
+}
+
+// Signed ...
+func (v *DataInfoV1) Signed() bool {
+	return v.Signer != "" || v.Signature != ""
+}
+
 // Verify ...
 func (v *DataInfoV1) Verify(hash string) bool {
 	return strings.Compare(v.Hash(), hash) == 0
@@ -25,6 +25,7 @@ type DataStoreInfo struct {
 	Addresses       []string `json:"Addresses"`
 	AgentVersion    string   `json:"AgentVersion"`
 	ProtocolVersion string   `json:"ProtocolVersion"`
+	Network         string   `json:"Network,omitempty"` //fingerprint of the private network, empty is the public network
 }
 
 // ContractInfo ...
@@ -18,5 +18,8 @@ type NodeManager interface {
 
 	//RegisterLDRequest(func() ([]string, error))
 	RegisterAddrCallback(f func(info peer.AddrInfo) error)
+	RegisterBandwidthRecorder(r BandwidthRecorder)
+	RegisterRequestLimiter(l RequestLimiter)
 	ConnRemoteFromHash(hash string) error
+	CacheStats() []CacheStat
 }
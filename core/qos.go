@@ -0,0 +1,14 @@
+package core
+
+// RequestLimiter limits the custom requests received from the linked nodes
+type RequestLimiter interface {
+	AllowRequest(peer string, ip string) bool
+}
+
+// LimitStat ...
+type LimitStat struct {
+	Scope     string
+	Throttled uint64 //writes delayed by the bucket
+	Rejected  uint64 //requests refused by the bucket
+	WaitTime  int64  //total delayed milliseconds
+}
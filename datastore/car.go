@@ -0,0 +1,146 @@
+package datastore
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+
+	"github.com/ipfs/go-cid"
+	format "github.com/ipfs/go-ipld-format"
+	car "github.com/ipld/go-car"
+)
+
+// Versions of the car files
+const (
+	CARv1 = 1
+	CARv2 = 2
+)
+
+// carV2Pragma is the fixed beginning of a car v2 file
+var carV2Pragma = []byte{0x0a, 0xa1, 0x67, 0x76, 0x65, 0x72, 0x73, 0x69, 0x6f, 0x6e, 0x02}
+
+// carV2HeaderSize is the size of the car v2 header after the pragma
+const carV2HeaderSize = 40
+
+// carImportBatch is the count of the blocks added to the dag at once
+const carImportBatch = 128
+
+// ErrCARVersion ...
+var ErrCARVersion = errors.New("unsupported car version")
+
+// ExportCAR writes the dags of roots to w as a car v1 or v2 file, the v2 file has no index
+func ExportCAR(ctx context.Context, dag format.NodeGetter, roots []cid.Cid, w io.Writer, version int) error {
+	switch version {
+	case CARv1, 0:
+		return car.WriteCar(ctx, dag, roots, w)
+	case CARv2:
+	default:
+		return ErrCARVersion
+	}
+	//the v2 header needs the size of the v1 data
+	tmp, err := ioutil.TempFile("", "car")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	defer tmp.Close()
+	if err := car.WriteCar(ctx, dag, roots, tmp); err != nil {
+		return err
+	}
+	size, err := tmp.Seek(0, io.SeekCurrent)
+	if err != nil {
+		return err
+	}
+	if _, err := tmp.Seek(0, io.SeekStart); err != nil {
+		return err
+	}
+	header := make([]byte, carV2HeaderSize)
+	//16 bytes of characteristics are zero, the data follows the header and there is no index
+	binary.LittleEndian.PutUint64(header[16:], uint64(len(carV2Pragma)+carV2HeaderSize))
+	binary.LittleEndian.PutUint64(header[24:], uint64(size))
+	if _, err := w.Write(carV2Pragma); err != nil {
+		return err
+	}
+	if _, err := w.Write(header); err != nil {
+		return err
+	}
+	_, err = io.Copy(w, tmp)
+	return err
+}
+
+// carData returns the reader of the car v1 data in a car v1 or v2 file
+func carData(r io.Reader) (io.Reader, int, error) {
+	br := bufio.NewReader(r)
+	pragma, err := br.Peek(len(carV2Pragma))
+	if err != nil && err != io.EOF {
+		return nil, 0, err
+	}
+	if !bytes.Equal(pragma, carV2Pragma) {
+		return br, CARv1, nil
+	}
+	if _, err := br.Discard(len(carV2Pragma)); err != nil {
+		return nil, 0, err
+	}
+	header := make([]byte, carV2HeaderSize)
+	if _, err := io.ReadFull(br, header); err != nil {
+		return nil, 0, err
+	}
+	offset := binary.LittleEndian.Uint64(header[16:])
+	size := binary.LittleEndian.Uint64(header[24:])
+	skip := int64(offset) - int64(len(carV2Pragma)+carV2HeaderSize)
+	if skip < 0 {
+		return nil, 0, fmt.Errorf("wrong car v2 data offset(%d)", offset)
+	}
+	if _, err := io.CopyN(ioutil.Discard, br, skip); err != nil {
+		return nil, 0, err
+	}
+	return io.LimitReader(br, int64(size)), CARv2, nil
+}
+
+// ImportCAR adds the blocks of a car v1 or v2 file to dag, the roots of the file and the count of the blocks are returned
+func ImportCAR(ctx context.Context, dag format.NodeAdder, r io.Reader) ([]cid.Cid, int, error) {
+	data, _, err := carData(r)
+	if err != nil {
+		return nil, 0, err
+	}
+	cr, err := car.NewCarReader(data)
+	if err != nil {
+		return nil, 0, err
+	}
+	count := 0
+	batch := make([]format.Node, 0, carImportBatch)
+	for {
+		block, err := cr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, count, err
+		}
+		n, err := format.Decode(block)
+		if err != nil {
+			return nil, count, fmt.Errorf("decode block %s:%w", block.Cid(), err)
+		}
+		batch = append(batch, n)
+		if len(batch) == carImportBatch {
+			if err := dag.AddMany(ctx, batch); err != nil {
+				return nil, count, err
+			}
+			count += len(batch)
+			batch = batch[:0]
+		}
+	}
+	if len(batch) > 0 {
+		if err := dag.AddMany(ctx, batch); err != nil {
+			return nil, count, err
+		}
+		count += len(batch)
+	}
+	return cr.Header.Roots, count, nil
+}
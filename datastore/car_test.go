@@ -0,0 +1,71 @@
+package datastore
+
+import (
+	"bytes"
+	"context"
+	"testing"
+
+	"github.com/ipfs/go-cid"
+	format "github.com/ipfs/go-ipld-format"
+	"github.com/ipfs/go-merkledag"
+	dstest "github.com/ipfs/go-merkledag/test"
+)
+
+func testDAG(t *testing.T, dag format.DAGService) *merkledag.ProtoNode {
+	root := merkledag.NodeWithData([]byte("root"))
+	for _, data := range []string{"a", "b", "c"} {
+		child := merkledag.NodeWithData([]byte(data))
+		if err := dag.Add(context.Background(), child); err != nil {
+			t.Fatal(err)
+		}
+		if err := root.AddNodeLink(data, child); err != nil {
+			t.Fatal(err)
+		}
+	}
+	raw := merkledag.NewRawNode([]byte("raw leaf"))
+	if err := dag.Add(context.Background(), raw); err != nil {
+		t.Fatal(err)
+	}
+	if err := root.AddNodeLink("raw", raw); err != nil {
+		t.Fatal(err)
+	}
+	if err := dag.Add(context.Background(), root); err != nil {
+		t.Fatal(err)
+	}
+	return root
+}
+
+func TestCAR(t *testing.T) {
+	from := dstest.Mock()
+	root := testDAG(t, from)
+	for _, version := range []int{CARv1, CARv2} {
+		var buf bytes.Buffer
+		if err := ExportCAR(context.Background(), from, []cid.Cid{root.Cid()}, &buf, version); err != nil {
+			t.Fatal(err)
+		}
+		if v2 := bytes.HasPrefix(buf.Bytes(), carV2Pragma); v2 != (version == CARv2) {
+			t.Fatalf("car v%d has wrong pragma", version)
+		}
+		to := dstest.Mock()
+		roots, count, err := ImportCAR(context.Background(), to, &buf)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(roots) != 1 || !roots[0].Equals(root.Cid()) {
+			t.Fatalf("wrong roots %v", roots)
+		}
+		if count != 5 {
+			t.Fatalf("want 5 blocks, got %d", count)
+		}
+		n, err := to.Get(context.Background(), root.Cid())
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(n.Links()) != 4 {
+			t.Fatalf("want 4 links, got %d", len(n.Links()))
+		}
+	}
+	if err := ExportCAR(context.Background(), from, []cid.Cid{root.Cid()}, &bytes.Buffer{}, 3); err != ErrCARVersion {
+		t.Fatalf("want ErrCARVersion, got %v", err)
+	}
+}
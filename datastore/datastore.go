@@ -0,0 +1,41 @@
+package datastore
+
+import (
+	"context"
+
+	"github.com/glvd/accipfs/core"
+	"github.com/ipfs/go-cid"
+	iface "github.com/ipfs/interface-go-ipfs-core"
+)
+
+// datastore modes
+const (
+	// ModeEmbedded runs the ipfs node in the process
+	ModeEmbedded = "embedded"
+	// ModeExternal uses the http api of an ipfs daemon
+	ModeExternal = "external"
+)
+
+// RepoStat ...
+type RepoStat struct {
+	Path    string
+	Size    uint64
+	Objects uint64
+	Pins    int
+}
+
+// DataStore is the ipfs node the controller works with
+type DataStore interface {
+	iface.CoreAPI
+	ID(ctx context.Context) (*core.DataStoreInfo, error)
+	// Bandwidth returns the total bytes exchanged with every peer
+	Bandwidth(ctx context.Context) (map[string]core.BandwidthTotal, error)
+	RepoSize(ctx context.Context) (uint64, error)
+	RepoStat(ctx context.Context) (*RepoStat, error)
+	// GC removes the unpinned blocks except the ones under the keep roots
+	GC(ctx context.Context, keep []cid.Cid) (removed uint64, err error)
+	// Verify re-hashes every block and returns the corrupt ones
+	Verify(ctx context.Context) (checked uint64, corrupt []cid.Cid, err error)
+	// Refetch drops the block and fetches it again from the providers
+	Refetch(ctx context.Context, k cid.Cid) error
+}
@@ -0,0 +1,9 @@
+package datastore
+
+import (
+	alog "github.com/glvd/accipfs/log"
+)
+
+const module = "datastore"
+
+var log = alog.Module(module)
@@ -0,0 +1,309 @@
+package datastore
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/glvd/accipfs/core"
+	"github.com/ipfs/go-cid"
+	httpapi "github.com/ipfs/go-ipfs-http-client"
+	"github.com/ipfs/interface-go-ipfs-core/options"
+	"github.com/ipfs/interface-go-ipfs-core/path"
+	"github.com/multiformats/go-multiaddr"
+)
+
+// Remote is the datastore of an ipfs daemon reached by its http api
+type Remote struct {
+	*httpapi.HttpApi
+	gcPins string
+}
+
+var _ DataStore = &Remote{}
+
+type repoStat struct {
+	RepoSize   uint64
+	NumObjects uint64
+	RepoPath   string
+}
+
+type bandwidthStat struct {
+	TotalIn  int64
+	TotalOut int64
+}
+
+type gcResult struct {
+	Key   map[string]string
+	Error string
+}
+
+type verifyProgress struct {
+	Msg      string
+	Progress uint64
+}
+
+// NewRemote create the datastore of the api address, addr is a multiaddr or an http url,
+// gcPins is the file of the temporary pins added during a gc, they are removed by
+// CleanGCPins when the gc was interrupted
+func NewRemote(addr string, gcPins string) (*Remote, error) {
+	var api *httpapi.HttpApi
+	var err error
+	if strings.HasPrefix(addr, "/") {
+		var ma multiaddr.Multiaddr
+		ma, err = multiaddr.NewMultiaddr(addr)
+		if err != nil {
+			return nil, err
+		}
+		api, err = httpapi.NewApiWithClient(ma, &http.Client{})
+	} else {
+		api, err = httpapi.NewURLApiWithClient(strings.TrimSuffix(addr, "/"), &http.Client{})
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &Remote{HttpApi: api, gcPins: gcPins}, nil
+}
+
+// ID ...
+func (r *Remote) ID(ctx context.Context) (*core.DataStoreInfo, error) {
+	info := &core.DataStoreInfo{}
+	if err := r.Request("id").Exec(ctx, info); err != nil {
+		return nil, err
+	}
+	return info, nil
+}
+
+// Bandwidth ...
+func (r *Remote) Bandwidth(ctx context.Context) (map[string]core.BandwidthTotal, error) {
+	peers, err := r.Swarm().Peers(ctx)
+	if err != nil {
+		return nil, err
+	}
+	totals := make(map[string]core.BandwidthTotal)
+	for _, p := range peers {
+		var bw bandwidthStat
+		if err := r.Request("stats/bw").Option("peer", p.ID().Pretty()).Exec(ctx, &bw); err != nil {
+			return nil, err
+		}
+		totals[p.ID().Pretty()] = core.BandwidthTotal{
+			In:  uint64(bw.TotalIn),
+			Out: uint64(bw.TotalOut),
+		}
+	}
+	return totals, nil
+}
+
+// RepoSize ...
+func (r *Remote) RepoSize(ctx context.Context) (uint64, error) {
+	var stat repoStat
+	if err := r.Request("repo/stat").Option("size-only", true).Exec(ctx, &stat); err != nil {
+		return 0, err
+	}
+	return stat.RepoSize, nil
+}
+
+// RepoStat ...
+func (r *Remote) RepoStat(ctx context.Context) (*RepoStat, error) {
+	var stat repoStat
+	if err := r.Request("repo/stat").Exec(ctx, &stat); err != nil {
+		return nil, err
+	}
+	resp := &RepoStat{
+		Path:    stat.RepoPath,
+		Size:    stat.RepoSize,
+		Objects: stat.NumObjects,
+	}
+	for _, typ := range []options.PinLsOption{options.Pin.Ls.Recursive(), options.Pin.Ls.Direct()} {
+		pins, err := r.Pin().Ls(ctx, typ)
+		if err != nil {
+			return nil, err
+		}
+		for range pins {
+			resp.Pins++
+		}
+	}
+	return resp, nil
+}
+
+// CleanGCPins removes the temporary pins left by an interrupted gc
+func (r *Remote) CleanGCPins(ctx context.Context) error {
+	if r.gcPins == "" {
+		return nil
+	}
+	b, err := ioutil.ReadFile(r.gcPins)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil
+		}
+		return err
+	}
+	var pins []string
+	if err := json.Unmarshal(b, &pins); err != nil {
+		log.Warnw("drop the wrong gc pins", "file", r.gcPins, "err", err)
+		return os.Remove(r.gcPins)
+	}
+	for _, v := range pins {
+		k, err := cid.Decode(v)
+		if err != nil {
+			continue
+		}
+		//the pin is absent when the gc stopped before adding it
+		if err := r.Pin().Rm(ctx, path.IpldPath(k)); err != nil {
+			log.Debugw("remove the stale gc pin", "cid", v, "err", err)
+		}
+	}
+	return os.Remove(r.gcPins)
+}
+
+// saveGCPins records the temporary pins of the gc
+func (r *Remote) saveGCPins(pins []cid.Cid) error {
+	if r.gcPins == "" || len(pins) == 0 {
+		return nil
+	}
+	list := make([]string, 0, len(pins))
+	for _, k := range pins {
+		list = append(list, k.String())
+	}
+	b, err := json.Marshal(list)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(r.gcPins), 0755); err != nil {
+		return err
+	}
+	return ioutil.WriteFile(r.gcPins, b, 0644)
+}
+
+// GC pins the keep roots which are not pinned during the gc, the daemon can not take extra roots.
+// The roots are pinned offline so only the dags stored entirely in the daemon are kept, the
+// missing blocks are never fetched by the gc.
+func (r *Remote) GC(ctx context.Context, keep []cid.Cid) (uint64, error) {
+	if err := r.CleanGCPins(ctx); err != nil {
+		return 0, err
+	}
+	pinned := make(map[string]bool)
+	pins, err := r.Pin().Ls(ctx, options.Pin.Ls.Recursive())
+	if err != nil {
+		return 0, err
+	}
+	for p := range pins {
+		pinned[p.Path().Cid().String()] = true
+	}
+	var temp []cid.Cid
+	for _, k := range keep {
+		if !pinned[k.String()] {
+			temp = append(temp, k)
+		}
+	}
+	if err := r.saveGCPins(temp); err != nil {
+		return 0, err
+	}
+	offline, err := r.WithOptions(options.Api.Offline(true))
+	if err != nil {
+		return 0, err
+	}
+	var added []path.Path
+	defer func() {
+		for _, p := range added {
+			if err := r.Pin().Rm(ctx, p); err != nil {
+				//the file is kept to remove the pins on the next start
+				log.Errorw("remove the gc pin", "path", p.String(), "err", err)
+				return
+			}
+		}
+		if r.gcPins != "" && len(temp) > 0 {
+			if err := os.Remove(r.gcPins); err != nil {
+				log.Errorw("remove the gc pins", "file", r.gcPins, "err", err)
+			}
+		}
+	}()
+	for _, k := range temp {
+		p := path.IpldPath(k)
+		if err := offline.Pin().Add(ctx, p, options.Pin.Recursive(true)); err != nil {
+			log.Warnw("keep root during gc", "cid", k, "err", err)
+			continue
+		}
+		added = append(added, p)
+	}
+
+	resp, err := r.Request("repo/gc").Send(ctx)
+	if err != nil {
+		return 0, err
+	}
+	if resp.Error != nil {
+		return 0, resp.Error
+	}
+	defer resp.Close()
+	var removed uint64
+	dec := json.NewDecoder(resp.Output)
+	for {
+		var res gcResult
+		if err := dec.Decode(&res); err != nil {
+			if err == io.EOF {
+				return removed, nil
+			}
+			return removed, err
+		}
+		if res.Error != "" {
+			log.Warnw("repo gc", "err", res.Error)
+			continue
+		}
+		removed++
+	}
+}
+
+// Verify ...
+func (r *Remote) Verify(ctx context.Context) (uint64, []cid.Cid, error) {
+	resp, err := r.Request("repo/verify").Send(ctx)
+	if err != nil {
+		return 0, nil, err
+	}
+	if resp.Error != nil {
+		return 0, nil, resp.Error
+	}
+	defer resp.Close()
+	var checked uint64
+	var corrupt []cid.Cid
+	dec := json.NewDecoder(resp.Output)
+	for {
+		var res verifyProgress
+		if err := dec.Decode(&res); err != nil {
+			//the daemon ends the stream with an error when some blocks are corrupt
+			if err == io.EOF || len(corrupt) > 0 {
+				return checked, corrupt, ctx.Err()
+			}
+			return checked, corrupt, err
+		}
+		if res.Progress > checked {
+			checked = res.Progress
+		}
+		//the daemon reports "block <cid> was corrupt (<err>)"
+		if strings.HasPrefix(res.Msg, "block ") && strings.Contains(res.Msg, " was corrupt") {
+			k, err := cid.Decode(strings.Fields(res.Msg)[1])
+			if err != nil {
+				log.Warnw("skip wrong corrupt block", "msg", res.Msg, "err", err)
+				continue
+			}
+			corrupt = append(corrupt, k)
+		}
+	}
+}
+
+// Refetch ...
+func (r *Remote) Refetch(ctx context.Context, k cid.Cid) error {
+	p := path.IpldPath(k)
+	if err := r.Block().Rm(ctx, p, options.Block.Force(true)); err != nil {
+		return err
+	}
+	reader, err := r.Block().Get(ctx, p)
+	if err != nil {
+		return err
+	}
+	_, err = io.Copy(ioutil.Discard, reader)
+	return err
+}
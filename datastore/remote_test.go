@@ -0,0 +1,233 @@
+package datastore
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/ipfs/go-cid"
+	"github.com/multiformats/go-multihash"
+)
+
+func testCid(t *testing.T, data string) cid.Cid {
+	h, err := multihash.Sum([]byte(data), multihash.SHA2_256, -1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return cid.NewCidV1(cid.Raw, h)
+}
+
+// fakeIPFS serves the commands of the ipfs http api used by Remote
+type fakeIPFS struct {
+	lock    sync.Mutex
+	pins    map[string]string
+	blocks  map[string]string
+	corrupt []string
+	calls   []string
+}
+
+func (f *fakeIPFS) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+	cmd := strings.TrimPrefix(r.URL.Path, "/api/v0/")
+	q := r.URL.Query()
+	f.calls = append(f.calls, cmd)
+	w.Header().Set("Content-Type", "application/json")
+	enc := json.NewEncoder(w)
+	switch cmd {
+	case "id":
+		enc.Encode(map[string]interface{}{
+			"ID":           "QmfQkD8pBSBCBxWEwFSu4XaDVSWK6bjnNuaWZjMyQbyDub",
+			"Addresses":    []string{"/ip4/127.0.0.1/tcp/4001"},
+			"AgentVersion": "go-ipfs/0.6.0/",
+		})
+	case "repo/stat":
+		enc.Encode(map[string]interface{}{
+			"RepoSize":   1024,
+			"NumObjects": len(f.blocks),
+			"RepoPath":   "/data/ipfs",
+		})
+	case "pin/ls":
+		keys := make(map[string]interface{})
+		for k, typ := range f.pins {
+			if q.Get("type") == typ {
+				keys[k] = map[string]string{"Type": typ}
+			}
+		}
+		enc.Encode(map[string]interface{}{"Keys": keys})
+	case "pin/add":
+		k := strings.TrimPrefix(q.Get("arg"), "/ipld/")
+		if _, ok := f.blocks[k]; !ok {
+			if q.Get("offline") == "true" {
+				w.WriteHeader(http.StatusInternalServerError)
+				enc.Encode(map[string]interface{}{"Message": "block was not found locally (offline)", "Code": 0, "Type": "error"})
+				return
+			}
+			//the online pin fetches the missing blocks
+			f.blocks[k] = "fetched"
+		}
+		f.pins[k] = "recursive"
+		enc.Encode(map[string]interface{}{"Pins": []string{q.Get("arg")}})
+	case "pin/rm":
+		delete(f.pins, strings.TrimPrefix(q.Get("arg"), "/ipld/"))
+		enc.Encode(map[string]interface{}{"Pins": []string{q.Get("arg")}})
+	case "repo/gc":
+		for k := range f.blocks {
+			if _, ok := f.pins[k]; !ok {
+				delete(f.blocks, k)
+				enc.Encode(map[string]interface{}{"Key": map[string]string{"/": k}})
+			}
+		}
+	case "repo/verify":
+		w.Header().Set("Trailer", "X-Stream-Error")
+		i := 0
+		for k := range f.blocks {
+			for _, c := range f.corrupt {
+				if c == k {
+					enc.Encode(map[string]interface{}{"Msg": fmt.Sprintf("block %s was corrupt (block in storage has different hash than requested)", k)})
+				}
+			}
+			i++
+			enc.Encode(map[string]interface{}{"Progress": i})
+		}
+		if len(f.corrupt) > 0 {
+			w.Header().Set("X-Stream-Error", "verify complete, some blocks were corrupt")
+		}
+	case "block/rm":
+		delete(f.blocks, strings.TrimPrefix(q.Get("arg"), "/ipld/"))
+		enc.Encode(map[string]interface{}{"Hash": q.Get("arg")})
+	case "block/get":
+		k := strings.TrimPrefix(q.Get("arg"), "/ipld/")
+		f.blocks[k] = "fetched"
+		w.Write([]byte(f.blocks[k]))
+	default:
+		w.WriteHeader(http.StatusNotFound)
+	}
+}
+
+func testRemote(t *testing.T, f *fakeIPFS) (*Remote, func()) {
+	srv := httptest.NewServer(f)
+	dir, err := ioutil.TempDir("", "remote")
+	if err != nil {
+		t.Fatal(err)
+	}
+	r, err := NewRemote(srv.URL, filepath.Join(dir, "gc_pins.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return r, func() {
+		srv.Close()
+		os.RemoveAll(dir)
+	}
+}
+
+func TestRemote_Stat(t *testing.T) {
+	a, b := testCid(t, "a"), testCid(t, "b")
+	f := &fakeIPFS{
+		pins:   map[string]string{a.String(): "recursive", b.String(): "direct"},
+		blocks: map[string]string{a.String(): "a", b.String(): "b"},
+	}
+	r, closer := testRemote(t, f)
+	defer closer()
+	ctx := context.Background()
+	info, err := r.ID(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.ID != "QmfQkD8pBSBCBxWEwFSu4XaDVSWK6bjnNuaWZjMyQbyDub" || len(info.Addresses) != 1 {
+		t.Fatalf("wrong id %+v", info)
+	}
+	size, err := r.RepoSize(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if size != 1024 {
+		t.Fatalf("wrong repo size %d", size)
+	}
+	stat, err := r.RepoStat(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if stat.Path != "/data/ipfs" || stat.Objects != 2 || stat.Pins != 2 {
+		t.Fatalf("wrong repo stat %+v", stat)
+	}
+}
+
+func TestRemote_GC(t *testing.T) {
+	pinned, kept, garbage := testCid(t, "pinned"), testCid(t, "kept"), testCid(t, "garbage")
+	missing, stale := testCid(t, "missing"), testCid(t, "stale")
+	f := &fakeIPFS{
+		pins: map[string]string{pinned.String(): "recursive"},
+		blocks: map[string]string{
+			pinned.String():  "pinned",
+			kept.String():    "kept",
+			garbage.String(): "garbage",
+			stale.String():   "stale",
+		},
+	}
+	r, closer := testRemote(t, f)
+	defer closer()
+	//the pin of an interrupted gc is removed first
+	f.pins[stale.String()] = "recursive"
+	if err := ioutil.WriteFile(r.gcPins, []byte(`["`+stale.String()+`"]`), 0644); err != nil {
+		t.Fatal(err)
+	}
+	removed, err := r.GC(context.Background(), []cid.Cid{pinned, kept, missing})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if removed != 2 {
+		t.Fatalf("want 2 removed blocks, got %d", removed)
+	}
+	if _, ok := f.blocks[missing.String()]; ok {
+		t.Fatal("the missing root should not be fetched")
+	}
+	if _, ok := f.pins[stale.String()]; ok {
+		t.Fatal("the stale gc pin should be removed")
+	}
+	if _, err := os.Stat(r.gcPins); !os.IsNotExist(err) {
+		t.Fatalf("the gc pins file should be removed: %v", err)
+	}
+	if _, ok := f.blocks[kept.String()]; !ok {
+		t.Fatal("the kept root should not be collected")
+	}
+	if _, ok := f.pins[kept.String()]; ok {
+		t.Fatal("the gc pin of the kept root should be removed")
+	}
+	if _, ok := f.pins[pinned.String()]; !ok {
+		t.Fatal("the user pin should not be removed")
+	}
+}
+
+func TestRemote_Verify(t *testing.T) {
+	good, bad := testCid(t, "good"), testCid(t, "bad")
+	f := &fakeIPFS{
+		pins:    map[string]string{},
+		blocks:  map[string]string{good.String(): "good", bad.String(): "broken"},
+		corrupt: []string{bad.String()},
+	}
+	r, closer := testRemote(t, f)
+	defer closer()
+	ctx := context.Background()
+	checked, corrupt, err := r.Verify(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if checked != 2 || len(corrupt) != 1 || !corrupt[0].Equals(bad) {
+		t.Fatalf("wrong verify result %d %v", checked, corrupt)
+	}
+	if err := r.Refetch(ctx, bad); err != nil {
+		t.Fatal(err)
+	}
+	if f.blocks[bad.String()] != "fetched" {
+		t.Fatal("the corrupt block should be fetched again")
+	}
+}
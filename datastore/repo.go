@@ -0,0 +1,171 @@
+package datastore
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/glvd/accipfs/config"
+	ipfsconfig "github.com/ipfs/go-ipfs-config"
+	"github.com/ipfs/go-ipfs/repo"
+)
+
+// datastore types of the bundled plugins
+const (
+	TypeBadger  = "badgerds"
+	TypeFlatfs  = "flatfs"
+	TypeLevelDB = "levelds"
+)
+
+// routing modes
+const (
+	RoutingDHT       = "dht"
+	RoutingDHTClient = "dhtclient"
+	RoutingNone      = "none"
+)
+
+const localAddr = "/ip4/127.0.0.1/tcp/%d"
+
+// AppliedProfilesKey is the key of the repo config recording the applied profiles,
+// ipfs does not know it and keeps it in the config file
+const AppliedProfilesKey = "AppliedProfiles"
+
+// DatastoreSpec returns the repo datastore spec of the type, badgerds is used when typ is empty
+func DatastoreSpec(typ string) (map[string]interface{}, error) {
+	switch typ {
+	case "", TypeBadger:
+		return map[string]interface{}{
+			"type":   "measure",
+			"prefix": "badger.datastore",
+			"child": map[string]interface{}{
+				"type":       "badgerds",
+				"path":       "badgerds",
+				"syncWrites": false,
+				"truncate":   true,
+			},
+		}, nil
+	case TypeFlatfs:
+		return map[string]interface{}{
+			"type": "mount",
+			"mounts": []interface{}{
+				map[string]interface{}{
+					"mountpoint": "/blocks",
+					"type":       "measure",
+					"prefix":     "flatfs.datastore",
+					"child": map[string]interface{}{
+						"type":      "flatfs",
+						"path":      "blocks",
+						"sync":      true,
+						"shardFunc": "/repo/flatfs/shard/v1/next-to-last/2",
+					},
+				},
+				map[string]interface{}{
+					"mountpoint": "/",
+					"type":       "measure",
+					"prefix":     "leveldb.datastore",
+					"child": map[string]interface{}{
+						"type":        "levelds",
+						"path":        "datastore",
+						"compression": "none",
+					},
+				},
+			},
+		}, nil
+	case TypeLevelDB:
+		return map[string]interface{}{
+			"type":   "measure",
+			"prefix": "leveldb.datastore",
+			"child": map[string]interface{}{
+				"type":        "levelds",
+				"path":        "datastore",
+				"compression": "none",
+			},
+		}, nil
+	}
+	return nil, fmt.Errorf("unknown datastore type %q", typ)
+}
+
+// ApplyConfig applies the profiles and the settings of cfg to the repo config,
+// the datastore spec is kept as the data on the disk depends on it.
+// A profile in applied is skipped like ipfs config profile apply does, as some
+// profiles such as randomports change the config every time they are applied.
+// It returns applied with the newly applied profiles.
+func ApplyConfig(c *ipfsconfig.Config, cfg config.IPFSConfig, applied []string) ([]string, error) {
+	spec := c.Datastore.Spec
+	applied = append([]string(nil), applied...)
+	done := make(map[string]bool, len(applied))
+	for _, name := range applied {
+		done[name] = true
+	}
+	for _, name := range cfg.Repo.Profiles {
+		if done[name] {
+			continue
+		}
+		profile, ok := ipfsconfig.Profiles[name]
+		if !ok {
+			return nil, fmt.Errorf("unknown ipfs profile %q", name)
+		}
+		if err := profile.Transform(c); err != nil {
+			return nil, fmt.Errorf("apply profile %s:%w", name, err)
+		}
+		done[name] = true
+		applied = append(applied, name)
+	}
+	c.Datastore.Spec = spec
+
+	switch cfg.Repo.Routing {
+	case "":
+	case RoutingDHT, RoutingDHTClient, RoutingNone:
+		c.Routing.Type = cfg.Repo.Routing
+	default:
+		return nil, fmt.Errorf("unknown routing mode %q", cfg.Repo.Routing)
+	}
+	if len(cfg.Repo.Swarm) > 0 {
+		c.Addresses.Swarm = cfg.Repo.Swarm
+	}
+	if cm := cfg.Repo.ConnMgr; cm.HighWater > 0 {
+		if cm.LowWater > cm.HighWater {
+			return nil, fmt.Errorf("connection manager low water %d is over high water %d", cm.LowWater, cm.HighWater)
+		}
+		c.Swarm.ConnMgr = ipfsconfig.ConnMgr{
+			Type:        "basic",
+			LowWater:    cm.LowWater,
+			HighWater:   cm.HighWater,
+			GracePeriod: (cm.GracePeriod * time.Second).String(),
+		}
+	}
+	if cfg.Repo.Private {
+		c.Bootstrap = stripBootstrap(c.Bootstrap)
+	}
+	if cfg.API > 0 {
+		c.Addresses.API = ipfsconfig.Strings{fmt.Sprintf(localAddr, cfg.API)}
+	}
+	if cfg.Gateway > 0 {
+		c.Addresses.Gateway = ipfsconfig.Strings{fmt.Sprintf(localAddr, cfg.Gateway)}
+	}
+	return applied, nil
+}
+
+// AppliedProfiles returns the profiles recorded as applied in the config of r
+func AppliedProfiles(r repo.Repo) []string {
+	v, err := r.GetConfigKey(AppliedProfilesKey)
+	if err != nil {
+		//not recorded yet
+		return nil
+	}
+	list, _ := v.([]interface{})
+	var applied []string
+	for _, name := range list {
+		if s, ok := name.(string); ok {
+			applied = append(applied, s)
+		}
+	}
+	return applied
+}
+
+// SetAppliedProfiles records the applied profiles in the config of r
+func SetAppliedProfiles(r repo.Repo, applied []string) error {
+	if applied == nil {
+		applied = []string{}
+	}
+	return r.SetConfigKey(AppliedProfilesKey, applied)
+}
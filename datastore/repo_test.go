@@ -0,0 +1,119 @@
+package datastore
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/glvd/accipfs/config"
+	ipfsconfig "github.com/ipfs/go-ipfs-config"
+)
+
+func TestApplyConfig(t *testing.T) {
+	c := &ipfsconfig.Config{}
+	spec, err := DatastoreSpec(TypeFlatfs)
+	if err != nil {
+		t.Fatal(err)
+	}
+	c.Datastore.Spec = spec
+	c.Discovery.MDNS.Enabled = true
+	cfg := config.IPFSConfig{
+		API:     5001,
+		Gateway: 8080,
+		Repo: config.IPFSRepoConfig{
+			Routing: RoutingDHTClient,
+			Swarm:   []string{"/ip4/0.0.0.0/tcp/4002"},
+			ConnMgr: config.ConnMgrConfig{
+				LowWater:    50,
+				HighWater:   100,
+				GracePeriod: 30,
+			},
+			//badgerds changes the datastore which must be kept
+			Profiles: []string{"server", "badgerds"},
+		},
+	}
+	applied, err := ApplyConfig(c, cfg, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(applied, cfg.Repo.Profiles) {
+		t.Fatalf("wrong applied profiles %v", applied)
+	}
+	if !reflect.DeepEqual(c.Datastore.Spec, spec) {
+		t.Fatal("the datastore spec should be kept")
+	}
+	if c.Discovery.MDNS.Enabled {
+		t.Fatal("the server profile should disable mdns")
+	}
+	if c.Routing.Type != RoutingDHTClient || c.Addresses.Swarm[0] != "/ip4/0.0.0.0/tcp/4002" {
+		t.Fatalf("wrong routing or swarm %v %v", c.Routing, c.Addresses.Swarm)
+	}
+	want := ipfsconfig.ConnMgr{Type: "basic", LowWater: 50, HighWater: 100, GracePeriod: "30s"}
+	if c.Swarm.ConnMgr != want {
+		t.Fatalf("wrong connection manager %+v", c.Swarm.ConnMgr)
+	}
+	if c.Addresses.API[0] != "/ip4/127.0.0.1/tcp/5001" || c.Addresses.Gateway[0] != "/ip4/127.0.0.1/tcp/8080" {
+		t.Fatalf("wrong api or gateway %v %v", c.Addresses.API, c.Addresses.Gateway)
+	}
+
+	//lowpower sets its own connection manager
+	c = &ipfsconfig.Config{}
+	if _, err := ApplyConfig(c, config.IPFSConfig{Repo: config.IPFSRepoConfig{Profiles: []string{"lowpower"}}}, nil); err != nil {
+		t.Fatal(err)
+	}
+	if c.Routing.Type != RoutingDHTClient || c.Swarm.ConnMgr.HighWater != 40 {
+		t.Fatalf("wrong lowpower config %v %+v", c.Routing, c.Swarm.ConnMgr)
+	}
+
+	for _, wrong := range []config.IPFSRepoConfig{
+		{Profiles: []string{"nothing"}},
+		{Routing: "flood"},
+		{ConnMgr: config.ConnMgrConfig{LowWater: 10, HighWater: 5}},
+	} {
+		if _, err := ApplyConfig(&ipfsconfig.Config{}, config.IPFSConfig{Repo: wrong}, nil); err == nil {
+			t.Fatalf("%+v should fail", wrong)
+		}
+	}
+	if _, err := DatastoreSpec("mongo"); err == nil {
+		t.Fatal("unknown datastore should fail")
+	}
+}
+
+func TestApplyConfigApplied(t *testing.T) {
+	cfg := config.IPFSConfig{Repo: config.IPFSRepoConfig{Profiles: []string{"server", "randomports"}}}
+	c := &ipfsconfig.Config{}
+	c.Addresses.Swarm = []string{"/ip4/0.0.0.0/tcp/4001"}
+	applied, err := ApplyConfig(c, cfg, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	swarm := c.Addresses.Swarm[0]
+	if swarm == "/ip4/0.0.0.0/tcp/4001" {
+		t.Fatal("randomports should change the swarm port")
+	}
+
+	//the applied profiles are not applied again
+	c.Discovery.MDNS.Enabled = true
+	again, err := ApplyConfig(c, cfg, applied)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if c.Addresses.Swarm[0] != swarm {
+		t.Fatalf("randomports should not be applied again %v", c.Addresses.Swarm)
+	}
+	if !c.Discovery.MDNS.Enabled {
+		t.Fatal("server should not be applied again")
+	}
+	if !reflect.DeepEqual(again, applied) {
+		t.Fatalf("wrong applied profiles %v", again)
+	}
+
+	//a new profile is applied and recorded
+	cfg.Repo.Profiles = append(cfg.Repo.Profiles, "lowpower")
+	again, err = ApplyConfig(c, cfg, applied)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if c.Swarm.ConnMgr.HighWater != 40 || len(again) != 3 || again[2] != "lowpower" {
+		t.Fatalf("lowpower should be applied %+v %v", c.Swarm.ConnMgr, again)
+	}
+}
@@ -0,0 +1,90 @@
+package datastore
+
+import (
+	"bytes"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	ipfsconfig "github.com/ipfs/go-ipfs-config"
+	"github.com/libp2p/go-libp2p-core/pnet"
+)
+
+// SwarmKeyFile is the name of the private network key in the repo
+const SwarmKeyFile = "swarm.key"
+
+const swarmKeyHeader = "/key/swarm/psk/1.0.0/\n/base16/\n"
+
+// ErrNoSwarmKey ...
+var ErrNoSwarmKey = errors.New("private network requires the swarm.key in the repo")
+
+// GenerateSwarmKey creates a new private network key in the swarm.key format
+func GenerateSwarmKey() ([]byte, error) {
+	key := make([]byte, 32)
+	if _, err := rand.Read(key); err != nil {
+		return nil, err
+	}
+	return []byte(swarmKeyHeader + hex.EncodeToString(key) + "\n"), nil
+}
+
+// Fingerprint returns the id of the private network of the swarm key, the key itself can not be derived from it
+func Fingerprint(swarmKey []byte) (string, error) {
+	psk, err := pnet.DecodeV1PSK(bytes.NewReader(swarmKey))
+	if err != nil {
+		return "", err
+	}
+	sum := sha256.Sum256(append([]byte("accipfs/pnet/"), psk...))
+	return hex.EncodeToString(sum[:16]), nil
+}
+
+// ReadSwarmKey returns the swarm key of the repo, nil is returned when the repo is in the public network
+func ReadSwarmKey(repoPath string) ([]byte, error) {
+	key, err := ioutil.ReadFile(filepath.Join(repoPath, SwarmKeyFile))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return key, nil
+}
+
+// WriteSwarmKey validates and writes the swarm key to the repo
+func WriteSwarmKey(repoPath string, swarmKey []byte) error {
+	if _, err := Fingerprint(swarmKey); err != nil {
+		return fmt.Errorf("wrong swarm key:%w", err)
+	}
+	if err := os.MkdirAll(repoPath, 0755); err != nil {
+		return err
+	}
+	return ioutil.WriteFile(filepath.Join(repoPath, SwarmKeyFile), swarmKey, 0600)
+}
+
+// NetworkOf returns the private network fingerprint of the repo, empty is the public network
+func NetworkOf(repoPath string) (string, error) {
+	key, err := ReadSwarmKey(repoPath)
+	if err != nil || key == nil {
+		return "", err
+	}
+	return Fingerprint(key)
+}
+
+// stripBootstrap removes the public bootstrap peers
+func stripBootstrap(peers []string) []string {
+	public := make(map[string]bool)
+	for _, p := range ipfsconfig.DefaultBootstrapAddresses {
+		public[p] = true
+	}
+	var kept []string
+	for _, p := range peers {
+		if !public[p] {
+			kept = append(kept, p)
+		}
+	}
+	return kept
+}
@@ -0,0 +1,63 @@
+package datastore
+
+import (
+	"io/ioutil"
+	"os"
+	"testing"
+
+	"github.com/glvd/accipfs/config"
+	ipfsconfig "github.com/ipfs/go-ipfs-config"
+)
+
+func TestSwarmKey(t *testing.T) {
+	dir, err := ioutil.TempDir("", "swarmkey")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	network, err := NetworkOf(dir)
+	if err != nil || network != "" {
+		t.Fatalf("the repo without key should be public, got %q %v", network, err)
+	}
+	key, err := GenerateSwarmKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := WriteSwarmKey(dir, key); err != nil {
+		t.Fatal(err)
+	}
+	network, err = NetworkOf(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want, err := Fingerprint(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if network == "" || network != want {
+		t.Fatalf("wrong network %q, want %q", network, want)
+	}
+	other, err := GenerateSwarmKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if fp, _ := Fingerprint(other); fp == network {
+		t.Fatal("different keys should have different networks")
+	}
+	if err := WriteSwarmKey(dir, []byte("not a key")); err == nil {
+		t.Fatal("wrong key should not be written")
+	}
+}
+
+func TestApplyConfig_Private(t *testing.T) {
+	custom := "/ip4/10.0.0.1/tcp/4001/p2p/QmfQkD8pBSBCBxWEwFSu4XaDVSWK6bjnNuaWZjMyQbyDub"
+	c := &ipfsconfig.Config{
+		Bootstrap: append([]string{custom}, ipfsconfig.DefaultBootstrapAddresses...),
+	}
+	if _, err := ApplyConfig(c, config.IPFSConfig{Repo: config.IPFSRepoConfig{Private: true}}, nil); err != nil {
+		t.Fatal(err)
+	}
+	if len(c.Bootstrap) != 1 || c.Bootstrap[0] != custom {
+		t.Fatalf("only the custom bootstrap should be kept, got %v", c.Bootstrap)
+	}
+}
@@ -0,0 +1,304 @@
+package gateway
+
+import (
+	"context"
+	"html/template"
+	"io"
+	"mime"
+	"net"
+	"net/http"
+	"path"
+	"strings"
+	"time"
+
+	files "github.com/ipfs/go-ipfs-files"
+)
+
+// Namespaces served by the gateway
+const (
+	NamespaceIPFS = "ipfs"
+	NamespaceIPNS = "ipns"
+)
+
+// indexFile is served for the directories which have it
+const indexFile = "index.html"
+
+// immutableCache is the cache control of the content addressed /ipfs paths
+const immutableCache = "public, max-age=29030400, immutable"
+
+// ResolveFunc returns the unixfs node of the content path like /ipfs/<cid>/a/b or /ipns/<name>/a
+type ResolveFunc func(ctx context.Context, contentPath string) (files.Node, error)
+
+// AccessFunc is called when a file of the root is served, size is the size of
+// the served file and not the one of the root
+type AccessFunc func(r *http.Request, namespace, root string, size int64)
+
+// WriterFunc wraps the writer of the served content
+type WriterFunc func(r *http.Request, root string, w io.Writer) io.Writer
+
+// Gateway serves the unixfs content of the /ipfs and /ipns paths over http,
+// the subdomain requests like <cid>.ipfs.<domain> are served as the site root
+type Gateway struct {
+	resolve ResolveFunc
+	domains []string
+	access  AccessFunc
+	writer  WriterFunc
+}
+
+// request is the content addressed by a http request
+type request struct {
+	namespace string
+	root      string
+	path      string //path in the root, empty or started with /
+	prefix    string //url prefix of the root, empty for the subdomain requests
+}
+
+func (r request) base() string {
+	return "/" + r.namespace + "/" + r.root
+}
+
+// New create a gateway resolving the content with resolve, the subdomain requests of domains are accepted
+func New(resolve ResolveFunc, domains ...string) *Gateway {
+	return &Gateway{
+		resolve: resolve,
+		domains: domains,
+	}
+}
+
+// RegisterAccess ...
+func (g *Gateway) RegisterAccess(f AccessFunc) {
+	g.access = f
+}
+
+// RegisterWriter ...
+func (g *Gateway) RegisterWriter(f WriterFunc) {
+	g.writer = f
+}
+
+// parse returns the content of r from the subdomain or the path
+func (g *Gateway) parse(r *http.Request) (request, bool) {
+	host := r.Host
+	if h, _, err := net.SplitHostPort(host); err == nil {
+		host = h
+	}
+	for _, domain := range g.domains {
+		if !strings.HasSuffix(host, "."+domain) {
+			continue
+		}
+		label := strings.TrimSuffix(host, "."+domain)
+		i := strings.LastIndex(label, ".")
+		if i <= 0 {
+			continue
+		}
+		req := request{namespace: label[i+1:], root: label[:i], path: strings.TrimSuffix(r.URL.Path, "/")}
+		return req, validNamespace(req.namespace)
+	}
+	parts := strings.SplitN(strings.TrimPrefix(r.URL.Path, "/"), "/", 3)
+	if len(parts) < 2 || parts[1] == "" || !validNamespace(parts[0]) {
+		return request{}, false
+	}
+	req := request{namespace: parts[0], root: parts[1]}
+	if len(parts) == 3 {
+		req.path = strings.TrimSuffix("/"+parts[2], "/")
+	}
+	req.prefix = req.base()
+	return req, true
+}
+
+func validNamespace(ns string) bool {
+	return ns == NamespaceIPFS || ns == NamespaceIPNS
+}
+
+// ServeHTTP ...
+func (g *Gateway) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		http.Error(w, http.StatusText(http.StatusMethodNotAllowed), http.StatusMethodNotAllowed)
+		return
+	}
+	req, ok := g.parse(r)
+	if !ok {
+		http.NotFound(w, r)
+		return
+	}
+	node, err := g.resolve(r.Context(), req.base()+req.path)
+	if err != nil {
+		if g.redirect(w, r, req) {
+			return
+		}
+		log.Warnw("resolve gateway path", "path", req.base()+req.path, "err", err)
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+	g.serveNode(w, r, req, node, http.StatusOK)
+}
+
+func (g *Gateway) serveNode(w http.ResponseWriter, r *http.Request, req request, node files.Node, status int) {
+	switch node := node.(type) {
+	case files.File:
+		g.serveFile(w, r, req, node, status)
+	case files.Directory:
+		if !strings.HasSuffix(r.URL.Path, "/") {
+			target := r.URL.Path + "/"
+			if r.URL.RawQuery != "" {
+				target += "?" + r.URL.RawQuery
+			}
+			http.Redirect(w, r, target, http.StatusMovedPermanently)
+			return
+		}
+		index, err := g.resolve(r.Context(), req.base()+req.path+"/"+indexFile)
+		if f, ok := index.(files.File); err == nil && ok {
+			req.path += "/" + indexFile
+			g.serveFile(w, r, req, f, status)
+			return
+		}
+		g.serveDirectory(w, r, req, node)
+	default:
+		http.Error(w, "unsupported file type", http.StatusBadRequest)
+	}
+}
+
+// redirect applies the _redirects rules of the root, false is returned when nothing matches
+func (g *Gateway) redirect(w http.ResponseWriter, r *http.Request, req request) bool {
+	node, err := g.resolve(r.Context(), req.base()+"/"+RedirectsFile)
+	if err != nil {
+		return false
+	}
+	f, ok := node.(files.File)
+	if !ok {
+		return false
+	}
+	defer f.Close()
+	rules, err := ParseRedirects(f)
+	if err != nil {
+		log.Warnw("parse redirects", "root", req.base(), "err", err)
+		return false
+	}
+	p := req.path
+	if p == "" {
+		p = "/"
+	}
+	rule, to, ok := MatchRedirects(rules, p)
+	if !ok {
+		return false
+	}
+	if !strings.HasPrefix(to, "/") {
+		//the external urls can only be redirected to
+		status := rule.Status
+		if rule.rewrite() {
+			status = http.StatusFound
+		}
+		http.Redirect(w, r, to, status)
+		return true
+	}
+	if !rule.rewrite() {
+		http.Redirect(w, r, req.prefix+to, rule.Status)
+		return true
+	}
+	target := req
+	target.path = strings.TrimSuffix(to, "/")
+	node, err = g.resolve(r.Context(), target.base()+target.path)
+	if err != nil {
+		return false
+	}
+	if _, ok := node.(files.Directory); ok {
+		node, err = g.resolve(r.Context(), target.base()+target.path+"/"+indexFile)
+		if err != nil {
+			return false
+		}
+		target.path += "/" + indexFile
+	}
+	f, ok = node.(files.File)
+	if !ok {
+		return false
+	}
+	g.serveFile(w, r, target, f, rule.Status)
+	return true
+}
+
+// responseWriter writes the content through the wrapped writer
+type responseWriter struct {
+	http.ResponseWriter
+	w io.Writer
+}
+
+// Write ...
+func (w *responseWriter) Write(p []byte) (int, error) {
+	return w.w.Write(p)
+}
+
+func (g *Gateway) serveFile(w http.ResponseWriter, r *http.Request, req request, f files.File, status int) {
+	defer f.Close()
+	size, _ := f.Size()
+	if g.access != nil {
+		g.access(r, req.namespace, req.root, size)
+	}
+	contentPath := req.base() + req.path
+	w.Header().Set("X-Ipfs-Path", contentPath)
+	if req.namespace == NamespaceIPFS {
+		w.Header().Set("Cache-Control", immutableCache)
+		w.Header().Set("Etag", `"`+contentPath+`"`)
+	}
+	if g.writer != nil {
+		w = &responseWriter{ResponseWriter: w, w: g.writer(r, req.root, w)}
+	}
+	_, err := f.Seek(0, io.SeekCurrent)
+	if status == http.StatusOK && err == nil {
+		http.ServeContent(w, r, path.Base(contentPath), time.Time{}, f)
+		return
+	}
+	//ranges are meaningless for the error pages and need a seekable file
+	if ctype := mime.TypeByExtension(path.Ext(contentPath)); ctype != "" {
+		w.Header().Set("Content-Type", ctype)
+	}
+	if status != http.StatusOK {
+		w.Header().Del("Cache-Control")
+		w.Header().Del("Etag")
+	}
+	w.WriteHeader(status)
+	if r.Method != http.MethodHead {
+		io.Copy(w, f)
+	}
+}
+
+var listTemplate = template.Must(template.New("list").Parse(`<!DOCTYPE html>
+<html>
+<head><meta charset="utf-8"><title>{{.Path}}</title></head>
+<body>
+<h1>Index of {{.Path}}</h1>
+<ul>
+{{range .Entries}}<li><a href="{{.}}">{{.}}</a></li>
+{{end}}</ul>
+</body>
+</html>
+`))
+
+func (g *Gateway) serveDirectory(w http.ResponseWriter, r *http.Request, req request, dir files.Directory) {
+	var entries []string
+	iter := dir.Entries()
+	for iter.Next() {
+		name := iter.Name()
+		if _, ok := iter.Node().(files.Directory); ok {
+			name += "/"
+		}
+		entries = append(entries, name)
+	}
+	if err := iter.Err(); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.Header().Set("X-Ipfs-Path", req.base()+req.path)
+	if r.Method == http.MethodHead {
+		return
+	}
+	err := listTemplate.Execute(w, struct {
+		Path    string
+		Entries []string
+	}{
+		Path:    req.base() + req.path + "/",
+		Entries: entries,
+	})
+	if err != nil {
+		log.Errorw("list directory", "path", req.base()+req.path, "err", err)
+	}
+}
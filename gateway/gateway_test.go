@@ -0,0 +1,183 @@
+package gateway
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	files "github.com/ipfs/go-ipfs-files"
+)
+
+// bytesFile is a seekable file, the files.NewBytesFile can not seek
+type bytesFile struct {
+	*bytes.Reader
+}
+
+func (f bytesFile) Close() error {
+	return nil
+}
+
+func (f bytesFile) Size() (int64, error) {
+	return f.Reader.Size(), nil
+}
+
+func newFile(s string) files.File {
+	return bytesFile{Reader: bytes.NewReader([]byte(s))}
+}
+
+// testSite returns a new tree of the site root, the nodes are read once so every resolve builds it again
+func testSite() files.Directory {
+	return files.NewMapDirectory(map[string]files.Node{
+		"index.html": newFile("<h1>home</h1>"),
+		"a.txt":      newFile("hello gateway"),
+		"404.html":   newFile("not here"),
+		RedirectsFile: newFile(`# rules
+/old /a.txt 302
+/docs/:page /sub/:page.txt 200
+/* /404.html 404
+`),
+		"sub": files.NewMapDirectory(map[string]files.Node{
+			"b.txt": newFile("bbb"),
+		}),
+	})
+}
+
+func testResolve(ctx context.Context, contentPath string) (files.Node, error) {
+	segments := strings.Split(strings.Trim(contentPath, "/"), "/")
+	if len(segments) < 2 || (segments[1] != "site" && segments[1] != "name") {
+		return nil, errors.New("root not found")
+	}
+	var node files.Node = testSite()
+	for _, name := range segments[2:] {
+		dir, ok := node.(files.Directory)
+		if !ok {
+			return nil, errors.New("not a directory")
+		}
+		var next files.Node
+		iter := dir.Entries()
+		for iter.Next() {
+			if iter.Name() == name {
+				next = iter.Node()
+			}
+		}
+		if next == nil {
+			return nil, errors.New("no link named " + name)
+		}
+		node = next
+	}
+	return node, nil
+}
+
+func get(t *testing.T, g *Gateway, host, target string, header map[string]string) *http.Response {
+	r := httptest.NewRequest(http.MethodGet, target, nil)
+	r.Host = host
+	for k, v := range header {
+		r.Header.Set(k, v)
+	}
+	w := httptest.NewRecorder()
+	g.ServeHTTP(w, r)
+	return w.Result()
+}
+
+func body(t *testing.T, resp *http.Response) string {
+	b, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(b)
+}
+
+func TestGateway_ServeHTTP(t *testing.T) {
+	g := New(testResolve, "localhost")
+	var accessed []string
+	g.RegisterAccess(func(r *http.Request, namespace, root string, size int64) {
+		accessed = append(accessed, namespace+"/"+root)
+	})
+	tests := []struct {
+		name     string
+		host     string
+		target   string
+		header   map[string]string
+		status   int
+		body     string
+		location string
+	}{
+		{name: "file", host: "127.0.0.1", target: "/ipfs/site/a.txt", status: http.StatusOK, body: "hello gateway"},
+		{name: "range", host: "127.0.0.1", target: "/ipfs/site/a.txt", header: map[string]string{"Range": "bytes=6-12"}, status: http.StatusPartialContent, body: "gateway"},
+		{name: "etag", host: "127.0.0.1", target: "/ipfs/site/a.txt", header: map[string]string{"If-None-Match": `"/ipfs/site/a.txt"`}, status: http.StatusNotModified},
+		{name: "slash", host: "127.0.0.1", target: "/ipfs/site", status: http.StatusMovedPermanently, location: "/ipfs/site/"},
+		{name: "index", host: "127.0.0.1", target: "/ipfs/site/", status: http.StatusOK, body: "<h1>home</h1>"},
+		{name: "list", host: "127.0.0.1", target: "/ipfs/site/sub/", status: http.StatusOK, body: `<a href="b.txt">`},
+		{name: "ipns", host: "127.0.0.1", target: "/ipns/name/sub/b.txt", status: http.StatusOK, body: "bbb"},
+		{name: "subdomain", host: "site.ipfs.localhost:8088", target: "/a.txt", status: http.StatusOK, body: "hello gateway"},
+		{name: "subdomain index", host: "site.ipfs.localhost", target: "/", status: http.StatusOK, body: "<h1>home</h1>"},
+		{name: "redirect", host: "127.0.0.1", target: "/ipfs/site/old", status: http.StatusFound, location: "/ipfs/site/a.txt"},
+		{name: "subdomain redirect", host: "site.ipfs.localhost", target: "/old", status: http.StatusFound, location: "/a.txt"},
+		{name: "rewrite", host: "site.ipfs.localhost", target: "/docs/b", status: http.StatusOK, body: "bbb"},
+		{name: "not found page", host: "site.ipfs.localhost", target: "/missing", status: http.StatusNotFound, body: "not here"},
+		{name: "no root", host: "127.0.0.1", target: "/ipfs/other/a.txt", status: http.StatusNotFound},
+		{name: "no namespace", host: "127.0.0.1", target: "/a.txt", status: http.StatusNotFound},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resp := get(t, g, tt.host, tt.target, tt.header)
+			if resp.StatusCode != tt.status {
+				t.Fatalf("status got %d, want %d", resp.StatusCode, tt.status)
+			}
+			if got := body(t, resp); !strings.Contains(got, tt.body) {
+				t.Fatalf("body got %q, want %q", got, tt.body)
+			}
+			if tt.location != "" && resp.Header.Get("Location") != tt.location {
+				t.Fatalf("location got %q, want %q", resp.Header.Get("Location"), tt.location)
+			}
+		})
+	}
+	resp := get(t, g, "127.0.0.1", "/ipfs/site/a.txt", nil)
+	if resp.Header.Get("Cache-Control") != immutableCache {
+		t.Fatalf("ipfs content should be immutable, got %q", resp.Header.Get("Cache-Control"))
+	}
+	resp = get(t, g, "127.0.0.1", "/ipns/name/a.txt", nil)
+	if resp.Header.Get("Cache-Control") != "" || resp.Header.Get("Etag") != "" {
+		t.Fatal("ipns content should not be cached as immutable")
+	}
+	if len(accessed) == 0 || accessed[0] != "ipfs/site" {
+		t.Fatalf("wrong access records %v", accessed)
+	}
+}
+
+func TestMatchRedirects(t *testing.T) {
+	rules, err := ParseRedirects(strings.NewReader(`
+/blog/:year/:month/:slug /posts/:year-:month/:slug.html 301
+/static/* /assets/:splat 200
+/exact /target
+`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		path   string
+		to     string
+		status int
+		ok     bool
+	}{
+		{path: "/blog/2020/07/hello", to: "/posts/2020-07/hello.html", status: 301, ok: true},
+		{path: "/static/js/app.js", to: "/assets/js/app.js", status: 200, ok: true},
+		{path: "/exact", to: "/target", status: 301, ok: true},
+		{path: "/exact/more", ok: false},
+		{path: "/blog/2020", ok: false},
+	}
+	for _, tt := range tests {
+		rule, to, ok := MatchRedirects(rules, tt.path)
+		if ok != tt.ok || to != tt.to || (ok && rule.Status != tt.status) {
+			t.Errorf("MatchRedirects(%s) got %s %d %v", tt.path, to, rule.Status, ok)
+		}
+	}
+	if _, err := ParseRedirects(strings.NewReader("/a /b 999")); err == nil {
+		t.Error("wrong status should be refused")
+	}
+}
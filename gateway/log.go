@@ -0,0 +1,9 @@
+package gateway
+
+import (
+	alog "github.com/glvd/accipfs/log"
+)
+
+const module = "gateway"
+
+var log = alog.Module(module)
@@ -0,0 +1,119 @@
+package gateway
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// RedirectsFile is the file of the redirect rules in the root directory of a site
+const RedirectsFile = "_redirects"
+
+// maxRedirectsSize is the max size of the _redirects file
+const maxRedirectsSize = 64 << 10
+
+const splat = ":splat"
+
+// Rule is a rule of the _redirects file
+type Rule struct {
+	From   string
+	To     string
+	Status int
+}
+
+// rewrite returns if the target is served instead of redirected to
+func (r Rule) rewrite() bool {
+	return r.Status == http.StatusOK || r.Status >= http.StatusBadRequest
+}
+
+// ParseRedirects parses the _redirects rules, every line is "from to [status]",
+// the empty lines and the comments started with # are skipped, the default status is 301
+func ParseRedirects(r io.Reader) ([]Rule, error) {
+	var rules []Rule
+	scanner := bufio.NewScanner(io.LimitReader(r, maxRedirectsSize))
+	for line := 1; scanner.Scan(); line++ {
+		text := strings.TrimSpace(scanner.Text())
+		if text == "" || strings.HasPrefix(text, "#") {
+			continue
+		}
+		fields := strings.Fields(text)
+		if len(fields) < 2 || len(fields) > 3 {
+			return nil, fmt.Errorf("wrong redirect rule at line %d", line)
+		}
+		rule := Rule{From: fields[0], To: fields[1], Status: http.StatusMovedPermanently}
+		if !strings.HasPrefix(rule.From, "/") {
+			return nil, fmt.Errorf("redirect source must be an absolute path at line %d", line)
+		}
+		if len(fields) == 3 {
+			status, err := strconv.Atoi(strings.TrimSuffix(fields[2], "!"))
+			if err != nil || !validStatus(status) {
+				return nil, fmt.Errorf("wrong redirect status(%s) at line %d", fields[2], line)
+			}
+			rule.Status = status
+		}
+		rules = append(rules, rule)
+	}
+	return rules, scanner.Err()
+}
+
+func validStatus(status int) bool {
+	switch status {
+	case http.StatusOK, http.StatusNotFound, http.StatusGone, http.StatusUnavailableForLegalReasons:
+		return true
+	}
+	return status >= http.StatusMultipleChoices && status <= http.StatusPermanentRedirect
+}
+
+// MatchRedirects returns the first rule matching path and its target with the placeholders replaced,
+// a :name segment matches one segment and a trailing * matches the rest as :splat
+func MatchRedirects(rules []Rule, path string) (Rule, string, bool) {
+	for _, rule := range rules {
+		if values, ok := match(rule.From, path); ok {
+			//the longer names are replaced first so :page is not replaced inside :pages
+			names := make([]string, 0, len(values))
+			for name := range values {
+				names = append(names, name)
+			}
+			sort.Slice(names, func(i, j int) bool {
+				return len(names[i]) > len(names[j])
+			})
+			var pairs []string
+			for _, name := range names {
+				pairs = append(pairs, name, values[name])
+			}
+			return rule, strings.NewReplacer(pairs...).Replace(rule.To), true
+		}
+	}
+	return Rule{}, "", false
+}
+
+func match(pattern, path string) (map[string]string, bool) {
+	patterns := strings.Split(strings.TrimSuffix(pattern, "/"), "/")
+	segments := strings.Split(strings.TrimSuffix(path, "/"), "/")
+	values := make(map[string]string)
+	for i, p := range patterns {
+		if p == "*" && i == len(patterns)-1 {
+			if i < len(segments) {
+				values[splat] = strings.Join(segments[i:], "/")
+			} else {
+				values[splat] = ""
+			}
+			return values, true
+		}
+		if i >= len(segments) {
+			return nil, false
+		}
+		if strings.HasPrefix(p, ":") && len(p) > 1 {
+			values[p] = segments[i]
+			continue
+		}
+		if p != segments[i] {
+			return nil, false
+		}
+	}
+	return values, len(patterns) == len(segments)
+}
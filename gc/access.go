@@ -0,0 +1,122 @@
+package gc
+
+import (
+	"encoding/json"
+	"sort"
+	"time"
+
+	"github.com/dgraph-io/badger/v2"
+	"github.com/glvd/accipfs/basis/kv"
+)
+
+// AccessStat is the access record of a root cid served by get
+type AccessStat struct {
+	CID        string `json:"cid"`
+	Size       uint64 `json:"size"`
+	Count      uint64 `json:"count"`
+	LastAccess int64  `json:"last_access"`
+}
+
+// Access keeps the access stats of the served root cids
+type Access struct {
+	db *badger.DB
+}
+
+// Marshal ...
+func (s AccessStat) Marshal() ([]byte, error) {
+	return json.Marshal(s)
+}
+
+// Unmarshal ...
+func (s *AccessStat) Unmarshal(b []byte) error {
+	return json.Unmarshal(b, s)
+}
+
+// OpenAccess ...
+func OpenAccess(path string) (*Access, error) {
+	db, err := kv.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	return &Access{db: db}, nil
+}
+
+// Touch records an access of cid, the size is updated when it is not zero
+func (a *Access) Touch(cid string, size uint64) error {
+	return a.db.Update(func(txn *badger.Txn) error {
+		s := AccessStat{CID: cid}
+		item, err := txn.Get([]byte(cid))
+		switch err {
+		case nil:
+			err = item.Value(func(val []byte) error {
+				return s.Unmarshal(val)
+			})
+			if err != nil {
+				return err
+			}
+		case badger.ErrKeyNotFound:
+		default:
+			return err
+		}
+		if size != 0 {
+			s.Size = size
+		}
+		s.Count++
+		s.LastAccess = time.Now().UnixNano()
+		encode, err := s.Marshal()
+		if err != nil {
+			return err
+		}
+		return txn.Set([]byte(cid), encode)
+	})
+}
+
+// Stats returns all the access stats, the most recent accessed is the first
+func (a *Access) Stats() ([]AccessStat, error) {
+	var stats []AccessStat
+	err := a.db.View(func(txn *badger.Txn) error {
+		iter := txn.NewIterator(badger.DefaultIteratorOptions)
+		defer iter.Close()
+		for iter.Rewind(); iter.Valid(); iter.Next() {
+			var s AccessStat
+			err := iter.Item().Value(func(val []byte) error {
+				return s.Unmarshal(val)
+			})
+			if err != nil {
+				return err
+			}
+			stats = append(stats, s)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	sort.Slice(stats, func(i, j int) bool {
+		return stats[i].LastAccess > stats[j].LastAccess
+	})
+	return stats, nil
+}
+
+// Remove ...
+func (a *Access) Remove(cids ...string) error {
+	return a.db.Update(func(txn *badger.Txn) error {
+		for _, cid := range cids {
+			if err := txn.Delete([]byte(cid)); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// Close ...
+func (a *Access) Close() error {
+	if a.db != nil {
+		defer func() {
+			a.db = nil
+		}()
+		return a.db.Close()
+	}
+	return nil
+}
@@ -0,0 +1,9 @@
+package gc
+
+import (
+	alog "github.com/glvd/accipfs/log"
+)
+
+const module = "gc"
+
+var log = alog.Module(module)
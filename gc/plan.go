@@ -0,0 +1,60 @@
+package gc
+
+// Unlimited is the budget used when no storage quota is set
+const Unlimited = ^uint64(0)
+
+// Plan is the result of an eviction plan
+type Plan struct {
+	Keep  []string //roots kept by the collection
+	Evict []string //roots whose blocks will be collected
+}
+
+// Watermarks returns the high and low watermark bytes of limit
+func Watermarks(limit uint64, high, low int) (uint64, uint64) {
+	if limit == 0 {
+		return Unlimited, Unlimited
+	}
+	if high <= 0 || high > 100 {
+		high = 100
+	}
+	if low <= 0 || low > high {
+		low = high
+	}
+	return limit / 100 * uint64(high), limit / 100 * uint64(low)
+}
+
+// NewPlan keeps the protected roots and the most recent accessed roots which fit in budget with them,
+// the other accessed roots are evicted (least recently used first).
+// stats must be sorted by the access time with the most recent first.
+func NewPlan(stats []AccessStat, protected []string, budget uint64) *Plan {
+	p := &Plan{}
+	sizes := make(map[string]uint64, len(stats))
+	for _, s := range stats {
+		sizes[s.CID] = s.Size
+	}
+	var used uint64
+	keep := make(map[string]bool, len(protected))
+	for _, cid := range protected {
+		if keep[cid] {
+			continue
+		}
+		keep[cid] = true
+		used += sizes[cid]
+		p.Keep = append(p.Keep, cid)
+	}
+	for _, s := range stats {
+		if keep[s.CID] {
+			continue
+		}
+		if budget == Unlimited || used+s.Size <= budget {
+			used += s.Size
+			keep[s.CID] = true
+			p.Keep = append(p.Keep, s.CID)
+			continue
+		}
+		//stop keeping after the first overflow so no evicted root is more recent than a kept one
+		budget = used
+		p.Evict = append(p.Evict, s.CID)
+	}
+	return p
+}
@@ -0,0 +1,75 @@
+package gc
+
+import (
+	"io/ioutil"
+	"os"
+	"reflect"
+	"testing"
+)
+
+func TestNewPlan(t *testing.T) {
+	stats := []AccessStat{
+		{CID: "a", Size: 40},
+		{CID: "b", Size: 40},
+		{CID: "c", Size: 10},
+		{CID: "d", Size: 10},
+	}
+	p := NewPlan(stats, []string{"d"}, 90)
+	if !reflect.DeepEqual(p.Keep, []string{"d", "a", "b"}) {
+		t.Fatalf("wrong keep: %v", p.Keep)
+	}
+	if !reflect.DeepEqual(p.Evict, []string{"c"}) {
+		t.Fatalf("wrong evict: %v", p.Evict)
+	}
+	p = NewPlan(stats, nil, Unlimited)
+	if len(p.Keep) != 4 || len(p.Evict) != 0 {
+		t.Fatalf("unlimited plan should keep all: %+v", p)
+	}
+}
+
+func TestWatermarks(t *testing.T) {
+	high, low := Watermarks(1000, 90, 70)
+	if high != 900 || low != 700 {
+		t.Fatalf("wrong watermarks %d %d", high, low)
+	}
+	high, _ = Watermarks(0, 90, 70)
+	if high != Unlimited {
+		t.Fatal("zero limit should be unlimited")
+	}
+}
+
+func TestAccess_Touch(t *testing.T) {
+	dir, err := ioutil.TempDir("", "access")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	a, err := OpenAccess(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer a.Close()
+	if err := a.Touch("a", 10); err != nil {
+		t.Fatal(err)
+	}
+	if err := a.Touch("b", 20); err != nil {
+		t.Fatal(err)
+	}
+	if err := a.Touch("a", 0); err != nil {
+		t.Fatal(err)
+	}
+	stats, err := a.Stats()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(stats) != 2 || stats[0].CID != "a" || stats[0].Count != 2 || stats[0].Size != 10 {
+		t.Fatalf("wrong stats: %+v", stats)
+	}
+	if err := a.Remove("a"); err != nil {
+		t.Fatal(err)
+	}
+	stats, _ = a.Stats()
+	if len(stats) != 1 || stats[0].CID != "b" {
+		t.Fatalf("wrong stats after remove: %+v", stats)
+	}
+}
@@ -0,0 +1,76 @@
+package ingest
+
+import (
+	"bufio"
+	"encoding/json"
+	"os"
+	"sync"
+)
+
+// Checkpoint records the items ingested successfully in a json lines file to resume an interrupted ingest
+type Checkpoint struct {
+	lock sync.Mutex
+	file *os.File
+	done map[string]*Result
+}
+
+// OpenCheckpoint loads the results recorded in path and appends the new ones to it
+func OpenCheckpoint(path string) (*Checkpoint, error) {
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_RDWR|os.O_APPEND, 0644)
+	if err != nil {
+		return nil, err
+	}
+	cp := &Checkpoint{
+		file: file,
+		done: make(map[string]*Result),
+	}
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		var result Result
+		if err := json.Unmarshal(scanner.Bytes(), &result); err != nil {
+			//the last line may be cut by the interruption
+			log.Warnw("skip checkpoint line", "err", err)
+			continue
+		}
+		if result.Error == "" {
+			cp.done[result.Path] = &result
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		file.Close()
+		return nil, err
+	}
+	return cp, nil
+}
+
+// Done returns the recorded result of a path ingested successfully
+func (cp *Checkpoint) Done(path string) (*Result, bool) {
+	cp.lock.Lock()
+	defer cp.lock.Unlock()
+	result, ok := cp.done[path]
+	return result, ok
+}
+
+// Record appends a successful result to the checkpoint, the failed ones are retried on resume
+func (cp *Checkpoint) Record(result *Result) error {
+	if result.Error != "" {
+		return nil
+	}
+	line, err := json.Marshal(result)
+	if err != nil {
+		return err
+	}
+	cp.lock.Lock()
+	defer cp.lock.Unlock()
+	if _, err := cp.file.Write(append(line, '\n')); err != nil {
+		return err
+	}
+	cp.done[result.Path] = result
+	return nil
+}
+
+// Close ...
+func (cp *Checkpoint) Close() error {
+	return cp.file.Close()
+}
@@ -0,0 +1,268 @@
+package ingest
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/glvd/accipfs/core"
+	"github.com/glvd/accipfs/task"
+)
+
+// DefaultWorkers ...
+const DefaultWorkers = 4
+
+const jobKind = "ingest"
+
+// UploadFunc adds the file of path to the datastore and returns its hash
+type UploadFunc func(ctx context.Context, path string) (string, error)
+
+// AddFunc adds the uploaded hash with its info to the node and returns the result hash
+type AddFunc func(ctx context.Context, hash string, info *core.DataInfoV1) (string, error)
+
+// Result is the result of an item
+type Result struct {
+	Path    string `json:"path"`
+	No      string `json:"no,omitempty"`
+	Hash    string `json:"hash,omitempty"`
+	Root    string `json:"root,omitempty"`
+	Media   string `json:"media,omitempty"` //hash of the hls directory
+	Error   string `json:"error,omitempty"`
+	Skipped bool   `json:"skipped,omitempty"`
+}
+
+// Report is the summary of an ingest
+type Report struct {
+	Total   int       `json:"total"`
+	Added   int       `json:"added"`
+	Skipped int       `json:"skipped"`
+	Failed  int       `json:"failed"`
+	Results []*Result `json:"results"`
+}
+
+// Ingester adds the items of a manifest concurrently
+type Ingester struct {
+	upload     UploadFunc
+	add        AddFunc
+	workers    int
+	retries    int
+	lock       sync.Mutex
+	checkpoint *Checkpoint
+	transcoder Transcoder
+	workDir    string
+	onResult   func(result *Result)
+}
+
+// New returns an ingester running on workers, DefaultWorkers is used when workers is not positive
+func New(upload UploadFunc, add AddFunc, workers int) *Ingester {
+	if workers <= 0 {
+		workers = DefaultWorkers
+	}
+	return &Ingester{
+		upload:  upload,
+		add:     add,
+		workers: workers,
+		retries: -1,
+	}
+}
+
+// SetRetries retries a failed item with backoff up to retries times, the items are not retried by default
+func (i *Ingester) SetRetries(retries int) {
+	if retries <= 0 {
+		retries = -1
+	}
+	i.retries = retries
+}
+
+// SetCheckpoint skips the items done in the checkpoint and records the new ones to it
+func (i *Ingester) SetCheckpoint(cp *Checkpoint) {
+	i.checkpoint = cp
+}
+
+// SetTranscoder transcodes the items to hls in workDir before adding them, the system temp directory is used when workDir is empty
+func (i *Ingester) SetTranscoder(t Transcoder, workDir string) {
+	i.transcoder = t
+	i.workDir = workDir
+}
+
+// OnResult registers a callback called once the result of an item is known
+func (i *Ingester) OnResult(f func(result *Result)) {
+	i.onResult = f
+}
+
+// Run ingests the items and returns the report in the order of the items
+func (i *Ingester) Run(ctx context.Context, items []*Item) (*Report, error) {
+	report := &Report{
+		Total:   len(items),
+		Results: make([]*Result, len(items)),
+	}
+	q, err := task.NewQueue(task.Options{
+		Workers:    i.workers,
+		MaxRetries: i.retries,
+		Backoff:    time.Second,
+		MaxBackoff: time.Minute,
+	}, nil)
+	if err != nil {
+		return nil, err
+	}
+	var lock sync.Mutex
+	results := make(map[int]*Result)
+	q.Register(jobKind, func(ctx context.Context, job *task.Job) error {
+		idx, err := strconv.Atoi(string(job.Payload))
+		if err != nil || idx < 0 || idx >= len(items) {
+			return task.Permanent(fmt.Errorf("wrong item(%s)", job.Payload))
+		}
+		result := &Result{Path: items[idx].Path, No: items[idx].MediaInfo.No}
+		err = i.do(ctx, items[idx], result)
+		if err != nil {
+			result.Error = err.Error()
+		}
+		lock.Lock()
+		results[idx] = result
+		lock.Unlock()
+		return err
+	})
+	q.OnFinish(func(job *task.Job) {
+		idx, _ := strconv.Atoi(string(job.Payload))
+		lock.Lock()
+		result := results[idx]
+		lock.Unlock()
+		if result == nil {
+			result = &Result{Path: items[idx].Path, No: items[idx].MediaInfo.No, Error: job.Error}
+		}
+		i.record(report, idx, result)
+	})
+	qctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	go q.Run(qctx)
+	for idx, item := range items {
+		if i.checkpoint != nil {
+			if done, ok := i.checkpoint.Done(item.Path); ok {
+				result := *done
+				result.Skipped = true
+				i.record(report, idx, &result)
+				continue
+			}
+		}
+		if _, err := q.PushKind(jobKind, []byte(strconv.Itoa(idx)), task.PriorityNormal); err != nil {
+			return report, err
+		}
+	}
+	if err := q.Wait(ctx); err != nil {
+		return report, err
+	}
+	return report, nil
+}
+
+// record sets the result of the item idx to the report and the checkpoint
+func (i *Ingester) record(report *Report, idx int, result *Result) {
+	i.lock.Lock()
+	defer i.lock.Unlock()
+	report.Results[idx] = result
+	switch {
+	case result.Skipped:
+		report.Skipped++
+	case result.Error != "":
+		report.Failed++
+	default:
+		report.Added++
+	}
+	if i.checkpoint != nil && !result.Skipped {
+		if err := i.checkpoint.Record(result); err != nil {
+			log.Errorw("record checkpoint", "path", result.Path, "err", err)
+		}
+	}
+	if i.onResult != nil {
+		i.onResult(result)
+	}
+}
+
+func (i *Ingester) do(ctx context.Context, item *Item, result *Result) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	hash, err := i.upload(ctx, item.Path)
+	if err != nil {
+		return err
+	}
+	if hash == "" {
+		return errors.New("upload returned no hash")
+	}
+	result.Hash = hash
+	info := item.DataInfoV1
+	info.RootHash = hash
+	info.Version = core.DataInfoVersion1
+	info.LastUpdate = time.Now().Unix()
+	if i.transcoder != nil {
+		if err := i.transcode(ctx, item.Path, &info); err != nil {
+			return err
+		}
+		result.Media = info.MediaHash
+	}
+	root, err := i.add(ctx, hash, &info)
+	if err != nil {
+		return err
+	}
+	result.Root = root
+	return nil
+}
+
+// transcode adds the hls tree, the thumbnail and the poster of the video to the info
+func (i *Ingester) transcode(ctx context.Context, path string, info *core.DataInfoV1) error {
+	dir, err := ioutil.TempDir(i.workDir, "transcode")
+	if err != nil {
+		return err
+	}
+	defer os.RemoveAll(dir)
+	out, err := i.transcoder.Transcode(ctx, path, dir)
+	if err != nil {
+		return fmt.Errorf("transcode:%w", err)
+	}
+	media, err := i.upload(ctx, out.HLS)
+	if err != nil {
+		return err
+	}
+	info.MediaHash = media
+	info.MediaIndex = out.Index
+	info.MediaURI = "/ipfs/" + media + "/" + out.Index
+	if out.Thumb != "" {
+		thumb, err := i.upload(ctx, out.Thumb)
+		if err != nil {
+			return err
+		}
+		info.Info.ThumbHash = thumb
+		info.Info.ThumbURI = "/ipfs/" + thumb
+	}
+	if out.Poster != "" {
+		poster, err := i.upload(ctx, out.Poster)
+		if err != nil {
+			return err
+		}
+		info.Info.PosterHash = poster
+		info.Info.PosterURI = "/ipfs/" + poster
+	}
+	if info.MediaInfo.Length == "" && out.Duration > 0 {
+		seconds := int(out.Duration / time.Second)
+		info.MediaInfo.Length = fmt.Sprintf("%02d:%02d:%02d", seconds/3600, seconds/60%60, seconds%60)
+	}
+	if info.MediaInfo.Sharpness == "" && out.Height > 0 {
+		info.MediaInfo.Sharpness = fmt.Sprintf("%dP", out.Height)
+	}
+	return nil
+}
+
+// CIDs returns the hashes of the items added or skipped
+func (r *Report) CIDs() []string {
+	var cids []string
+	for _, result := range r.Results {
+		if result != nil && result.Error == "" && result.Hash != "" {
+			cids = append(cids, result.Hash)
+		}
+	}
+	return cids
+}
@@ -0,0 +1,223 @@
+package ingest
+
+import (
+	"context"
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/glvd/accipfs/core"
+)
+
+const testCSV = `path,no,intro,role,tags,uncensored
+/video/a.mp4,ABP-001,intro a,r1|r2,t1,true
+/video/b.mp4,ABP-002,intro b,,,false
+/video/c.mp4,ABP-003,,,,
+`
+
+const testJSON = `[
+	{"path":"/video/a.mp4","media_info":{"no":"ABP-001","role":["r1","r2"]}},
+	{"path":"/video/b.mp4","media_info":{"no":"ABP-002"},"info":{"thumb_hash":"thumb"}}
+]`
+
+func TestParseCSV(t *testing.T) {
+	items, err := ParseCSV(strings.NewReader(testCSV))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(items) != 3 {
+		t.Fatalf("want 3 items, got %d", len(items))
+	}
+	a := items[0]
+	if a.Path != "/video/a.mp4" || a.MediaInfo.No != "ABP-001" || len(a.MediaInfo.Role) != 2 || !a.MediaInfo.Uncensored {
+		t.Fatalf("wrong item %+v", a)
+	}
+	if _, err := ParseCSV(strings.NewReader("path,unknown\n/a,b\n")); err == nil {
+		t.Fatal("unknown column should fail")
+	}
+	if _, err := ParseCSV(strings.NewReader("no\nABP-001\n")); err == nil {
+		t.Fatal("manifest without path should fail")
+	}
+}
+
+func TestParseJSON(t *testing.T) {
+	items, err := ParseJSON(strings.NewReader(testJSON))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(items) != 2 || items[0].MediaInfo.Role[1] != "r2" || items[1].Info.ThumbHash != "thumb" {
+		t.Fatalf("wrong items %+v", items)
+	}
+}
+
+type fakeNode struct {
+	lock  sync.Mutex
+	added map[string]*core.DataInfoV1
+	fail  string
+}
+
+func (n *fakeNode) upload(ctx context.Context, path string) (string, error) {
+	if path == n.fail {
+		return "", errors.New("upload failed")
+	}
+	return "Qm" + filepath.Base(path), nil
+}
+
+func (n *fakeNode) add(ctx context.Context, hash string, info *core.DataInfoV1) (string, error) {
+	n.lock.Lock()
+	defer n.lock.Unlock()
+	n.added[hash] = info
+	return "root-" + hash, nil
+}
+
+func TestIngester_Resume(t *testing.T) {
+	dir, err := ioutil.TempDir("", "ingest")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	items, err := ParseCSV(strings.NewReader(testCSV))
+	if err != nil {
+		t.Fatal(err)
+	}
+	node := &fakeNode{added: make(map[string]*core.DataInfoV1), fail: "/video/b.mp4"}
+	cp, err := OpenCheckpoint(filepath.Join(dir, "checkpoint"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	in := New(node.upload, node.add, 2)
+	in.SetCheckpoint(cp)
+	report, err := in.Run(context.Background(), items)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cp.Close()
+	if report.Added != 2 || report.Failed != 1 || report.Skipped != 0 {
+		t.Fatalf("wrong report %+v", report)
+	}
+	if info := node.added["Qma.mp4"]; info == nil || info.RootHash != "Qma.mp4" || info.MediaInfo.No != "ABP-001" {
+		t.Fatalf("wrong added info %+v", info)
+	}
+
+	node.fail = ""
+	node.added = make(map[string]*core.DataInfoV1)
+	cp, err = OpenCheckpoint(filepath.Join(dir, "checkpoint"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer cp.Close()
+	in.SetCheckpoint(cp)
+	report, err = in.Run(context.Background(), items)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if report.Added != 1 || report.Skipped != 2 || report.Failed != 0 {
+		t.Fatalf("wrong resumed report %+v", report)
+	}
+	if len(node.added) != 1 || node.added["Qmb.mp4"] == nil {
+		t.Fatalf("only the failed item should be added again %v", node.added)
+	}
+	if cids := report.CIDs(); len(cids) != 3 || cids[0] != "Qma.mp4" {
+		t.Fatalf("wrong cids %v", cids)
+	}
+}
+
+// fakeTranscoder writes the files of a transcoding without ffmpeg
+type fakeTranscoder struct {
+	inputs []string
+}
+
+func (f *fakeTranscoder) Transcode(ctx context.Context, input string, dir string) (*Transcoded, error) {
+	f.inputs = append(f.inputs, input)
+	out := &Transcoded{
+		HLS:      filepath.Join(dir, HLSDir),
+		Index:    MasterPlaylist,
+		Thumb:    filepath.Join(dir, ThumbFile),
+		Poster:   filepath.Join(dir, PosterFile),
+		Duration: 5432 * time.Second,
+		Height:   720,
+	}
+	if err := os.MkdirAll(out.HLS, 0755); err != nil {
+		return nil, err
+	}
+	if err := WriteMaster(filepath.Join(out.HLS, MasterPlaylist), DefaultRenditions[1:]); err != nil {
+		return nil, err
+	}
+	for _, path := range []string{out.Thumb, out.Poster} {
+		if err := ioutil.WriteFile(path, []byte("jpg"), 0644); err != nil {
+			return nil, err
+		}
+	}
+	return out, nil
+}
+
+func TestIngester_Transcode(t *testing.T) {
+	node := &fakeNode{added: make(map[string]*core.DataInfoV1)}
+	uploaded := make(map[string]bool)
+	upload := func(ctx context.Context, path string) (string, error) {
+		if _, err := os.Stat(path); err != nil && !strings.HasPrefix(path, "/video/") {
+			return "", err
+		}
+		uploaded[filepath.Base(path)] = true
+		return node.upload(ctx, path)
+	}
+	fake := &fakeTranscoder{}
+	in := New(upload, node.add, 1)
+	in.SetTranscoder(fake, "")
+	items := []*Item{{Path: "/video/a.mp4"}}
+	items[0].MediaInfo.Sharpness = "HD"
+	report, err := in.Run(context.Background(), items)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if report.Added != 1 || report.Results[0].Media != "Qmhls" {
+		t.Fatalf("wrong report %+v %+v", report, report.Results[0])
+	}
+	if len(fake.inputs) != 1 || fake.inputs[0] != "/video/a.mp4" {
+		t.Fatalf("wrong transcoded inputs %v", fake.inputs)
+	}
+	info := node.added["Qma.mp4"]
+	if info.MediaHash != "Qmhls" || info.MediaIndex != MasterPlaylist || info.MediaURI != "/ipfs/Qmhls/master.m3u8" {
+		t.Fatalf("wrong media of info %+v", info)
+	}
+	if info.Info.ThumbHash != "Qmthumb.jpg" || info.Info.PosterHash != "Qmposter.jpg" {
+		t.Fatalf("wrong thumb or poster %+v", info.Info)
+	}
+	if info.MediaInfo.Length != "01:30:32" || info.MediaInfo.Sharpness != "HD" {
+		t.Fatalf("wrong length or sharpness %+v", info.MediaInfo)
+	}
+}
+
+func TestWriteMaster(t *testing.T) {
+	dir, err := ioutil.TempDir("", "hls")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, MasterPlaylist)
+	if err := WriteMaster(path, DefaultRenditions[1:]); err != nil {
+		t.Fatal(err)
+	}
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := "#EXTM3U\n#EXT-X-VERSION:3\n" +
+		"#EXT-X-STREAM-INF:BANDWIDTH=2928000,RESOLUTION=1280x720\n720p/index.m3u8\n" +
+		"#EXT-X-STREAM-INF:BANDWIDTH=1528000,RESOLUTION=852x480\n480p/index.m3u8\n"
+	if string(data) != want {
+		t.Fatalf("wrong master playlist\n%s", data)
+	}
+	f := &FFmpeg{Renditions: DefaultRenditions}
+	if r := f.renditions(720); len(r) != 2 || r[0].Name != "720p" {
+		t.Fatalf("renditions of 720 source %v", r)
+	}
+	if r := f.renditions(360); len(r) != 1 || r[0].Name != "480p" {
+		t.Fatalf("small source should keep the lowest rendition %v", r)
+	}
+}
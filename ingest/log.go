@@ -0,0 +1,9 @@
+package ingest
+
+import (
+	alog "github.com/glvd/accipfs/log"
+)
+
+const module = "ingest"
+
+var log = alog.Module(module)
@@ -0,0 +1,171 @@
+package ingest
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/glvd/accipfs/core"
+)
+
+// ListSeparator separates the values of the list columns in a csv manifest
+const ListSeparator = "|"
+
+// ErrManifestFormat ...
+var ErrManifestFormat = errors.New("unsupported manifest format")
+
+// Item is a file of the manifest with the info added along with it
+type Item struct {
+	Path string `json:"path"`
+	core.DataInfoV1
+}
+
+// LoadManifest reads the items of a json or csv manifest by the file extension
+func LoadManifest(path string) ([]*Item, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".json":
+		return ParseJSON(file)
+	case ".csv":
+		return ParseCSV(file)
+	}
+	return nil, ErrManifestFormat
+}
+
+// ParseJSON reads the items of a json manifest which is an array of items
+func ParseJSON(r io.Reader) ([]*Item, error) {
+	var items []*Item
+	if err := json.NewDecoder(r).Decode(&items); err != nil {
+		return nil, err
+	}
+	for i, item := range items {
+		if item.Path == "" {
+			return nil, fmt.Errorf("item(%d) has no path", i)
+		}
+	}
+	return items, nil
+}
+
+// ParseCSV reads the items of a csv manifest, the header names the columns by path and the json names of the media info
+func ParseCSV(r io.Reader) ([]*Item, error) {
+	cr := csv.NewReader(r)
+	cr.TrimLeadingSpace = true
+	header, err := cr.Read()
+	if err != nil {
+		return nil, err
+	}
+	pathCol := -1
+	for i, name := range header {
+		header[i] = strings.ToLower(strings.TrimSpace(name))
+		if header[i] == "path" {
+			pathCol = i
+		}
+	}
+	if pathCol < 0 {
+		return nil, errors.New("manifest has no path column")
+	}
+	var items []*Item
+	for line := 2; ; line++ {
+		record, err := cr.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		item := &Item{Path: record[pathCol]}
+		if item.Path == "" {
+			return nil, fmt.Errorf("line(%d) has no path", line)
+		}
+		for i, name := range header {
+			if i == pathCol || record[i] == "" {
+				continue
+			}
+			if err := setMediaInfo(&item.MediaInfo, name, record[i]); err != nil {
+				return nil, fmt.Errorf("line(%d):%w", line, err)
+			}
+		}
+		items = append(items, item)
+	}
+	return items, nil
+}
+
+func splitList(v string) []string {
+	var list []string
+	for _, s := range strings.Split(v, ListSeparator) {
+		if s = strings.TrimSpace(s); s != "" {
+			list = append(list, s)
+		}
+	}
+	return list
+}
+
+func setMediaInfo(info *core.MediaInfo, name, value string) error {
+	switch name {
+	case "no":
+		info.No = value
+	case "intro":
+		info.Intro = value
+	case "alias":
+		info.Alias = splitList(value)
+	case "role":
+		info.Role = splitList(value)
+	case "director":
+		info.Director = value
+	case "systematics":
+		info.Systematics = value
+	case "season":
+		info.Season = value
+	case "total_episode":
+		info.TotalEpisode = value
+	case "episode":
+		info.Episode = value
+	case "producer":
+		info.Producer = value
+	case "publisher":
+		info.Publisher = value
+	case "type":
+		info.Type = value
+	case "format":
+		info.Format = value
+	case "language":
+		info.Language = value
+	case "caption":
+		info.Caption = value
+	case "group":
+		info.Group = value
+	case "index":
+		info.Index = value
+	case "date":
+		info.Date = value
+	case "sharpness":
+		info.Sharpness = value
+	case "series":
+		info.Series = value
+	case "tags":
+		info.Tags = splitList(value)
+	case "length":
+		info.Length = value
+	case "sample":
+		info.Sample = splitList(value)
+	case "uncensored":
+		b, err := strconv.ParseBool(value)
+		if err != nil {
+			return fmt.Errorf("wrong uncensored(%s)", value)
+		}
+		info.Uncensored = b
+	default:
+		return fmt.Errorf("unknown column(%s)", name)
+	}
+	return nil
+}
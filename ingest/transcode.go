@@ -0,0 +1,203 @@
+package ingest
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Names of the files in the output directory of a transcoding
+const (
+	MasterPlaylist = "master.m3u8"
+	ThumbFile      = "thumb.jpg"
+	PosterFile     = "poster.jpg"
+	HLSDir         = "hls"
+)
+
+// ErrNoFFmpeg ...
+var ErrNoFFmpeg = errors.New("ffmpeg is not found")
+
+// Rendition is a variant stream of the hls output
+type Rendition struct {
+	Name         string
+	Height       int
+	VideoBitrate int //kbps
+	AudioBitrate int //kbps
+}
+
+// DefaultRenditions ...
+var DefaultRenditions = []Rendition{
+	{Name: "1080p", Height: 1080, VideoBitrate: 5000, AudioBitrate: 192},
+	{Name: "720p", Height: 720, VideoBitrate: 2800, AudioBitrate: 128},
+	{Name: "480p", Height: 480, VideoBitrate: 1400, AudioBitrate: 128},
+}
+
+// Transcoded is the output of a transcoding, the paths are in the output directory
+type Transcoded struct {
+	HLS      string //directory of the hls tree with the master playlist
+	Index    string //path of the master playlist in the hls directory
+	Thumb    string
+	Poster   string
+	Duration time.Duration
+	Height   int //height of the highest rendition
+}
+
+// Transcoder makes the hls renditions, the thumbnail and the poster of a video in dir
+type Transcoder interface {
+	Transcode(ctx context.Context, input string, dir string) (*Transcoded, error)
+}
+
+// FFmpeg transcodes the videos with the local ffmpeg binary, ffprobe is used to skip the renditions higher than the source
+type FFmpeg struct {
+	Path        string
+	ProbePath   string
+	Renditions  []Rendition
+	SegmentTime int //seconds of a hls segment
+	ThumbWidth  int
+}
+
+// LookupFFmpeg returns the transcoder of the ffmpeg found in PATH, ErrNoFFmpeg is returned when it is absent
+func LookupFFmpeg() (*FFmpeg, error) {
+	path, err := exec.LookPath("ffmpeg")
+	if err != nil {
+		return nil, ErrNoFFmpeg
+	}
+	probe, _ := exec.LookPath("ffprobe")
+	return &FFmpeg{
+		Path:        path,
+		ProbePath:   probe,
+		Renditions:  DefaultRenditions,
+		SegmentTime: 6,
+		ThumbWidth:  320,
+	}, nil
+}
+
+// probe is the output of ffprobe
+type probe struct {
+	Streams []struct {
+		Width  int `json:"width"`
+		Height int `json:"height"`
+	} `json:"streams"`
+	Format struct {
+		Duration string `json:"duration"`
+	} `json:"format"`
+}
+
+func (f *FFmpeg) run(ctx context.Context, name string, args ...string) ([]byte, error) {
+	var stderr bytes.Buffer
+	cmd := exec.CommandContext(ctx, name, args...)
+	cmd.Stderr = &stderr
+	out, err := cmd.Output()
+	if err != nil {
+		msg := strings.TrimSpace(stderr.String())
+		if i := strings.LastIndex(msg, "\n"); i >= 0 {
+			msg = msg[i+1:]
+		}
+		return nil, fmt.Errorf("%s:%w(%s)", filepath.Base(name), err, msg)
+	}
+	return out, nil
+}
+
+// probe returns the height and the duration of the video, zero values are returned without ffprobe
+func (f *FFmpeg) probe(ctx context.Context, input string) (int, time.Duration, error) {
+	if f.ProbePath == "" {
+		return 0, 0, nil
+	}
+	out, err := f.run(ctx, f.ProbePath, "-v", "error", "-select_streams", "v:0",
+		"-show_entries", "stream=width,height:format=duration", "-of", "json", input)
+	if err != nil {
+		return 0, 0, err
+	}
+	var p probe
+	if err := json.Unmarshal(out, &p); err != nil {
+		return 0, 0, err
+	}
+	height := 0
+	if len(p.Streams) > 0 {
+		height = p.Streams[0].Height
+	}
+	seconds, _ := strconv.ParseFloat(p.Format.Duration, 64)
+	return height, time.Duration(seconds * float64(time.Second)), nil
+}
+
+// renditions returns the renditions not higher than the source, the lowest one is kept for a small source
+func (f *FFmpeg) renditions(height int) []Rendition {
+	var list []Rendition
+	for _, r := range f.Renditions {
+		if height == 0 || r.Height <= height {
+			list = append(list, r)
+		}
+	}
+	if len(list) == 0 && len(f.Renditions) > 0 {
+		list = append(list, f.Renditions[len(f.Renditions)-1])
+	}
+	return list
+}
+
+// Transcode ...
+func (f *FFmpeg) Transcode(ctx context.Context, input string, dir string) (*Transcoded, error) {
+	height, duration, err := f.probe(ctx, input)
+	if err != nil {
+		return nil, err
+	}
+	out := &Transcoded{
+		HLS:      filepath.Join(dir, HLSDir),
+		Index:    MasterPlaylist,
+		Thumb:    filepath.Join(dir, ThumbFile),
+		Poster:   filepath.Join(dir, PosterFile),
+		Duration: duration,
+	}
+	renditions := f.renditions(height)
+	for _, r := range renditions {
+		rdir := filepath.Join(out.HLS, r.Name)
+		if err := os.MkdirAll(rdir, 0755); err != nil {
+			return nil, err
+		}
+		_, err := f.run(ctx, f.Path, "-y", "-v", "error", "-i", input,
+			"-vf", fmt.Sprintf("scale=-2:%d", r.Height),
+			"-c:v", "libx264", "-b:v", fmt.Sprintf("%dk", r.VideoBitrate),
+			"-c:a", "aac", "-b:a", fmt.Sprintf("%dk", r.AudioBitrate),
+			"-f", "hls", "-hls_time", strconv.Itoa(f.SegmentTime), "-hls_playlist_type", "vod",
+			"-hls_segment_filename", filepath.Join(rdir, "%05d.ts"),
+			filepath.Join(rdir, "index.m3u8"))
+		if err != nil {
+			return nil, err
+		}
+		if r.Height > out.Height {
+			out.Height = r.Height
+		}
+	}
+	if err := WriteMaster(filepath.Join(out.HLS, MasterPlaylist), renditions); err != nil {
+		return nil, err
+	}
+	//the thumbnail and the poster are taken at 10% of the video to skip the opening
+	at := fmt.Sprintf("%.3f", (duration / 10).Seconds())
+	if _, err := f.run(ctx, f.Path, "-y", "-v", "error", "-ss", at, "-i", input, "-frames:v", "1",
+		"-vf", fmt.Sprintf("scale=%d:-2", f.ThumbWidth), out.Thumb); err != nil {
+		return nil, err
+	}
+	if _, err := f.run(ctx, f.Path, "-y", "-v", "error", "-ss", at, "-i", input, "-frames:v", "1", out.Poster); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// WriteMaster writes the master playlist of the renditions, the playlist of a rendition is <name>/index.m3u8
+func WriteMaster(path string, renditions []Rendition) error {
+	var buf bytes.Buffer
+	buf.WriteString("#EXTM3U\n#EXT-X-VERSION:3\n")
+	for _, r := range renditions {
+		fmt.Fprintf(&buf, "#EXT-X-STREAM-INF:BANDWIDTH=%d,RESOLUTION=%dx%d\n%s/index.m3u8\n",
+			(r.VideoBitrate+r.AudioBitrate)*1000, r.Height*16/9/2*2, r.Height, r.Name)
+	}
+	return ioutil.WriteFile(path, buf.Bytes(), 0644)
+}
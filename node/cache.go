@@ -3,10 +3,10 @@ package node
 import (
 	"encoding/json"
 	"github.com/dgraph-io/badger/v2"
-	"github.com/dgraph-io/badger/v2/options"
+	"github.com/glvd/accipfs/basis/kv"
 	"github.com/glvd/accipfs/config"
 	"github.com/glvd/accipfs/core"
-	"os"
+	"io"
 	"path/filepath"
 )
 
@@ -37,6 +37,8 @@ type Cacher interface {
 	Update(hash string, fn func(bytes []byte) (core.Marshaler, error)) error
 	Close() error
 	Range(f func(hash string, value string) bool)
+	Stat() (keys int, size int64, err error)
+	Backup(w io.Writer) error
 }
 
 // DataHashInfo ...
@@ -71,20 +73,7 @@ func (v *DataHashInfo) Unmarshal(b []byte) error {
 // HashCacher ...
 func HashCacher(cfg *config.Config) Cacher {
 	path := filepath.Join(cfg.Path, cacheDir, hashNodeName)
-	_, err := os.Stat(path)
-	if err != nil && os.IsNotExist(err) {
-		err := os.MkdirAll(path, 0755)
-		if err != nil {
-			panic(err)
-		}
-	}
-	opts := badger.DefaultOptions(path)
-	opts.CompactL0OnClose = false
-	opts.Truncate = true
-	opts.ValueLogLoadingMode = options.FileIO
-	opts.TableLoadingMode = options.MemoryMap
-	opts.MaxTableSize = 16 << 20
-	db, err := badger.Open(opts)
+	db, err := kv.Open(path)
 	if err != nil {
 		panic(err)
 	}
@@ -186,6 +175,28 @@ func (c *baseCache) Range(f func(key, value string) bool) {
 	}
 }
 
+// Stat returns the key count and the disk size of the cache
+func (c *baseCache) Stat() (keys int, size int64, err error) {
+	err = c.db.View(func(txn *badger.Txn) error {
+		opts := badger.DefaultIteratorOptions
+		opts.PrefetchValues = false
+		iter := txn.NewIterator(opts)
+		defer iter.Close()
+		for iter.Rewind(); iter.Valid(); iter.Next() {
+			keys++
+		}
+		return nil
+	})
+	lsm, vlog := c.db.Size()
+	return keys, lsm + vlog, err
+}
+
+// Backup writes a consistent badger backup of the cache
+func (c *baseCache) Backup(w io.Writer) error {
+	_, err := c.db.Backup(w, 0)
+	return err
+}
+
 // Close ...
 func (c *baseCache) Close() error {
 	if c.db != nil {
@@ -200,21 +211,7 @@ func (c *baseCache) Close() error {
 // NodeCacher ...
 func NodeCacher(cfg *config.Config) Cacher {
 	path := filepath.Join(cfg.Path, cacheDir, nodeName)
-	_, err := os.Stat(path)
-	if err != nil && os.IsNotExist(err) {
-		err := os.MkdirAll(path, 0755)
-		if err != nil {
-			panic(err)
-		}
-	}
-	opts := badger.DefaultOptions(path)
-	opts.CompactL0OnClose = false
-	opts.Truncate = true
-	opts.ValueLogLoadingMode = options.FileIO
-	opts.TableLoadingMode = options.MemoryMap
-	//opts.ValueLogFileSize = 1<<28 - 1
-	opts.MaxTableSize = 16 << 20
-	db, err := badger.Open(opts)
+	db, err := kv.Open(path)
 	if err != nil {
 		panic(err)
 	}
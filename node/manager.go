@@ -1,12 +1,14 @@
 package node
 
 import (
+	"bytes"
 	"context"
 	"encoding/json"
 	"errors"
 	"fmt"
 	"github.com/panjf2000/ants/v2"
 	"net"
+	"sort"
 	"sync"
 	"time"
 
@@ -37,6 +39,8 @@ type manager struct {
 	RequestLD       func() ([]string, error)
 	gc              *atomic.Bool
 	addrCB          func(info peer.AddrInfo) error
+	recorder        core.BandwidthRecorder
+	limiter         core.RequestLimiter
 }
 
 var _nodes = "bl.nodes"
@@ -179,6 +183,10 @@ func (m *manager) Link(ctx context.Context, req *core.NodeLinkReq) (*core.NodeLi
 			if err != nil {
 				return &core.NodeLinkResp{}, err
 			}
+			if err := m.verifyNetwork(info); err != nil {
+				conn.Close()
+				return &core.NodeLinkResp{}, err
+			}
 			infos = append(infos, info)
 		}
 	}
@@ -208,9 +216,20 @@ func (m *manager) NodeAddrInfo(ctx context.Context, req *core.AddrReq) (*core.Ad
 	if !b {
 		return &core.AddrResp{}, fmt.Errorf("transfer to node failed id(%s)", req.ID)
 	}
-	panic("//todo")
-	fmt.Print(v)
-	return nil, nil
+	info, err := v.GetInfo()
+	if err != nil {
+		return &core.AddrResp{}, err
+	}
+	return &core.AddrResp{AddrInfo: info.AddrInfo}, nil
+}
+
+// Backup ...
+func (m *manager) Backup(ctx context.Context, req *core.NodeBackupReq) (*core.NodeBackupResp, error) {
+	var buf bytes.Buffer
+	if err := m.nodes.Backup(&buf); err != nil {
+		return nil, err
+	}
+	return &core.NodeBackupResp{Cache: buf.Bytes()}, nil
 }
 
 // List ...
@@ -326,7 +345,7 @@ func (m *manager) loop() {
 
 // newConn ...
 func (m *manager) newConn(c net.Conn) (core.Node, error) {
-	acceptNode, err := CoreNode(c, m.local)
+	acceptNode, err := CoreNode(c, m.local, m.recorder, m.limiter)
 	if err != nil {
 		return nil, err
 	}
@@ -381,10 +400,13 @@ func (m *manager) mainProc(v interface{}) {
 	log.Infow("sync node info", "info", info.JSON())
 	if err == nil {
 		if info.ID != m.cfg.Identity {
+			if err := m.verifyNetwork(info); err != nil {
+				log.Warnw("refuse node", "id", id, "network", info.DataStore.Network, "err", err)
+				return
+			}
 			m.local.Update(func(data *core.LocalData) {
 				data.Nodes[info.ID] = info
 			})
-			m.connectRemoteDataStore(info.DataStore)
 		}
 	}
 	if !n.IsClosed() {
@@ -411,6 +433,25 @@ func (m *manager) mainProc(v interface{}) {
 	}
 }
 
+// sameNetwork returns if the datastore of the node is in the private network of the local one
+func (m *manager) sameNetwork(info core.NodeInfo) bool {
+	return info.DataStore.Network == m.local.Data().Node.DataStore.Network
+}
+
+// verifyNetwork connects the datastore of the node, the fingerprint of the private network is
+// reported by the node itself so the node is refused until its datastore is connected through
+// the pnet swarm, which requires the swarm key
+func (m *manager) verifyNetwork(info core.NodeInfo) error {
+	if !m.sameNetwork(info) {
+		return ErrNetworkMismatch
+	}
+	err := m.connectRemoteDataStore(info.DataStore)
+	if err != nil && info.DataStore.Network != "" {
+		return fmt.Errorf("%w:%v", ErrNetworkUnproven, err)
+	}
+	return nil
+}
+
 func (m *manager) getLinkData(node core.Node) ([]string, error) {
 	ds, err := node.LDs()
 	if err != nil {
@@ -424,28 +465,36 @@ func (m *manager) getLinkData(node core.Node) ([]string, error) {
 	return ds, nil
 }
 
-func (m *manager) connectRemoteDataStore(info core.DataStoreInfo) {
+// connectRemoteDataStore connects the swarm to the datastore, an error is returned when no address of the datastore id is connected
+func (m *manager) connectRemoteDataStore(info core.DataStoreInfo) error {
 	timeout, cancelFunc := context.WithTimeout(context.TODO(), time.Second*30)
 	defer cancelFunc()
 
-	if m.addrCB != nil {
-		addresses, err := basis.ParseAddresses(timeout, info.Addresses)
-		if err != nil {
-			return
+	if m.addrCB == nil {
+		return ErrNoSwarm
+	}
+	addresses, err := basis.ParseAddresses(timeout, info.Addresses)
+	if err != nil {
+		return err
+	}
+	connected := 0
+	for _, addr := range addresses {
+		if addr.ID.String() != info.ID {
+			continue
 		}
-		total := len(addresses)
-		for _, addr := range addresses {
-			err = m.addrCB(addr)
-			if err != nil {
-				total = total - 1
-				continue
-			}
+		if err = m.addrCB(addr); err != nil {
+			continue
 		}
-		if total <= 0 {
-			log.Infow("addr callback failed", "err", err, "addrinfo", info.Addresses)
+		connected++
+	}
+	if connected == 0 {
+		log.Infow("addr callback failed", "err", err, "addrinfo", info.Addresses)
+		if err == nil {
+			err = ErrNoSwarm
 		}
+		return err
 	}
-
+	return nil
 }
 
 func (m *manager) syncPeers(wg *sync.WaitGroup, n core.Node) {
@@ -461,7 +510,7 @@ func (m *manager) syncPeers(wg *sync.WaitGroup, n core.Node) {
 	}
 }
 
-func decodeNode(m core.NodeManager, b []byte, api core.API) error {
+func decodeNode(m *manager, b []byte, api core.API) error {
 	nodes := map[string]jsonNode{}
 	err := json.Unmarshal(b, &nodes)
 	if err != nil {
@@ -470,7 +519,7 @@ func decodeNode(m core.NodeManager, b []byte, api core.API) error {
 
 	for _, nodes := range nodes {
 		for _, addr := range nodes.Addrs {
-			connectNode, err := ConnectNode(addr, 0, m.Local())
+			connectNode, err := ConnectNode(addr, 0, m.Local(), m.recorder, m.limiter)
 			if err != nil {
 				continue
 			}
@@ -616,6 +665,40 @@ func (m *manager) RegisterAddrCallback(f func(info peer.AddrInfo) error) {
 	m.addrCB = f
 }
 
+// RegisterBandwidthRecorder ...
+func (m *manager) RegisterBandwidthRecorder(r core.BandwidthRecorder) {
+	m.recorder = r
+}
+
+// CacheStats ...
+func (m *manager) CacheStats() []core.CacheStat {
+	var stats []core.CacheStat
+	for name, c := range map[string]Cacher{
+		nodeName:     m.nodes,
+		hashNodeName: m.hashNodes,
+	} {
+		keys, size, err := c.Stat()
+		if err != nil {
+			log.Errorw("cache stat", "name", name, "err", err)
+			continue
+		}
+		stats = append(stats, core.CacheStat{
+			Name: name,
+			Keys: keys,
+			Size: size,
+		})
+	}
+	sort.Slice(stats, func(i, j int) bool {
+		return stats[i].Name < stats[j].Name
+	})
+	return stats
+}
+
+// RegisterRequestLimiter ...
+func (m *manager) RegisterRequestLimiter(l core.RequestLimiter) {
+	m.limiter = l
+}
+
 // ConnRemoteFromHash ...
 func (m *manager) ConnRemoteFromHash(hash string) error {
 	var nodes Nodes
@@ -1,12 +1,13 @@
 package node
 
 import (
+	"errors"
 	"fmt"
-	"github.com/glvd/accipfs/basis"
 	"github.com/glvd/accipfs/config"
 	"github.com/glvd/accipfs/controller"
 	"github.com/glvd/accipfs/core"
 	alog "github.com/glvd/accipfs/log"
+	"github.com/libp2p/go-libp2p-core/peer"
 	ma "github.com/multiformats/go-multiaddr"
 	"sync"
 	"testing"
@@ -26,18 +27,21 @@ func TestManager_Store(t *testing.T) {
 	//context := controller.NewContext(cfg)
 	//c := controller.New(cfg)
 	//c.API()
-	nodeManager := InitManager(cfg)
+	nodeManager, err := InitManager(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
 	multiaddr, err := ma.NewMultiaddr("/ip4/127.0.0.1/tcp/12345")
 	if err != nil {
 		panic(err)
 	}
 	for i := 0; i < 100; i++ {
-		connectNode, err := ConnectNode(multiaddr, 0, core.NodeInfo{}, &dummyAPI{})
+		connectNode, err := ConnectNode(multiaddr, 0, nodeManager.Local(), nil, nil)
 		if err != nil {
 			continue
 		}
 		nodeManager.Push(connectNode)
-		err = nodeManager.Store()
+		err = nodeManager.SaveNode()
 		if err != nil {
 			continue
 		}
@@ -51,16 +55,14 @@ func store(wg *sync.WaitGroup, nodeManager core.NodeManager) {
 		panic(err)
 	}
 	for i := 0; i < 1000; i++ {
-		connectNode, err := ConnectNode(multiaddr, 0, core.NodeInfo{}, &dummyAPI{
-			id: basis.UUID(),
-		})
-
+		connectNode, err := ConnectNode(multiaddr, 0, nodeManager.Local(), nil, nil)
 		if err != nil {
 			fmt.Println("error", err)
+			continue
 		}
 		fmt.Println("remote id:", connectNode.ID())
 		nodeManager.Push(connectNode)
-		err = nodeManager.Store()
+		err = nodeManager.SaveNode()
 		if err != nil {
 			fmt.Println("error", err)
 		}
@@ -82,12 +84,53 @@ func load(wg *sync.WaitGroup, nodeManager core.NodeManager) {
 func TestManager_Load(t *testing.T) {
 	cfg := config.Default()
 	controller.New(cfg)
-	InitManager(cfg)
-	//nodeManager := Manager(cfg, ctx)
-	defer GlobalManager.Close()
+	nodeManager, err := InitManager(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer nodeManager.Close()
 	wg := &sync.WaitGroup{}
 	wg.Add(2)
-	go load(wg, GlobalManager)
-	store(wg, GlobalManager)
+	go load(wg, nodeManager)
+	store(wg, nodeManager)
 	wg.Done()
 }
+
+func TestManager_VerifyNetwork(t *testing.T) {
+	const dsID = "QmfQkD8pBSBCBxWEwFSu4XaDVSWK6bjnNuaWZjMyQbyDub"
+	local := core.DefaultLocalData().Safe()
+	local.Update(func(data *core.LocalData) {
+		data.Node.DataStore.Network = "private"
+	})
+	var swarm error
+	m := &manager{
+		local: local,
+		addrCB: func(info peer.AddrInfo) error {
+			return swarm
+		},
+	}
+	info := core.NodeInfo{}
+	info.DataStore = core.DataStoreInfo{
+		ID:        dsID,
+		Addresses: []string{"/ip4/127.0.0.1/tcp/4001/p2p/" + dsID},
+		Network:   "public",
+	}
+	if err := m.verifyNetwork(info); !errors.Is(err, ErrNetworkMismatch) {
+		t.Fatalf("node of another network accepted: %v", err)
+	}
+	//the reported fingerprint is not enough without the pnet connection
+	info.DataStore.Network = "private"
+	swarm = errors.New("pnet handshake failed")
+	if err := m.verifyNetwork(info); !errors.Is(err, ErrNetworkUnproven) {
+		t.Fatalf("node not connected by the swarm accepted: %v", err)
+	}
+	swarm = nil
+	if err := m.verifyNetwork(info); err != nil {
+		t.Fatal(err)
+	}
+	//an address of another datastore does not prove the network
+	info.DataStore.ID = "QmQmzWRRsEsxjuEcq4zySnqzwa7wkunWnQhVYDBzp8dvNh"
+	if err := m.verifyNetwork(info); !errors.Is(err, ErrNetworkUnproven) {
+		t.Fatalf("node with a foreign datastore address accepted: %v", err)
+	}
+}
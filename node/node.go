@@ -5,6 +5,7 @@ import (
 	"errors"
 	"fmt"
 	"github.com/glvd/accipfs/core"
+	"github.com/glvd/accipfs/stats"
 	"github.com/godcong/scdt"
 	"github.com/libp2p/go-libp2p-core/peer"
 	ma "github.com/multiformats/go-multiaddr"
@@ -27,6 +28,7 @@ const (
 
 type node struct {
 	scdt.Connection
+	meter          *stats.Conn
 	local          core.SafeLocalData
 	remoteID       *atomic.String
 	remote         peer.AddrInfo
@@ -78,6 +80,18 @@ var _ core.Node = &node{}
 // ErrNoData ...
 var ErrNoData = errors.New("no data respond")
 
+// ErrRequestLimited ...
+var ErrRequestLimited = errors.New("request is limited")
+
+// ErrNetworkMismatch ...
+var ErrNetworkMismatch = errors.New("datastore is not in the same private network")
+
+// ErrNetworkUnproven ...
+var ErrNetworkUnproven = errors.New("datastore is not connected through the private network")
+
+// ErrNoSwarm ...
+var ErrNoSwarm = errors.New("datastore swarm is not reachable")
+
 // SendClose ...
 func (n *node) SendClose() {
 	n.Connection.SendClose([]byte("connected"))
@@ -170,8 +184,8 @@ func (n *node) Verify() bool {
 }
 
 // CoreNode ...
-func CoreNode(conn net.Conn, local core.SafeLocalData) (core.Node, error) {
-	n := defaultAPINode(conn, local, 30*time.Second)
+func CoreNode(conn net.Conn, local core.SafeLocalData, recorder core.BandwidthRecorder, limiter core.RequestLimiter) (core.Node, error) {
+	n := defaultAPINode(conn, local, recorder, limiter, 30*time.Second)
 	netAddr, err := mnet.FromNetAddr(conn.RemoteAddr())
 	if err != nil {
 		return nil, err
@@ -223,7 +237,7 @@ func MultiDial(addr ma.Multiaddr, bind int) (net.Conn, error) {
 }
 
 // ConnectNode ...
-func ConnectNode(addr ma.Multiaddr, bind int, local core.SafeLocalData) (core.Node, error) {
+func ConnectNode(addr ma.Multiaddr, bind int, local core.SafeLocalData, recorder core.BandwidthRecorder, limiter core.RequestLimiter) (core.Node, error) {
 	localAddr, err := ma.NewMultiaddr(fmt.Sprintf("/ip4/0.0.0.0/tcp/%d", bind))
 	if err != nil {
 		return nil, err
@@ -238,13 +252,14 @@ func ConnectNode(addr ma.Multiaddr, bind int, local core.SafeLocalData) (core.No
 	if err != nil {
 		return nil, err
 	}
-	n := defaultAPINode(conn, local, 0)
+	n := defaultAPINode(conn, local, recorder, limiter, 0)
 	n.AppendAddr(addr)
 	return n, nil
 }
 
-func defaultAPINode(c net.Conn, local core.SafeLocalData, duration time.Duration) *node {
-	conn := scdt.Connect(c, func(c *scdt.Config) {
+func defaultAPINode(c net.Conn, local core.SafeLocalData, recorder core.BandwidthRecorder, limiter core.RequestLimiter, duration time.Duration) *node {
+	meter := stats.NewConn(c, recorder)
+	conn := scdt.Connect(meter, func(c *scdt.Config) {
 		c.Timeout = duration
 		c.CustomIDer = func() string {
 			return local.Data().Node.ID
@@ -252,6 +267,7 @@ func defaultAPINode(c net.Conn, local core.SafeLocalData, duration time.Duration
 	})
 	n := &node{
 		local:      local,
+		meter:      meter,
 		Connection: conn,
 	}
 
@@ -262,6 +278,9 @@ func defaultAPINode(c net.Conn, local core.SafeLocalData, duration time.Duration
 
 	conn.RecvCustomData(func(message *scdt.Message) ([]byte, bool, error) {
 		//fmt.Printf("recv custom data:%+v\n", message)
+		if limiter != nil && message.CustomID != AlreadyConnectedRequest && !limiter.AllowRequest(n.ID(), remoteIP(c)) {
+			return nil, true, ErrRequestLimited
+		}
 		switch message.CustomID {
 		case InfoRequest:
 			request, b, err := n.RecvInfoRequest(message)
@@ -281,6 +300,14 @@ func defaultAPINode(c net.Conn, local core.SafeLocalData, duration time.Duration
 	return n
 }
 
+func remoteIP(c net.Conn) string {
+	host, _, err := net.SplitHostPort(c.RemoteAddr().String())
+	if err != nil {
+		return ""
+	}
+	return host
+}
+
 // AppendAddr ...
 func (n *node) AppendAddr(addrs ...ma.Multiaddr) {
 	if addrs != nil {
@@ -309,6 +336,7 @@ func (n *node) ID() string {
 		return ""
 	}
 	n.remoteID = atomic.NewString(id)
+	n.meter.SetPeer(id)
 	return id
 }
 
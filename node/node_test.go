@@ -73,11 +73,10 @@ func TestConnectNode(t *testing.T) {
 		wg.Add(1)
 		go func(i int) {
 			defer wg.Done()
-			toNode, err := ConnectNode(multiaddr, 0, core.NodeInfo{}, &dummyAPI{
-				id: fmt.Sprintf("id(%v),client request", 0),
-			})
+			toNode, err := ConnectNode(multiaddr, 0, core.DefaultLocalData().Safe(), nil, nil)
 			if err != nil {
-				t.Fatal(err)
+				t.Error(err)
+				return
 			}
 			j := 0
 			for ; j < 10; j++ {
@@ -0,0 +1,156 @@
+package payment
+
+import (
+	"encoding/json"
+	"errors"
+	"math/big"
+
+	"github.com/dgraph-io/badger/v2"
+	"github.com/glvd/accipfs/basis/kv"
+)
+
+// mebibyte is the metering unit of the price
+const mebibyte = 1 << 20
+
+// ErrNotFound ...
+var ErrNotFound = errors.New("payment entry not found")
+
+// Entry ...
+type Entry struct {
+	Peer       string   `json:"peer"`
+	Address    string   `json:"address"`
+	Bytes      uint64   `json:"bytes"`
+	Paid       *big.Int `json:"paid"`
+	Pending    *big.Int `json:"pending,omitempty"` //part of paid sent without a confirmed transfer
+	LastSettle int64    `json:"last_settle"`
+}
+
+// Ledger ...
+type Ledger struct {
+	db *badger.DB
+}
+
+// Marshal ...
+func (e Entry) Marshal() ([]byte, error) {
+	return json.Marshal(e)
+}
+
+// Unmarshal ...
+func (e *Entry) Unmarshal(b []byte) error {
+	return json.Unmarshal(b, e)
+}
+
+// Cost returns the token cost of the metered bytes, started MiB is charged as a whole
+func (e Entry) Cost(price *big.Int) *big.Int {
+	mib := new(big.Int).SetUint64((e.Bytes + mebibyte - 1) / mebibyte)
+	return mib.Mul(mib, price)
+}
+
+// Balance returns paid minus cost, negative means the peer should be paid
+func (e Entry) Balance(price *big.Int) *big.Int {
+	paid := e.Paid
+	if paid == nil {
+		paid = new(big.Int)
+	}
+	return new(big.Int).Sub(paid, e.Cost(price))
+}
+
+// OpenLedger ...
+func OpenLedger(path string) (*Ledger, error) {
+	db, err := kv.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	return &Ledger{db: db}, nil
+}
+
+// Load ...
+func (l *Ledger) Load(peer string) (*Entry, error) {
+	var e Entry
+	err := l.db.View(func(txn *badger.Txn) error {
+		item, err := txn.Get([]byte(peer))
+		if err != nil {
+			if err == badger.ErrKeyNotFound {
+				return ErrNotFound
+			}
+			return err
+		}
+		return item.Value(func(val []byte) error {
+			return e.Unmarshal(val)
+		})
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &e, nil
+}
+
+// Update load the entry of peer (create a new one when not found) and store it after fn
+func (l *Ledger) Update(peer string, fn func(e *Entry) error) error {
+	return l.db.Update(func(txn *badger.Txn) error {
+		e := &Entry{
+			Peer: peer,
+			Paid: new(big.Int),
+		}
+		item, err := txn.Get([]byte(peer))
+		switch err {
+		case nil:
+			err = item.Value(func(val []byte) error {
+				return e.Unmarshal(val)
+			})
+			if err != nil {
+				return err
+			}
+		case badger.ErrKeyNotFound:
+		default:
+			return err
+		}
+		if err := fn(e); err != nil {
+			return err
+		}
+		encode, err := e.Marshal()
+		if err != nil {
+			return err
+		}
+		return txn.Set([]byte(peer), encode)
+	})
+}
+
+// Delete ...
+func (l *Ledger) Delete(peer string) error {
+	return l.db.Update(func(txn *badger.Txn) error {
+		return txn.Delete([]byte(peer))
+	})
+}
+
+// Range ...
+func (l *Ledger) Range(f func(e *Entry) bool) error {
+	return l.db.View(func(txn *badger.Txn) error {
+		iter := txn.NewIterator(badger.DefaultIteratorOptions)
+		defer iter.Close()
+		for iter.Rewind(); iter.Valid(); iter.Next() {
+			var e Entry
+			err := iter.Item().Value(func(val []byte) error {
+				return e.Unmarshal(val)
+			})
+			if err != nil {
+				return err
+			}
+			if !f(&e) {
+				return nil
+			}
+		}
+		return nil
+	})
+}
+
+// Close ...
+func (l *Ledger) Close() error {
+	if l.db != nil {
+		defer func() {
+			l.db = nil
+		}()
+		return l.db.Close()
+	}
+	return nil
+}
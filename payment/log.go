@@ -0,0 +1,9 @@
+package payment
+
+import (
+	alog "github.com/glvd/accipfs/log"
+)
+
+const module = "payment"
+
+var log = alog.Module(module)
@@ -0,0 +1,103 @@
+package payment
+
+import (
+	"context"
+	"errors"
+	"math/big"
+
+	"github.com/ethereum/go-ethereum/accounts/abi/bind"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/glvd/accipfs/contract"
+	"github.com/glvd/accipfs/contract/token"
+)
+
+// ErrTransactionFailed ...
+var ErrTransactionFailed = errors.New("token transaction failed")
+
+// Payer ...
+type Payer interface {
+	Address() common.Address
+	Transfer(ctx context.Context, to common.Address, amount *big.Int) error
+	BalanceOf(ctx context.Context, addr common.Address) (*big.Int, error)
+}
+
+type tokenPayer struct {
+	token   *token.DhToken
+	backend bind.DeployBackend
+	opts    *bind.TransactOpts
+}
+
+type contractPayer struct {
+	c contract.Contractor
+}
+
+// NewTokenPayer create a payer with a bound DhToken and waits the transfer be mined on backend
+func NewTokenPayer(t *token.DhToken, backend bind.DeployBackend, opts *bind.TransactOpts) Payer {
+	return &tokenPayer{
+		token:   t,
+		backend: backend,
+		opts:    opts,
+	}
+}
+
+// Address ...
+func (p *tokenPayer) Address() common.Address {
+	return p.opts.From
+}
+
+// Transfer ...
+func (p *tokenPayer) Transfer(ctx context.Context, to common.Address, amount *big.Int) error {
+	opts := *p.opts
+	opts.Context = ctx
+	tx, err := p.token.Transfer(&opts, to, amount)
+	if err != nil {
+		return err
+	}
+	receipt, err := bind.WaitMined(ctx, p.backend, tx)
+	if err != nil {
+		return err
+	}
+	if receipt.Status != types.ReceiptStatusSuccessful {
+		return ErrTransactionFailed
+	}
+	return nil
+}
+
+// BalanceOf ...
+func (p *tokenPayer) BalanceOf(ctx context.Context, addr common.Address) (*big.Int, error) {
+	return p.token.BalanceOf(&bind.CallOpts{
+		Pending: true,
+		From:    p.opts.From,
+		Context: ctx,
+	}, addr)
+}
+
+// NewContractPayer create a payer which sends the transfer through the contract loader
+func NewContractPayer(c contract.Contractor) Payer {
+	return &contractPayer{
+		c: c,
+	}
+}
+
+// Address ...
+func (p *contractPayer) Address() common.Address {
+	return p.c.Address()
+}
+
+// Transfer ...
+func (p *contractPayer) Transfer(ctx context.Context, to common.Address, amount *big.Int) error {
+	_, err := p.c.TokenTransact(ctx, func(t *token.DhToken, opts *bind.TransactOpts) (*types.Transaction, error) {
+		return t.Transfer(opts, to, amount)
+	})
+	return err
+}
+
+// BalanceOf ...
+func (p *contractPayer) BalanceOf(ctx context.Context, addr common.Address) (balance *big.Int, err error) {
+	err = p.c.Token(ctx, func(t *token.DhTokenCaller, opts *bind.CallOpts) error {
+		balance, err = t.BalanceOf(opts, addr)
+		return err
+	})
+	return
+}
@@ -0,0 +1,235 @@
+package payment
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/big"
+	"sync"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/glvd/accipfs/core"
+)
+
+// DefaultSettleInterval is used when the settle interval is not set
+const DefaultSettleInterval = 10 * time.Minute
+
+// ErrWrongAddress ...
+var ErrWrongAddress = errors.New("wrong payee address")
+
+// ErrPending is returned when a transfer to the peer is not recorded, the peer is not paid again until it is cleared in the ledger
+var ErrPending = errors.New("payment transfer is pending")
+
+// Settler meters the bytes served by the paid peers and settles them with the token
+type Settler struct {
+	lock   sync.Mutex
+	settle sync.Mutex //one settle at once
+	ledger *Ledger
+	payer  Payer
+	price  *big.Int
+	last   map[string]uint64 //last observed totals of the bandwidth reporter
+}
+
+// NewSettler ...
+func NewSettler(ledger *Ledger, payer Payer, price int64) *Settler {
+	return &Settler{
+		ledger: ledger,
+		payer:  payer,
+		price:  big.NewInt(price),
+		last:   make(map[string]uint64),
+	}
+}
+
+// Prepay transfer amount to the peer address at once and record it to the ledger
+func (s *Settler) Prepay(ctx context.Context, peer string, address string, amount *big.Int) error {
+	if !common.IsHexAddress(address) {
+		return ErrWrongAddress
+	}
+	err := s.pay(ctx, peer, func(e *Entry) (*big.Int, error) {
+		e.Address = address
+		return amount, nil
+	})
+	if err != nil {
+		return fmt.Errorf("prepay failed(%w)", err)
+	}
+	return nil
+}
+
+// pay records the amount returned by due as paid and pending, transfers it without the lock and clears
+// the pending amount, so a transfer is never sent twice for the same due. The record is reverted when the
+// transfer fails and kept pending when the result is not recorded, nothing is paid to the peer until it is cleared.
+func (s *Settler) pay(ctx context.Context, peer string, due func(e *Entry) (*big.Int, error)) error {
+	var address string
+	var amount *big.Int
+	created := false
+	s.lock.Lock()
+	err := s.ledger.Update(peer, func(e *Entry) error {
+		if e.Pending != nil && e.Pending.Sign() > 0 {
+			return fmt.Errorf("%w(%s)", ErrPending, e.Pending)
+		}
+		created = e.Address == ""
+		a, err := due(e)
+		if err != nil {
+			return err
+		}
+		address, amount = e.Address, a
+		if amount.Sign() > 0 {
+			e.Paid.Add(e.Paid, amount)
+			e.Pending = new(big.Int).Set(amount)
+		}
+		return nil
+	})
+	s.lock.Unlock()
+	if err != nil || amount.Sign() <= 0 {
+		return err
+	}
+
+	terr := s.payer.Transfer(ctx, common.HexToAddress(address), amount)
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	if terr != nil && created {
+		if err := s.ledger.Delete(peer); err != nil {
+			log.Errorw("revert payment", "peer", peer, "amount", amount, "err", err)
+		}
+		return terr
+	}
+	err = s.ledger.Update(peer, func(e *Entry) error {
+		if terr != nil {
+			e.Paid.Sub(e.Paid, amount)
+		} else {
+			e.LastSettle = time.Now().Unix()
+		}
+		e.Pending = nil
+		return nil
+	})
+	if err != nil {
+		log.Errorw("record payment", "peer", peer, "amount", amount, "transferred", terr == nil, "err", err)
+	}
+	if terr != nil {
+		return terr
+	}
+	return err
+}
+
+// Meter add n served bytes to a paid peer, bytes of unknown peers are ignored
+func (s *Settler) Meter(peer string, n uint64) error {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	return s.meter(peer, n)
+}
+
+func (s *Settler) meter(peer string, n uint64) error {
+	if n == 0 {
+		return nil
+	}
+	if _, err := s.ledger.Load(peer); err != nil {
+		return err
+	}
+	return s.ledger.Update(peer, func(e *Entry) error {
+		e.Bytes += n
+		return nil
+	})
+}
+
+// Observe meters the difference between totals and the previous observed totals
+func (s *Settler) Observe(totals map[string]uint64) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	for peer, total := range totals {
+		last := s.last[peer]
+		s.last[peer] = total
+		if total < last {
+			//reporter was restarted
+			last = 0
+		}
+		err := s.meter(peer, total-last)
+		if err != nil && err != ErrNotFound {
+			log.Errorw("meter failed", "peer", peer, "err", err)
+		}
+	}
+}
+
+// Settle transfer the due token to every peer which served more than paid,
+// the transfers are sent without holding the lock so the metering goes on
+func (s *Settler) Settle(ctx context.Context) (err error) {
+	s.settle.Lock()
+	defer s.settle.Unlock()
+	var peers []string
+	s.lock.Lock()
+	err = s.ledger.Range(func(e *Entry) bool {
+		if new(big.Int).Neg(e.Balance(s.price)).Sign() > 0 {
+			peers = append(peers, e.Peer)
+		}
+		return true
+	})
+	s.lock.Unlock()
+	if err != nil {
+		return err
+	}
+	for _, peer := range peers {
+		perr := s.pay(ctx, peer, func(e *Entry) (*big.Int, error) {
+			//the due is taken again as it was metered after the snapshot
+			return new(big.Int).Neg(e.Balance(s.price)), nil
+		})
+		if perr != nil {
+			log.Errorw("settle failed", "peer", peer, "err", perr)
+			err = perr
+		}
+	}
+	return err
+}
+
+// Balances ...
+func (s *Settler) Balances(ctx context.Context, peer string) (*core.PayResp, error) {
+	resp := &core.PayResp{
+		Account: s.payer.Address().Hex(),
+	}
+	balance, err := s.payer.BalanceOf(ctx, s.payer.Address())
+	if err != nil {
+		log.Warnw("get token balance failed", "err", err)
+	} else {
+		resp.Token = balance.String()
+	}
+	err = s.ledger.Range(func(e *Entry) bool {
+		if peer != "" && e.Peer != peer {
+			return true
+		}
+		resp.Balances = append(resp.Balances, core.PayBalance{
+			Peer:       e.Peer,
+			Address:    e.Address,
+			Bytes:      e.Bytes,
+			Paid:       e.Paid.String(),
+			Balance:    e.Balance(s.price).String(),
+			LastSettle: e.LastSettle,
+		})
+		return true
+	})
+	if err != nil {
+		return nil, err
+	}
+	return resp, nil
+}
+
+// Run settle the ledger every interval until ctx is done, the bandwidth totals are observed before settle,
+// DefaultSettleInterval is used when interval is not positive
+func (s *Settler) Run(ctx context.Context, interval time.Duration, totals func() map[string]uint64) {
+	if interval <= 0 {
+		interval = DefaultSettleInterval
+	}
+	t := time.NewTicker(interval)
+	defer t.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-t.C:
+			if totals != nil {
+				s.Observe(totals())
+			}
+			if err := s.Settle(ctx); err != nil {
+				log.Errorw("settle payment", "err", err)
+			}
+		}
+	}
+}
@@ -0,0 +1,237 @@
+package payment
+
+import (
+	"context"
+	"errors"
+	"io/ioutil"
+	"math/big"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/accounts/abi/bind"
+	"github.com/ethereum/go-ethereum/accounts/abi/bind/backends"
+	"github.com/ethereum/go-ethereum/common"
+	ethcore "github.com/ethereum/go-ethereum/core"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/glvd/accipfs/contract/token"
+)
+
+// testTokenABI and testTokenBin is the ethereum.org sample token, it is ERC20 compatible with DhToken
+const testTokenABI = `[{"constant":true,"inputs":[],"name":"name","outputs":[{"name":"","type":"string"}],"type":"function"},{"constant":false,"inputs":[{"name":"_from","type":"address"},{"name":"_to","type":"address"},{"name":"_value","type":"uint256"}],"name":"transferFrom","outputs":[{"name":"success","type":"bool"}],"type":"function"},{"constant":true,"inputs":[],"name":"decimals","outputs":[{"name":"","type":"uint8"}],"type":"function"},{"constant":true,"inputs":[{"name":"","type":"address"}],"name":"balanceOf","outputs":[{"name":"","type":"uint256"}],"type":"function"},{"constant":true,"inputs":[],"name":"symbol","outputs":[{"name":"","type":"string"}],"type":"function"},{"constant":false,"inputs":[{"name":"_to","type":"address"},{"name":"_value","type":"uint256"}],"name":"transfer","outputs":[],"type":"function"},{"constant":false,"inputs":[{"name":"_spender","type":"address"},{"name":"_value","type":"uint256"},{"name":"_extraData","type":"bytes"}],"name":"approveAndCall","outputs":[{"name":"success","type":"bool"}],"type":"function"},{"constant":true,"inputs":[{"name":"","type":"address"},{"name":"","type":"address"}],"name":"spentAllowance","outputs":[{"name":"","type":"uint256"}],"type":"function"},{"constant":true,"inputs":[{"name":"","type":"address"},{"name":"","type":"address"}],"name":"allowance","outputs":[{"name":"","type":"uint256"}],"type":"function"},{"inputs":[{"name":"initialSupply","type":"uint256"},{"name":"tokenName","type":"string"},{"name":"decimalUnits","type":"uint8"},{"name":"tokenSymbol","type":"string"}],"type":"constructor"},{"anonymous":false,"inputs":[{"indexed":true,"name":"from","type":"address"},{"indexed":true,"name":"to","type":"address"},{"indexed":false,"name":"value","type":"uint256"}],"name":"Transfer","type":"event"}]`
+
+const testTokenBin = `60606040526040516107fd3803806107fd83398101604052805160805160a05160c051929391820192909101600160a060020a0333166000908152600360209081526040822086905581548551838052601f6002600019610100600186161502019093169290920482018390047f290decd9548b62a8d60345a988386fc84ba6bc95484008f6362f93160ef3e56390810193919290918801908390106100e857805160ff19168380011785555b506101189291505b8082111561017157600081556001016100b4565b50506002805460ff19168317905550505050610658806101a56000396000f35b828001600101855582156100ac579182015b828111156100ac5782518260005055916020019190600101906100fa565b50508060016000509080519060200190828054600181600116156101000203166002900490600052602060002090601f016020900481019282601f1061017557805160ff19168380011785555b506100c89291506100b4565b5090565b82800160010185558215610165579182015b8281111561016557825182600050559160200191906001019061018756606060405236156100775760e060020a600035046306fdde03811461007f57806323b872dd146100dc578063313ce5671461010e57806370a082311461011a57806395d89b4114610132578063a9059cbb1461018e578063cae9ca51146101bd578063dc3080f21461031c578063dd62ed3e14610341575b610365610002565b61036760008054602060026001831615610100026000190190921691909104601f810182900490910260809081016040526060828152929190828280156104eb5780601f106104c0576101008083540402835291602001916104eb565b6103d5600435602435604435600160a060020a038316600090815260036020526040812054829010156104f357610002565b6103e760025460ff1681565b6103d560043560036020526000908152604090205481565b610367600180546020600282841615610100026000190190921691909104601f810182900490910260809081016040526060828152929190828280156104eb5780601f106104c0576101008083540402835291602001916104eb565b610365600435602435600160a060020a033316600090815260036020526040902054819010156103f157610002565b60806020604435600481810135601f8101849004909302840160405260608381526103d5948235946024803595606494939101919081908382808284375094965050505050505060006000836004600050600033600160a060020a03168152602001908152602001600020600050600087600160a060020a031681526020019081526020016000206000508190555084905080600160a060020a0316638f4ffcb1338630876040518560e060020a0281526004018085600160a060020a0316815260200184815260200183600160a060020a03168152602001806020018281038252838181518152602001915080519060200190808383829060006004602084601f0104600f02600301f150905090810190601f1680156102f25780820380516001836020036101000a031916815260200191505b50955050505050506000604051808303816000876161da5a03f11561000257505050509392505050565b6005602090815260043560009081526040808220909252602435815220546103d59081565b60046020818152903560009081526040808220909252602435815220546103d59081565b005b60405180806020018281038252838181518152602001915080519060200190808383829060006004602084601f0104600f02600301f150905090810190601f1680156103c75780820380516001836020036101000a031916815260200191505b509250505060405180910390f35b60408051918252519081900360200190f35b6060908152602090f35b600160a060020a03821660009081526040902054808201101561041357610002565b806003600050600033600160a060020a03168152602001908152602001600020600082828250540392505081905550806003600050600084600160a060020a0316815260200190815260200160002060008282825054019250508190555081600160a060020a031633600160a060020a03167fddf252ad1be2c89b69c2b068fc378daa952ba7f163c4a11628f55a4df523b3ef836040518082815260200191505060405180910390a35050565b820191906000526020600020905b8154815290600101906020018083116104ce57829003601f168201915b505050505081565b600160a060020a03831681526040812054808301101561051257610002565b600160a060020a0380851680835260046020908152604080852033949094168086529382528085205492855260058252808520938552929052908220548301111561055c57610002565b816003600050600086600160a060020a03168152602001908152602001600020600082828250540392505081905550816003600050600085600160a060020a03168152602001908152602001600020600082828250540192505081905550816005600050600086600160a060020a03168152602001908152602001600020600050600033600160a060020a0316815260200190815260200160002060008282825054019250508190555082600160a060020a031633600160a060020a03167fddf252ad1be2c89b69c2b068fc378daa952ba7f163c4a11628f55a4df523b3ef846040518082815260200191505060405180910390a3939250505056`
+
+// simulatedPayer commit a block after every transfer
+type simulatedPayer struct {
+	Payer
+	backend *backends.SimulatedBackend
+}
+
+func (p *simulatedPayer) Transfer(ctx context.Context, to common.Address, amount *big.Int) error {
+	done := make(chan error)
+	go func() {
+		done <- p.Payer.Transfer(ctx, to, amount)
+	}()
+	for {
+		select {
+		case err := <-done:
+			return err
+		case <-time.After(10 * time.Millisecond):
+			p.backend.Commit()
+		}
+	}
+}
+
+func testSettler(t *testing.T, price int64) (*Settler, *simulatedPayer, func()) {
+	key, err := crypto.GenerateKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	auth := bind.NewKeyedTransactor(key)
+	backend := backends.NewSimulatedBackend(ethcore.GenesisAlloc{
+		auth.From: {Balance: big.NewInt(1000000000000000000)},
+	}, 8000000)
+
+	parsed, err := abi.JSON(strings.NewReader(testTokenABI))
+	if err != nil {
+		t.Fatal(err)
+	}
+	addr, _, _, err := bind.DeployContract(auth, parsed, common.FromHex(testTokenBin), backend, big.NewInt(1000000), "Token", uint8(0), "TK")
+	if err != nil {
+		t.Fatal(err)
+	}
+	backend.Commit()
+
+	dhToken, err := token.NewDhToken(addr, backend)
+	if err != nil {
+		t.Fatal(err)
+	}
+	payer := &simulatedPayer{
+		Payer:   NewTokenPayer(dhToken, backend, auth),
+		backend: backend,
+	}
+
+	dir, err := ioutil.TempDir("", "payment")
+	if err != nil {
+		t.Fatal(err)
+	}
+	ledger, err := OpenLedger(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return NewSettler(ledger, payer, price), payer, func() {
+		ledger.Close()
+		backend.Close()
+		os.RemoveAll(dir)
+	}
+}
+
+func TestSettler_Settle(t *testing.T) {
+	settler, payer, closer := testSettler(t, 10)
+	defer closer()
+	ctx := context.Background()
+	payee := common.HexToAddress("0x945d35cd4a6549213e8d37feb5d708ec98906902")
+
+	err := settler.Prepay(ctx, "peer1", payee.Hex(), big.NewInt(100))
+	if err != nil {
+		t.Fatal(err)
+	}
+	balance, err := payer.BalanceOf(ctx, payee)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if balance.Int64() != 100 {
+		t.Fatalf("prepay balance got %v, want 100", balance)
+	}
+
+	//unpaid peer is not metered
+	settler.Observe(map[string]uint64{"peer1": 5 * mebibyte, "peer2": mebibyte})
+	//5MiB is covered by the prepay
+	if err := settler.Settle(ctx); err != nil {
+		t.Fatal(err)
+	}
+	balance, _ = payer.BalanceOf(ctx, payee)
+	if balance.Int64() != 100 {
+		t.Fatalf("settle balance got %v, want 100", balance)
+	}
+
+	settler.Observe(map[string]uint64{"peer1": 15*mebibyte + 1})
+	if err := settler.Settle(ctx); err != nil {
+		t.Fatal(err)
+	}
+	balance, _ = payer.BalanceOf(ctx, payee)
+	if balance.Int64() != 160 {
+		t.Fatalf("settle balance got %v, want 160", balance)
+	}
+
+	resp, err := settler.Balances(ctx, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(resp.Balances) != 1 || resp.Balances[0].Balance != "0" || resp.Token != "999840" {
+		t.Fatalf("wrong balances %+v", resp)
+	}
+}
+
+func TestSettler_Prepay(t *testing.T) {
+	settler, _, closer := testSettler(t, 1)
+	defer closer()
+	err := settler.Prepay(context.Background(), "peer1", "wrong", big.NewInt(1))
+	if err != ErrWrongAddress {
+		t.Fatalf("got %v, want %v", err, ErrWrongAddress)
+	}
+	err = settler.Prepay(context.Background(), "peer1", "0x945d35cd4a6549213e8d37feb5d708ec98906902", big.NewInt(2000000))
+	if err == nil {
+		t.Fatal("prepay more than the token balance should be failed")
+	}
+}
+
+func TestSettler_RunZeroInterval(t *testing.T) {
+	settler, _, closer := testSettler(t, 1)
+	defer closer()
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	settler.Run(ctx, 0, nil)
+}
+
+// blockingPayer holds the transfers until release is closed
+type blockingPayer struct {
+	Payer
+	started chan struct{}
+	release chan struct{}
+	sent    int
+}
+
+func (p *blockingPayer) Transfer(ctx context.Context, to common.Address, amount *big.Int) error {
+	p.sent++
+	p.started <- struct{}{}
+	<-p.release
+	return nil
+}
+
+func TestSettler_SettleUnlocked(t *testing.T) {
+	settler, payer, closer := testSettler(t, 10)
+	defer closer()
+	ctx := context.Background()
+	blocking := &blockingPayer{Payer: payer, started: make(chan struct{}, 1), release: make(chan struct{})}
+	settler.payer = blocking
+
+	err := settler.ledger.Update("peer1", func(e *Entry) error {
+		e.Address = "0x945d35cd4a6549213e8d37feb5d708ec98906902"
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	settler.Observe(map[string]uint64{"peer1": mebibyte})
+	done := make(chan error)
+	go func() {
+		done <- settler.Settle(ctx)
+	}()
+	<-blocking.started
+
+	//metering goes on during the transfer and the due is recorded as pending
+	observed := make(chan struct{})
+	go func() {
+		settler.Observe(map[string]uint64{"peer1": 3 * mebibyte})
+		close(observed)
+	}()
+	select {
+	case <-observed:
+	case <-time.After(time.Second):
+		t.Fatal("observe blocked by the transfer")
+	}
+	e, err := settler.ledger.Load("peer1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if e.Paid.Int64() != 10 || e.Pending.Int64() != 10 {
+		t.Fatalf("paid %v pending %v during the transfer", e.Paid, e.Pending)
+	}
+	close(blocking.release)
+	if err := <-done; err != nil {
+		t.Fatal(err)
+	}
+	e, _ = settler.ledger.Load("peer1")
+	if e.Paid.Int64() != 10 || e.Pending != nil || e.LastSettle == 0 {
+		t.Fatalf("paid %v pending %v after the transfer", e.Paid, e.Pending)
+	}
+
+	//an unconfirmed transfer is not sent again
+	err = settler.ledger.Update("peer1", func(e *Entry) error {
+		e.Pending = big.NewInt(20)
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := settler.Settle(ctx); !errors.Is(err, ErrPending) {
+		t.Fatalf("settle with a pending transfer: %v", err)
+	}
+	if blocking.sent != 1 {
+		t.Fatalf("sent %d transfers", blocking.sent)
+	}
+}
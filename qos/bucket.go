@@ -0,0 +1,119 @@
+package qos
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// Bucket is a token bucket refilled with rate tokens per second up to burst
+type Bucket struct {
+	lock   sync.Mutex
+	rate   float64
+	burst  float64
+	tokens float64
+	last   time.Time
+}
+
+// NewBucket create a full bucket, burst is set to rate when it is not positive
+func NewBucket(rate, burst int64) *Bucket {
+	if burst <= 0 {
+		burst = rate
+	}
+	return &Bucket{
+		rate:   float64(rate),
+		burst:  float64(burst),
+		tokens: float64(burst),
+		last:   time.Now(),
+	}
+}
+
+// SetRate changes the rate and the burst, the tokens over the new burst are dropped
+func (b *Bucket) SetRate(rate, burst int64) {
+	if burst <= 0 {
+		burst = rate
+	}
+	b.lock.Lock()
+	defer b.lock.Unlock()
+	b.advance(time.Now())
+	b.rate = float64(rate)
+	b.burst = float64(burst)
+	if b.tokens > b.burst {
+		b.tokens = b.burst
+	}
+}
+
+// Burst ...
+func (b *Bucket) Burst() int64 {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+	return int64(b.burst)
+}
+
+func (b *Bucket) advance(now time.Time) {
+	if elapsed := now.Sub(b.last); elapsed > 0 {
+		b.tokens += elapsed.Seconds() * b.rate
+		if b.tokens > b.burst {
+			b.tokens = b.burst
+		}
+		b.last = now
+	}
+}
+
+// Full returns true when the bucket was not used for a whole refill
+func (b *Bucket) Full(now time.Time) bool {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+	b.advance(now)
+	return b.tokens >= b.burst
+}
+
+// Allow takes n tokens when the bucket has enough
+func (b *Bucket) Allow(n int64) bool {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+	b.advance(time.Now())
+	if b.tokens < float64(n) {
+		return false
+	}
+	b.tokens -= float64(n)
+	return true
+}
+
+// reserve takes n tokens and returns the duration to wait until they are refilled
+func (b *Bucket) reserve(n int64) time.Duration {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+	b.advance(time.Now())
+	b.tokens -= float64(n)
+	if b.tokens >= 0 {
+		return 0
+	}
+	return time.Duration(-b.tokens / b.rate * float64(time.Second))
+}
+
+func (b *Bucket) cancel(n int64) {
+	b.lock.Lock()
+	b.tokens += float64(n)
+	if b.tokens > b.burst {
+		b.tokens = b.burst
+	}
+	b.lock.Unlock()
+}
+
+// Wait blocks until n tokens are taken or ctx is done, the waited duration is returned
+func (b *Bucket) Wait(ctx context.Context, n int64) (time.Duration, error) {
+	d := b.reserve(n)
+	if d == 0 {
+		return 0, nil
+	}
+	t := time.NewTimer(d)
+	defer t.Stop()
+	select {
+	case <-ctx.Done():
+		b.cancel(n)
+		return 0, ctx.Err()
+	case <-t.C:
+		return d, nil
+	}
+}
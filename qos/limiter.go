@@ -0,0 +1,283 @@
+package qos
+
+import (
+	"context"
+	"io"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/glvd/accipfs/config"
+	"github.com/glvd/accipfs/core"
+	"go.uber.org/atomic"
+)
+
+// Scope ...
+type Scope string
+
+const (
+	// ScopeGlobal ...
+	ScopeGlobal Scope = "global"
+	// ScopeToken ...
+	ScopeToken Scope = "token"
+	// ScopeIP ...
+	ScopeIP Scope = "ip"
+	// ScopePeer ...
+	ScopePeer Scope = "peer"
+)
+
+// DefaultClass is the priority class of the tokens and peers not configured
+const DefaultClass = "free"
+
+// maxChunk is the max bytes written once by the limited writer
+const maxChunk = 32 << 10
+
+// maxBuckets is the bucket count which triggers the idle bucket cleanup
+const maxBuckets = 4096
+
+// Keys identify the caller of a get stream
+type Keys struct {
+	Token string
+	IP    string
+}
+
+// classBucket is a bucket with the class its rate is made of
+type classBucket struct {
+	*Bucket
+	class string
+}
+
+type metric struct {
+	throttled *atomic.Uint64
+	rejected  *atomic.Uint64
+	wait      *atomic.Int64
+}
+
+// Limiter limits the get streams and node requests with token buckets
+type Limiter struct {
+	cfg        config.QoSConfig
+	global     *Bucket
+	lock       sync.Mutex
+	buckets    map[Scope]map[string]*classBucket
+	metrics    map[Scope]*metric
+	classifier func(peer string) string
+}
+
+var _ core.RequestLimiter = &Limiter{}
+
+// NewLimiter ...
+func NewLimiter(cfg config.QoSConfig) *Limiter {
+	l := &Limiter{
+		cfg:     cfg,
+		buckets: make(map[Scope]map[string]*classBucket),
+		metrics: make(map[Scope]*metric),
+	}
+	if cfg.Global.Rate > 0 {
+		l.global = NewBucket(cfg.Global.Rate, cfg.Global.Burst)
+	}
+	for _, s := range []Scope{ScopeGlobal, ScopeToken, ScopeIP, ScopePeer} {
+		l.buckets[s] = make(map[string]*classBucket)
+		l.metrics[s] = &metric{
+			throttled: atomic.NewUint64(0),
+			rejected:  atomic.NewUint64(0),
+			wait:      atomic.NewInt64(0),
+		}
+	}
+	return l
+}
+
+// SetPeerClassifier set the class func used for the peers not configured in the peers list
+func (l *Limiter) SetPeerClassifier(f func(peer string) string) {
+	l.lock.Lock()
+	l.classifier = f
+	l.lock.Unlock()
+}
+
+func (l *Limiter) rateConfig(s Scope) config.RateConfig {
+	switch s {
+	case ScopeToken:
+		return l.cfg.Token
+	case ScopeIP:
+		return l.cfg.IP
+	case ScopePeer:
+		return l.cfg.Peer
+	}
+	return l.cfg.Global
+}
+
+// class returns the priority class of the key in scope, the classifier is called without the lock
+func (l *Limiter) class(s Scope, key string, classifier func(peer string) string) string {
+	switch s {
+	case ScopeToken:
+		if c, b := l.cfg.Tokens[key]; b {
+			return c
+		}
+	case ScopePeer:
+		if c, b := l.cfg.Peers[key]; b {
+			return c
+		}
+		if classifier != nil {
+			if c := classifier(key); c != "" {
+				return c
+			}
+		}
+	}
+	return DefaultClass
+}
+
+// bucket returns the bucket of key in scope, nil is returned when the scope is unlimited,
+// the class of the key is evaluated on every call so the bucket follows the changed class at once
+func (l *Limiter) bucket(s Scope, key string) *Bucket {
+	if s == ScopeGlobal {
+		return l.global
+	}
+	rc := l.rateConfig(s)
+	if rc.Rate <= 0 || key == "" {
+		return nil
+	}
+	l.lock.Lock()
+	classifier := l.classifier
+	l.lock.Unlock()
+	class := l.class(s, key, classifier)
+	l.lock.Lock()
+	defer l.lock.Unlock()
+	rate, burst := l.rate(rc, class)
+	buckets := l.buckets[s]
+	if b, ok := buckets[key]; ok {
+		if b.class != class {
+			b.SetRate(rate, burst)
+			b.class = class
+		}
+		return b.Bucket
+	}
+	if len(buckets) >= maxBuckets {
+		now := time.Now()
+		for k, b := range buckets {
+			if b.Full(now) {
+				delete(buckets, k)
+			}
+		}
+	}
+	b := &classBucket{
+		Bucket: NewBucket(rate, burst),
+		class:  class,
+	}
+	buckets[key] = b
+	return b.Bucket
+}
+
+// rate returns the rate and the burst of the class
+func (l *Limiter) rate(rc config.RateConfig, class string) (int64, int64) {
+	multiple, ok := l.cfg.Classes[class]
+	if !ok || multiple <= 0 {
+		multiple = 1
+	}
+	burst := rc.Burst
+	if burst <= 0 {
+		burst = rc.Rate
+	}
+	return int64(float64(rc.Rate) * multiple), int64(float64(burst) * multiple)
+}
+
+// AllowRequest take a request token of the peer, the ip and the global buckets,
+// so a peer flooding the requests is limited with the get streams of its ip
+func (l *Limiter) AllowRequest(peer string, ip string) bool {
+	for _, k := range []struct {
+		scope Scope
+		key   string
+	}{{ScopePeer, peer}, {ScopeIP, ip}, {ScopeGlobal, ""}} {
+		b := l.bucket(k.scope, k.key)
+		if b == nil || b.Allow(1) {
+			continue
+		}
+		l.metrics[k.scope].rejected.Inc()
+		log.Debugw("request limited", "scope", k.scope, "peer", peer, "ip", ip)
+		return false
+	}
+	return true
+}
+
+// Writer returns a writer limited by the global bucket and the buckets of keys
+func (l *Limiter) Writer(ctx context.Context, w io.Writer, keys Keys) io.Writer {
+	lw := &writer{
+		ctx:     ctx,
+		w:       w,
+		l:       l,
+		chunk:   maxChunk,
+		buckets: make(map[Scope]*Bucket),
+	}
+	for s, key := range map[Scope]string{
+		ScopeGlobal: "",
+		ScopeToken:  keys.Token,
+		ScopeIP:     keys.IP,
+	} {
+		b := l.bucket(s, key)
+		if b == nil {
+			continue
+		}
+		lw.buckets[s] = b
+		if b.Burst() < lw.chunk {
+			lw.chunk = b.Burst()
+		}
+	}
+	if lw.chunk <= 0 {
+		lw.chunk = 1
+	}
+	if len(lw.buckets) == 0 {
+		return w
+	}
+	return lw
+}
+
+// Stats ...
+func (l *Limiter) Stats() []core.LimitStat {
+	var stats []core.LimitStat
+	for s, m := range l.metrics {
+		stats = append(stats, core.LimitStat{
+			Scope:     string(s),
+			Throttled: m.throttled.Load(),
+			Rejected:  m.rejected.Load(),
+			WaitTime:  m.wait.Load(),
+		})
+	}
+	sort.Slice(stats, func(i, j int) bool {
+		return stats[i].Scope < stats[j].Scope
+	})
+	return stats
+}
+
+type writer struct {
+	ctx     context.Context
+	w       io.Writer
+	l       *Limiter
+	chunk   int64
+	buckets map[Scope]*Bucket
+}
+
+// Write ...
+func (w *writer) Write(p []byte) (n int, err error) {
+	for len(p) > 0 {
+		size := int64(len(p))
+		if size > w.chunk {
+			size = w.chunk
+		}
+		for s, b := range w.buckets {
+			d, err := b.Wait(w.ctx, size)
+			if err != nil {
+				return n, err
+			}
+			if d > 0 {
+				m := w.l.metrics[s]
+				m.throttled.Inc()
+				m.wait.Add(int64(d / time.Millisecond))
+			}
+		}
+		written, err := w.w.Write(p[:size])
+		n += written
+		if err != nil {
+			return n, err
+		}
+		p = p[written:]
+	}
+	return n, nil
+}
@@ -0,0 +1,139 @@
+package qos
+
+import (
+	"bytes"
+	"context"
+	"testing"
+	"time"
+
+	"github.com/glvd/accipfs/config"
+)
+
+func TestBucket_Wait(t *testing.T) {
+	b := NewBucket(1000, 100)
+	if !b.Allow(100) {
+		t.Fatal("full bucket should allow burst")
+	}
+	if b.Allow(50) {
+		t.Fatal("empty bucket should not allow")
+	}
+	d, err := b.Wait(context.Background(), 50)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if d <= 0 || d > 100*time.Millisecond {
+		t.Fatalf("wrong wait duration %v", d)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := b.Wait(ctx, 1000); err == nil {
+		t.Fatal("canceled wait should return error")
+	}
+}
+
+func TestLimiter_AllowRequest(t *testing.T) {
+	l := NewLimiter(config.QoSConfig{
+		Enable:  true,
+		Peer:    config.RateConfig{Rate: 1, Burst: 2},
+		Classes: map[string]float64{"free": 1, "paid": 2},
+		Peers:   map[string]string{"paid": "paid"},
+	})
+	for i := 0; i < 2; i++ {
+		if !l.AllowRequest("free", "") {
+			t.Fatalf("request %d should be allowed", i)
+		}
+	}
+	if l.AllowRequest("free", "") {
+		t.Fatal("request over burst should be rejected")
+	}
+	for i := 0; i < 4; i++ {
+		if !l.AllowRequest("paid", "") {
+			t.Fatalf("paid request %d should be allowed", i)
+		}
+	}
+	for _, s := range l.Stats() {
+		if s.Scope == string(ScopePeer) && s.Rejected != 1 {
+			t.Fatalf("wrong rejected count %d", s.Rejected)
+		}
+	}
+}
+
+func TestLimiter_AllowRequestIP(t *testing.T) {
+	l := NewLimiter(config.QoSConfig{
+		Enable: true,
+		Global: config.RateConfig{Rate: 1, Burst: 3},
+		IP:     config.RateConfig{Rate: 1, Burst: 2},
+		Peer:   config.RateConfig{Rate: 1, Burst: 10},
+	})
+	//the peers of an ip share its bucket
+	if !l.AllowRequest("peer1", "10.0.0.1") || !l.AllowRequest("peer2", "10.0.0.1") {
+		t.Fatal("requests under the ip burst should be allowed")
+	}
+	if l.AllowRequest("peer3", "10.0.0.1") {
+		t.Fatal("request over the ip burst should be rejected")
+	}
+	if !l.AllowRequest("peer3", "10.0.0.2") {
+		t.Fatal("request of another ip should be allowed")
+	}
+	if l.AllowRequest("peer4", "10.0.0.3") {
+		t.Fatal("request over the global burst should be rejected")
+	}
+	for _, s := range l.Stats() {
+		if (s.Scope == string(ScopeIP) || s.Scope == string(ScopeGlobal)) && s.Rejected != 1 {
+			t.Fatalf("wrong rejected count %d of %s", s.Rejected, s.Scope)
+		}
+	}
+}
+
+func TestLimiter_Writer(t *testing.T) {
+	l := NewLimiter(config.QoSConfig{
+		Enable: true,
+		IP:     config.RateConfig{Rate: 64 << 10, Burst: 16 << 10},
+	})
+	var buf bytes.Buffer
+	w := l.Writer(context.Background(), &buf, Keys{IP: "127.0.0.1"})
+	data := make([]byte, 32<<10)
+	start := time.Now()
+	n, err := w.Write(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n != len(data) || buf.Len() != len(data) {
+		t.Fatalf("wrong written size %d", n)
+	}
+	if time.Since(start) < 200*time.Millisecond {
+		t.Fatal("write should be throttled")
+	}
+	if w := l.Writer(context.Background(), &buf, Keys{}); w != &buf {
+		t.Fatal("unlimited keys should return the origin writer")
+	}
+}
+
+func TestLimiter_ClassChange(t *testing.T) {
+	l := NewLimiter(config.QoSConfig{
+		Enable:  true,
+		Peer:    config.RateConfig{Rate: 1, Burst: 2},
+		Classes: map[string]float64{"free": 1, "paid": 4},
+	})
+	class := DefaultClass
+	l.SetPeerClassifier(func(peer string) string {
+		return class
+	})
+	for i := 0; i < 2; i++ {
+		if !l.AllowRequest("peer", "") {
+			t.Fatalf("request %d should be allowed", i)
+		}
+	}
+	if l.AllowRequest("peer", "") {
+		t.Fatal("request over burst should be rejected")
+	}
+	//the peer pays, its bucket is raised without waiting for the eviction
+	class = "paid"
+	if b := l.bucket(ScopePeer, "peer"); b.Burst() != 8 {
+		t.Fatalf("paid burst %d, want 8", b.Burst())
+	}
+	class = DefaultClass
+	if l.bucket(ScopePeer, "peer").Burst() != 2 {
+		t.Fatal("burst should fall back with the class")
+	}
+}
@@ -0,0 +1,9 @@
+package qos
+
+import (
+	alog "github.com/glvd/accipfs/log"
+)
+
+const module = "qos"
+
+var log = alog.Module(module)
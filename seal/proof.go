@@ -0,0 +1,55 @@
+package seal
+
+import (
+	"crypto/ecdsa"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/ethereum/go-ethereum/crypto"
+)
+
+// ProofWindow is how far the time of a proof may be from the time it is verified
+const ProofWindow = 5 * time.Minute
+
+// ErrProof ...
+var ErrProof = errors.New("wrong or expired proof of the recipient")
+
+// proofDigest is the digest signed by a recipient opening the content of hash at the unix time t
+func proofDigest(hash string, t int64) []byte {
+	return crypto.Keccak256([]byte(fmt.Sprintf("accipfs open %s %d", hash, t)))
+}
+
+// Prove signs the proof that the key of a recipient opens the content of hash at the unix time t,
+// the signature is returned in hex
+func Prove(hash string, t int64, priv *ecdsa.PrivateKey) (string, error) {
+	sig, err := crypto.Sign(proofDigest(hash, t), priv)
+	if err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(sig), nil
+}
+
+// VerifyProof checks the proof of Prove is signed by a recipient of the envelope within ProofWindow of now
+func VerifyProof(env *Envelope, hash string, t int64, proof string, now time.Time) error {
+	if d := now.Sub(time.Unix(t, 0)); d > ProofWindow || d < -ProofWindow {
+		return ErrProof
+	}
+	sig, err := hex.DecodeString(proof)
+	if err != nil {
+		return ErrProof
+	}
+	pub, err := crypto.SigToPub(proofDigest(hash, t), sig)
+	if err != nil {
+		return ErrProof
+	}
+	signer := PublicKeyHex(pub)
+	for _, r := range env.Recipients {
+		if strings.EqualFold(r.PublicKey, signer) {
+			return nil
+		}
+	}
+	return ErrNotRecipient
+}
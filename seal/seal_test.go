@@ -0,0 +1,199 @@
+package seal
+
+import (
+	"bytes"
+	"crypto/ecdsa"
+	"encoding/hex"
+	"io/ioutil"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/crypto"
+)
+
+// vectors are sealed by the key 00..1f, the nonce prefix a0..a6 and chunks of 16 bytes
+var vectors = []struct {
+	plain  string
+	sealed string
+}{
+	{"", "4143534c0100000010a0a1a2a3a4a5a62cc82eff98251642f9f222b6f51dfd16"},
+	{"hello accipfs", "4143534c0100000010a0a1a2a3a4a5a61f042572d72681a3600ca849bcaf911b38c643c18693ff520ac19d49df"},
+	{"0123456789abcdef0123456789abcdef01234567", "4143534c0100000010a0a1a2a3a4a5a63ae02e9aa261730a541aa5e2fa64330d8080a85021312d5a4fea9c76f088bd2e61d325f4b909c0b8d8b4df16ae46cb56fd2c78b221efb644c5a414373cc55d9cd19cb3ddc2db2973f17ac6971ee48aa3349b7016b820c74f"},
+}
+
+// wrapVector is the key 00..1f wrapped for testPriv
+const wrapVector = "seal1:eyJyZWNpcGllbnRzIjpbeyJwdWIiOiIwMjRlM2I4MWFmOWMyMjM0Y2FkMDlkNjc5Y2U2MDM1ZWQxMzkyMzQ3Y2U2NGNlNDA1ZjVkY2QzNjIyOGEyNWRlNmUiLCJrZXkiOiJCTFZVMzhiRklNNTgwcWpkRFBiVVQvczBQaFcvQ1dVQmwweG03NFUwTTFEUlJEV2tFa28wejlxTUpRbE1yZWZGRm5VYWlVTDM3Umh6cVJIUFhaRFluOUxOSm1JVlRtMTJzcks1TG5EQXlzUE1nRmNWVlVpWSt2eS9saWlBOXBGNlRUeExKU0dUM01ZeDlPYVM3ZGRnQUVjNHNqLzRzdkU4cThqOU45Y3UwYnpJbnVjT2VvZmdPUmV2cEE4VVFLSGtWUT09In1dfQ"
+
+const testHash = "QmPZ9gcCEpqKTo6aq61g2nXGUhM4iCL3ewB6LDXZCtioEB"
+
+const testPriv = "4c0883a69102937d6231471b5dbb6204fe5129617082792ae468d01a3f362318"
+
+func testKey() []byte {
+	key := make([]byte, KeySize)
+	for i := range key {
+		key[i] = byte(i)
+	}
+	return key
+}
+
+func TestVectors(t *testing.T) {
+	prefix := []byte{0xa0, 0xa1, 0xa2, 0xa3, 0xa4, 0xa5, 0xa6}
+	for _, v := range vectors {
+		var buf bytes.Buffer
+		w, err := newWriter(&buf, testKey(), prefix, 16)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := w.Write([]byte(v.plain)); err != nil {
+			t.Fatal(err)
+		}
+		if err := w.Close(); err != nil {
+			t.Fatal(err)
+		}
+		if got := hex.EncodeToString(buf.Bytes()); got != v.sealed {
+			t.Fatalf("seal %q\n got %s\nwant %s", v.plain, got, v.sealed)
+		}
+		sealed, _ := hex.DecodeString(v.sealed)
+		r, err := NewReader(bytes.NewReader(sealed), testKey())
+		if err != nil {
+			t.Fatal(err)
+		}
+		plain, err := ioutil.ReadAll(r)
+		if err != nil || string(plain) != v.plain {
+			t.Fatalf("open %q got %q %v", v.plain, plain, err)
+		}
+	}
+}
+
+func TestStream(t *testing.T) {
+	key, err := NewKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, size := range []int{0, 1, DefaultChunkSize - 1, DefaultChunkSize, DefaultChunkSize + 1, 3*DefaultChunkSize + 7} {
+		plain := bytes.Repeat([]byte{'x'}, size)
+		var buf bytes.Buffer
+		w, err := NewWriter(&buf, key)
+		if err != nil {
+			t.Fatal(err)
+		}
+		//small writes cross the chunks
+		for i := 0; i < size; i += 1000 {
+			end := i + 1000
+			if end > size {
+				end = size
+			}
+			if _, err := w.Write(plain[i:end]); err != nil {
+				t.Fatal(err)
+			}
+		}
+		if err := w.Close(); err != nil {
+			t.Fatal(err)
+		}
+		sealed := buf.Bytes()
+		r, err := NewReader(bytes.NewReader(sealed), key)
+		if err != nil {
+			t.Fatal(err)
+		}
+		got, err := ioutil.ReadAll(r)
+		if err != nil || !bytes.Equal(got, plain) {
+			t.Fatalf("size %d round trip failed %v", size, err)
+		}
+		if size <= DefaultChunkSize {
+			continue
+		}
+		//cut at a chunk boundary
+		if _, err := openAll(sealed[:headerSize+DefaultChunkSize+overhead], key); err != ErrAuth {
+			t.Fatalf("truncated content should fail, got %v", err)
+		}
+	}
+	sealed, _ := hex.DecodeString(vectors[1].sealed)
+	wrong := append([]byte(nil), testKey()...)
+	wrong[0] ^= 1
+	if _, err := NewReader(bytes.NewReader(sealed), wrong); err != ErrAuth {
+		t.Fatalf("wrong key should fail, got %v", err)
+	}
+	sealed[len(sealed)-1] ^= 1
+	if _, err := NewReader(bytes.NewReader(sealed), testKey()); err != ErrAuth {
+		t.Fatalf("tampered content should fail, got %v", err)
+	}
+	if _, err := NewReader(bytes.NewReader([]byte("plain content of a file")), testKey()); err != ErrNotSealed {
+		t.Fatalf("plain content should fail, got %v", err)
+	}
+}
+
+func openAll(sealed []byte, key []byte) ([]byte, error) {
+	r, err := NewReader(bytes.NewReader(sealed), key)
+	if err != nil {
+		return nil, err
+	}
+	return ioutil.ReadAll(r)
+}
+
+func TestWrap(t *testing.T) {
+	priv, err := crypto.HexToECDSA(testPriv)
+	if err != nil {
+		t.Fatal(err)
+	}
+	key, err := Unwrap(wrapVector, priv)
+	if err != nil || !bytes.Equal(key, testKey()) {
+		t.Fatalf("unwrap vector got %x %v", key, err)
+	}
+	other, _ := crypto.GenerateKey()
+	pub, err := ParsePublicKey(PublicKeyHex(&other.PublicKey))
+	if err != nil {
+		t.Fatal(err)
+	}
+	uncompressed, err := ParsePublicKey(hex.EncodeToString(crypto.FromECDSAPub(&priv.PublicKey)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	security, err := Wrap(testKey(), []*ecdsa.PublicKey{pub, uncompressed})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, k := range []*ecdsa.PrivateKey{priv, other} {
+		key, err := Unwrap(security, k)
+		if err != nil || !bytes.Equal(key, testKey()) {
+			t.Fatalf("unwrap got %x %v", key, err)
+		}
+	}
+	stranger, _ := crypto.GenerateKey()
+	if _, err := Unwrap(security, stranger); err != ErrNotRecipient {
+		t.Fatalf("want ErrNotRecipient, got %v", err)
+	}
+}
+
+func TestProof(t *testing.T) {
+	priv, err := crypto.HexToECDSA(testPriv)
+	if err != nil {
+		t.Fatal(err)
+	}
+	env, err := ParseEnvelope(wrapVector)
+	if err != nil {
+		t.Fatal(err)
+	}
+	now := time.Now()
+	proof, err := Prove(testHash, now.Unix(), priv)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := VerifyProof(env, testHash, now.Unix(), proof, now); err != nil {
+		t.Fatal(err)
+	}
+	//the proof is bound to the hash and the time
+	if err := VerifyProof(env, "QmOther", now.Unix(), proof, now); err == nil {
+		t.Fatal("proof of another hash should fail")
+	}
+	if err := VerifyProof(env, testHash, now.Unix(), proof, now.Add(2*ProofWindow)); err != ErrProof {
+		t.Fatalf("want ErrProof of an expired proof, got %v", err)
+	}
+	stranger, _ := crypto.GenerateKey()
+	proof, err = Prove(testHash, now.Unix(), stranger)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := VerifyProof(env, testHash, now.Unix(), proof, now); err != ErrNotRecipient {
+		t.Fatalf("want ErrNotRecipient, got %v", err)
+	}
+}
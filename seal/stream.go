@@ -0,0 +1,259 @@
+package seal
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/binary"
+	"errors"
+	"io"
+)
+
+// KeySize is the size of a content key, the content is encrypted by AES-256-GCM
+const KeySize = 32
+
+// DefaultChunkSize ...
+const DefaultChunkSize = 64 * 1024
+
+// maxChunkSize bounds the chunk size read from a header
+const maxChunkSize = 16 * 1024 * 1024
+
+const (
+	version     = 1
+	prefixSize  = 7
+	headerSize  = len(magicString) + 1 + 4 + prefixSize
+	nonceSize   = prefixSize + 4 + 1
+	lastFlag    = 1
+	maxCounter  = 1<<32 - 1
+	overhead    = 16
+	magicString = "ACSL"
+)
+
+var magic = []byte(magicString)
+
+// Errors ...
+var (
+	ErrKeySize   = errors.New("wrong content key size")
+	ErrNotSealed = errors.New("content is not sealed")
+	ErrVersion   = errors.New("unsupported sealed version")
+	ErrAuth      = errors.New("sealed content authentication failed")
+	ErrTooLarge  = errors.New("sealed content is too large")
+)
+
+// NewKey returns a random content key
+func NewKey() ([]byte, error) {
+	key := make([]byte, KeySize)
+	if _, err := io.ReadFull(rand.Reader, key); err != nil {
+		return nil, err
+	}
+	return key, nil
+}
+
+// IsSealed returns whether b starts with the header of the sealed content
+func IsSealed(b []byte) bool {
+	return len(b) >= len(magic) && bytes.Equal(b[:len(magic)], magic)
+}
+
+func newAEAD(key []byte) (cipher.AEAD, error) {
+	if len(key) != KeySize {
+		return nil, ErrKeySize
+	}
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+// nonce returns the nonce of the chunk: prefix | big endian counter | last flag
+func nonce(prefix []byte, counter uint32, last bool) []byte {
+	n := make([]byte, nonceSize)
+	copy(n, prefix)
+	binary.BigEndian.PutUint32(n[prefixSize:], counter)
+	if last {
+		n[nonceSize-1] = lastFlag
+	}
+	return n
+}
+
+// Writer encrypts the written content in chunks, Close must be called to write the last chunk
+type Writer struct {
+	w       io.Writer
+	aead    cipher.AEAD
+	header  []byte
+	prefix  []byte
+	buf     []byte
+	size    int
+	counter uint32
+	closed  bool
+}
+
+// NewWriter returns a writer sealing the content to w by the key in chunks of DefaultChunkSize
+func NewWriter(w io.Writer, key []byte) (*Writer, error) {
+	prefix := make([]byte, prefixSize)
+	if _, err := io.ReadFull(rand.Reader, prefix); err != nil {
+		return nil, err
+	}
+	return newWriter(w, key, prefix, DefaultChunkSize)
+}
+
+func newWriter(w io.Writer, key []byte, prefix []byte, size int) (*Writer, error) {
+	aead, err := newAEAD(key)
+	if err != nil {
+		return nil, err
+	}
+	header := make([]byte, 0, headerSize)
+	header = append(header, magic...)
+	header = append(header, version)
+	header = append(header, 0, 0, 0, 0)
+	binary.BigEndian.PutUint32(header[len(magic)+1:], uint32(size))
+	header = append(header, prefix...)
+	if _, err := w.Write(header); err != nil {
+		return nil, err
+	}
+	return &Writer{
+		w:      w,
+		aead:   aead,
+		header: header,
+		prefix: prefix,
+		buf:    make([]byte, 0, size),
+		size:   size,
+	}, nil
+}
+
+// Write ...
+func (w *Writer) Write(p []byte) (int, error) {
+	if w.closed {
+		return 0, errors.New("write to closed writer")
+	}
+	n := 0
+	for len(p) > 0 {
+		//a full chunk is kept until more data comes to know whether it is the last one
+		if len(w.buf) == w.size {
+			if err := w.flush(false); err != nil {
+				return n, err
+			}
+		}
+		c := copy(w.buf[len(w.buf):w.size], p)
+		w.buf = w.buf[:len(w.buf)+c]
+		p = p[c:]
+		n += c
+	}
+	return n, nil
+}
+
+func (w *Writer) flush(last bool) error {
+	if w.counter == maxCounter {
+		return ErrTooLarge
+	}
+	sealed := w.aead.Seal(nil, nonce(w.prefix, w.counter, last), w.buf, w.header)
+	w.counter++
+	w.buf = w.buf[:0]
+	_, err := w.w.Write(sealed)
+	return err
+}
+
+// Close writes the last chunk, it does not close the underlying writer
+func (w *Writer) Close() error {
+	if w.closed {
+		return nil
+	}
+	w.closed = true
+	return w.flush(true)
+}
+
+// Reader decrypts the sealed content
+type Reader struct {
+	r       *bufio.Reader
+	aead    cipher.AEAD
+	header  []byte
+	prefix  []byte
+	chunk   []byte
+	plain   []byte
+	counter uint32
+	done    bool
+}
+
+// NewReader returns a reader opening the sealed content of r by the key,
+// the first chunk is opened at once so a wrong key fails here with ErrAuth
+func NewReader(r io.Reader, key []byte) (*Reader, error) {
+	aead, err := newAEAD(key)
+	if err != nil {
+		return nil, err
+	}
+	br := bufio.NewReader(r)
+	header := make([]byte, headerSize)
+	if _, err := io.ReadFull(br, header); err != nil {
+		if err == io.EOF || err == io.ErrUnexpectedEOF {
+			return nil, ErrNotSealed
+		}
+		return nil, err
+	}
+	if !IsSealed(header) {
+		return nil, ErrNotSealed
+	}
+	if header[len(magic)] != version {
+		return nil, ErrVersion
+	}
+	size := binary.BigEndian.Uint32(header[len(magic)+1:])
+	if size == 0 || size > maxChunkSize {
+		return nil, ErrNotSealed
+	}
+	sr := &Reader{
+		r:      br,
+		aead:   aead,
+		header: header,
+		prefix: header[len(magic)+1+4:],
+		chunk:  make([]byte, int(size)+overhead),
+	}
+	if err := sr.next(); err != nil {
+		return nil, err
+	}
+	return sr, nil
+}
+
+// next opens the next chunk to plain
+func (r *Reader) next() error {
+	n, err := io.ReadFull(r.r, r.chunk)
+	last := false
+	switch err {
+	case nil:
+		if _, err := r.r.Peek(1); err == io.EOF {
+			last = true
+		} else if err != nil {
+			return err
+		}
+	case io.ErrUnexpectedEOF:
+		last = true
+	case io.EOF:
+		//the content ended without the last chunk
+		return ErrAuth
+	default:
+		return err
+	}
+	plain, err := r.aead.Open(r.chunk[:0:0], nonce(r.prefix, r.counter, last), r.chunk[:n], r.header)
+	if err != nil {
+		return ErrAuth
+	}
+	r.counter++
+	r.plain = plain
+	r.done = last
+	return nil
+}
+
+// Read ...
+func (r *Reader) Read(p []byte) (int, error) {
+	for len(r.plain) == 0 {
+		if r.done {
+			return 0, io.EOF
+		}
+		if err := r.next(); err != nil {
+			return 0, err
+		}
+	}
+	n := copy(p, r.plain)
+	r.plain = r.plain[n:]
+	return n, nil
+}
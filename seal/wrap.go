@@ -0,0 +1,120 @@
+package seal
+
+import (
+	"bytes"
+	"crypto/ecdsa"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"io"
+	"strings"
+
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/crypto/ecies"
+)
+
+// SecurityPrefix is the prefix of the wrapped keys kept in DataInfoV1.Security.Key
+const SecurityPrefix = "seal1:"
+
+// ErrNotRecipient ...
+var ErrNotRecipient = errors.New("key is not a recipient of the content")
+
+// Recipient is the content key wrapped for a public key
+type Recipient struct {
+	PublicKey string `json:"pub"` //compressed public key in hex
+	Key       []byte `json:"key"` //ecies encrypted content key
+}
+
+// Envelope is the content key wrapped for every recipient
+type Envelope struct {
+	Recipients []Recipient `json:"recipients"`
+}
+
+// ParsePublicKey parses a secp256k1 public key of the compressed or the uncompressed hex
+func ParsePublicKey(s string) (*ecdsa.PublicKey, error) {
+	b, err := hex.DecodeString(strings.TrimPrefix(s, "0x"))
+	if err != nil {
+		return nil, err
+	}
+	if len(b) == 33 {
+		return crypto.DecompressPubkey(b)
+	}
+	return crypto.UnmarshalPubkey(b)
+}
+
+// PublicKeyHex returns the compressed hex of the public key
+func PublicKeyHex(pub *ecdsa.PublicKey) string {
+	return hex.EncodeToString(crypto.CompressPubkey(pub))
+}
+
+// Wrap encrypts the content key for the recipients and returns the envelope encoded for Security.Key
+func Wrap(key []byte, recipients []*ecdsa.PublicKey) (string, error) {
+	return wrap(rand.Reader, key, recipients)
+}
+
+func wrap(random io.Reader, key []byte, recipients []*ecdsa.PublicKey) (string, error) {
+	if len(key) != KeySize {
+		return "", ErrKeySize
+	}
+	if len(recipients) == 0 {
+		return "", errors.New("no recipient of the content key")
+	}
+	var env Envelope
+	for _, pub := range recipients {
+		wrapped, err := ecies.Encrypt(random, ecies.ImportECDSAPublic(pub), key, nil, nil)
+		if err != nil {
+			return "", err
+		}
+		env.Recipients = append(env.Recipients, Recipient{
+			PublicKey: PublicKeyHex(pub),
+			Key:       wrapped,
+		})
+	}
+	b, err := json.Marshal(&env)
+	if err != nil {
+		return "", err
+	}
+	return SecurityPrefix + base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+// ParseEnvelope decodes the envelope of Security.Key
+func ParseEnvelope(security string) (*Envelope, error) {
+	if !strings.HasPrefix(security, SecurityPrefix) {
+		return nil, ErrNotSealed
+	}
+	b, err := base64.RawURLEncoding.DecodeString(strings.TrimPrefix(security, SecurityPrefix))
+	if err != nil {
+		return nil, err
+	}
+	var env Envelope
+	if err := json.Unmarshal(b, &env); err != nil {
+		return nil, err
+	}
+	return &env, nil
+}
+
+// Unwrap returns the content key of the envelope of Security.Key by the private key of a recipient
+func Unwrap(security string, priv *ecdsa.PrivateKey) ([]byte, error) {
+	env, err := ParseEnvelope(security)
+	if err != nil {
+		return nil, err
+	}
+	pub := crypto.CompressPubkey(&priv.PublicKey)
+	for _, r := range env.Recipients {
+		b, err := hex.DecodeString(r.PublicKey)
+		if err != nil || !bytes.Equal(b, pub) {
+			continue
+		}
+		key, err := ecies.ImportECDSA(priv).Decrypt(r.Key, nil, nil)
+		if err != nil {
+			return nil, err
+		}
+		if len(key) != KeySize {
+			return nil, ErrKeySize
+		}
+		return key, nil
+	}
+	return nil, ErrNotRecipient
+}
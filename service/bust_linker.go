@@ -2,35 +2,80 @@ package service
 
 import (
 	"context"
+	"encoding/json"
+	"fmt"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/ethclient"
 	"github.com/glvd/accipfs/account"
+	"github.com/glvd/accipfs/backup"
+	"github.com/glvd/accipfs/catalog"
+	"github.com/glvd/accipfs/chain"
 	"github.com/glvd/accipfs/config"
+	"github.com/glvd/accipfs/contract"
 	"github.com/glvd/accipfs/controller"
 	"github.com/glvd/accipfs/core"
+	"github.com/glvd/accipfs/gc"
 	"github.com/glvd/accipfs/node"
+	"github.com/glvd/accipfs/payment"
+	"github.com/glvd/accipfs/qos"
+	"github.com/glvd/accipfs/sign"
+	"github.com/glvd/accipfs/stats"
 	"github.com/glvd/accipfs/task"
+	"github.com/libp2p/go-libp2p-core/peer"
+	ma "github.com/multiformats/go-multiaddr"
 	"go.uber.org/atomic"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sync"
 	"time"
 )
 
+const paymentDir = "payment"
+const bandwidthDir = "bandwidth"
+const accessDir = "access"
+const chainDir = "chain"
+const catalogDir = "catalog"
+const taskDir = "task"
+
 // BustLinker ...
 type BustLinker struct {
+	ctx        context.Context
+	cancel     context.CancelFunc
 	id         core.Node
 	manager    core.NodeManager
-	tasks      task.Task
+	tasks      *task.Queue
 	lock       *atomic.Bool
 	self       *account.Account
 	cfg        *config.Config
 	listener   core.Listener
 	controller *controller.Controller
+	settler    *payment.Settler
+	bw         *stats.Bandwidth
+	limiter    *qos.Limiter
+	indexer    *chain.Indexer
 	api        *APIContext
+	stores     []io.Closer //badger stores closed in Stop
+	running    sync.WaitGroup
 }
 
 // NewBustLinker ...
 func NewBustLinker(cfg *config.Config) (linker *BustLinker, err error) {
+	ctx, cancel := context.WithCancel(context.Background())
 	linker = &BustLinker{
-		lock: atomic.NewBool(false),
-		cfg:  cfg,
+		ctx:    ctx,
+		cancel: cancel,
+		lock:   atomic.NewBool(false),
+		cfg:    cfg,
 	}
+	self := linker
+	defer func() {
+		if err != nil {
+			cancel()
+			self.closeStores()
+		}
+	}()
 
 	selfAcc, err := account.LoadAccount(cfg)
 	if err != nil {
@@ -39,30 +84,141 @@ func NewBustLinker(cfg *config.Config) (linker *BustLinker, err error) {
 	linker.self = selfAcc
 
 	linker.controller = controller.New(cfg)
+	access, err := gc.OpenAccess(filepath.Join(config.DataDirCache(), accessDir))
+	if err != nil {
+		return nil, err
+	}
+	linker.stores = append(linker.stores, access)
+	linker.controller.RegisterAccess(access)
 	linker.manager, err = node.InitManager(cfg)
 	linker.manager.RegisterAddrCallback(linker.controller.HandleSwarm)
+	linker.controller.RegisterProtector(linker.linkedData)
+	linker.controller.RegisterCaches(linker.manager.CacheStats)
+	linker.controller.RegisterLinked(func(ctx context.Context, hash string) error {
+		_, err := linker.manager.NodeAPI().Add(ctx, &core.NodeAddReq{Hash: hash})
+		return err
+	})
 	linker.api = NewAPIContext(cfg, linker.manager, linker.controller)
+	linker.bw, err = stats.OpenBandwidth(filepath.Join(config.DataDirCache(), bandwidthDir))
+	if err != nil {
+		return nil, err
+	}
+	linker.stores = append(linker.stores, linker.bw)
+	linker.manager.RegisterBandwidthRecorder(linker.bw)
+	linker.api.setBandwidth(linker.bw)
+	records, err := catalog.OpenCatalog(filepath.Join(config.DataDirCache(), catalogDir))
+	if err != nil {
+		return nil, err
+	}
+	linker.stores = append(linker.stores, records)
+	linker.api.setCatalog(records)
+	trust, err := sign.NewTrust(cfg.Trust.Publishers, cfg.Trust.RequireSigned)
+	if err != nil {
+		return nil, err
+	}
+	linker.api.setTrust(trust)
+	jobs, err := task.OpenStore(filepath.Join(config.DataDirCache(), taskDir))
+	if err != nil {
+		return nil, err
+	}
+	linker.stores = append(linker.stores, jobs)
+	linker.tasks, err = task.NewQueue(task.Options{
+		Workers:    cfg.Task.Workers,
+		MaxRetries: cfg.Task.MaxRetries,
+		Backoff:    cfg.Task.Backoff * time.Second,
+		MaxBackoff: cfg.Task.MaxBackoff * time.Second,
+		History:    task.DefaultOptions().History,
+	}, jobs)
+	if err != nil {
+		return nil, err
+	}
+	linker.tasks.Register(jobPin, pinHandler(linker.controller))
+	linker.api.setTasks(linker.tasks)
+	linker.api.setPrefetcher(linker.api.newPrefetcher(linker.ctx))
+	if cfg.Pay.Enable {
+		ledger, err := payment.OpenLedger(filepath.Join(config.DataDirCache(), paymentDir))
+		if err != nil {
+			return nil, err
+		}
+		linker.stores = append(linker.stores, ledger)
+		c, err := contract.Loader(cfg)
+		if err != nil {
+			return nil, err
+		}
+		linker.settler = payment.NewSettler(ledger, payment.NewContractPayer(c), cfg.Pay.Price)
+		linker.api.setSettler(linker.settler)
+	}
+	if cfg.QoS.Enable {
+		linker.limiter = qos.NewLimiter(cfg.QoS)
+		linker.manager.RegisterRequestLimiter(linker.limiter)
+		linker.api.setLimiter(linker.limiter)
+	}
+	if cfg.ETH.Enable && cfg.ETH.Index.Enable {
+		client, err := ethclient.Dial(config.ETHAddr())
+		if err != nil {
+			return nil, err
+		}
+		store, err := chain.OpenStore(filepath.Join(config.DataDirCache(), chainDir))
+		if err != nil {
+			return nil, err
+		}
+		linker.stores = append(linker.stores, store)
+		linker.indexer, err = chain.NewIndexer(client, store, chain.Contracts{
+			Tag:     common.HexToAddress(cfg.ETH.DTagAddr),
+			Node:    common.HexToAddress(cfg.ETH.NodeAddr),
+			Token:   common.HexToAddress(cfg.ETH.TokenAddr),
+			Message: common.HexToAddress(cfg.ETH.MessageAddr),
+		}, cfg.ETH.Index.Start, cfg.ETH.Index.Confirmations)
+		if err != nil {
+			return nil, err
+		}
+		linker.indexer.RegisterNodes(linker.registryNodes)
+		linker.indexer.RegisterInfos(func(infos []*core.DataInfoV1) {
+			catalogInfos(records, trust, infos)
+		})
+		linker.api.setIndexer(linker.indexer)
+	}
 
 	linker.listener = newLinkListener(cfg, linker.manager.Conn)
 	return linker, nil
 }
 
 // Start ...
-func (l *BustLinker) Start() {
+func (l *BustLinker) Start() error {
 	l.controller.Run()
 	l.controller.WaitAllReady()
-	l.api.Start()
-	err := l.afterStart()
-	log.Infow("after start info", "err", err)
+	if err := l.controller.CleanGCPins(l.ctx); err != nil {
+		log.Errorw("clean gc pins", "err", err)
+	}
+	if err := l.api.Start(); err != nil {
+		return err
+	}
+	//the nodes of the private network are refused until the local network is known
+	if err := l.afterStart(); err != nil {
+		return fmt.Errorf("after start:%w", err)
+	}
 
 	go func() {
 		err := l.manager.LoadNode()
 		log.Infow("load node on goroutine", "err", err)
 	}()
 
+	l.goRun(func() { l.bw.Run(l.ctx, 30*time.Second, l.controller.BandwidthTotals) })
+	l.goRun(func() { l.tasks.Run(l.ctx) })
+	go l.restorePins()
+	if l.cfg.GC.Enable {
+		l.goRun(func() { l.controller.RunGC(l.ctx, l.cfg.GC.Interval*time.Second) })
+	}
+	if l.indexer != nil {
+		l.goRun(func() { l.indexer.Run(l.ctx, l.cfg.ETH.Index.Interval*time.Second) })
+	}
+	if l.settler != nil {
+		l.goRun(func() { l.settler.Run(l.ctx, l.cfg.Pay.Interval*time.Second, l.controller.BandwidthByPeer) })
+	}
+
 	//start handle
 	go l.listener.Listen()
-
+	return nil
 }
 
 // Run ...
@@ -81,6 +237,104 @@ func (l *BustLinker) WaitingForReady() {
 
 // Stop ...
 func (l *BustLinker) Stop() {
+	if l.cancel != nil {
+		l.cancel()
+	}
+	if l.api != nil {
+		if err := l.api.Stop(); err != nil {
+			log.Errorw("stop api", "err", err)
+		}
+	}
+	l.running.Wait()
+	l.closeStores()
+}
+
+// goRun runs f on a goroutine which Stop waits for before the stores are closed
+func (l *BustLinker) goRun(f func()) {
+	l.running.Add(1)
+	go func() {
+		defer l.running.Done()
+		f()
+	}()
+}
+
+// closeStores closes the opened stores in the reverse order
+func (l *BustLinker) closeStores() {
+	for i := len(l.stores) - 1; i >= 0; i-- {
+		if err := l.stores[i].Close(); err != nil {
+			log.Errorw("close store", "err", err)
+		}
+	}
+	l.stores = nil
+}
+
+// restorePins queues the pins of the list restored from a backup archive and removes it after success
+func (l *BustLinker) restorePins() {
+	path := filepath.Join(config.DataDirCache(), backup.PinsFile)
+	b, err := ioutil.ReadFile(path)
+	if err != nil {
+		return
+	}
+	var pins []string
+	if err := json.Unmarshal(b, &pins); err != nil {
+		log.Errorw("restore pins", "err", err)
+		return
+	}
+	for _, pin := range pins {
+		if err := l.api.queuePin(pin, task.PriorityLow); err != nil {
+			log.Errorw("restore pins", "pin", pin, "err", err)
+			return
+		}
+	}
+	if err := os.Remove(path); err != nil {
+		log.Errorw("remove restored pins", "err", err)
+	}
+}
+
+// registryNodes connects the ipfs nodes of the node contract registry
+func (l *BustLinker) registryNodes(kind string, nodes []string) {
+	if kind != chain.NodeIPFS && kind != chain.NodePublicIPFS {
+		return
+	}
+	for _, n := range nodes {
+		addr, err := ma.NewMultiaddr(n)
+		if err != nil {
+			log.Debugw("registry node", "node", n, "err", err)
+			continue
+		}
+		info, err := peer.AddrInfoFromP2pAddr(addr)
+		if err != nil {
+			log.Debugw("registry node", "node", n, "err", err)
+			continue
+		}
+		go func(info peer.AddrInfo) {
+			if err := l.controller.HandleSwarm(info); err != nil {
+				log.Debugw("connect registry node", "id", info.ID, "err", err)
+			}
+		}(*info)
+	}
+}
+
+// catalogInfos puts the trusted infos indexed from the tags to the local catalog
+func catalogInfos(records *catalog.Catalog, trust *sign.Trust, infos []*core.DataInfoV1) {
+	for _, info := range infos {
+		if err := trust.Check(info); err != nil {
+			log.Debugw("indexed info is not trusted", "no", info.MediaInfo.No, "err", err)
+			continue
+		}
+		if err := records.Put(info); err != nil {
+			log.Errorw("catalog indexed info", "no", info.MediaInfo.No, "err", err)
+		}
+	}
+}
+
+// linkedData returns the linked data hashes served to the other nodes
+func (l *BustLinker) linkedData() []string {
+	var lds []string
+	for ld := range l.manager.Local().Data().LDs {
+		lds = append(lds, ld)
+	}
+	return lds
 }
 
 func (l *BustLinker) afterStart() error {
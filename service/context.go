@@ -3,33 +3,61 @@ package service
 import (
 	"context"
 	"encoding/base64"
+	"encoding/hex"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"github.com/gin-gonic/gin"
+	"github.com/glvd/accipfs/catalog"
+	"github.com/glvd/accipfs/chain"
 	"github.com/glvd/accipfs/config"
 	"github.com/glvd/accipfs/controller"
 	"github.com/glvd/accipfs/core"
+	"github.com/glvd/accipfs/payment"
+	"github.com/glvd/accipfs/qos"
+	"github.com/glvd/accipfs/seal"
+	"github.com/glvd/accipfs/sign"
+	"github.com/glvd/accipfs/stats"
+	"github.com/glvd/accipfs/stream"
+	"github.com/glvd/accipfs/task"
+	"github.com/ipfs/go-cid"
 	files "github.com/ipfs/go-ipfs-files"
 	ic "github.com/libp2p/go-libp2p-core/crypto"
 	"github.com/libp2p/go-libp2p-core/peer"
 	ma "github.com/multiformats/go-multiaddr"
 	"go.uber.org/atomic"
 	"io"
+	"math/big"
 	"net"
 	"net/http"
 	"reflect"
+	"strconv"
+	"strings"
+	"time"
 )
 
+// tokenHeader is the header carrying the api token used by qos
+const tokenHeader = "Authorization"
+
 // APIContext ...
 type APIContext struct {
-	cfg      *config.Config
-	eng      *gin.Engine
-	listener net.Listener
-	serv     *http.Server
-	ready    *atomic.Bool
-	c        *controller.Controller
-	m        core.NodeManager
-	msg      func(s string)
+	cfg        *config.Config
+	eng        *gin.Engine
+	listener   net.Listener
+	serv       *http.Server
+	gateway    *http.Server
+	ready      *atomic.Bool
+	c          *controller.Controller
+	m          core.NodeManager
+	settler    *payment.Settler
+	bw         *stats.Bandwidth
+	limiter    *qos.Limiter
+	indexer    *chain.Indexer
+	catalog    *catalog.Catalog
+	trust      *sign.Trust
+	tasks      *task.Queue
+	prefetcher *stream.Prefetcher
+	msg        func(s string)
 }
 
 var _ core.API = &APIContext{}
@@ -90,6 +118,134 @@ func (c *APIContext) DataStoreAPI() core.DataStoreAPI {
 	return c.c
 }
 
+// ProcessAPI ...
+func (c *APIContext) ProcessAPI() core.ProcessAPI {
+	return c.c
+}
+
+// NameAPI ...
+func (c *APIContext) NameAPI() core.NameAPI {
+	return c
+}
+
+// Publish ...
+func (c *APIContext) Publish(ctx context.Context, req *core.NamePublishReq) (*core.NamePublishResp, error) {
+	if req.Catalog {
+		export, err := c.CatalogExport(ctx, &core.CatalogExportReq{})
+		if err != nil {
+			return nil, err
+		}
+		publish := *req
+		publish.Path = "/ipfs/" + export.Root
+		req = &publish
+	}
+	return c.c.Publish(ctx, req)
+}
+
+// Resolve ...
+func (c *APIContext) Resolve(ctx context.Context, req *core.NameResolveReq) (*core.NameResolveResp, error) {
+	return c.c.Resolve(ctx, req)
+}
+
+// KeyGen ...
+func (c *APIContext) KeyGen(ctx context.Context, req *core.KeyGenReq) (*core.KeyGenResp, error) {
+	return c.c.KeyGen(ctx, req)
+}
+
+// KeyList ...
+func (c *APIContext) KeyList(ctx context.Context, req *core.KeyListReq) (*core.KeyListResp, error) {
+	return c.c.KeyList(ctx, req)
+}
+
+// KeyRm ...
+func (c *APIContext) KeyRm(ctx context.Context, req *core.KeyRmReq) (*core.KeyRmResp, error) {
+	return c.c.KeyRm(ctx, req)
+}
+
+// CatalogAPI ...
+func (c *APIContext) CatalogAPI() core.CatalogAPI {
+	return c
+}
+
+// CatalogExport ...
+func (c *APIContext) CatalogExport(ctx context.Context, req *core.CatalogExportReq) (*core.CatalogExportResp, error) {
+	if c.catalog == nil {
+		return nil, errors.New("catalog is not enabled")
+	}
+	dag, err := c.c.Dag()
+	if err != nil {
+		return nil, err
+	}
+	records, err := c.catalog.Records()
+	if err != nil {
+		return nil, err
+	}
+	root, err := catalog.Export(ctx, dag, records, catalog.ExportOptions{
+		Codec:     req.Codec,
+		PrefixLen: req.PrefixLen,
+	})
+	if err != nil {
+		return nil, err
+	}
+	//the exported nodes are collected by the gc unless the root is pinned
+	_, err = c.c.PinAdd(ctx, &core.DataStorePinAddReq{Pins: []string{"/ipfs/" + root.String()}})
+	if err != nil {
+		return nil, err
+	}
+	return &core.CatalogExportResp{
+		Root:    root.String(),
+		Records: len(records),
+	}, nil
+}
+
+// CatalogImport ...
+func (c *APIContext) CatalogImport(ctx context.Context, req *core.CatalogImportReq) (*core.CatalogImportResp, error) {
+	if c.catalog == nil {
+		return nil, errors.New("catalog is not enabled")
+	}
+	root, err := cid.Decode(strings.TrimPrefix(req.Root, "/ipfs/"))
+	if err != nil {
+		return nil, err
+	}
+	dag, err := c.c.Dag()
+	if err != nil {
+		return nil, err
+	}
+	imported, err := catalog.ImportChecked(ctx, dag, root, c.trust.Check)
+	if err != nil {
+		return nil, err
+	}
+	resp := &core.CatalogImportResp{
+		Records: len(imported.Records),
+		Invalid: imported.Invalid,
+	}
+	if !req.NoPin {
+		for _, ref := range append([]cid.Cid{root}, imported.Refs...) {
+			_, err := c.c.PinAdd(ctx, &core.DataStorePinAddReq{Pins: []string{"/ipfs/" + ref.String()}})
+			if err != nil {
+				log.Warnw("pin catalog reference", "cid", ref.String(), "err", err)
+				resp.Failed = append(resp.Failed, ref.String())
+				if err := c.queuePin("/ipfs/"+ref.String(), task.PriorityNormal); err != nil {
+					log.Warnw("queue catalog reference", "cid", ref.String(), "err", err)
+				}
+				continue
+			}
+			resp.Pinned = append(resp.Pinned, ref.String())
+		}
+	}
+	for _, info := range imported.Records {
+		if err := c.catalog.Put(info); err != nil {
+			return nil, err
+		}
+		if info.RootHash != "" {
+			if _, err := c.NodeAPI().Add(ctx, &core.NodeAddReq{Hash: info.RootHash}); err != nil {
+				return nil, err
+			}
+		}
+	}
+	return resp, nil
+}
+
 // Link ...
 func (c *APIContext) Link(ctx context.Context, req *core.NodeLinkReq) (*core.NodeLinkResp, error) {
 	return c.NodeAPI().Link(ctx, req)
@@ -100,6 +256,11 @@ func (c *APIContext) List(ctx context.Context, req *core.NodeListReq) (*core.Nod
 	return c.NodeAPI().List(ctx, req)
 }
 
+// Backup ...
+func (c *APIContext) Backup(ctx context.Context, req *core.NodeBackupReq) (*core.NodeBackupResp, error) {
+	return c.NodeAPI().Backup(ctx, req)
+}
+
 // NodeAPI ...
 func (c *APIContext) NodeAPI() core.NodeAPI {
 	return c.m.NodeAPI()
@@ -107,9 +268,100 @@ func (c *APIContext) NodeAPI() core.NodeAPI {
 
 // Add ...
 func (c *APIContext) Add(ctx context.Context, req *core.NodeAddReq) (*core.NodeAddResp, error) {
+	if req.JSNFO != "" && c.catalog != nil {
+		var info core.DataInfoV1
+		if err := info.Unmarshal([]byte(req.JSNFO)); err != nil {
+			return nil, err
+		}
+		if info.Security.Key != "" {
+			if _, err := seal.ParseEnvelope(info.Security.Key); err != nil {
+				return nil, fmt.Errorf("wrong security key:%w", err)
+			}
+			//a sealed file added without its media info only keeps the envelope
+			if info.MediaInfo.No == "" {
+				if err := c.catalog.PutSecurity(info.RootHash, info.Security.Key); err != nil {
+					return nil, err
+				}
+				return c.NodeAPI().Add(ctx, req)
+			}
+		}
+		if err := c.trust.Check(&info); err != nil {
+			return nil, err
+		}
+		if err := c.catalog.Put(&info); err != nil {
+			return nil, err
+		}
+	}
 	return c.NodeAPI().Add(ctx, req)
 }
 
+// Pay ...
+func (c *APIContext) Pay(ctx context.Context, req *core.PayReq) (*core.PayResp, error) {
+	if c.settler == nil {
+		return nil, errors.New("payment is not enabled")
+	}
+	if req.Amount != "" {
+		amount, b := new(big.Int).SetString(req.Amount, 10)
+		if !b || amount.Sign() < 0 {
+			return nil, fmt.Errorf("wrong amount(%s)", req.Amount)
+		}
+		if err := c.settler.Prepay(ctx, req.Peer, req.Address, amount); err != nil {
+			return nil, err
+		}
+	}
+	return c.settler.Balances(ctx, req.Peer)
+}
+
+// StatsAPI ...
+func (c *APIContext) StatsAPI() core.StatsAPI {
+	return c
+}
+
+// Bandwidth ...
+func (c *APIContext) Bandwidth(ctx context.Context, req *core.StatsBandwidthReq) (*core.StatsBandwidthResp, error) {
+	if c.bw == nil {
+		return nil, errors.New("bandwidth stats is not enabled")
+	}
+	var err error
+	resp := &core.StatsBandwidthResp{}
+	if req.Kind == "" || req.Kind == string(stats.KindPeer) {
+		resp.Peers, err = c.bw.Top(stats.KindPeer, req.Top, req.Window)
+		if err != nil {
+			return nil, err
+		}
+	}
+	if req.Kind == "" || req.Kind == string(stats.KindCID) {
+		resp.CIDs, err = c.bw.Top(stats.KindCID, req.Top, req.Window)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return resp, nil
+}
+
+// QoS ...
+func (c *APIContext) QoS(ctx context.Context, req *core.StatsQoSReq) (*core.StatsQoSResp, error) {
+	if c.limiter == nil {
+		return nil, errors.New("qos is not enabled")
+	}
+	return &core.StatsQoSResp{
+		Limits: c.limiter.Stats(),
+	}, nil
+}
+
+// ChainAPI ...
+func (c *APIContext) ChainAPI() core.ChainAPI {
+	return c
+}
+
+// Status ...
+func (c *APIContext) Status(ctx context.Context, req *core.ChainStatusReq) (*core.ChainStatusResp, error) {
+	if c.indexer == nil {
+		return nil, errors.New("chain indexer is not enabled")
+	}
+	return c.indexer.Status(ctx, req)
+}
+
 // Ping ...
 func (c *APIContext) Ping(ctx context.Context, req *core.PingReq) (*core.PingResp, error) {
 	return &core.PingResp{
@@ -212,6 +464,19 @@ func (c *APIContext) Start() error {
 		return err
 	}
 	c.registerRoutes()
+	if c.cfg.Gateway.Enable {
+		host := c.cfg.Gateway.Host
+		if host == "" {
+			host = config.DefaultGatewayHost
+		}
+		gl, err := net.Listen("tcp", net.JoinHostPort(host, strconv.Itoa(c.cfg.Gateway.Port)))
+		if err != nil {
+			l.Close()
+			return err
+		}
+		c.gateway = &http.Server{Handler: c.newGateway()}
+		go c.gateway.Serve(gl)
+	}
 	if c.cfg.API.UseTLS {
 		go c.serv.ServeTLS(l, c.cfg.API.TLS.KeyFile, c.cfg.API.TLS.KeyPassFile)
 		return nil
@@ -234,15 +499,43 @@ func (c *APIContext) registerRoutes() {
 	v0.POST("/node/link", c.nodeLink())
 	v0.POST("/node/unlink", c.nodeUnlink())
 	v0.POST("/node/list", c.nodeList())
+	v0.POST("/node/backup", c.nodeBackup())
 	v0.POST("/ds/pin/ls", c.datastorePinLs())
 	v0.POST("/ds/upload", c.datastoreUploadFile())
+	v0.POST("/ds/repo/gc", c.datastoreRepoGC())
+	v0.POST("/ds/repo/stat", c.datastoreRepoStat())
+	v0.POST("/ds/repo/verify", c.datastoreRepoVerify())
+	v0.POST("/ds/repo/repair", c.datastoreRepoRepair())
+	v0.GET("/ds/dag/export", c.datastoreDagExport())
+	v0.POST("/ds/dag/import", c.datastoreDagImport())
+	v0.POST("/pay", c.pay())
+	v0.POST("/stats/bw", c.statsBandwidth())
+	v0.POST("/stats/qos", c.statsQoS())
+	v0.POST("/stats/stream", c.statsStream())
+	v0.POST("/chain/status", c.chainStatus())
+	v0.POST("/process/list", c.processList())
+	v0.POST("/name/publish", c.namePublish())
+	v0.POST("/name/resolve", c.nameResolve())
+	v0.POST("/key/gen", c.keyGen())
+	v0.POST("/key/list", c.keyList())
+	v0.POST("/key/rm", c.keyRm())
+	v0.POST("/catalog/export", c.catalogExport())
+	v0.POST("/catalog/import", c.catalogImport())
+	v0.POST("/task/list", c.taskList())
+	v0.POST("/task/cancel", c.taskCancel())
 	v0.GET("/get/:hash", c.get)
 	v0.GET("/get/:hash/*endpoint", c.get)
+	v0.GET("/stream/:hash/*endpoint", c.stream)
 	v0.GET("/query", c.query)
 }
 
 // Stop ...
 func (c *APIContext) Stop() error {
+	if c.gateway != nil {
+		if err := c.gateway.Shutdown(context.TODO()); err != nil {
+			return err
+		}
+	}
 	if c.serv != nil {
 		if err := c.serv.Shutdown(context.TODO()); err != nil {
 			return err
@@ -273,6 +566,30 @@ func (c *APIContext) setController(controller *controller.Controller) {
 	c.c = controller
 }
 
+func (c *APIContext) setSettler(settler *payment.Settler) {
+	c.settler = settler
+}
+
+func (c *APIContext) setBandwidth(bw *stats.Bandwidth) {
+	c.bw = bw
+}
+
+func (c *APIContext) setLimiter(limiter *qos.Limiter) {
+	c.limiter = limiter
+}
+
+func (c *APIContext) setCatalog(catalog *catalog.Catalog) {
+	c.catalog = catalog
+}
+
+func (c *APIContext) setTrust(trust *sign.Trust) {
+	c.trust = trust
+}
+
+func (c *APIContext) setIndexer(indexer *chain.Indexer) {
+	c.indexer = indexer
+}
+
 func (c *APIContext) id(ctx *gin.Context) {
 	id, err := c.ID(ctx.Request.Context(), &core.IDReq{})
 	JSON(ctx, id, err)
@@ -292,9 +609,30 @@ func (c *APIContext) get(ctx *gin.Context) {
 		ctx.Writer.WriteHeader(http.StatusBadRequest)
 		return
 	}
+	var w io.Writer = ctx.Writer
+	if c.limiter != nil {
+		w = c.limiter.Writer(ctx.Request.Context(), w, qos.Keys{
+			Token: strings.TrimPrefix(ctx.GetHeader(tokenHeader), "Bearer "),
+			IP:    ctx.ClientIP(),
+		})
+	}
+	if c.bw != nil {
+		w = stats.NewWriter(w, c.bw, hash)
+	}
 	switch fs := fs.(type) {
 	case files.File:
-		_, err = io.Copy(ctx.Writer, fs)
+		c.c.RecordAccess(ctx.Request.Context(), hash)
+		//a sealed content is served sealed unless a recipient proves it opens it
+		var r io.Reader = fs
+		if key := ctx.GetHeader(core.ContentKeyHeader); key != "" {
+			r, err = c.openSealed(ctx, hash, key, fs)
+			if err != nil {
+				log.Debugw("open sealed content", "hash", hash, "err", err)
+				ctx.Writer.WriteHeader(http.StatusForbidden)
+				return
+			}
+		}
+		_, err = io.Copy(w, r)
 		if err != nil {
 			ctx.Writer.WriteHeader(http.StatusBadRequest)
 			return
@@ -314,6 +652,34 @@ func (c *APIContext) get(ctx *gin.Context) {
 	return
 }
 
+// openSealed returns the reader opening the sealed content of hash by the hex content key,
+// the caller proves it is a recipient of the envelope kept in the catalog for the hash
+func (c *APIContext) openSealed(ctx *gin.Context, hash string, key string, r io.Reader) (io.Reader, error) {
+	if c.catalog == nil {
+		return nil, errors.New("catalog is not enabled")
+	}
+	security, err := c.catalog.Security(hash)
+	if err != nil {
+		return nil, err
+	}
+	env, err := seal.ParseEnvelope(security)
+	if err != nil {
+		return nil, err
+	}
+	t, err := strconv.ParseInt(ctx.GetHeader(core.ContentTimeHeader), 10, 64)
+	if err != nil {
+		return nil, seal.ErrProof
+	}
+	if err := seal.VerifyProof(env, hash, t, ctx.GetHeader(core.ContentProofHeader), time.Now()); err != nil {
+		return nil, err
+	}
+	b, err := hex.DecodeString(key)
+	if err != nil {
+		return nil, err
+	}
+	return seal.NewReader(r, b)
+}
+
 func directoryView(root files.Node) (interface{}, bool) {
 	switch fs := root.(type) {
 	case files.File:
@@ -334,6 +700,9 @@ func directoryView(root files.Node) (interface{}, bool) {
 }
 
 func ipfsGetURL(uri string) string {
+	if config.Global().Gateway.Enable {
+		return fmt.Sprintf("%s/%s", config.GatewayURL(), strings.TrimPrefix(uri, "/"))
+	}
 	return fmt.Sprintf("%s/%s", config.IPFSGatewayURL(), uri)
 }
 
@@ -408,6 +777,13 @@ func (c *APIContext) nodeList() func(ctx *gin.Context) {
 	}
 }
 
+func (c *APIContext) nodeBackup() func(ctx *gin.Context) {
+	return func(ctx *gin.Context) {
+		backup, err := c.Backup(ctx.Request.Context(), &core.NodeBackupReq{})
+		JSON(ctx, backup, err)
+	}
+}
+
 func (c *APIContext) datastorePinLs() gin.HandlerFunc {
 	return func(ctx *gin.Context) {
 		list, err := c.PinLs(ctx.Request.Context(), &core.DataStorePinLsReq{})
@@ -432,6 +808,138 @@ func (c *APIContext) add() gin.HandlerFunc {
 	}
 }
 
+func (c *APIContext) pay() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		var req core.PayReq
+		err := ctx.BindJSON(&req)
+		if err != nil {
+			JSON(ctx, nil, err)
+			return
+		}
+		resp, err := c.Pay(ctx.Request.Context(), &req)
+		JSON(ctx, resp, err)
+	}
+}
+
+func (c *APIContext) statsBandwidth() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		var req core.StatsBandwidthReq
+		err := ctx.BindJSON(&req)
+		if err != nil {
+			JSON(ctx, nil, err)
+			return
+		}
+		resp, err := c.Bandwidth(ctx.Request.Context(), &req)
+		JSON(ctx, resp, err)
+	}
+}
+
+func (c *APIContext) statsQoS() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		resp, err := c.QoS(ctx.Request.Context(), &core.StatsQoSReq{})
+		JSON(ctx, resp, err)
+	}
+}
+
+func (c *APIContext) chainStatus() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		resp, err := c.ChainAPI().Status(ctx.Request.Context(), &core.ChainStatusReq{})
+		JSON(ctx, resp, err)
+	}
+}
+
+func (c *APIContext) processList() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		resp, err := c.ProcessAPI().Processes(ctx.Request.Context(), &core.ProcessListReq{})
+		JSON(ctx, resp, err)
+	}
+}
+
+func (c *APIContext) namePublish() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		var req core.NamePublishReq
+		err := ctx.BindJSON(&req)
+		if err != nil {
+			JSON(ctx, nil, err)
+			return
+		}
+		resp, err := c.NameAPI().Publish(ctx.Request.Context(), &req)
+		JSON(ctx, resp, err)
+	}
+}
+
+func (c *APIContext) nameResolve() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		var req core.NameResolveReq
+		err := ctx.BindJSON(&req)
+		if err != nil {
+			JSON(ctx, nil, err)
+			return
+		}
+		resp, err := c.NameAPI().Resolve(ctx.Request.Context(), &req)
+		JSON(ctx, resp, err)
+	}
+}
+
+func (c *APIContext) keyGen() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		var req core.KeyGenReq
+		err := ctx.BindJSON(&req)
+		if err != nil {
+			JSON(ctx, nil, err)
+			return
+		}
+		resp, err := c.NameAPI().KeyGen(ctx.Request.Context(), &req)
+		JSON(ctx, resp, err)
+	}
+}
+
+func (c *APIContext) keyList() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		resp, err := c.NameAPI().KeyList(ctx.Request.Context(), &core.KeyListReq{})
+		JSON(ctx, resp, err)
+	}
+}
+
+func (c *APIContext) keyRm() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		var req core.KeyRmReq
+		err := ctx.BindJSON(&req)
+		if err != nil {
+			JSON(ctx, nil, err)
+			return
+		}
+		resp, err := c.NameAPI().KeyRm(ctx.Request.Context(), &req)
+		JSON(ctx, resp, err)
+	}
+}
+
+func (c *APIContext) catalogExport() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		var req core.CatalogExportReq
+		err := ctx.BindJSON(&req)
+		if err != nil {
+			JSON(ctx, nil, err)
+			return
+		}
+		resp, err := c.CatalogAPI().CatalogExport(ctx.Request.Context(), &req)
+		JSON(ctx, resp, err)
+	}
+}
+
+func (c *APIContext) catalogImport() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		var req core.CatalogImportReq
+		err := ctx.BindJSON(&req)
+		if err != nil {
+			JSON(ctx, nil, err)
+			return
+		}
+		resp, err := c.CatalogAPI().CatalogImport(ctx.Request.Context(), &req)
+		JSON(ctx, resp, err)
+	}
+}
+
 func (c *APIContext) datastoreUploadFile() gin.HandlerFunc {
 	return func(ctx *gin.Context) {
 		var req core.UploadReq
@@ -445,6 +953,96 @@ func (c *APIContext) datastoreUploadFile() gin.HandlerFunc {
 	}
 }
 
+func (c *APIContext) datastoreRepoGC() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		var req core.RepoGCReq
+		err := ctx.BindJSON(&req)
+		if err != nil {
+			JSON(ctx, nil, err)
+			return
+		}
+		resp, err := c.DataStoreAPI().RepoGC(ctx.Request.Context(), &req)
+		JSON(ctx, resp, err)
+	}
+}
+
+func (c *APIContext) datastoreRepoStat() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		resp, err := c.DataStoreAPI().RepoStat(ctx.Request.Context(), &core.RepoStatReq{})
+		JSON(ctx, resp, err)
+	}
+}
+
+func (c *APIContext) datastoreRepoVerify() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		resp, err := c.DataStoreAPI().RepoVerify(ctx.Request.Context(), &core.RepoVerifyReq{})
+		JSON(ctx, resp, err)
+	}
+}
+
+func (c *APIContext) datastoreRepoRepair() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		var req core.RepoRepairReq
+		err := ctx.BindJSON(&req)
+		if err != nil {
+			JSON(ctx, nil, err)
+			return
+		}
+		resp, err := c.DataStoreAPI().RepoRepair(ctx.Request.Context(), &req)
+		JSON(ctx, resp, err)
+	}
+}
+
+// carContentType is the media type of the car files
+const carContentType = "application/vnd.ipld.car"
+
+// writtenWriter records if anything is written, an error can only be responded before it
+type writtenWriter struct {
+	w       io.Writer
+	written bool
+}
+
+// Write ...
+func (w *writtenWriter) Write(p []byte) (int, error) {
+	w.written = true
+	return w.w.Write(p)
+}
+
+func (c *APIContext) datastoreDagExport() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		version, err := strconv.Atoi(ctx.DefaultQuery("version", "1"))
+		if err != nil {
+			JSON(ctx, nil, err)
+			return
+		}
+		ctx.Header("Content-Type", carContentType)
+		w := &writtenWriter{w: ctx.Writer}
+		_, err = c.DataStoreAPI().DagExport(ctx.Request.Context(), &core.DagExportReq{
+			Roots:   ctx.QueryArray("root"),
+			Version: version,
+			Writer:  w,
+		})
+		if err != nil {
+			if !w.written {
+				ctx.Writer.Header().Del("Content-Type")
+				JSON(ctx, nil, err)
+				return
+			}
+			log.Errorw("dag export", "err", err)
+		}
+	}
+}
+
+func (c *APIContext) datastoreDagImport() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		resp, err := c.DataStoreAPI().DagImport(ctx.Request.Context(), &core.DagImportReq{
+			Reader:   ctx.Request.Body,
+			PinRoots: ctx.Query("pin") == "true",
+		})
+		JSON(ctx, resp, err)
+	}
+}
+
 // JSON ...
 func JSON(c *gin.Context, v interface{}, e error) {
 	if e != nil {
@@ -0,0 +1,54 @@
+package service
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/glvd/accipfs/gateway"
+	"github.com/glvd/accipfs/qos"
+	"github.com/glvd/accipfs/stats"
+	files "github.com/ipfs/go-ipfs-files"
+)
+
+// newGateway create the http gateway served by the daemon, the content is read from the controller
+// with the same access records, bandwidth stats and qos limits of the get api
+func (c *APIContext) newGateway() *gateway.Gateway {
+	gw := gateway.New(func(ctx context.Context, contentPath string) (files.Node, error) {
+		if p := strings.TrimPrefix(contentPath, "/"+gateway.NamespaceIPFS+"/"); p != contentPath {
+			hash := strings.SplitN(p, "/", 2)[0]
+			if err := c.m.ConnRemoteFromHash(hash); err != nil {
+				log.Debugw("no accelerator node to connect", "hash", hash, "err", err)
+			}
+		}
+		return c.c.GetUnixfs(ctx, contentPath, "")
+	}, c.cfg.Gateway.Domains...)
+	gw.RegisterAccess(func(r *http.Request, namespace, root string, _ int64) {
+		if namespace == gateway.NamespaceIPFS {
+			c.c.RecordAccess(r.Context(), root)
+		}
+	})
+	gw.RegisterWriter(func(r *http.Request, root string, w io.Writer) io.Writer {
+		if c.limiter != nil {
+			w = c.limiter.Writer(r.Context(), w, qos.Keys{
+				Token: strings.TrimPrefix(r.Header.Get(tokenHeader), "Bearer "),
+				IP:    clientIP(r),
+			})
+		}
+		if c.bw != nil {
+			w = stats.NewWriter(w, c.bw, root)
+		}
+		return w
+	})
+	return gw
+}
+
+// clientIP returns the remote ip of r without the port
+func clientIP(r *http.Request) string {
+	addr := r.RemoteAddr
+	if i := strings.LastIndex(addr, ":"); i > 0 {
+		addr = addr[:i]
+	}
+	return strings.Trim(addr, "[]")
+}
@@ -0,0 +1,181 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"io/ioutil"
+	"mime"
+	"net/http"
+	"path"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/glvd/accipfs/core"
+	"github.com/glvd/accipfs/qos"
+	"github.com/glvd/accipfs/stats"
+	"github.com/glvd/accipfs/stream"
+	files "github.com/ipfs/go-ipfs-files"
+)
+
+// newPrefetcher returns the prefetcher reading the segments of <hash>/<endpoint> to the local blockstore
+func (c *APIContext) newPrefetcher(ctx context.Context) *stream.Prefetcher {
+	return stream.NewPrefetcher(ctx, func(ctx context.Context, segment string) error {
+		parts := strings.SplitN(segment, "/", 2)
+		if len(parts) != 2 {
+			return errors.New("wrong segment path")
+		}
+		fs, err := c.c.GetUnixfs(ctx, parts[0], parts[1])
+		if err != nil {
+			return err
+		}
+		defer fs.Close()
+		file, ok := fs.(files.File)
+		if !ok {
+			return errors.New("segment is not a file")
+		}
+		_, err = io.Copy(ioutil.Discard, file)
+		return err
+	}, c.cfg.Stream.Prefetch, c.cfg.Stream.Workers)
+}
+
+func (c *APIContext) setPrefetcher(prefetcher *stream.Prefetcher) {
+	c.prefetcher = prefetcher
+}
+
+// Stream ...
+func (c *APIContext) Stream(ctx context.Context, req *core.StatsStreamReq) (*core.StatsStreamResp, error) {
+	if c.prefetcher == nil {
+		return nil, errors.New("stream prefetch is not enabled")
+	}
+	s := c.prefetcher.Stats()
+	return &core.StatsStreamResp{
+		Requests:   s.Requests,
+		Hits:       s.Hits,
+		Late:       s.Late,
+		Misses:     s.Misses,
+		Prefetched: s.Prefetched,
+		Failed:     s.Failed,
+		HitRate:    s.HitRate,
+	}, nil
+}
+
+func (c *APIContext) statsStream() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		resp, err := c.Stream(ctx.Request.Context(), &core.StatsStreamReq{})
+		JSON(ctx, resp, err)
+	}
+}
+
+// responseWriter writes the content through the wrapped writer
+type responseWriter struct {
+	http.ResponseWriter
+	w io.Writer
+}
+
+// Write ...
+func (w *responseWriter) Write(p []byte) (int, error) {
+	return w.w.Write(p)
+}
+
+// stream serves the hls playlists with the segment uris rewritten to this endpoint,
+// the dash manifests as they are and the segments with ranges and the following ones prefetched.
+// The segments of a dash SegmentTemplate are served but not prefetched.
+func (c *APIContext) stream(ctx *gin.Context) {
+	hash := ctx.Param("hash")
+	ep := strings.TrimPrefix(ctx.Param("endpoint"), "/")
+	if err := c.m.ConnRemoteFromHash(hash); err != nil {
+		log.Debugw("no accelerator node to connect", "hash", hash, "err", err)
+	}
+	fs, err := c.c.GetUnixfs(ctx.Request.Context(), hash, ep)
+	if err != nil {
+		log.Errorw("get unixfs failed", "err", err)
+		ctx.Writer.WriteHeader(http.StatusBadRequest)
+		return
+	}
+	defer fs.Close()
+	file, ok := fs.(files.File)
+	if !ok {
+		ctx.Writer.WriteHeader(http.StatusBadRequest)
+		return
+	}
+	if stream.IsPlaylist(ep) {
+		c.streamPlaylist(ctx, hash, ep, file)
+		return
+	}
+	if stream.IsManifest(ep) {
+		c.streamManifest(ctx, hash, ep, file)
+		return
+	}
+	if c.prefetcher != nil {
+		c.prefetcher.Served(path.Join(hash, ep))
+	}
+	var w io.Writer = ctx.Writer
+	if c.limiter != nil {
+		w = c.limiter.Writer(ctx.Request.Context(), w, qos.Keys{
+			Token: strings.TrimPrefix(ctx.GetHeader(tokenHeader), "Bearer "),
+			IP:    ctx.ClientIP(),
+		})
+	}
+	if c.bw != nil {
+		w = stats.NewWriter(w, c.bw, hash)
+	}
+	c.c.RecordAccess(ctx.Request.Context(), hash)
+	if typ := mime.TypeByExtension(path.Ext(ep)); typ != "" {
+		ctx.Header("Content-Type", typ)
+	}
+	http.ServeContent(&responseWriter{ResponseWriter: ctx.Writer, w: w}, ctx.Request, path.Base(ep), time.Time{}, file)
+}
+
+func (c *APIContext) streamPlaylist(ctx *gin.Context, hash, ep string, file files.File) {
+	playlist, err := stream.ParsePlaylist(file)
+	if err != nil {
+		ctx.Writer.WriteHeader(http.StatusBadRequest)
+		return
+	}
+	prefix := strings.TrimSuffix(ctx.Request.URL.Path, ctx.Param("endpoint"))
+	var segments []string
+	body := playlist.Rewrite(func(uri string) string {
+		resolved := stream.Resolve(ep, uri)
+		if strings.HasPrefix(resolved, "../") {
+			//the uri out of the root is kept
+			return uri
+		}
+		return prefix + "/" + resolved
+	})
+	if !playlist.Master && c.prefetcher != nil {
+		for _, uri := range playlist.Segments {
+			if resolved := stream.Resolve(ep, uri); stream.IsLocal(uri) && !strings.HasPrefix(resolved, "../") {
+				segments = append(segments, path.Join(hash, resolved))
+			}
+		}
+		c.prefetcher.Index(segments)
+	}
+	ctx.Data(http.StatusOK, stream.PlaylistType, []byte(body))
+}
+
+func (c *APIContext) streamManifest(ctx *gin.Context, hash, ep string, file files.File) {
+	body, err := ioutil.ReadAll(file)
+	if err != nil {
+		ctx.Writer.WriteHeader(http.StatusBadRequest)
+		return
+	}
+	manifest, err := stream.ParseManifest(bytes.NewReader(body))
+	if err != nil {
+		ctx.Writer.WriteHeader(http.StatusBadRequest)
+		return
+	}
+	if c.prefetcher != nil {
+		var segments []string
+		for _, uri := range manifest.Segments {
+			if resolved := stream.Resolve(ep, uri); stream.IsLocal(uri) && !strings.HasPrefix(resolved, "../") {
+				segments = append(segments, path.Join(hash, resolved))
+			}
+		}
+		c.prefetcher.Index(segments)
+	}
+	//the relative uris already resolve to this endpoint
+	ctx.Data(http.StatusOK, stream.ManifestType, body)
+}
@@ -0,0 +1,98 @@
+package service
+
+import (
+	"context"
+	"errors"
+
+	"github.com/gin-gonic/gin"
+	"github.com/glvd/accipfs/core"
+	"github.com/glvd/accipfs/task"
+)
+
+// jobPin is the kind of the jobs replicating a path to this node by pinning it, the payload is the path
+const jobPin = "pin"
+
+// pinHandler returns the handler of the pin jobs
+func pinHandler(ds core.DataStoreAPI) task.Handler {
+	return func(ctx context.Context, job *task.Job) error {
+		_, err := ds.PinAdd(ctx, &core.DataStorePinAddReq{Pins: []string{string(job.Payload)}})
+		return err
+	}
+}
+
+// queuePin pushes a pin job of the path to replicate it in background
+func (c *APIContext) queuePin(path string, priority int) error {
+	if c.tasks == nil {
+		return errors.New("task queue is not enabled")
+	}
+	_, err := c.tasks.PushKind(jobPin, []byte(path), priority)
+	return err
+}
+
+// TaskAPI ...
+func (c *APIContext) TaskAPI() core.TaskAPI {
+	return c
+}
+
+// Jobs ...
+func (c *APIContext) Jobs(ctx context.Context, req *core.TaskListReq) (*core.TaskListResp, error) {
+	if c.tasks == nil {
+		return nil, errors.New("task queue is not enabled")
+	}
+	resp := &core.TaskListResp{}
+	for _, job := range c.tasks.Jobs(task.State(req.State)) {
+		resp.Jobs = append(resp.Jobs, core.JobStatus{
+			ID:        job.ID,
+			Kind:      job.Kind,
+			Priority:  job.Priority,
+			State:     string(job.State),
+			Attempts:  job.Attempts,
+			Error:     job.Error,
+			Created:   job.Created,
+			Updated:   job.Updated,
+			NotBefore: job.NotBefore,
+		})
+	}
+	return resp, nil
+}
+
+// JobCancel ...
+func (c *APIContext) JobCancel(ctx context.Context, req *core.TaskCancelReq) (*core.TaskCancelResp, error) {
+	if c.tasks == nil {
+		return nil, errors.New("task queue is not enabled")
+	}
+	if err := c.tasks.Cancel(req.ID); err != nil {
+		return nil, err
+	}
+	return &core.TaskCancelResp{}, nil
+}
+
+func (c *APIContext) setTasks(tasks *task.Queue) {
+	c.tasks = tasks
+}
+
+func (c *APIContext) taskList() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		var req core.TaskListReq
+		err := ctx.BindJSON(&req)
+		if err != nil {
+			JSON(ctx, nil, err)
+			return
+		}
+		resp, err := c.TaskAPI().Jobs(ctx.Request.Context(), &req)
+		JSON(ctx, resp, err)
+	}
+}
+
+func (c *APIContext) taskCancel() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		var req core.TaskCancelReq
+		err := ctx.BindJSON(&req)
+		if err != nil {
+			JSON(ctx, nil, err)
+			return
+		}
+		resp, err := c.TaskAPI().JobCancel(ctx.Request.Context(), &req)
+		JSON(ctx, resp, err)
+	}
+}
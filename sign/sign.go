@@ -0,0 +1,167 @@
+package sign
+
+import (
+	"bytes"
+	"crypto/ecdsa"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/ethereum/go-ethereum/accounts"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/glvd/accipfs/core"
+	ic "github.com/libp2p/go-libp2p-core/crypto"
+	"github.com/libp2p/go-libp2p-core/peer"
+)
+
+// Prefixes of DataInfoV1.Signer by the kind of the publisher key
+const (
+	PrefixETH = "eth:"
+	PrefixP2P = "p2p:"
+)
+
+// ErrUnsigned ...
+var ErrUnsigned = errors.New("info is not signed")
+
+// ErrSignature ...
+var ErrSignature = errors.New("signature is invalid")
+
+// ErrUntrusted ...
+var ErrUntrusted = errors.New("signer is not trusted")
+
+// ErrNoPublisher ...
+var ErrNoPublisher = errors.New("signed infos are required without a publisher")
+
+// ETH signs the info with an ethereum account key, the digest is signed as a personal message (EIP-191)
+// so a wallet can sign it too, Signer is eth:<address> and Signature is the 65 bytes signature in hex
+func ETH(info *core.DataInfoV1, key *ecdsa.PrivateKey) error {
+	digest, err := info.Digest()
+	if err != nil {
+		return err
+	}
+	sig, err := crypto.Sign(accounts.TextHash(digest), key)
+	if err != nil {
+		return err
+	}
+	info.Signer = PrefixETH + strings.ToLower(crypto.PubkeyToAddress(key.PublicKey).Hex())
+	info.Signature = hex.EncodeToString(sig)
+	return nil
+}
+
+// P2P signs the info with a libp2p identity key, Signer is p2p:<peer id>
+// and Signature is <public key>.<signature> in base64, the public key is carried for the keys not inlined in the id
+func P2P(info *core.DataInfoV1, key ic.PrivKey) error {
+	digest, err := info.Digest()
+	if err != nil {
+		return err
+	}
+	sig, err := key.Sign(digest)
+	if err != nil {
+		return err
+	}
+	id, err := peer.IDFromPrivateKey(key)
+	if err != nil {
+		return err
+	}
+	pub, err := ic.MarshalPublicKey(key.GetPublic())
+	if err != nil {
+		return err
+	}
+	info.Signer = PrefixP2P + id.Pretty()
+	info.Signature = base64.RawStdEncoding.EncodeToString(pub) + "." + base64.RawStdEncoding.EncodeToString(sig)
+	return nil
+}
+
+// Verify checks the signature of the info against its digest and returns the normalized signer
+func Verify(info *core.DataInfoV1) (string, error) {
+	if !info.Signed() {
+		return "", ErrUnsigned
+	}
+	digest, err := info.Digest()
+	if err != nil {
+		return "", err
+	}
+	switch {
+	case strings.HasPrefix(info.Signer, PrefixETH):
+		return verifyETH(info, digest)
+	case strings.HasPrefix(info.Signer, PrefixP2P):
+		return verifyP2P(info, digest)
+	}
+	return "", fmt.Errorf("%w(unknown signer %s)", ErrSignature, info.Signer)
+}
+
+func verifyETH(info *core.DataInfoV1, digest []byte) (string, error) {
+	signer, err := Normalize(info.Signer)
+	if err != nil {
+		return "", err
+	}
+	sig, err := hex.DecodeString(strings.TrimPrefix(info.Signature, "0x"))
+	if err != nil || len(sig) != crypto.SignatureLength {
+		return "", fmt.Errorf("%w(bad signature)", ErrSignature)
+	}
+	//the signatures of the wallets use 27/28 as the recovery id
+	if sig[crypto.RecoveryIDOffset] >= 27 {
+		sig[crypto.RecoveryIDOffset] -= 27
+	}
+	pub, err := crypto.SigToPub(accounts.TextHash(digest), sig)
+	if err != nil {
+		return "", fmt.Errorf("%w(%v)", ErrSignature, err)
+	}
+	if !bytes.Equal(crypto.PubkeyToAddress(*pub).Bytes(), common.HexToAddress(strings.TrimPrefix(signer, PrefixETH)).Bytes()) {
+		return "", fmt.Errorf("%w(signer mismatch)", ErrSignature)
+	}
+	return signer, nil
+}
+
+func verifyP2P(info *core.DataInfoV1, digest []byte) (string, error) {
+	id, err := peer.Decode(strings.TrimPrefix(info.Signer, PrefixP2P))
+	if err != nil {
+		return "", fmt.Errorf("%w(%v)", ErrSignature, err)
+	}
+	parts := strings.Split(info.Signature, ".")
+	if len(parts) != 2 {
+		return "", fmt.Errorf("%w(bad signature)", ErrSignature)
+	}
+	pubBytes, err := base64.RawStdEncoding.DecodeString(parts[0])
+	if err != nil {
+		return "", fmt.Errorf("%w(%v)", ErrSignature, err)
+	}
+	sig, err := base64.RawStdEncoding.DecodeString(parts[1])
+	if err != nil {
+		return "", fmt.Errorf("%w(%v)", ErrSignature, err)
+	}
+	pub, err := ic.UnmarshalPublicKey(pubBytes)
+	if err != nil {
+		return "", fmt.Errorf("%w(%v)", ErrSignature, err)
+	}
+	if !id.MatchesPublicKey(pub) {
+		return "", fmt.Errorf("%w(signer mismatch)", ErrSignature)
+	}
+	ok, err := pub.Verify(digest, sig)
+	if err != nil || !ok {
+		return "", ErrSignature
+	}
+	return PrefixP2P + id.Pretty(), nil
+}
+
+// Normalize returns the signer in the form kept in Signer, a bare ethereum address is taken as eth:<address>
+func Normalize(signer string) (string, error) {
+	signer = strings.TrimSpace(signer)
+	switch {
+	case strings.HasPrefix(signer, PrefixP2P):
+		id, err := peer.Decode(strings.TrimPrefix(signer, PrefixP2P))
+		if err != nil {
+			return "", fmt.Errorf("invalid signer(%s):%w", signer, err)
+		}
+		return PrefixP2P + id.Pretty(), nil
+	case strings.HasPrefix(signer, PrefixETH):
+		signer = strings.TrimPrefix(signer, PrefixETH)
+	}
+	if !common.IsHexAddress(signer) {
+		return "", fmt.Errorf("invalid signer(%s)", signer)
+	}
+	return PrefixETH + strings.ToLower(common.HexToAddress(signer).Hex()), nil
+}
@@ -0,0 +1,150 @@
+package sign
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/glvd/accipfs/core"
+	ic "github.com/libp2p/go-libp2p-core/crypto"
+)
+
+func testInfo() *core.DataInfoV1 {
+	return &core.DataInfoV1{
+		RootHash:  "QmRoot",
+		MediaInfo: core.MediaInfo{No: "abc-001", Intro: "intro"},
+		MediaHash: "QmMedia",
+	}
+}
+
+func TestETH(t *testing.T) {
+	key, err := crypto.GenerateKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	info := testInfo()
+	hash := info.Hash()
+	if err := ETH(info, key); err != nil {
+		t.Fatal(err)
+	}
+	if info.Hash() != hash {
+		t.Fatal("signing changed the hash of the info")
+	}
+	signer, err := Verify(info)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := PrefixETH + strings.ToLower(crypto.PubkeyToAddress(key.PublicKey).Hex())
+	if signer != want || info.Signer != want {
+		t.Fatalf("signer %s want %s", signer, want)
+	}
+
+	//a wallet signature with the 27/28 recovery id is accepted
+	sig, _ := hex.DecodeString(info.Signature)
+	sig[64] += 27
+	wallet := *info
+	wallet.Signature = "0x" + hex.EncodeToString(sig)
+	if _, err := Verify(&wallet); err != nil {
+		t.Fatal(err)
+	}
+
+	info.MediaInfo.Intro = "changed"
+	if _, err := Verify(info); !errors.Is(err, ErrSignature) {
+		t.Fatalf("tampered info verified: %v", err)
+	}
+}
+
+func TestP2P(t *testing.T) {
+	for _, typ := range []int{ic.Ed25519, ic.Secp256k1, ic.RSA} {
+		key, _, err := ic.GenerateKeyPairWithReader(typ, 2048, rand.Reader)
+		if err != nil {
+			t.Fatal(err)
+		}
+		info := testInfo()
+		if err := P2P(info, key); err != nil {
+			t.Fatal(err)
+		}
+		signer, err := Verify(info)
+		if err != nil {
+			t.Fatalf("type %d: %v", typ, err)
+		}
+		if signer != info.Signer || !strings.HasPrefix(signer, PrefixP2P) {
+			t.Fatalf("signer %s", signer)
+		}
+
+		//a signature with another key does not verify for the signer
+		other, _, _ := ic.GenerateKeyPairWithReader(ic.Ed25519, 0, rand.Reader)
+		forged := testInfo()
+		if err := P2P(forged, other); err != nil {
+			t.Fatal(err)
+		}
+		forged.Signer = info.Signer
+		if _, err := Verify(forged); !errors.Is(err, ErrSignature) {
+			t.Fatalf("forged info verified: %v", err)
+		}
+	}
+}
+
+func TestTrust(t *testing.T) {
+	trusted, _ := crypto.GenerateKey()
+	other, _ := crypto.GenerateKey()
+	addr := crypto.PubkeyToAddress(trusted.PublicKey).Hex()
+
+	trust, err := NewTrust([]string{addr}, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	info := testInfo()
+	if err := trust.Check(info); err != nil {
+		t.Fatalf("unsigned info refused: %v", err)
+	}
+	if err := ETH(info, trusted); err != nil {
+		t.Fatal(err)
+	}
+	if err := trust.Check(info); err != nil {
+		t.Fatal(err)
+	}
+	if err := ETH(info, other); err != nil {
+		t.Fatal(err)
+	}
+	if err := trust.Check(info); !errors.Is(err, ErrUntrusted) {
+		t.Fatalf("untrusted signer accepted: %v", err)
+	}
+
+	//an empty list refuses every signer
+	empty, err := NewTrust(nil, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := empty.Check(info); !errors.Is(err, ErrUntrusted) {
+		t.Fatalf("signer accepted by an empty list: %v", err)
+	}
+	if err := empty.Check(testInfo()); err != nil {
+		t.Fatalf("unsigned info refused: %v", err)
+	}
+	var none *Trust
+	if err := none.Check(info); !errors.Is(err, ErrUntrusted) {
+		t.Fatalf("signer accepted by a nil trust: %v", err)
+	}
+
+	if _, err := NewTrust(nil, true); !errors.Is(err, ErrNoPublisher) {
+		t.Fatalf("required without publishers: %v", err)
+	}
+	required, err := NewTrust([]string{crypto.PubkeyToAddress(other.PublicKey).Hex()}, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := required.Check(info); err != nil {
+		t.Fatal(err)
+	}
+	if err := required.Check(testInfo()); !errors.Is(err, ErrUnsigned) {
+		t.Fatalf("unsigned info accepted: %v", err)
+	}
+
+	if _, err := NewTrust([]string{"not a signer"}, false); err == nil {
+		t.Fatal("invalid signer accepted")
+	}
+}
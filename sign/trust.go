@@ -0,0 +1,56 @@
+package sign
+
+import (
+	"fmt"
+
+	"github.com/glvd/accipfs/core"
+)
+
+// Trust checks the signed infos against a list of accepted publishers
+type Trust struct {
+	signers  map[string]bool
+	required bool
+}
+
+// NewTrust returns the trust of the signers, only the listed signers are accepted so a signed info is refused
+// when the list is empty, the unsigned infos are refused only when required is set which needs a signer
+func NewTrust(signers []string, required bool) (*Trust, error) {
+	if required && len(signers) == 0 {
+		return nil, ErrNoPublisher
+	}
+	t := &Trust{
+		signers:  make(map[string]bool, len(signers)),
+		required: required,
+	}
+	for _, s := range signers {
+		signer, err := Normalize(s)
+		if err != nil {
+			return nil, err
+		}
+		t.signers[signer] = true
+	}
+	return t, nil
+}
+
+// Trusted reports whether the signer is accepted
+func (t *Trust) Trusted(signer string) bool {
+	return t != nil && t.signers[signer]
+}
+
+// Check verifies the signature of the info and its signer, a nil trust refuses every signed info
+func (t *Trust) Check(info *core.DataInfoV1) error {
+	if !info.Signed() {
+		if t != nil && t.required {
+			return ErrUnsigned
+		}
+		return nil
+	}
+	signer, err := Verify(info)
+	if err != nil {
+		return err
+	}
+	if !t.Trusted(signer) {
+		return fmt.Errorf("%w(%s)", ErrUntrusted, signer)
+	}
+	return nil
+}
@@ -0,0 +1,281 @@
+package stats
+
+import (
+	"context"
+	"encoding/json"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/dgraph-io/badger/v2"
+	"github.com/glvd/accipfs/basis/kv"
+	"github.com/glvd/accipfs/core"
+)
+
+// Kind ...
+type Kind string
+
+const (
+	// KindPeer ...
+	KindPeer Kind = "peer"
+	// KindCID ...
+	KindCID Kind = "cid"
+)
+
+// MaxBuckets is the hours of rolling counters kept for every key
+const MaxBuckets = 24
+
+// Bucket ...
+type Bucket struct {
+	Hour int64  `json:"hour"`
+	In   uint64 `json:"in"`
+	Out  uint64 `json:"out"`
+}
+
+// Counter ...
+type Counter struct {
+	Key        string   `json:"key"`
+	In         uint64   `json:"in"`
+	Out        uint64   `json:"out"`
+	Buckets    []Bucket `json:"buckets"`
+	LastUpdate int64    `json:"last_update"`
+}
+
+// Bandwidth keeps the rolling bandwidth counters of peers and root cids
+type Bandwidth struct {
+	db      *badger.DB
+	lock    sync.Mutex
+	pending map[string]core.BandwidthTotal
+	last    map[string]core.BandwidthTotal
+}
+
+var _ core.BandwidthRecorder = &Bandwidth{}
+
+// Marshal ...
+func (c Counter) Marshal() ([]byte, error) {
+	return json.Marshal(c)
+}
+
+// Unmarshal ...
+func (c *Counter) Unmarshal(b []byte) error {
+	return json.Unmarshal(b, c)
+}
+
+func (c *Counter) add(now time.Time, in, out uint64) {
+	hour := now.Unix() / 3600
+	c.In += in
+	c.Out += out
+	c.LastUpdate = now.Unix()
+	if n := len(c.Buckets); n > 0 && c.Buckets[n-1].Hour == hour {
+		c.Buckets[n-1].In += in
+		c.Buckets[n-1].Out += out
+	} else {
+		c.Buckets = append(c.Buckets, Bucket{
+			Hour: hour,
+			In:   in,
+			Out:  out,
+		})
+	}
+	for len(c.Buckets) > 0 && c.Buckets[0].Hour <= hour-MaxBuckets {
+		c.Buckets = c.Buckets[1:]
+	}
+}
+
+// Window returns the bytes counted in the last d, all the bytes are returned when d is zero
+func (c Counter) Window(now time.Time, d time.Duration) (in uint64, out uint64) {
+	if d <= 0 {
+		return c.In, c.Out
+	}
+	from := now.Add(-d).Unix() / 3600
+	for _, b := range c.Buckets {
+		if b.Hour < from {
+			continue
+		}
+		in += b.In
+		out += b.Out
+	}
+	return
+}
+
+func dbKey(kind Kind, key string) string {
+	return string(kind) + "/" + key
+}
+
+// OpenBandwidth ...
+func OpenBandwidth(path string) (*Bandwidth, error) {
+	db, err := kv.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	return &Bandwidth{
+		db:      db,
+		pending: make(map[string]core.BandwidthTotal),
+		last:    make(map[string]core.BandwidthTotal),
+	}, nil
+}
+
+func (b *Bandwidth) record(key string, in, out uint64) {
+	if in == 0 && out == 0 {
+		return
+	}
+	b.lock.Lock()
+	t := b.pending[key]
+	t.In += in
+	t.Out += out
+	b.pending[key] = t
+	b.lock.Unlock()
+}
+
+// RecordPeer ...
+func (b *Bandwidth) RecordPeer(id string, in, out uint64) {
+	if id == "" {
+		return
+	}
+	b.record(dbKey(KindPeer, id), in, out)
+}
+
+// RecordCID ...
+func (b *Bandwidth) RecordCID(cid string, in, out uint64) {
+	if cid == "" {
+		return
+	}
+	b.record(dbKey(KindCID, cid), in, out)
+}
+
+// ObservePeers records the difference between the peer totals and the previous observed totals
+func (b *Bandwidth) ObservePeers(totals map[string]core.BandwidthTotal) {
+	for id, total := range totals {
+		b.lock.Lock()
+		last := b.last[id]
+		b.last[id] = total
+		b.lock.Unlock()
+		if total.In < last.In || total.Out < last.Out {
+			//reporter was restarted
+			last = core.BandwidthTotal{}
+		}
+		b.RecordPeer(id, total.In-last.In, total.Out-last.Out)
+	}
+}
+
+// Flush writes the pending counters to the datastore
+func (b *Bandwidth) Flush() error {
+	b.lock.Lock()
+	pending := b.pending
+	b.pending = make(map[string]core.BandwidthTotal)
+	b.lock.Unlock()
+	if len(pending) == 0 {
+		return nil
+	}
+	now := time.Now()
+	return b.db.Update(func(txn *badger.Txn) error {
+		for key, t := range pending {
+			var c Counter
+			item, err := txn.Get([]byte(key))
+			switch err {
+			case nil:
+				err = item.Value(func(val []byte) error {
+					return c.Unmarshal(val)
+				})
+				if err != nil {
+					return err
+				}
+			case badger.ErrKeyNotFound:
+				c.Key = key[strings.Index(key, "/")+1:]
+			default:
+				return err
+			}
+			c.add(now, t.In, t.Out)
+			encode, err := c.Marshal()
+			if err != nil {
+				return err
+			}
+			if err := txn.Set([]byte(key), encode); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// Top returns the n counters of kind with the most bytes in the window, all counters are returned when n is zero
+func (b *Bandwidth) Top(kind Kind, n int, window time.Duration) ([]core.BandwidthStat, error) {
+	if err := b.Flush(); err != nil {
+		return nil, err
+	}
+	now := time.Now()
+	var stats []core.BandwidthStat
+	prefix := []byte(dbKey(kind, ""))
+	err := b.db.View(func(txn *badger.Txn) error {
+		iter := txn.NewIterator(badger.DefaultIteratorOptions)
+		defer iter.Close()
+		for iter.Seek(prefix); iter.ValidForPrefix(prefix); iter.Next() {
+			var c Counter
+			err := iter.Item().Value(func(val []byte) error {
+				return c.Unmarshal(val)
+			})
+			if err != nil {
+				return err
+			}
+			in, out := c.Window(now, window)
+			if in == 0 && out == 0 {
+				continue
+			}
+			stats = append(stats, core.BandwidthStat{
+				Key:        c.Key,
+				In:         in,
+				Out:        out,
+				TotalIn:    c.In,
+				TotalOut:   c.Out,
+				LastUpdate: c.LastUpdate,
+			})
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	sort.Slice(stats, func(i, j int) bool {
+		return stats[i].In+stats[i].Out > stats[j].In+stats[j].Out
+	})
+	if n > 0 && len(stats) > n {
+		stats = stats[:n]
+	}
+	return stats, nil
+}
+
+// Run observes the peer totals and flushes the counters every interval until ctx is done
+func (b *Bandwidth) Run(ctx context.Context, interval time.Duration, totals func() map[string]core.BandwidthTotal) {
+	t := time.NewTicker(interval)
+	defer t.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			if err := b.Flush(); err != nil {
+				log.Errorw("flush bandwidth", "err", err)
+			}
+			return
+		case <-t.C:
+			if totals != nil {
+				b.ObservePeers(totals())
+			}
+			if err := b.Flush(); err != nil {
+				log.Errorw("flush bandwidth", "err", err)
+			}
+		}
+	}
+}
+
+// Close ...
+func (b *Bandwidth) Close() error {
+	if b.db != nil {
+		defer func() {
+			b.db = nil
+		}()
+		if err := b.Flush(); err != nil {
+			log.Errorw("flush bandwidth", "err", err)
+		}
+		return b.db.Close()
+	}
+	return nil
+}
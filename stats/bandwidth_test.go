@@ -0,0 +1,76 @@
+package stats
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/glvd/accipfs/core"
+)
+
+func TestBandwidth_Top(t *testing.T) {
+	dir, err := ioutil.TempDir("", "bandwidth")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	bw, err := OpenBandwidth(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer bw.Close()
+
+	bw.RecordPeer("peer1", 10, 100)
+	bw.RecordPeer("peer2", 1, 1)
+	bw.ObservePeers(map[string]core.BandwidthTotal{"peer2": {In: 50, Out: 50}})
+	bw.ObservePeers(map[string]core.BandwidthTotal{"peer2": {In: 60, Out: 500}})
+
+	var buf bytes.Buffer
+	w := NewWriter(&buf, bw, "cid1")
+	if _, err := w.Write([]byte("hello")); err != nil {
+		t.Fatal(err)
+	}
+
+	peers, err := bw.Top(KindPeer, 0, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(peers) != 2 {
+		t.Fatalf("want 2 peers got %d", len(peers))
+	}
+	if peers[0].Key != "peer2" || peers[0].In != 61 || peers[0].Out != 501 {
+		t.Fatalf("wrong top peer: %+v", peers[0])
+	}
+	top, err := bw.Top(KindPeer, 1, time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(top) != 1 || top[0].Key != "peer2" {
+		t.Fatalf("wrong top 1: %+v", top)
+	}
+	cids, err := bw.Top(KindCID, 0, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(cids) != 1 || cids[0].Key != "cid1" || cids[0].Out != 5 {
+		t.Fatalf("wrong cids: %+v", cids)
+	}
+}
+
+func TestCounter_Window(t *testing.T) {
+	now := time.Now()
+	var c Counter
+	c.add(now.Add(-3*time.Hour), 1, 1)
+	c.add(now.Add(-MaxBuckets*time.Hour-time.Hour), 100, 100)
+	c.add(now, 2, 2)
+	in, out := c.Window(now, time.Hour)
+	if in != 2 || out != 2 {
+		t.Fatalf("wrong window: %d %d", in, out)
+	}
+	in, _ = c.Window(now, 0)
+	if in != 103 {
+		t.Fatalf("wrong total: %d", in)
+	}
+}
@@ -0,0 +1,97 @@
+package stats
+
+import (
+	"io"
+	"net"
+
+	"github.com/glvd/accipfs/core"
+	"go.uber.org/atomic"
+)
+
+// Conn counts the bytes read and written on a connection for the remote peer,
+// bytes are kept pending until the peer id is known
+type Conn struct {
+	net.Conn
+	recorder   core.BandwidthRecorder
+	peer       *atomic.String
+	pendingIn  *atomic.Uint64
+	pendingOut *atomic.Uint64
+}
+
+// Writer counts the bytes written to a root cid
+type Writer struct {
+	io.Writer
+	recorder core.BandwidthRecorder
+	cid      string
+}
+
+// NewConn ...
+func NewConn(conn net.Conn, recorder core.BandwidthRecorder) *Conn {
+	return &Conn{
+		Conn:       conn,
+		recorder:   recorder,
+		peer:       atomic.NewString(""),
+		pendingIn:  atomic.NewUint64(0),
+		pendingOut: atomic.NewUint64(0),
+	}
+}
+
+// SetPeer ...
+func (c *Conn) SetPeer(id string) {
+	if id == "" || c.peer.Load() != "" {
+		return
+	}
+	c.peer.Store(id)
+	c.record(0, 0)
+}
+
+func (c *Conn) record(in, out uint64) {
+	if c.recorder == nil {
+		return
+	}
+	id := c.peer.Load()
+	if id == "" {
+		c.pendingIn.Add(in)
+		c.pendingOut.Add(out)
+		return
+	}
+	in += c.pendingIn.Swap(0)
+	out += c.pendingOut.Swap(0)
+	c.recorder.RecordPeer(id, in, out)
+}
+
+// Read ...
+func (c *Conn) Read(b []byte) (n int, err error) {
+	n, err = c.Conn.Read(b)
+	if n > 0 {
+		c.record(uint64(n), 0)
+	}
+	return
+}
+
+// Write ...
+func (c *Conn) Write(b []byte) (n int, err error) {
+	n, err = c.Conn.Write(b)
+	if n > 0 {
+		c.record(0, uint64(n))
+	}
+	return
+}
+
+// NewWriter ...
+func NewWriter(w io.Writer, recorder core.BandwidthRecorder, cid string) *Writer {
+	return &Writer{
+		Writer:   w,
+		recorder: recorder,
+		cid:      cid,
+	}
+}
+
+// Write ...
+func (w *Writer) Write(b []byte) (n int, err error) {
+	n, err = w.Writer.Write(b)
+	if n > 0 && w.recorder != nil {
+		w.recorder.RecordCID(w.cid, 0, uint64(n))
+	}
+	return
+}
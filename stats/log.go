@@ -0,0 +1,9 @@
+package stats
+
+import (
+	alog "github.com/glvd/accipfs/log"
+)
+
+const module = "stats"
+
+var log = alog.Module(module)
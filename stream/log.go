@@ -0,0 +1,9 @@
+package stream
+
+import (
+	alog "github.com/glvd/accipfs/log"
+)
+
+const module = "stream"
+
+var log = alog.Module(module)
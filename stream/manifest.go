@@ -0,0 +1,76 @@
+package stream
+
+import (
+	"encoding/xml"
+	"io"
+	"path"
+	"strings"
+)
+
+// ManifestExt ...
+const ManifestExt = ".mpd"
+
+// ManifestType ...
+const ManifestType = "application/dash+xml"
+
+// Manifest is a dash manifest, it is served as it is because the uris are relative to the manifest.
+// Only the segments listed by the SegmentList are known, the ones of a SegmentTemplate are not
+// expanded and not prefetched.
+type Manifest struct {
+	Segments []string //uris of the initialization and media segments in the order
+}
+
+// IsManifest returns whether the path is a dash manifest
+func IsManifest(p string) bool {
+	return strings.EqualFold(path.Ext(p), ManifestExt)
+}
+
+// ParseManifest ...
+func ParseManifest(r io.Reader) (*Manifest, error) {
+	m := &Manifest{}
+	d := xml.NewDecoder(r)
+	//the base url of every open element
+	bases := []string{""}
+	for {
+		tok, err := d.Token()
+		if err == io.EOF {
+			return m, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		switch t := tok.(type) {
+		case xml.StartElement:
+			base := bases[len(bases)-1]
+			if t.Name.Local == "BaseURL" {
+				var v string
+				if err := d.DecodeElement(&v, &t); err != nil {
+					return nil, err
+				}
+				//the base url applies to the parent element
+				bases[len(bases)-1] = joinBase(base, strings.TrimSpace(v))
+				continue
+			}
+			bases = append(bases, base)
+			for _, attr := range t.Attr {
+				if (t.Name.Local == "SegmentURL" && attr.Name.Local == "media") ||
+					(t.Name.Local == "Initialization" && attr.Name.Local == "sourceURL") {
+					m.Segments = append(m.Segments, joinBase(base, attr.Value))
+				}
+			}
+		case xml.EndElement:
+			bases = bases[:len(bases)-1]
+		}
+	}
+}
+
+// joinBase returns the uri relative to the base url
+func joinBase(base, uri string) string {
+	if base == "" || !IsLocal(uri) {
+		return uri
+	}
+	if strings.HasSuffix(base, "/") {
+		return base + uri
+	}
+	return path.Join(path.Dir(base), uri)
+}
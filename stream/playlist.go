@@ -0,0 +1,87 @@
+package stream
+
+import (
+	"bufio"
+	"io"
+	"path"
+	"regexp"
+	"strings"
+)
+
+// PlaylistExt ...
+const PlaylistExt = ".m3u8"
+
+// PlaylistType ...
+const PlaylistType = "application/vnd.apple.mpegurl"
+
+var uriAttr = regexp.MustCompile(`URI="([^"]*)"`)
+
+// Playlist is a hls playlist, the uri lines and the uri attributes of the tags can be rewritten
+type Playlist struct {
+	Master   bool     //the playlist lists the variant streams instead of the segments
+	Lines    []string //the lines without the line endings
+	Segments []string //uris of the media segments or the variant playlists in the order
+}
+
+// IsPlaylist returns whether the path is a hls playlist
+func IsPlaylist(p string) bool {
+	return strings.EqualFold(path.Ext(p), PlaylistExt)
+}
+
+// ParsePlaylist ...
+func ParsePlaylist(r io.Reader) (*Playlist, error) {
+	p := &Playlist{}
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		line := strings.TrimRight(scanner.Text(), "\r")
+		p.Lines = append(p.Lines, line)
+		trimmed := strings.TrimSpace(line)
+		switch {
+		case trimmed == "":
+		case strings.HasPrefix(trimmed, "#EXT-X-STREAM-INF"):
+			p.Master = true
+		case strings.HasPrefix(trimmed, "#"):
+		default:
+			p.Segments = append(p.Segments, trimmed)
+		}
+	}
+	return p, scanner.Err()
+}
+
+// IsLocal returns whether the uri is relative to the playlist
+func IsLocal(uri string) bool {
+	return uri != "" && !strings.HasPrefix(uri, "/") && !strings.Contains(uri, "://") && !strings.HasPrefix(uri, "data:")
+}
+
+// Resolve returns the path of a local uri relative to the playlist path, the query is dropped
+func Resolve(playlist, uri string) string {
+	if i := strings.IndexAny(uri, "?#"); i >= 0 {
+		uri = uri[:i]
+	}
+	return path.Join(path.Dir(playlist), uri)
+}
+
+// Rewrite returns the playlist with the local uris replaced by f
+func (p *Playlist) Rewrite(f func(uri string) string) string {
+	var b strings.Builder
+	for _, line := range p.Lines {
+		trimmed := strings.TrimSpace(line)
+		switch {
+		case trimmed == "":
+		case strings.HasPrefix(trimmed, "#"):
+			line = uriAttr.ReplaceAllStringFunc(line, func(attr string) string {
+				uri := uriAttr.FindStringSubmatch(attr)[1]
+				if !IsLocal(uri) {
+					return attr
+				}
+				return `URI="` + f(uri) + `"`
+			})
+		case IsLocal(trimmed):
+			line = f(trimmed)
+		}
+		b.WriteString(line)
+		b.WriteString("\n")
+	}
+	return b.String()
+}
@@ -0,0 +1,185 @@
+package stream
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"go.uber.org/atomic"
+)
+
+// maxEntries bounds the indexed segments and the prefetched states
+const maxEntries = 16384
+
+// fetchTimeout bounds a segment prefetch
+const fetchTimeout = 2 * time.Minute
+
+// FetchFunc reads the content of the path to the local blockstore
+type FetchFunc func(ctx context.Context, path string) error
+
+type fetchState int
+
+const (
+	stateFetching fetchState = iota + 1
+	stateFetched
+)
+
+// Stats is the prefetch metrics, a request is a hit when its segment was prefetched before,
+// late when the prefetch is still running and a miss otherwise
+type Stats struct {
+	Requests   uint64
+	Hits       uint64
+	Late       uint64
+	Misses     uint64
+	Prefetched uint64
+	Failed     uint64
+	HitRate    float64
+}
+
+// Prefetcher fetches the segments following the requested one in the background
+type Prefetcher struct {
+	ctx    context.Context
+	fetch  FetchFunc
+	depth  int
+	sem    chan struct{}
+	lock   sync.Mutex
+	next   map[string][]string //segment -> the following segments of its playlist
+	states map[string]fetchState
+	order  []string //prefetched segments in the fetched order to bound the states
+
+	requests   *atomic.Uint64
+	hits       *atomic.Uint64
+	late       *atomic.Uint64
+	misses     *atomic.Uint64
+	prefetched *atomic.Uint64
+	failed     *atomic.Uint64
+}
+
+// NewPrefetcher returns a prefetcher fetching depth segments ahead on workers at once until ctx is done
+func NewPrefetcher(ctx context.Context, fetch FetchFunc, depth, workers int) *Prefetcher {
+	if workers <= 0 {
+		workers = 1
+	}
+	return &Prefetcher{
+		ctx:        ctx,
+		fetch:      fetch,
+		depth:      depth,
+		sem:        make(chan struct{}, workers),
+		next:       make(map[string][]string),
+		states:     make(map[string]fetchState),
+		requests:   atomic.NewUint64(0),
+		hits:       atomic.NewUint64(0),
+		late:       atomic.NewUint64(0),
+		misses:     atomic.NewUint64(0),
+		prefetched: atomic.NewUint64(0),
+		failed:     atomic.NewUint64(0),
+	}
+}
+
+// Index records the order of the segments of a media playlist and prefetches the first ones
+func (p *Prefetcher) Index(segments []string) {
+	if p.depth <= 0 || len(segments) == 0 {
+		return
+	}
+	p.lock.Lock()
+	if len(p.next)+len(segments) > maxEntries {
+		p.next = make(map[string][]string)
+	}
+	for i, seg := range segments {
+		end := i + 1 + p.depth
+		if end > len(segments) {
+			end = len(segments)
+		}
+		p.next[seg] = segments[i+1 : end]
+	}
+	p.lock.Unlock()
+	first := p.depth
+	if first > len(segments) {
+		first = len(segments)
+	}
+	p.prefetch(segments[:first])
+}
+
+// Served records the request of a segment and prefetches the following ones
+func (p *Prefetcher) Served(segment string) {
+	p.requests.Inc()
+	p.lock.Lock()
+	state := p.states[segment]
+	next := p.next[segment]
+	p.lock.Unlock()
+	switch state {
+	case stateFetched:
+		p.hits.Inc()
+	case stateFetching:
+		p.late.Inc()
+	default:
+		p.misses.Inc()
+	}
+	p.prefetch(next)
+}
+
+func (p *Prefetcher) prefetch(segments []string) {
+	for _, seg := range segments {
+		p.lock.Lock()
+		if _, ok := p.states[seg]; ok {
+			p.lock.Unlock()
+			continue
+		}
+		p.states[seg] = stateFetching
+		p.lock.Unlock()
+		go p.run(seg)
+	}
+}
+
+func (p *Prefetcher) run(seg string) {
+	select {
+	case p.sem <- struct{}{}:
+	case <-p.ctx.Done():
+		p.drop(seg)
+		return
+	}
+	defer func() {
+		<-p.sem
+	}()
+	ctx, cancel := context.WithTimeout(p.ctx, fetchTimeout)
+	defer cancel()
+	if err := p.fetch(ctx, seg); err != nil {
+		log.Debugw("prefetch segment", "segment", seg, "err", err)
+		p.failed.Inc()
+		p.drop(seg)
+		return
+	}
+	p.prefetched.Inc()
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	p.states[seg] = stateFetched
+	p.order = append(p.order, seg)
+	if len(p.order) > maxEntries {
+		for _, old := range p.order[:len(p.order)-maxEntries] {
+			delete(p.states, old)
+		}
+		p.order = append([]string(nil), p.order[len(p.order)-maxEntries:]...)
+	}
+}
+
+func (p *Prefetcher) drop(seg string) {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	delete(p.states, seg)
+}
+
+// Stats ...
+func (p *Prefetcher) Stats() Stats {
+	s := Stats{
+		Requests:   p.requests.Load(),
+		Hits:       p.hits.Load(),
+		Late:       p.late.Load(),
+		Misses:     p.misses.Load(),
+		Prefetched: p.prefetched.Load(),
+		Failed:     p.failed.Load(),
+	}
+	if s.Requests > 0 {
+		s.HitRate = float64(s.Hits) / float64(s.Requests)
+	}
+	return s
+}
@@ -0,0 +1,128 @@
+package stream
+
+import (
+	"context"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+const testMedia = `#EXTM3U
+#EXT-X-VERSION:3
+#EXT-X-TARGETDURATION:6
+#EXT-X-KEY:METHOD=AES-128,URI="key.bin"
+#EXTINF:6.0,
+00000.ts
+#EXTINF:6.0,
+00001.ts?v=1
+#EXTINF:6.0,
+http://cdn.example.com/00002.ts
+#EXT-X-ENDLIST
+`
+
+func TestPlaylist(t *testing.T) {
+	p, err := ParsePlaylist(strings.NewReader(testMedia))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if p.Master || len(p.Segments) != 3 {
+		t.Fatalf("wrong playlist %+v", p)
+	}
+	got := p.Rewrite(func(uri string) string {
+		return "/stream/Qm/" + Resolve("720p/index.m3u8", uri)
+	})
+	for _, want := range []string{
+		`URI="/stream/Qm/720p/key.bin"`,
+		"\n/stream/Qm/720p/00000.ts\n",
+		"\n/stream/Qm/720p/00001.ts\n",
+		"\nhttp://cdn.example.com/00002.ts\n",
+	} {
+		if !strings.Contains(got, want) {
+			t.Fatalf("rewritten playlist has no %q\n%s", want, got)
+		}
+	}
+	master, err := ParsePlaylist(strings.NewReader("#EXTM3U\n#EXT-X-STREAM-INF:BANDWIDTH=1\n720p/index.m3u8\n"))
+	if err != nil || !master.Master {
+		t.Fatalf("wrong master playlist %+v %v", master, err)
+	}
+	if !IsPlaylist("a/INDEX.M3U8") || IsPlaylist("a/0.ts") {
+		t.Fatal("wrong playlist ext check")
+	}
+}
+
+const testManifest = `<?xml version="1.0"?>
+<MPD xmlns="urn:mpeg:dash:schema:mpd:2011" type="static">
+  <Period>
+    <AdaptationSet mimeType="video/mp4">
+      <Representation id="720p" bandwidth="2000000">
+        <BaseURL>720p/</BaseURL>
+        <SegmentList duration="6">
+          <Initialization sourceURL="init.mp4"/>
+          <SegmentURL media="00000.m4s"/>
+          <SegmentURL media="00001.m4s"/>
+          <SegmentURL media="http://cdn.example.com/00002.m4s"/>
+        </SegmentList>
+      </Representation>
+      <Representation id="360p" bandwidth="800000">
+        <SegmentTemplate media="360p/$Number$.m4s" initialization="360p/init.mp4"/>
+      </Representation>
+    </AdaptationSet>
+  </Period>
+</MPD>
+`
+
+func TestManifest(t *testing.T) {
+	m, err := ParseManifest(strings.NewReader(testManifest))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{"720p/init.mp4", "720p/00000.m4s", "720p/00001.m4s", "http://cdn.example.com/00002.m4s"}
+	if strings.Join(m.Segments, ",") != strings.Join(want, ",") {
+		t.Fatalf("wrong segments %v", m.Segments)
+	}
+	if !IsManifest("a/Index.MPD") || IsManifest("a/0.m4s") {
+		t.Fatal("wrong manifest ext check")
+	}
+	if _, err := ParseManifest(strings.NewReader("<MPD><Period>")); err == nil {
+		t.Fatal("truncated manifest parsed")
+	}
+}
+
+func TestPrefetcher(t *testing.T) {
+	var lock sync.Mutex
+	fetched := make(map[string]int)
+	p := NewPrefetcher(context.Background(), func(ctx context.Context, path string) error {
+		lock.Lock()
+		defer lock.Unlock()
+		fetched[path]++
+		return nil
+	}, 2, 2)
+	wait := func(n int) {
+		for i := 0; i < 100; i++ {
+			if p.Stats().Prefetched == uint64(n) {
+				return
+			}
+			time.Sleep(10 * time.Millisecond)
+		}
+		t.Fatalf("want %d prefetched, got %+v", n, p.Stats())
+	}
+	p.Index([]string{"s0", "s1", "s2", "s3"})
+	wait(2)
+	p.Served("s0")
+	p.Served("s1")
+	wait(4)
+	p.Served("s3")
+	p.Served("unknown")
+	stats := p.Stats()
+	if stats.Requests != 4 || stats.Hits+stats.Late != 3 || stats.Misses != 1 {
+		t.Fatalf("wrong stats %+v", stats)
+	}
+	lock.Lock()
+	defer lock.Unlock()
+	for _, seg := range []string{"s0", "s1", "s2", "s3"} {
+		if fetched[seg] != 1 {
+			t.Fatalf("segment %s fetched %d times", seg, fetched[seg])
+		}
+	}
+}
@@ -0,0 +1,9 @@
+package supervisor
+
+import (
+	alog "github.com/glvd/accipfs/log"
+)
+
+const module = "supervisor"
+
+var log = alog.Module(module)
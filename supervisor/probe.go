@@ -0,0 +1,54 @@
+package supervisor
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+
+	"github.com/ethereum/go-ethereum/rpc"
+)
+
+// Probe checks if a running process is alive
+type Probe interface {
+	Probe(ctx context.Context) error
+}
+
+// ProbeFunc ...
+type ProbeFunc func(ctx context.Context) error
+
+// Probe ...
+func (f ProbeFunc) Probe(ctx context.Context) error {
+	return f(ctx)
+}
+
+// HTTPProbe succeeds when the url responds a 2xx status to the method
+func HTTPProbe(method, url string) Probe {
+	return ProbeFunc(func(ctx context.Context) error {
+		req, err := http.NewRequest(method, url, nil)
+		if err != nil {
+			return err
+		}
+		resp, err := http.DefaultClient.Do(req.WithContext(ctx))
+		if err != nil {
+			return err
+		}
+		defer resp.Body.Close()
+		if resp.StatusCode < 200 || resp.StatusCode > 299 {
+			return fmt.Errorf("probe %s: %s", url, resp.Status)
+		}
+		return nil
+	})
+}
+
+// RPCProbe succeeds when the JSON-RPC method called without params returns no error
+func RPCProbe(url, method string) Probe {
+	return ProbeFunc(func(ctx context.Context) error {
+		client, err := rpc.DialContext(ctx, url)
+		if err != nil {
+			return err
+		}
+		defer client.Close()
+		var result interface{}
+		return client.CallContext(ctx, &result, method)
+	})
+}
@@ -0,0 +1,398 @@
+package supervisor
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/glvd/accipfs/config"
+	"github.com/glvd/accipfs/core"
+)
+
+// Policy decides if an exited process is restarted
+type Policy string
+
+const (
+	// RestartAlways ...
+	RestartAlways Policy = "always"
+	// RestartOnFailure restarts when the process exits with an error or fails the liveness probe
+	RestartOnFailure Policy = "on-failure"
+	// RestartNever ...
+	RestartNever Policy = "never"
+)
+
+// process states
+const (
+	StateStarting = "starting"
+	StateRunning  = "running"
+	StateBackoff  = "backoff"
+	StateStopped  = "stopped"
+	StateExited   = "exited"
+	StateFailed   = "failed"
+)
+
+// ErrRunning ...
+var ErrRunning = errors.New("process is already running")
+
+// ErrProbeFailed ...
+var ErrProbeFailed = errors.New("liveness probe failed")
+
+// Spec describes how a process is run and supervised
+type Spec struct {
+	Name          string
+	Path          string
+	Args          []string
+	Env           []string //appended to the environment of the supervisor
+	VersionArgs   []string //run before the first start, the start fails when it fails
+	Restart       Policy
+	MinBackoff    time.Duration
+	MaxBackoff    time.Duration //a run longer than it resets the backoff
+	MaxRestarts   int           //0 means unlimited
+	Probe         Probe
+	ProbeInterval time.Duration //0 disables the probe
+	ProbeTimeout  time.Duration
+	ProbeFailures int //failed probes in a row before the process is killed
+	LogFile       string
+	LogMaxSize    int64
+	LogMaxFiles   int
+	Output        func(string) //called with every output line
+}
+
+// Configure sets the restart, probe and log settings from cfg
+func (s *Spec) Configure(cfg config.SuperviseConfig) {
+	s.Restart = Policy(cfg.Restart)
+	s.MinBackoff = cfg.MinBackoff * time.Second
+	s.MaxBackoff = cfg.MaxBackoff * time.Second
+	s.MaxRestarts = cfg.MaxRestarts
+	s.ProbeInterval = cfg.ProbeInterval * time.Second
+	s.ProbeTimeout = cfg.ProbeTimeout * time.Second
+	s.ProbeFailures = cfg.ProbeFailures
+	s.LogMaxSize = cfg.LogMaxSize << 20
+	s.LogMaxFiles = cfg.LogMaxFiles
+}
+
+// Process runs a command and restarts it by the policy with exponential backoff
+type Process struct {
+	spec   Spec
+	lock   sync.RWMutex
+	status core.ProcessStatus
+	cancel context.CancelFunc
+	done   chan struct{}
+	log    *RotateWriter
+	output io.Writer
+}
+
+// NewProcess ...
+func NewProcess(spec Spec) *Process {
+	if spec.Restart == "" {
+		spec.Restart = RestartAlways
+	}
+	if spec.MinBackoff <= 0 {
+		spec.MinBackoff = time.Second
+	}
+	if spec.MaxBackoff < spec.MinBackoff {
+		spec.MaxBackoff = spec.MinBackoff
+	}
+	if spec.ProbeFailures <= 0 {
+		spec.ProbeFailures = 1
+	}
+	return &Process{
+		spec: spec,
+		status: core.ProcessStatus{
+			Name:    spec.Name,
+			Path:    spec.Path,
+			State:   StateStopped,
+			LogFile: spec.LogFile,
+		},
+	}
+}
+
+// Name ...
+func (p *Process) Name() string {
+	return p.spec.Name
+}
+
+// Start checks the version and starts the process, it is supervised until Stop is called
+func (p *Process) Start() error {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	if p.done != nil {
+		return ErrRunning
+	}
+	if len(p.spec.VersionArgs) > 0 {
+		out, err := exec.Command(p.spec.Path, p.spec.VersionArgs...).CombinedOutput()
+		if err != nil {
+			return fmt.Errorf("%s version:%w", p.spec.Name, err)
+		}
+		line, _ := bufio.NewReader(bytes.NewReader(out)).ReadString('\n')
+		p.status.Version = strings.TrimSpace(line)
+	}
+	var writers []io.Writer
+	if p.spec.LogFile != "" {
+		w, err := NewRotateWriter(p.spec.LogFile, p.spec.LogMaxSize, p.spec.LogMaxFiles)
+		if err != nil {
+			return err
+		}
+		p.log = w
+		writers = append(writers, w)
+	}
+	if p.spec.Output != nil {
+		writers = append(writers, &lineWriter{f: p.spec.Output})
+	}
+	p.output = io.MultiWriter(writers...)
+	p.status.Restarts = 0
+	ctx, cancel := context.WithCancel(context.Background())
+	cmd, err := p.start(ctx)
+	if err != nil {
+		cancel()
+		p.closeLog()
+		return err
+	}
+	p.cancel = cancel
+	p.done = make(chan struct{})
+	go p.run(ctx, cmd, p.done)
+	return nil
+}
+
+// start runs the command, the caller must hold the lock
+func (p *Process) start(ctx context.Context) (*exec.Cmd, error) {
+	cmd := exec.CommandContext(ctx, p.spec.Path, p.spec.Args...)
+	if len(p.spec.Env) > 0 {
+		cmd.Env = append(os.Environ(), p.spec.Env...)
+	}
+	//the same writer makes the output written by one goroutine
+	cmd.Stdout = p.output
+	cmd.Stderr = p.output
+	p.status.State = StateStarting
+	if err := cmd.Start(); err != nil {
+		p.status.State = StateFailed
+		p.status.LastError = err.Error()
+		return nil, err
+	}
+	p.status.State = StateRunning
+	p.status.Pid = cmd.Process.Pid
+	p.status.Started = time.Now().Unix()
+	p.status.Healthy = p.spec.Probe == nil
+	log.Infow("process started", "name", p.spec.Name, "pid", p.status.Pid, "args", cmd.Args)
+	return cmd, nil
+}
+
+func (p *Process) run(ctx context.Context, cmd *exec.Cmd, done chan struct{}) {
+	defer close(done)
+	attempt := 0
+	for {
+		started := time.Now()
+		err := p.wait(ctx, cmd)
+		if ctx.Err() != nil {
+			p.stopped()
+			return
+		}
+		if !p.exited(err) {
+			return
+		}
+		if time.Since(started) >= p.spec.MaxBackoff {
+			attempt = 0
+		}
+		for {
+			if !p.backoff(ctx, attempt) {
+				p.stopped()
+				return
+			}
+			attempt++
+			p.lock.Lock()
+			cmd, err = p.start(ctx)
+			p.lock.Unlock()
+			if err == nil {
+				break
+			}
+			log.Errorw("process restart", "name", p.spec.Name, "err", err)
+			if !p.exited(err) {
+				return
+			}
+		}
+	}
+}
+
+// wait waits for the process to exit, it is killed when the liveness probe keeps failing
+func (p *Process) wait(ctx context.Context, cmd *exec.Cmd) error {
+	exit := make(chan error, 1)
+	go func() {
+		exit <- cmd.Wait()
+	}()
+	if p.spec.Probe == nil || p.spec.ProbeInterval <= 0 {
+		return <-exit
+	}
+	t := time.NewTicker(p.spec.ProbeInterval)
+	defer t.Stop()
+	failures := 0
+	for {
+		select {
+		case err := <-exit:
+			return err
+		case <-t.C:
+			err := p.probe(ctx)
+			p.lock.Lock()
+			p.status.Healthy = err == nil
+			if err != nil {
+				p.status.LastError = err.Error()
+			}
+			p.lock.Unlock()
+			if err == nil {
+				failures = 0
+				continue
+			}
+			failures++
+			log.Warnw("process probe", "name", p.spec.Name, "failures", failures, "err", err)
+			if failures >= p.spec.ProbeFailures {
+				_ = cmd.Process.Kill()
+				<-exit
+				return fmt.Errorf("%w:%v", ErrProbeFailed, err)
+			}
+		}
+	}
+}
+
+func (p *Process) probe(ctx context.Context) error {
+	if p.spec.ProbeTimeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, p.spec.ProbeTimeout)
+		defer cancel()
+	}
+	return p.spec.Probe.Probe(ctx)
+}
+
+// exited records the exit of the process and returns if it should be restarted
+func (p *Process) exited(err error) bool {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	p.status.Pid = 0
+	p.status.Healthy = false
+	p.status.ExitCode = exitCode(err)
+	if err != nil {
+		p.status.LastError = err.Error()
+		log.Warnw("process exited", "name", p.spec.Name, "err", err)
+	}
+	restart := p.spec.Restart == RestartAlways || (p.spec.Restart == RestartOnFailure && err != nil)
+	if restart && p.spec.MaxRestarts > 0 && p.status.Restarts >= p.spec.MaxRestarts {
+		restart = false
+	}
+	if !restart {
+		p.status.State = StateExited
+		if err != nil {
+			p.status.State = StateFailed
+		}
+		return false
+	}
+	p.status.Restarts++
+	p.status.State = StateBackoff
+	return true
+}
+
+// backoff waits before the restart, false is returned when the process is stopped
+func (p *Process) backoff(ctx context.Context, attempt int) bool {
+	delay := p.spec.MinBackoff
+	for i := 0; i < attempt && delay < p.spec.MaxBackoff; i++ {
+		delay *= 2
+	}
+	if delay > p.spec.MaxBackoff {
+		delay = p.spec.MaxBackoff
+	}
+	log.Infow("process restart backoff", "name", p.spec.Name, "delay", delay)
+	t := time.NewTimer(delay)
+	defer t.Stop()
+	select {
+	case <-ctx.Done():
+		return false
+	case <-t.C:
+		return true
+	}
+}
+
+func (p *Process) stopped() {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	p.status.State = StateStopped
+	p.status.Pid = 0
+	p.status.Healthy = false
+}
+
+func exitCode(err error) int {
+	if err == nil {
+		return 0
+	}
+	var exitErr *exec.ExitError
+	if errors.As(err, &exitErr) {
+		return exitErr.ExitCode()
+	}
+	return -1
+}
+
+// Stop kills the process and stops the supervision
+func (p *Process) Stop() error {
+	p.lock.Lock()
+	cancel, done := p.cancel, p.done
+	p.cancel, p.done = nil, nil
+	p.lock.Unlock()
+	if cancel == nil {
+		return nil
+	}
+	cancel()
+	<-done
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	return p.closeLog()
+}
+
+func (p *Process) closeLog() error {
+	if p.log == nil {
+		return nil
+	}
+	defer func() {
+		p.log = nil
+	}()
+	return p.log.Close()
+}
+
+// Done returns a channel closed when the supervision ends, nil is returned when not started
+func (p *Process) Done() <-chan struct{} {
+	p.lock.RLock()
+	defer p.lock.RUnlock()
+	return p.done
+}
+
+// Status ...
+func (p *Process) Status() core.ProcessStatus {
+	p.lock.RLock()
+	defer p.lock.RUnlock()
+	return p.status
+}
+
+// lineWriter calls f with every non empty line written
+type lineWriter struct {
+	f   func(string)
+	buf []byte
+}
+
+// Write ...
+func (w *lineWriter) Write(b []byte) (int, error) {
+	w.buf = append(w.buf, b...)
+	for {
+		i := bytes.IndexByte(w.buf, '\n')
+		if i < 0 {
+			break
+		}
+		if s := strings.TrimSpace(string(w.buf[:i])); s != "" {
+			w.f(s)
+		}
+		w.buf = w.buf[i+1:]
+	}
+	return len(b), nil
+}
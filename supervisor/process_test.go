@@ -0,0 +1,142 @@
+package supervisor
+
+import (
+	"context"
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func waitState(t *testing.T, p *Process, f func(s string) bool) {
+	deadline := time.Now().Add(5 * time.Second)
+	for time.Now().Before(deadline) {
+		if f(p.Status().State) {
+			return
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	t.Fatalf("wrong process status %+v", p.Status())
+}
+
+func TestProcess_Restart(t *testing.T) {
+	dir, err := ioutil.TempDir("", "supervisor")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	var lines []string
+	p := NewProcess(Spec{
+		Name:        "crash",
+		Path:        "/bin/sh",
+		Args:        []string{"-c", "echo started; exit 3"},
+		VersionArgs: []string{"-c", "echo sh 1.0"},
+		Restart:     RestartOnFailure,
+		MinBackoff:  10 * time.Millisecond,
+		MaxBackoff:  40 * time.Millisecond,
+		MaxRestarts: 3,
+		LogFile:     filepath.Join(dir, "crash.log"),
+		Output: func(s string) {
+			lines = append(lines, s)
+		},
+	})
+	if err := p.Start(); err != nil {
+		t.Fatal(err)
+	}
+	<-p.Done()
+	status := p.Status()
+	if status.State != StateFailed || status.Restarts != 3 || status.ExitCode != 3 || status.Version != "sh 1.0" {
+		t.Fatalf("wrong process status %+v", status)
+	}
+	if err := p.Stop(); err != nil {
+		t.Fatal(err)
+	}
+	if len(lines) != 4 {
+		t.Fatalf("want 4 output lines, got %v", lines)
+	}
+	b, err := ioutil.ReadFile(filepath.Join(dir, "crash.log"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Count(string(b), "started") != 4 {
+		t.Fatalf("wrong log file %q", b)
+	}
+}
+
+func TestProcess_Policy(t *testing.T) {
+	p := NewProcess(Spec{
+		Name:       "once",
+		Path:       "/bin/sh",
+		Args:       []string{"-c", "exit 0"},
+		Restart:    RestartOnFailure,
+		MinBackoff: 10 * time.Millisecond,
+	})
+	if err := p.Start(); err != nil {
+		t.Fatal(err)
+	}
+	<-p.Done()
+	if status := p.Status(); status.State != StateExited || status.Restarts != 0 {
+		t.Fatalf("wrong process status %+v", status)
+	}
+	p.Stop()
+
+	p = NewProcess(Spec{Name: "missing", Path: "/bin/sh", VersionArgs: []string{"-c", "exit 1"}})
+	if err := p.Start(); err == nil {
+		t.Fatal("start should fail on the version check")
+	}
+}
+
+func TestProcess_Probe(t *testing.T) {
+	probe := ProbeFunc(func(ctx context.Context) error {
+		return errors.New("not alive")
+	})
+	p := NewProcess(Spec{
+		Name:          "hang",
+		Path:          "/bin/sh",
+		Args:          []string{"-c", "exec sleep 10"},
+		Restart:       RestartOnFailure,
+		MinBackoff:    10 * time.Millisecond,
+		MaxRestarts:   1,
+		Probe:         probe,
+		ProbeInterval: 10 * time.Millisecond,
+		ProbeFailures: 2,
+	})
+	if err := p.Start(); err != nil {
+		t.Fatal(err)
+	}
+	<-p.Done()
+	status := p.Status()
+	if status.State != StateFailed || status.Restarts != 1 || !strings.Contains(status.LastError, ErrProbeFailed.Error()) {
+		t.Fatalf("wrong process status %+v", status)
+	}
+	p.Stop()
+}
+
+func TestProcess_Stop(t *testing.T) {
+	p := NewProcess(Spec{
+		Name: "sleep",
+		Path: "/bin/sh",
+		Args: []string{"-c", "exec sleep 10"},
+	})
+	if err := p.Start(); err != nil {
+		t.Fatal(err)
+	}
+	waitState(t, p, func(s string) bool { return s == StateRunning })
+	if err := p.Start(); err != ErrRunning {
+		t.Fatalf("want ErrRunning, got %v", err)
+	}
+	if err := p.Stop(); err != nil {
+		t.Fatal(err)
+	}
+	if status := p.Status(); status.State != StateStopped || status.Pid != 0 {
+		t.Fatalf("wrong process status %+v", status)
+	}
+	s := New()
+	s.Add(p)
+	if list := s.Status(); len(list) != 1 || list[0].Name != "sleep" {
+		t.Fatalf("wrong supervisor status %+v", list)
+	}
+}
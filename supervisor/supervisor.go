@@ -0,0 +1,67 @@
+package supervisor
+
+import (
+	"sync"
+
+	"github.com/glvd/accipfs/core"
+)
+
+// Supervisor keeps the managed processes
+type Supervisor struct {
+	lock  sync.RWMutex
+	procs []*Process
+}
+
+// New ...
+func New() *Supervisor {
+	return &Supervisor{}
+}
+
+// Add registers the process, a process with the same name is replaced
+func (s *Supervisor) Add(p *Process) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	for i := range s.procs {
+		if s.procs[i].Name() == p.Name() {
+			s.procs[i] = p
+			return
+		}
+	}
+	s.procs = append(s.procs, p)
+}
+
+// Get ...
+func (s *Supervisor) Get(name string) *Process {
+	s.lock.RLock()
+	defer s.lock.RUnlock()
+	for _, p := range s.procs {
+		if p.Name() == name {
+			return p
+		}
+	}
+	return nil
+}
+
+// Status returns the status of every managed process
+func (s *Supervisor) Status() []core.ProcessStatus {
+	s.lock.RLock()
+	defer s.lock.RUnlock()
+	status := make([]core.ProcessStatus, 0, len(s.procs))
+	for _, p := range s.procs {
+		status = append(status, p.Status())
+	}
+	return status
+}
+
+// Stop stops all the processes
+func (s *Supervisor) Stop() (e error) {
+	s.lock.RLock()
+	defer s.lock.RUnlock()
+	for _, p := range s.procs {
+		if err := p.Stop(); err != nil {
+			log.Errorw("stop process", "name", p.Name(), "err", err)
+			e = err
+		}
+	}
+	return
+}
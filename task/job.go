@@ -0,0 +1,135 @@
+package task
+
+import (
+	"context"
+	"errors"
+	"time"
+)
+
+// Priorities of the jobs, the jobs of the same priority run in the pushed order
+const (
+	PriorityLow    = -10
+	PriorityNormal = 0
+	PriorityHigh   = 10
+)
+
+// State ...
+type State string
+
+// States of a job, the finished jobs are done, failed or canceled
+const (
+	StatePending  State = "pending"
+	StateRunning  State = "running"
+	StateRetrying State = "retrying"
+	StateDone     State = "done"
+	StateFailed   State = "failed"
+	StateCanceled State = "canceled"
+)
+
+// Finished ...
+func (s State) Finished() bool {
+	return s == StateDone || s == StateFailed || s == StateCanceled
+}
+
+// Handler runs a job of a kind, ctx is canceled when the job is canceled or the queue stops
+type Handler func(ctx context.Context, job *Job) error
+
+// Job ...
+type Job struct {
+	ID         string `json:"id"`
+	Kind       string `json:"kind"`
+	Payload    []byte `json:"payload,omitempty"`
+	Priority   int    `json:"priority"`
+	MaxRetries int    `json:"max_retries"` //0 uses the retries of the queue, negative never retries
+	Attempts   int    `json:"attempts"`
+	State      State  `json:"state"`
+	Error      string `json:"error,omitempty"`
+	Created    int64  `json:"created"`
+	Updated    int64  `json:"updated"`
+	NotBefore  int64  `json:"not_before,omitempty"` //unix nano of the next retry
+
+	seq    uint64
+	index  int
+	cancel context.CancelFunc
+	timer  *time.Timer
+}
+
+// copy returns the exported fields of the job
+func (j *Job) copy() *Job {
+	return &Job{
+		ID:         j.ID,
+		Kind:       j.Kind,
+		Payload:    j.Payload,
+		Priority:   j.Priority,
+		MaxRetries: j.MaxRetries,
+		Attempts:   j.Attempts,
+		State:      j.State,
+		Error:      j.Error,
+		Created:    j.Created,
+		Updated:    j.Updated,
+		NotBefore:  j.NotBefore,
+	}
+}
+
+// permanentError is an error which should not be retried
+type permanentError struct {
+	err error
+}
+
+func (e *permanentError) Error() string {
+	return e.err.Error()
+}
+
+func (e *permanentError) Unwrap() error {
+	return e.err
+}
+
+// Permanent marks the error of a handler to fail the job without retries
+func Permanent(err error) error {
+	if err == nil {
+		return nil
+	}
+	return &permanentError{err: err}
+}
+
+// IsPermanent ...
+func IsPermanent(err error) bool {
+	var p *permanentError
+	return errors.As(err, &p)
+}
+
+// jobHeap orders the pending jobs by the priority and then by the pushed order
+type jobHeap []*Job
+
+func (h jobHeap) Len() int {
+	return len(h)
+}
+
+func (h jobHeap) Less(i, j int) bool {
+	if h[i].Priority != h[j].Priority {
+		return h[i].Priority > h[j].Priority
+	}
+	return h[i].seq < h[j].seq
+}
+
+func (h jobHeap) Swap(i, j int) {
+	h[i], h[j] = h[j], h[i]
+	h[i].index = i
+	h[j].index = j
+}
+
+func (h *jobHeap) Push(x interface{}) {
+	job := x.(*Job)
+	job.index = len(*h)
+	*h = append(*h, job)
+}
+
+func (h *jobHeap) Pop() interface{} {
+	old := *h
+	n := len(old)
+	job := old[n-1]
+	old[n-1] = nil
+	job.index = -1
+	*h = old[:n-1]
+	return job
+}
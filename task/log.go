@@ -0,0 +1,9 @@
+package task
+
+import (
+	alog "github.com/glvd/accipfs/log"
+)
+
+const module = "task"
+
+var log = alog.Module(module)
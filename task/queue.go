@@ -0,0 +1,404 @@
+package task
+
+import (
+	"container/heap"
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+)
+
+// ErrUnknownKind ...
+var ErrUnknownKind = errors.New("no handler of the job kind")
+
+// ErrNotFound ...
+var ErrNotFound = errors.New("job not found")
+
+// Options ...
+type Options struct {
+	Workers    int           //count of the jobs running at once
+	MaxRetries int           //default retries of a failed job
+	Backoff    time.Duration //delay of the first retry, doubled on every retry
+	MaxBackoff time.Duration
+	History    int //count of the finished jobs kept for the status
+}
+
+// DefaultOptions ...
+func DefaultOptions() Options {
+	return Options{
+		Workers:    4,
+		MaxRetries: 3,
+		Backoff:    5 * time.Second,
+		MaxBackoff: 10 * time.Minute,
+		History:    100,
+	}
+}
+
+// Queue runs the pushed jobs on a bounded count of workers by the priority,
+// the unfinished jobs are kept in the store when it is set
+type Queue struct {
+	opts     Options
+	store    *Store
+	lock     sync.Mutex
+	cond     *sync.Cond
+	seq      uint64
+	pending  jobHeap
+	jobs     map[string]*Job
+	history  []*Job
+	handlers map[string]Handler
+	onFinish func(job *Job)
+}
+
+// NewQueue returns a queue loading the unfinished jobs of the store, store can be nil to keep the jobs in memory only
+func NewQueue(opts Options, store *Store) (*Queue, error) {
+	def := DefaultOptions()
+	if opts.Workers <= 0 {
+		opts.Workers = def.Workers
+	}
+	if opts.Backoff <= 0 {
+		opts.Backoff = def.Backoff
+	}
+	if opts.MaxBackoff < opts.Backoff {
+		opts.MaxBackoff = opts.Backoff
+	}
+	if opts.History < 0 {
+		opts.History = 0
+	}
+	q := &Queue{
+		opts:     opts,
+		store:    store,
+		jobs:     make(map[string]*Job),
+		handlers: make(map[string]Handler),
+	}
+	q.cond = sync.NewCond(&q.lock)
+	if store == nil {
+		return q, nil
+	}
+	jobs, err := store.Jobs()
+	if err != nil {
+		return nil, err
+	}
+	sort.Slice(jobs, func(i, j int) bool {
+		if jobs[i].Created != jobs[j].Created {
+			return jobs[i].Created < jobs[j].Created
+		}
+		return jobs[i].ID < jobs[j].ID
+	})
+	q.lock.Lock()
+	defer q.lock.Unlock()
+	for _, job := range jobs {
+		q.seq++
+		job.seq = q.seq
+		q.jobs[job.ID] = job
+		if job.State == StateRetrying {
+			q.retry(job, time.Until(time.Unix(0, job.NotBefore)))
+			continue
+		}
+		//the job interrupted by the last stop runs again
+		job.State = StatePending
+		heap.Push(&q.pending, job)
+	}
+	return q, nil
+}
+
+// Register sets the handler of a job kind
+func (q *Queue) Register(kind string, handler Handler) {
+	q.lock.Lock()
+	defer q.lock.Unlock()
+	q.handlers[kind] = handler
+}
+
+// OnFinish registers a callback called with every job done, failed or canceled,
+// it is called with the lock of the queue held and must not call the queue
+func (q *Queue) OnFinish(f func(job *Job)) {
+	q.lock.Lock()
+	defer q.lock.Unlock()
+	q.onFinish = f
+}
+
+// Push adds a job of a registered kind and returns its id
+func (q *Queue) Push(job *Job) (string, error) {
+	q.lock.Lock()
+	defer q.lock.Unlock()
+	if _, ok := q.handlers[job.Kind]; !ok {
+		return "", fmt.Errorf("%w(%s)", ErrUnknownKind, job.Kind)
+	}
+	now := time.Now()
+	q.seq++
+	job.seq = q.seq
+	if job.ID == "" {
+		job.ID = fmt.Sprintf("%d-%d", now.UnixNano(), q.seq)
+	}
+	if _, ok := q.jobs[job.ID]; ok {
+		return "", fmt.Errorf("job(%s) exists", job.ID)
+	}
+	job.State = StatePending
+	job.Attempts = 0
+	job.Error = ""
+	job.Created = now.UnixNano()
+	job.Updated = job.Created
+	if err := q.persist(job); err != nil {
+		return "", err
+	}
+	q.jobs[job.ID] = job
+	heap.Push(&q.pending, job)
+	q.cond.Signal()
+	return job.ID, nil
+}
+
+// PushKind adds a job of the kind with the payload and the priority
+func (q *Queue) PushKind(kind string, payload []byte, priority int) (string, error) {
+	return q.Push(&Job{
+		Kind:     kind,
+		Payload:  payload,
+		Priority: priority,
+	})
+}
+
+// Cancel cancels a pending, retrying or running job
+func (q *Queue) Cancel(id string) error {
+	q.lock.Lock()
+	defer q.lock.Unlock()
+	job, ok := q.jobs[id]
+	if !ok {
+		return ErrNotFound
+	}
+	switch job.State {
+	case StatePending:
+		heap.Remove(&q.pending, job.index)
+	case StateRetrying:
+		job.timer.Stop()
+	case StateRunning:
+		//the worker finishes the job once the handler returns
+		job.State = StateCanceled
+		job.cancel()
+		return nil
+	}
+	job.State = StateCanceled
+	q.finish(job)
+	return nil
+}
+
+// Jobs returns the unfinished jobs and the recent finished ones in the pushed order,
+// all the states are returned when state is empty
+func (q *Queue) Jobs(state State) []*Job {
+	q.lock.Lock()
+	defer q.lock.Unlock()
+	var jobs []*Job
+	for _, job := range q.history {
+		if state == "" || job.State == state {
+			jobs = append(jobs, job.copy())
+		}
+	}
+	for _, job := range q.jobs {
+		if state == "" || job.State == state {
+			jobs = append(jobs, job.copy())
+		}
+	}
+	sort.Slice(jobs, func(i, j int) bool {
+		return jobs[i].Created < jobs[j].Created
+	})
+	return jobs
+}
+
+// Len returns the count of the unfinished jobs
+func (q *Queue) Len() int {
+	q.lock.Lock()
+	defer q.lock.Unlock()
+	return len(q.jobs)
+}
+
+// Run runs the jobs until ctx is done, the running jobs are canceled and kept pending in the store
+func (q *Queue) Run(ctx context.Context) {
+	stop := make(chan struct{})
+	defer close(stop)
+	go func() {
+		select {
+		case <-ctx.Done():
+			q.lock.Lock()
+			q.cond.Broadcast()
+			q.lock.Unlock()
+		case <-stop:
+		}
+	}()
+	var wg sync.WaitGroup
+	for i := 0; i < q.opts.Workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for {
+				job, jctx := q.next(ctx)
+				if job == nil {
+					return
+				}
+				q.process(ctx, job, jctx)
+			}
+		}()
+	}
+	wg.Wait()
+}
+
+// Wait blocks until all the jobs are finished or ctx is done
+func (q *Queue) Wait(ctx context.Context) error {
+	stop := make(chan struct{})
+	defer close(stop)
+	go func() {
+		select {
+		case <-ctx.Done():
+			q.lock.Lock()
+			q.cond.Broadcast()
+			q.lock.Unlock()
+		case <-stop:
+		}
+	}()
+	q.lock.Lock()
+	defer q.lock.Unlock()
+	for len(q.jobs) > 0 {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		q.cond.Wait()
+	}
+	return nil
+}
+
+func (q *Queue) next(ctx context.Context) (*Job, context.Context) {
+	q.lock.Lock()
+	defer q.lock.Unlock()
+	for q.pending.Len() == 0 {
+		if ctx.Err() != nil {
+			return nil, nil
+		}
+		q.cond.Wait()
+	}
+	if ctx.Err() != nil {
+		return nil, nil
+	}
+	job := heap.Pop(&q.pending).(*Job)
+	jctx, cancel := context.WithCancel(ctx)
+	job.cancel = cancel
+	job.State = StateRunning
+	job.Attempts++
+	job.Updated = time.Now().UnixNano()
+	if err := q.persist(job); err != nil {
+		log.Errorw("persist job", "id", job.ID, "err", err)
+	}
+	return job, jctx
+}
+
+func (q *Queue) process(ctx context.Context, job *Job, jctx context.Context) {
+	q.lock.Lock()
+	handler, ok := q.handlers[job.Kind]
+	view := job.copy()
+	q.lock.Unlock()
+	var err error
+	if ok {
+		err = handler(jctx, view)
+	} else {
+		err = Permanent(fmt.Errorf("%w(%s)", ErrUnknownKind, job.Kind))
+	}
+
+	q.lock.Lock()
+	defer q.lock.Unlock()
+	job.cancel()
+	job.Updated = time.Now().UnixNano()
+	if err != nil {
+		job.Error = err.Error()
+	}
+	switch {
+	case job.State == StateCanceled:
+	case err == nil:
+		job.State = StateDone
+		job.Error = ""
+	case ctx.Err() != nil:
+		//the queue is stopped, the job runs again on the next run
+		job.Attempts--
+		job.State = StatePending
+		heap.Push(&q.pending, job)
+		if err := q.persist(job); err != nil {
+			log.Errorw("persist job", "id", job.ID, "err", err)
+		}
+		return
+	case IsPermanent(err) || job.Attempts > q.retries(job):
+		job.State = StateFailed
+	default:
+		log.Debugw("retry job", "id", job.ID, "kind", job.Kind, "attempts", job.Attempts, "err", err)
+		q.retry(job, q.backoff(job.Attempts))
+		if err := q.persist(job); err != nil {
+			log.Errorw("persist job", "id", job.ID, "err", err)
+		}
+		return
+	}
+	q.finish(job)
+}
+
+func (q *Queue) retries(job *Job) int {
+	if job.MaxRetries < 0 {
+		return 0
+	}
+	if job.MaxRetries == 0 {
+		return q.opts.MaxRetries
+	}
+	return job.MaxRetries
+}
+
+// backoff returns the delay before the retry after the attempts
+func (q *Queue) backoff(attempts int) time.Duration {
+	delay := q.opts.Backoff
+	for i := 1; i < attempts && delay < q.opts.MaxBackoff; i++ {
+		delay *= 2
+	}
+	if delay > q.opts.MaxBackoff {
+		delay = q.opts.MaxBackoff
+	}
+	return delay
+}
+
+// retry pushes the job back to the pending jobs after the delay, it is called with the lock held
+func (q *Queue) retry(job *Job, delay time.Duration) {
+	if delay < 0 {
+		delay = 0
+	}
+	job.State = StateRetrying
+	job.NotBefore = time.Now().Add(delay).UnixNano()
+	job.timer = time.AfterFunc(delay, func() {
+		q.lock.Lock()
+		defer q.lock.Unlock()
+		if job.State != StateRetrying {
+			return
+		}
+		job.State = StatePending
+		job.NotBefore = 0
+		heap.Push(&q.pending, job)
+		q.cond.Signal()
+	})
+}
+
+// finish moves a finished job to the history, it is called with the lock held
+func (q *Queue) finish(job *Job) {
+	delete(q.jobs, job.ID)
+	if q.store != nil {
+		if err := q.store.Delete(job.ID); err != nil {
+			log.Errorw("delete job", "id", job.ID, "err", err)
+		}
+	}
+	if q.opts.History > 0 {
+		q.history = append(q.history, job)
+		if len(q.history) > q.opts.History {
+			q.history = q.history[len(q.history)-q.opts.History:]
+		}
+	}
+	if q.onFinish != nil {
+		q.onFinish(job.copy())
+	}
+	q.cond.Broadcast()
+}
+
+func (q *Queue) persist(job *Job) error {
+	if q.store == nil {
+		return nil
+	}
+	return q.store.Put(job)
+}
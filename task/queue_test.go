@@ -0,0 +1,196 @@
+package task
+
+import (
+	"context"
+	"errors"
+	"io/ioutil"
+	"os"
+	"sync"
+	"testing"
+	"time"
+)
+
+func testOptions() Options {
+	return Options{
+		Workers:    1,
+		MaxRetries: 2,
+		Backoff:    time.Millisecond,
+		MaxBackoff: 4 * time.Millisecond,
+		History:    10,
+	}
+}
+
+func TestQueue_Priority(t *testing.T) {
+	q, err := NewQueue(testOptions(), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var lock sync.Mutex
+	var order []string
+	q.Register("test", func(ctx context.Context, job *Job) error {
+		lock.Lock()
+		defer lock.Unlock()
+		order = append(order, string(job.Payload))
+		return nil
+	})
+	for _, v := range []struct {
+		payload  string
+		priority int
+	}{{"low", PriorityLow}, {"normal1", PriorityNormal}, {"high", PriorityHigh}, {"normal2", PriorityNormal}} {
+		if _, err := q.PushKind("test", []byte(v.payload), v.priority); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if _, err := q.PushKind("unknown", nil, PriorityNormal); !errors.Is(err, ErrUnknownKind) {
+		t.Fatalf("want ErrUnknownKind, got %v", err)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go q.Run(ctx)
+	if err := q.Wait(ctx); err != nil {
+		t.Fatal(err)
+	}
+	want := []string{"high", "normal1", "normal2", "low"}
+	for i := range want {
+		if order[i] != want[i] {
+			t.Fatalf("want order %v, got %v", want, order)
+		}
+	}
+	if jobs := q.Jobs(StateDone); len(jobs) != 4 {
+		t.Fatalf("want 4 done jobs, got %d", len(jobs))
+	}
+}
+
+func TestQueue_Retry(t *testing.T) {
+	q, err := NewQueue(testOptions(), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	q.Register("flaky", func(ctx context.Context, job *Job) error {
+		if job.Attempts < 3 {
+			return errors.New("flaky")
+		}
+		return nil
+	})
+	q.Register("broken", func(ctx context.Context, job *Job) error {
+		return errors.New("broken")
+	})
+	q.Register("permanent", func(ctx context.Context, job *Job) error {
+		return Permanent(errors.New("permanent"))
+	})
+	flaky, _ := q.PushKind("flaky", nil, PriorityNormal)
+	broken, _ := q.PushKind("broken", nil, PriorityNormal)
+	permanent, _ := q.PushKind("permanent", nil, PriorityNormal)
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	go q.Run(ctx)
+	if err := q.Wait(ctx); err != nil {
+		t.Fatal(err)
+	}
+	states := make(map[string]*Job)
+	for _, job := range q.Jobs("") {
+		states[job.ID] = job
+	}
+	if job := states[flaky]; job.State != StateDone || job.Attempts != 3 {
+		t.Fatalf("flaky job should be done on the third attempt %+v", job)
+	}
+	if job := states[broken]; job.State != StateFailed || job.Attempts != 3 || job.Error != "broken" {
+		t.Fatalf("broken job should fail after 2 retries %+v", job)
+	}
+	if job := states[permanent]; job.State != StateFailed || job.Attempts != 1 {
+		t.Fatalf("permanent error should not be retried %+v", job)
+	}
+}
+
+func TestQueue_Cancel(t *testing.T) {
+	q, err := NewQueue(testOptions(), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	started := make(chan struct{})
+	q.Register("block", func(ctx context.Context, job *Job) error {
+		close(started)
+		<-ctx.Done()
+		return ctx.Err()
+	})
+	q.Register("test", func(ctx context.Context, job *Job) error {
+		return nil
+	})
+	running, _ := q.PushKind("block", nil, PriorityHigh)
+	pending, _ := q.PushKind("test", nil, PriorityNormal)
+	if err := q.Cancel(pending); err != nil {
+		t.Fatal(err)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	go q.Run(ctx)
+	<-started
+	if err := q.Cancel(running); err != nil {
+		t.Fatal(err)
+	}
+	if err := q.Wait(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if jobs := q.Jobs(StateCanceled); len(jobs) != 2 {
+		t.Fatalf("want 2 canceled jobs, got %+v", jobs)
+	}
+	if err := q.Cancel("none"); err != ErrNotFound {
+		t.Fatalf("want ErrNotFound, got %v", err)
+	}
+}
+
+func TestQueue_Persist(t *testing.T) {
+	dir, err := ioutil.TempDir("", "task")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	store, err := OpenStore(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	q, err := NewQueue(testOptions(), store)
+	if err != nil {
+		t.Fatal(err)
+	}
+	q.Register("test", func(ctx context.Context, job *Job) error {
+		return nil
+	})
+	for _, payload := range []string{"a", "b"} {
+		if _, err := q.PushKind("test", []byte(payload), PriorityNormal); err != nil {
+			t.Fatal(err)
+		}
+	}
+	store.Close()
+
+	store, err = OpenStore(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer store.Close()
+	q, err = NewQueue(testOptions(), store)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var payloads []string
+	q.Register("test", func(ctx context.Context, job *Job) error {
+		payloads = append(payloads, string(job.Payload))
+		return nil
+	})
+	if q.Len() != 2 {
+		t.Fatalf("want 2 restored jobs, got %d", q.Len())
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	go q.Run(ctx)
+	if err := q.Wait(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if len(payloads) != 2 || payloads[0] != "a" || payloads[1] != "b" {
+		t.Fatalf("restored jobs should run in the pushed order %v", payloads)
+	}
+	jobs, err := store.Jobs()
+	if err != nil || len(jobs) != 0 {
+		t.Fatalf("finished jobs should be removed from the store %d %v", len(jobs), err)
+	}
+}
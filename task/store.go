@@ -0,0 +1,71 @@
+package task
+
+import (
+	"encoding/json"
+
+	"github.com/dgraph-io/badger/v2"
+	"github.com/glvd/accipfs/basis/kv"
+)
+
+const jobPrefix = "job/"
+
+// Store keeps the unfinished jobs to resume them after a restart
+type Store struct {
+	db *badger.DB
+}
+
+// OpenStore ...
+func OpenStore(path string) (*Store, error) {
+	db, err := kv.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	return &Store{db: db}, nil
+}
+
+// Put ...
+func (s *Store) Put(job *Job) error {
+	v, err := json.Marshal(job)
+	if err != nil {
+		return err
+	}
+	return s.db.Update(func(txn *badger.Txn) error {
+		return txn.Set([]byte(jobPrefix+job.ID), v)
+	})
+}
+
+// Delete ...
+func (s *Store) Delete(id string) error {
+	return s.db.Update(func(txn *badger.Txn) error {
+		return txn.Delete([]byte(jobPrefix + id))
+	})
+}
+
+// Jobs returns the stored jobs
+func (s *Store) Jobs() ([]*Job, error) {
+	var jobs []*Job
+	err := s.db.View(func(txn *badger.Txn) error {
+		opts := badger.DefaultIteratorOptions
+		opts.Prefix = []byte(jobPrefix)
+		it := txn.NewIterator(opts)
+		defer it.Close()
+		for it.Rewind(); it.Valid(); it.Next() {
+			v, err := it.Item().ValueCopy(nil)
+			if err != nil {
+				return err
+			}
+			var job Job
+			if err := json.Unmarshal(v, &job); err != nil {
+				return err
+			}
+			jobs = append(jobs, &job)
+		}
+		return nil
+	})
+	return jobs, err
+}
+
+// Close ...
+func (s *Store) Close() error {
+	return s.db.Close()
+}